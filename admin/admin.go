@@ -2,15 +2,19 @@ package admin
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
-	"strings"
 	"time"
 
+	"burma2d/config"
+	"burma2d/proxy"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -21,6 +25,11 @@ func InitDB(database *sql.DB) {
 	db = database
 }
 
+// getUploadsDir returns the local uploads directory (UPLOADS_PATH, default ./uploads)
+func getUploadsDir() string {
+	return config.String("UPLOADS_PATH", "./uploads")
+}
+
 // AdminDashboardHandler renders the admin dashboard home
 func AdminDashboardHandler(c *gin.Context) {
 	c.HTML(200, "dashboard.html", gin.H{
@@ -117,25 +126,34 @@ func GetSliderByIDHandler(c *gin.Context) {
 	}
 
 	query := `
-		SELECT id, image_link, forward_link, title, order_num, is_active, created_at
+		SELECT id, image_link, forward_link, title, order_num, is_active, start_at, end_at, created_at
 		FROM sliders WHERE id = $1
 	`
 	var slider struct {
-		ID          int    `json:"id"`
-		ImageLink   string `json:"image_link"`
-		ForwardLink string `json:"forward_link"`
-		Title       string `json:"title"`
-		Order       int    `json:"order"`
-		IsActive    bool   `json:"is_active"`
-		CreatedAt   string `json:"created_at"`
-	}
-
+		ID          int        `json:"id"`
+		ImageLink   string     `json:"image_link"`
+		ForwardLink string     `json:"forward_link"`
+		Title       string     `json:"title"`
+		Order       int        `json:"order"`
+		IsActive    bool       `json:"is_active"`
+		StartAt     *time.Time `json:"start_at"`
+		EndAt       *time.Time `json:"end_at"`
+		CreatedAt   string     `json:"created_at"`
+	}
+
+	var startAt, endAt sql.NullTime
 	err = db.QueryRow(query, id).Scan(&slider.ID, &slider.ImageLink, &slider.ForwardLink,
-		&slider.Title, &slider.Order, &slider.IsActive, &slider.CreatedAt)
+		&slider.Title, &slider.Order, &slider.IsActive, &startAt, &endAt, &slider.CreatedAt)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Slider not found"})
 		return
 	}
+	if startAt.Valid {
+		slider.StartAt = &startAt.Time
+	}
+	if endAt.Valid {
+		slider.EndAt = &endAt.Time
+	}
 
 	c.JSON(http.StatusOK, slider)
 }
@@ -143,18 +161,138 @@ func GetSliderByIDHandler(c *gin.Context) {
 // UploadImageHandler handles image uploads and returns the file path
 // Supports both local storage and Cloudflare R2 (controlled by USE_R2 env var)
 func UploadImageHandler(c *gin.Context) {
+	// Stop reading oversized bodies instead of spooling them to disk
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxUploadBytes+multipartOverhead)
+
 	// Get the file from form data
 	file, err := c.FormFile("image")
 	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			uploadTooLarge(c)
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": "No image file provided"})
 		return
 	}
 
+	image, uerr := storeImage(c, file)
+	if uerr != nil {
+		if uerr.status == http.StatusRequestEntityTooLarge {
+			uploadTooLarge(c)
+			return
+		}
+		c.JSON(uerr.status, gin.H{"error": uerr.message})
+		return
+	}
+
+	response := gin.H{
+		"success":   true,
+		"image_url": image.ImageURL,
+		"filename":  image.Filename,
+	}
+	if image.Storage == "r2" {
+		response["storage"] = "r2"
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// BatchUploadImagesHandler saves several images from the "images" form
+// field. Each file is validated and stored independently; failures are
+// reported per file without aborting the rest of the batch.
+func BatchUploadImagesHandler(c *gin.Context) {
+	maxFiles := config.Int("UPLOAD_MAX_FILES", 20)
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, int64(maxFiles)*(maxUploadBytes+multipartOverhead))
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": fmt.Sprintf("Batch too large. Max %d files of %s each", maxFiles, uploadLimitText()),
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid multipart form"})
+		return
+	}
+
+	files := form.File["images"]
+	if len(files) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No image files provided"})
+		return
+	}
+	if len(files) > maxFiles {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Too many files: %d (max %d)", len(files), maxFiles)})
+		return
+	}
+
+	type fileError struct {
+		Filename string `json:"filename"`
+		Error    string `json:"error"`
+	}
+
+	images := []storedImage{}
+	failed := []fileError{}
+	seen := make(map[string]bool, len(files))
+	for _, file := range files {
+		// Stored names are "<unix timestamp>_<name>", so repeats in one batch would collide
+		name := filepath.Base(file.Filename)
+		if seen[name] {
+			failed = append(failed, fileError{Filename: file.Filename, Error: "Duplicate file name in batch"})
+			continue
+		}
+		seen[name] = true
+
+		image, uerr := storeImage(c, file)
+		if uerr != nil {
+			failed = append(failed, fileError{Filename: file.Filename, Error: uerr.message})
+			continue
+		}
+		images = append(images, image)
+	}
+
+	log.Printf("📸 Batch upload: %d saved, %d failed", len(images), len(failed))
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": len(failed) == 0,
+		"images":  images,
+		"errors":  failed,
+	})
+}
+
+// storedImage describes a saved upload
+type storedImage struct {
+	Filename     string `json:"filename"`
+	ImageURL     string `json:"image_url"`
+	ThumbnailURL string `json:"thumbnail_url"` // Same as image_url until thumbnails are generated
+	Storage      string `json:"storage"`       // "r2" or "local"
+}
+
+// uploadError is a rejected or failed upload with the status to report
+type uploadError struct {
+	status  int
+	message string
+}
+
+// storeImage validates one uploaded image (size, extension, content) and
+// saves it to R2 when enabled, otherwise to the local uploads directory
+func storeImage(c *gin.Context, file *multipart.FileHeader) (storedImage, *uploadError) {
+	if err := checkUploadSize(file); err != nil {
+		return storedImage{}, &uploadError{http.StatusRequestEntityTooLarge, "Image too large. Max upload size is " + uploadLimitText()}
+	}
+
 	// Validate file type
 	ext := filepath.Ext(file.Filename)
 	if ext != ".jpg" && ext != ".jpeg" && ext != ".png" && ext != ".gif" && ext != ".webp" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file type. Only jpg, png, gif, webp allowed"})
-		return
+		return storedImage{}, &uploadError{http.StatusBadRequest, "Invalid file type. Only jpg, png, gif, webp allowed"}
+	}
+
+	// The extension is only a cheap first gate: the content must be that image type too
+	contentType, err := sniffImageType(file)
+	if err != nil {
+		log.Printf("⚠️ Rejected upload %s: %v", file.Filename, err)
+		return storedImage{}, &uploadError{http.StatusBadRequest, "File content does not match an allowed image type"}
 	}
 
 	// If R2 is enabled, upload to Cloudflare R2
@@ -162,33 +300,27 @@ func UploadImageHandler(c *gin.Context) {
 		imageURL, err := UploadToR2(file)
 		if err != nil {
 			log.Printf("❌ R2 upload failed: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload to R2"})
-			return
+			return storedImage{}, &uploadError{http.StatusInternalServerError, "Failed to upload to R2"}
 		}
 
 		log.Printf("✅ R2 upload successful: %s", imageURL)
-		c.JSON(http.StatusOK, gin.H{
-			"success":   true,
-			"image_url": imageURL,
-			"filename":  filepath.Base(imageURL),
-			"storage":   "r2",
-		})
-		return
+		return storedImage{
+			Filename:     filepath.Base(imageURL),
+			ImageURL:     imageURL,
+			ThumbnailURL: imageURL,
+			Storage:      "r2",
+		}, nil
 	}
 
 	// Otherwise, use local storage (original behavior)
 	log.Println("📁 Using local storage (R2 disabled)")
 
 	// Get uploads directory from env or use default
-	uploadsDir := os.Getenv("UPLOADS_PATH")
-	if uploadsDir == "" {
-		uploadsDir = "./uploads"
-	}
+	uploadsDir := getUploadsDir()
 
 	// Create uploads directory if not exists with 755 permissions
 	if err := os.MkdirAll(uploadsDir, 0755); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create uploads directory"})
-		return
+		return storedImage{}, &uploadError{http.StatusInternalServerError, "Failed to create uploads directory"}
 	}
 
 	// FORCE uploads directory to 755 - this is critical for nginx/cloudflare access
@@ -205,10 +337,19 @@ func UploadImageHandler(c *gin.Context) {
 	filename := fmt.Sprintf("%d_%s", timestamp, filepath.Base(file.Filename))
 	filePath := filepath.Join(uploadsDir, filename)
 
-	// Save the file
-	if err := c.SaveUploadedFile(file, filePath); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save image"})
-		return
+	// Save the file, downscaled when it is larger than UPLOAD_MAX_DIMENSION
+	resized, err := downscaleUpload(file, contentType)
+	if err != nil {
+		log.Printf("⚠️ Storing %s without downscaling: %v", file.Filename, err)
+	}
+	if resized != nil {
+		err = os.WriteFile(filePath, resized, 0644)
+		log.Printf("📐 Downscaled %s: %d -> %d bytes", filename, file.Size, len(resized))
+	} else {
+		err = c.SaveUploadedFile(file, filePath)
+	}
+	if err != nil {
+		return storedImage{}, &uploadError{http.StatusInternalServerError, "Failed to save image"}
 	}
 
 	// Set file permissions to 644 (readable by everyone)
@@ -219,55 +360,20 @@ func UploadImageHandler(c *gin.Context) {
 
 	log.Printf("💾 Image saved: %s (path: %s, file perms: 644, dir perms: 755)", filename, filePath)
 
-	// Build URL dynamically based on the incoming request
-	// Detect HTTPS from multiple sources (direct TLS, proxy headers, or port)
-	scheme := "http"
-
-	// Log all relevant headers for debugging
-	log.Printf("🔍 Image Upload - Host: %s, TLS: %v", c.Request.Host, c.Request.TLS != nil)
-	log.Printf("🔍 X-Forwarded-Proto: %s", c.GetHeader("X-Forwarded-Proto"))
-	log.Printf("🔍 CF-Visitor: %s", c.GetHeader("CF-Visitor"))
-	log.Printf("🔍 X-Forwarded-Ssl: %s", c.GetHeader("X-Forwarded-Ssl"))
-
-	// Check 1: Direct TLS connection
-	if c.Request.TLS != nil {
-		scheme = "https"
-	}
-
-	// Check 2: Proxy headers (Cloudflare, nginx, etc.)
-	forwardedProto := c.GetHeader("X-Forwarded-Proto")
-	if forwardedProto == "https" {
-		scheme = "https"
-	}
-
-	// Check 3: Cloudflare specific header
-	cfVisitor := c.GetHeader("CF-Visitor")
-	if len(cfVisitor) > 0 && (cfVisitor == `{"scheme":"https"}` || strings.Contains(cfVisitor, `"scheme":"https"`)) {
-		scheme = "https"
-	}
-
-	// Check 4: Standard forwarded header
-	if c.GetHeader("X-Forwarded-Ssl") == "on" {
-		scheme = "https"
-	}
-
-	// Check 5: If host doesn't have port and not localhost, assume HTTPS (production CDN)
-	host := c.Request.Host
-	if !strings.Contains(host, ":") && !strings.Contains(host, "localhost") && !strings.Contains(host, "127.0.0.1") {
-		scheme = "https"
-	}
-
-	log.Printf("✅ Final URL scheme: %s://%s", scheme, host)
+	// Build URL from the original scheme/host (proxy headers are only trusted from TRUSTED_PROXIES)
+	baseURL := proxy.BaseURL(c)
+	log.Printf("✅ Final URL base: %s", baseURL)
 
 	// Return the full image URL using /uploads/ path
-	imageURL := fmt.Sprintf("%s://%s/uploads/%s", scheme, host, filename)
+	imageURL := fmt.Sprintf("%s/uploads/%s", baseURL, filename)
 	log.Printf("📸 Generated image URL: %s", imageURL)
 
-	c.JSON(http.StatusOK, gin.H{
-		"success":   true,
-		"image_url": imageURL,
-		"filename":  filename,
-	})
+	return storedImage{
+		Filename:     filename,
+		ImageURL:     imageURL,
+		ThumbnailURL: imageURL,
+		Storage:      "local",
+	}, nil
 }
 
 // DeleteImageHandler deletes an uploaded image file
@@ -480,30 +586,34 @@ func ServeImageHandler(c *gin.Context) {
 		return
 	}
 
-	// Get uploads directory - check env variable first, then use relative path
-	uploadsDir := os.Getenv("UPLOADS_PATH")
-	if uploadsDir == "" {
-		uploadsDir = "./uploads"
+	// Only plain file names inside the uploads directory
+	if filename != filepath.Base(filename) || filename == ".." {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid filename"})
+		return
 	}
 
+	// Get uploads directory - check env variable first, then use relative path
+	uploadsDir := getUploadsDir()
+
 	// Construct file path
 	imagePath := filepath.Join(uploadsDir, filename)
 
 	log.Printf("📸 Serving image: %s (uploads dir: %s, full path: %s)", filename, uploadsDir, imagePath)
 
 	// Check if file exists
-	if _, err := os.Stat(imagePath); os.IsNotExist(err) {
+	info, err := os.Stat(imagePath)
+	if err != nil || info.IsDir() {
 		log.Printf("❌ Image not found: %s", imagePath)
 		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
 		return
 	}
 
-	// NO CACHE - Always serve fresh
-	c.Header("Cache-Control", "no-cache, no-store, must-revalidate")
-	c.Header("Pragma", "no-cache")
-	c.Header("Expires", "0")
+	// Timestamped uploads are cached long-term, others revalidate via ETag
+	setImageCacheHeaders(c, filename, info)
 
-	// Serve the file with appropriate content type (Gin handles this automatically)
+	// Serve the file with appropriate content type (Gin handles this automatically).
+	// c.File goes through http.ServeContent, which answers Range requests with 206
+	// and honors If-Range against the ETag set above.
 	log.Printf("✅ Serving image successfully: %s", filename)
 	c.File(imagePath)
 }
@@ -0,0 +1,29 @@
+package admin
+
+import (
+	"net/http"
+
+	"burma2d/config"
+	"burma2d/fcm"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetConfigHandler returns the effective, non-secret server settings (admin).
+// Settings come from the env lookups made at startup; secrets are redacted.
+func GetConfigHandler(c *gin.Context) {
+	getUploadsDir() // make sure it is recorded even before the first upload
+
+	circuitState, circuitFailures := fcm.CircuitStatus()
+
+	c.JSON(http.StatusOK, gin.H{
+		"settings": config.Snapshot(),
+		"status": gin.H{
+			"database":             db != nil,
+			"r2_enabled":           IsR2Enabled(),
+			"fcm_initialized":      fcm.IsInitialized(),
+			"fcm_circuit":          circuitState,
+			"fcm_circuit_failures": circuitFailures,
+		},
+	})
+}
@@ -0,0 +1,56 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"burma2d/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetConfigHandlerRedactsSecrets(t *testing.T) {
+	secrets := map[string]string{
+		"ADMIN_TOKEN":          "admin-token-value",
+		"ADMIN_SESSION_SECRET": "session-secret-value",
+		"CHAT_MODERATION_URL":  "https://moderation.example/hook?key=moderation-key-value",
+	}
+	for key, value := range secrets {
+		t.Setenv(key, value)
+		config.Secret(key, "")
+	}
+	t.Setenv("UPLOADS_PATH", "/srv/uploads")
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/admin/config", GetConfigHandler)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/config", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+
+	for key, value := range secrets {
+		if strings.Contains(w.Body.String(), value) {
+			t.Errorf("%s value leaked: %s", key, w.Body.String())
+		}
+	}
+	var resp struct {
+		Settings map[string]string `json:"settings"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	for key := range secrets {
+		if resp.Settings[key] != "[REDACTED]" {
+			t.Errorf("%s = %q, want it listed as redacted", key, resp.Settings[key])
+		}
+	}
+	// Ordinary settings are shown as they are
+	if resp.Settings["UPLOADS_PATH"] != "/srv/uploads" {
+		t.Errorf("UPLOADS_PATH = %q", resp.Settings["UPLOADS_PATH"])
+	}
+}
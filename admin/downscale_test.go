@@ -0,0 +1,173 @@
+package admin
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"mime/multipart"
+	"net/http/httptest"
+	"testing"
+)
+
+// encodeImage returns a width x height image in format ("jpeg" or "png")
+// whose left half is red and right half blue
+func encodeImage(t *testing.T, format string, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= width/2 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+
+	var buf bytes.Buffer
+	var err error
+	if format == "jpeg" {
+		err = jpeg.Encode(&buf, img, nil)
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// withOrientation inserts an EXIF APP1 segment carrying orientation into a JPEG
+func withOrientation(data []byte, orientation byte) []byte {
+	tiff := []byte{
+		'M', 'M', 0, 42, 0, 0, 0, 8, // big-endian header, IFD0 at 8
+		0, 1, // one entry
+		0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, orientation, 0, 0, // orientation, SHORT
+		0, 0, 0, 0, // no next IFD
+	}
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	size := len(segment) + 2
+
+	out := append([]byte{}, data[:2]...) // SOI
+	out = append(out, 0xFF, 0xE1, byte(size>>8), byte(size))
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+// fileHeader wraps data in a multipart upload as a handler receives it
+func fileHeader(t *testing.T, data []byte) *multipart.FileHeader {
+	t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, _ := w.CreateFormFile("image", "upload")
+	part.Write(data)
+	w.Close()
+
+	req := httptest.NewRequest("POST", "/", &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	if err := req.ParseMultipartForm(1 << 20); err != nil {
+		t.Fatal(err)
+	}
+	return req.MultipartForm.File["image"][0]
+}
+
+func useMaxDimension(t *testing.T, max int) {
+	t.Helper()
+	old := maxImageDimension
+	maxImageDimension = max
+	t.Cleanup(func() { maxImageDimension = old })
+}
+
+func TestDownscaleOversizedImage(t *testing.T) {
+	useMaxDimension(t, 100)
+
+	for _, tt := range []struct {
+		format, contentType string
+		width, height       int
+		wantW, wantH        int
+	}{
+		{"png", "image/png", 400, 300, 100, 75},
+		{"jpeg", "image/jpeg", 300, 400, 75, 100},
+	} {
+		resized, err := downscaleUpload(fileHeader(t, encodeImage(t, tt.format, tt.width, tt.height)), tt.contentType)
+		if err != nil || resized == nil {
+			t.Fatalf("%s: %v, %v; want a downscaled image", tt.format, resized, err)
+		}
+		img, format, err := image.Decode(bytes.NewReader(resized))
+		if err != nil || format != tt.format {
+			t.Fatalf("%s: decoded as %q: %v", tt.format, format, err)
+		}
+		if b := img.Bounds(); b.Dx() != tt.wantW || b.Dy() != tt.wantH {
+			t.Errorf("%s: %dx%d, want %dx%d", tt.format, b.Dx(), b.Dy(), tt.wantW, tt.wantH)
+		}
+		// The content is scaled, not cropped
+		if r, _, _, _ := img.At(5, 5).RGBA(); r>>8 < 200 {
+			t.Errorf("%s: left edge is not red", tt.format)
+		}
+		if _, _, b, _ := img.At(tt.wantW-5, 5).RGBA(); b>>8 < 200 {
+			t.Errorf("%s: right edge is not blue", tt.format)
+		}
+	}
+}
+
+func TestDownscaleLeavesSmallImages(t *testing.T) {
+	useMaxDimension(t, 100)
+
+	for _, tt := range []struct {
+		name, contentType string
+		data              []byte
+	}{
+		{"within bounds", "image/png", encodeImage(t, "png", 100, 60)},
+		{"unsupported type", "image/gif", encodeImage(t, "png", 400, 300)},
+	} {
+		if resized, err := downscaleUpload(fileHeader(t, tt.data), tt.contentType); resized != nil || err != nil {
+			t.Errorf("%s: %d bytes, %v; want the original kept", tt.name, len(resized), err)
+		}
+	}
+
+	useMaxDimension(t, 0)
+	if resized, _ := downscaleUpload(fileHeader(t, encodeImage(t, "png", 400, 300)), "image/png"); resized != nil {
+		t.Errorf("downscaled with the limit disabled")
+	}
+}
+
+func TestDownscaleAppliesOrientation(t *testing.T) {
+	useMaxDimension(t, 100)
+
+	// A sideways camera photo: stored 400x300, displayed rotated 90° clockwise
+	data := withOrientation(encodeImage(t, "jpeg", 400, 300), 6)
+	if got := jpegOrientation(data); got != 6 {
+		t.Fatalf("orientation = %d, want 6", got)
+	}
+
+	resized, err := downscaleUpload(fileHeader(t, data), "image/jpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, _, err := image.Decode(bytes.NewReader(resized))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 75 || b.Dy() != 100 {
+		t.Fatalf("%dx%d, want 75x100 upright", b.Dx(), b.Dy())
+	}
+	// Rotating clockwise puts the red left half on top
+	if r, _, _, _ := img.At(37, 5).RGBA(); r>>8 < 200 {
+		t.Errorf("top is not red")
+	}
+}
+
+func TestFitWithin(t *testing.T) {
+	for _, tt := range []struct{ w, h, max, wantW, wantH int }{
+		{4000, 3000, 1920, 1920, 1440},
+		{3000, 4000, 1920, 1440, 1920},
+		{5000, 5000, 1920, 1920, 1920},
+		{10000, 2, 1920, 1920, 1}, // never rounds to zero
+	} {
+		if w, h := fitWithin(tt.w, tt.h, tt.max); w != tt.wantW || h != tt.wantH {
+			t.Errorf("fitWithin(%d, %d, %d) = %d, %d; want %d, %d", tt.w, tt.h, tt.max, w, h, tt.wantW, tt.wantH)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"burma2d/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultMaxUploadMB is the image size limit when UPLOAD_MAX_MB is unset
+const defaultMaxUploadMB = 5
+
+// multipartOverhead allows for form boundaries and headers around the file
+const multipartOverhead = 64 << 10
+
+// ErrFileTooLarge is returned for uploads over the configured size limit
+var ErrFileTooLarge = errors.New("file too large")
+
+var maxUploadBytes int64 = defaultMaxUploadMB << 20
+
+// ConfigureUploads applies the upload size limit (UPLOAD_MAX_MB, default 5)
+// to the upload handlers and to Gin's in-memory multipart buffer, and the
+// longest edge images are downscaled to (UPLOAD_MAX_DIMENSION, default 1920)
+func ConfigureUploads(r *gin.Engine) {
+	mb := config.Int("UPLOAD_MAX_MB", defaultMaxUploadMB)
+	if mb < 1 {
+		mb = defaultMaxUploadMB
+	}
+	maxUploadBytes = int64(mb) << 20
+	r.MaxMultipartMemory = maxUploadBytes
+	log.Printf("✅ Max upload size: %d MB", mb)
+
+	maxImageDimension = config.Int("UPLOAD_MAX_DIMENSION", defaultMaxImageDimension)
+	if maxImageDimension > 0 {
+		log.Printf("✅ Uploaded JPEG/PNG images downscaled to %dpx (longest edge)", maxImageDimension)
+	}
+}
+
+// checkUploadSize rejects files over the limit before they are read
+func checkUploadSize(file *multipart.FileHeader) error {
+	if file.Size > maxUploadBytes {
+		return fmt.Errorf("%w: %d bytes, max upload size is %s", ErrFileTooLarge, file.Size, uploadLimitText())
+	}
+	return nil
+}
+
+// ErrNotImage is returned when an upload's content is not an allowed image
+var ErrNotImage = errors.New("file content is not an allowed image")
+
+// allowedImageTypes are the MIME types accepted for uploads
+var allowedImageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// sniffImageType reads the first 512 bytes of the upload and returns its
+// MIME type, rejecting content that is not an allowed image or that does
+// not match the file extension
+func sniffImageType(file *multipart.FileHeader) (string, error) {
+	src, err := file.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open uploaded file: %w", err)
+	}
+	defer src.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(src, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", fmt.Errorf("failed to read uploaded file: %w", err)
+	}
+
+	contentType := http.DetectContentType(head[:n])
+	if !allowedImageTypes[contentType] {
+		return "", fmt.Errorf("%w: detected %s", ErrNotImage, contentType)
+	}
+
+	ext := strings.ToLower(filepath.Ext(file.Filename))
+	if detectContentType(ext) != contentType {
+		return "", fmt.Errorf("%w: %s content with %s extension", ErrNotImage, contentType, ext)
+	}
+	return contentType, nil
+}
+
+// uploadLimitText describes the limit for error messages
+func uploadLimitText() string {
+	return fmt.Sprintf("%d MB", maxUploadBytes>>20)
+}
+
+// uploadTooLarge responds 413 with the limit so clients know the bound
+func uploadTooLarge(c *gin.Context) {
+	c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+		"error":     "Image too large. Max upload size is " + uploadLimitText(),
+		"max_bytes": maxUploadBytes,
+	})
+}
+
+// Cache policies for served images
+const (
+	immutableCacheControl  = "public, max-age=31536000, immutable"
+	revalidateCacheControl = "no-cache" // may be stored, but must revalidate (ETag/Last-Modified)
+)
+
+// Uploads are saved as "<unix timestamp>_<name>", so a name never changes content
+var immutableUploadName = regexp.MustCompile(`^\d{9,}_`)
+
+// setImageCacheHeaders sets Cache-Control and an ETag for an image file.
+// http.ServeContent (used by c.File and the static handler) then answers
+// If-None-Match / If-Modified-Since with 304.
+func setImageCacheHeaders(c *gin.Context, name string, info os.FileInfo) {
+	if immutableUploadName.MatchString(name) {
+		c.Header("Cache-Control", immutableCacheControl)
+	} else {
+		c.Header("Cache-Control", revalidateCacheControl)
+	}
+	c.Header("ETag", fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()))
+}
+
+// StaticImageCache adds image cache headers to a static route serving dir
+func StaticImageCache(dir string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := filepath.Base(c.Param("filepath"))
+		if info, err := os.Stat(filepath.Join(dir, filepath.Clean("/"+c.Param("filepath")))); err == nil && !info.IsDir() {
+			setImageCacheHeaders(c, name, info)
+		}
+		c.Next()
+	}
+}
+
+// RemoveReplacedImage deletes oldURL in the background after an image has
+// been replaced by newURL. Only files in our own storage (the R2 bucket or
+// the local uploads directory) are removed; external URLs are left alone,
+// and so are files another gift, slider or paper image still uses.
+// ownHost is the host local upload URLs were built with, normally the
+// request's Host. Failures are logged and never affect the caller.
+func RemoveReplacedImage(oldURL, newURL, ownHost string) {
+	if oldURL == "" || oldURL == newURL {
+		return
+	}
+	go func() {
+		if err := deleteStoredImage(oldURL, ownHost); err != nil {
+			log.Printf("⚠️ Failed to delete replaced image %s: %v", oldURL, err)
+		}
+	}()
+}
+
+// deleteStoredImage removes an image from R2 or the uploads directory
+// unless a row still references it
+func deleteStoredImage(imageURL, ownHost string) error {
+	if IsR2Enabled() && strings.HasPrefix(imageURL, r2Client.publicURL+"/") {
+		if inUse, err := imageInUse(imageURL, ""); err != nil || inUse {
+			return keepImage(imageURL, err)
+		}
+		return DeleteFromR2(imageURL)
+	}
+
+	name, ok := localUploadName(imageURL, ownHost)
+	if !ok {
+		log.Printf("ℹ️  Not deleting replaced image outside our storage: %s", imageURL)
+		return nil
+	}
+	if inUse, err := imageInUse(imageURL, name); err != nil || inUse {
+		return keepImage(imageURL, err)
+	}
+
+	err := os.Remove(filepath.Join(getUploadsDir(), name))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		log.Printf("🗑️  Deleted replaced upload: %s", name)
+	}
+	return nil
+}
+
+// imageReferences are the columns that can hold the URL of a stored image
+var imageReferences = []struct{ table, column string }{
+	{"gifts", "image_link"},
+	{"sliders", "image_link"},
+	{"paper_images", "image_url"},
+}
+
+// imageInUse reports whether any row still references imageURL. For a local
+// upload, name is its file name: the same file can be linked through
+// /uploads/ or /api/images/, relative or absolute, so any URL ending in
+// "/<name>" counts as a reference.
+func imageInUse(imageURL, name string) (bool, error) {
+	if db == nil {
+		return false, fmt.Errorf("database not initialized")
+	}
+
+	for _, ref := range imageReferences {
+		var n int
+		err := db.QueryRow(fmt.Sprintf(
+			"SELECT COUNT(*) FROM %s WHERE %s = ? OR (? != '' AND substr(%s, -?) = ?)",
+			ref.table, ref.column, ref.column,
+		), imageURL, name, len(name)+1, "/"+name).Scan(&n)
+		if err != nil {
+			return false, fmt.Errorf("failed to check %s for references: %w", ref.table, err)
+		}
+		if n > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// keepImage logs why a replaced image was not deleted; a failed reference
+// check is returned so the file is kept rather than removed blindly
+func keepImage(imageURL string, err error) error {
+	if err != nil {
+		return err
+	}
+	log.Printf("ℹ️  Keeping replaced image still in use: %s", imageURL)
+	return nil
+}
+
+// localUploadName returns the file name of an image served from /uploads/
+// or /api/images/ when it was saved by UploadImageHandler. The URL must be
+// relative or point at ownHost; the same path on another host is external.
+func localUploadName(imageURL, ownHost string) (string, bool) {
+	u, err := url.Parse(imageURL)
+	if err != nil {
+		return "", false
+	}
+	if u.Host != "" && !strings.EqualFold(u.Host, ownHost) {
+		return "", false
+	}
+	dir, name := path.Split(u.Path)
+	if dir != "/uploads/" && dir != "/api/images/" {
+		return "", false
+	}
+	if !immutableUploadName.MatchString(name) {
+		return "", false
+	}
+	return name, true
+}
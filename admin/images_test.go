@@ -0,0 +1,188 @@
+package admin
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// useUploads points the uploads directory at a temporary one
+func useUploads(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("UPLOADS_PATH", dir)
+	return dir
+}
+
+func writeUpload(t *testing.T, dir, name string, content []byte) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// serveImage requests name from ServeImageHandler
+func serveImage(t *testing.T, name string, header http.Header) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/images/:filename", ServeImageHandler)
+	req := httptest.NewRequest(http.MethodGet, "/api/images/"+name, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestLocalUploadName(t *testing.T) {
+	const host = "api.example.com"
+	tests := []struct {
+		url  string
+		name string
+		ok   bool
+	}{
+		{"/uploads/1712345678_gift.png", "1712345678_gift.png", true},
+		{"https://api.example.com/uploads/1712345678_gift.png", "1712345678_gift.png", true},
+		{"https://API.example.com/api/images/1712345678_gift.png", "1712345678_gift.png", true},
+		{"https://cdn.other.com/uploads/1712345678_gift.png", "", false},
+		{"https://api.example.com.evil.com/uploads/1712345678_gift.png", "", false},
+		{"https://api.example.com/static/1712345678_gift.png", "", false},
+		{"https://api.example.com/uploads/nested/1712345678_gift.png", "", false},
+		{"https://api.example.com/uploads/gift.png", "", false},
+		{"://bad", "", false},
+	}
+	for _, tt := range tests {
+		name, ok := localUploadName(tt.url, host)
+		if name != tt.name || ok != tt.ok {
+			t.Errorf("localUploadName(%q) = %q, %v; want %q, %v", tt.url, name, ok, tt.name, tt.ok)
+		}
+	}
+}
+
+func TestServeImageRevalidatesWithETag(t *testing.T) {
+	dir := useUploads(t)
+	content := []byte("\x89PNG fake image data")
+	for name, cacheControl := range map[string]string{
+		"1712345678_gift.png": immutableCacheControl,
+		"banner.png":          revalidateCacheControl,
+	} {
+		writeUpload(t, dir, name, content)
+		w := serveImage(t, name, nil)
+		etag := w.Header().Get("ETag")
+		if w.Code != http.StatusOK || etag == "" || w.Body.Len() != len(content) {
+			t.Fatalf("%s: status %d, ETag %q, %d bytes", name, w.Code, etag, w.Body.Len())
+		}
+		if got := w.Header().Get("Cache-Control"); got != cacheControl {
+			t.Errorf("%s: Cache-Control %q, want %q", name, got, cacheControl)
+		}
+
+		// Same file, same ETag: not modified and no body
+		w = serveImage(t, name, http.Header{"If-None-Match": {etag}})
+		if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+			t.Errorf("%s: revalidation status %d with %d bytes, want 304 and no body", name, w.Code, w.Body.Len())
+		}
+	}
+
+	// A stale ETag gets the file again
+	if w := serveImage(t, "banner.png", http.Header{"If-None-Match": {`"0-0"`}}); w.Code != http.StatusOK {
+		t.Errorf("stale ETag: status %d, want 200", w.Code)
+	}
+}
+
+func TestServeImageRange(t *testing.T) {
+	dir := useUploads(t)
+	content := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+	writeUpload(t, dir, "1712345678_gift.png", content)
+
+	w := serveImage(t, "1712345678_gift.png", http.Header{"Range": {"bytes=0-9"}})
+	if w.Code != http.StatusPartialContent {
+		t.Fatalf("status %d, want 206", w.Code)
+	}
+	if got := w.Header().Get("Content-Range"); got != "bytes 0-9/36" {
+		t.Errorf("Content-Range = %q, want bytes 0-9/36", got)
+	}
+	if w.Body.Len() != 10 || w.Body.String() != "0123456789" {
+		t.Errorf("body = %q, want the first 10 bytes", w.Body.String())
+	}
+
+	// A range that changed since the cached copy falls back to the whole file
+	w = serveImage(t, "1712345678_gift.png", http.Header{"Range": {"bytes=0-9"}, "If-Range": {`"0-0"`}})
+	if w.Code != http.StatusOK || w.Body.Len() != len(content) {
+		t.Errorf("stale If-Range: status %d with %d bytes, want the whole file", w.Code, w.Body.Len())
+	}
+}
+
+// useImageTables gives the admin package a database with the tables that
+// reference uploaded images
+func useImageTables(t *testing.T) {
+	t.Helper()
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	database.SetMaxOpenConns(1)
+	old := db
+	t.Cleanup(func() {
+		db = old
+		database.Close()
+	})
+	db = database
+	if _, err := db.Exec(`
+		CREATE TABLE gifts (id INTEGER PRIMARY KEY, image_link TEXT NOT NULL);
+		CREATE TABLE sliders (id INTEGER PRIMARY KEY, image_link TEXT NOT NULL);
+		CREATE TABLE paper_images (id INTEGER PRIMARY KEY, image_url TEXT NOT NULL);
+	`); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeleteStoredImageKeepsSharedFiles(t *testing.T) {
+	useImageTables(t)
+	dir := useUploads(t)
+	const host = "api.example.com"
+	for _, name := range []string{"1712345678_gift.png", "1712345678_slide.png", "1712345678_paper.png", "1712345678_old.png"} {
+		writeUpload(t, dir, name, []byte("image"))
+	}
+	db.Exec("INSERT INTO gifts (image_link) VALUES ('https://api.example.com/uploads/1712345678_gift.png')")
+	db.Exec("INSERT INTO sliders (image_link) VALUES ('/api/images/1712345678_slide.png')")
+	db.Exec("INSERT INTO paper_images (image_url) VALUES ('/uploads/1712345678_paper.png')")
+	// A similar name is not the same file
+	db.Exec("INSERT INTO gifts (image_link) VALUES ('/uploads/x1712345678_old.png')")
+
+	for name, kept := range map[string]bool{
+		"1712345678_gift.png":  true, // same file under another URL form
+		"1712345678_slide.png": true,
+		"1712345678_paper.png": true,
+		"1712345678_old.png":   false, // nothing uses it any more
+	} {
+		if err := deleteStoredImage("/uploads/"+name, host); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		_, err := os.Stat(filepath.Join(dir, name))
+		if exists := err == nil; exists != kept {
+			t.Errorf("%s: exists = %v, want %v", name, exists, kept)
+		}
+	}
+}
+
+func TestDeleteStoredImageKeepsFileWhenCheckFails(t *testing.T) {
+	useImageTables(t)
+	dir := useUploads(t)
+	writeUpload(t, dir, "1712345678_gift.png", []byte("image"))
+	db.Exec("DROP TABLE sliders")
+
+	if err := deleteStoredImage("/uploads/1712345678_gift.png", "api.example.com"); err == nil {
+		t.Error("no error when references could not be checked")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "1712345678_gift.png")); err != nil {
+		t.Errorf("file removed without a reference check: %v", err)
+	}
+}
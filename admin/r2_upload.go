@@ -1,13 +1,19 @@
 package admin
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"mime/multipart"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
+	"burma2d/config"
+
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
@@ -23,16 +29,39 @@ type R2Client struct {
 
 var r2Client *R2Client
 
-// InitR2 initializes the Cloudflare R2 client with hardcoded credentials
+// InitR2 initializes the Cloudflare R2 client from the environment.
+// R2 is used only when USE_R2=true; the account ID, access key, secret and
+// bucket (R2_ACCOUNT_ID, R2_ACCESS_KEY_ID, R2_SECRET_ACCESS_KEY,
+// R2_BUCKET_NAME) are then required and an error is returned if any is missing.
 func InitR2() error {
-	// Hardcoded R2 credentials - Updated November 15, 2025
-	accountID := "928f8d753ffd9a6246d5016edbe93035"
-	accessKeyID := "7d2a22232b529d7711f4f55771e6672d"
-	secretAccessKey := "6bc75671f05ab4d445472766190451eaeaea18c1ed2e4ad5d8415252ec208ae3"
-	bucketName := "burmatwod"
-	publicURL := "https://pub-05543fc5bedb4a55a1be0c32bab9858a.r2.dev"
+	useR2 := config.Bool("USE_R2", false)
+	accountID := config.String("R2_ACCOUNT_ID", "")
+	accessKeyID := config.Secret("R2_ACCESS_KEY_ID", "")
+	secretAccessKey := config.Secret("R2_SECRET_ACCESS_KEY", "")
+	bucketName := config.String("R2_BUCKET_NAME", "")
+	publicURL := config.String("R2_PUBLIC_URL", "")
+
+	if !useR2 {
+		log.Println("ℹ️  R2 disabled (set USE_R2=true to enable): using local storage")
+		return nil
+	}
 
-	log.Println("✅ R2 upload enabled with hardcoded credentials (updated Nov 15, 2025)")
+	var missing []string
+	for name, value := range map[string]string{
+		"R2_ACCOUNT_ID":        accountID,
+		"R2_ACCESS_KEY_ID":     accessKeyID,
+		"R2_SECRET_ACCESS_KEY": secretAccessKey,
+		"R2_BUCKET_NAME":       bucketName,
+	} {
+		if value == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("USE_R2=true but %s not set", strings.Join(missing, ", "))
+	}
+	log.Printf("🔑 R2 credentials loaded from environment (access key ID ending %s)", lastChars(accessKeyID, 4))
 
 	// Build R2 endpoint (S3-compatible)
 	endpoint := fmt.Sprintf("https://%s.r2.cloudflarestorage.com", accountID)
@@ -63,24 +92,53 @@ func InitR2() error {
 		client:     s3Client,
 		bucketName: bucketName,
 		publicURL:  publicURL,
-		enabled:    false, // Block R2 temporarily
+		enabled:    true,
 	}
 
-	log.Printf("⏸️ Cloudflare R2 temporarily disabled: using local storage only")
+	log.Printf("✅ Cloudflare R2 uploads enabled: bucket=%s, public URL=%s", bucketName, publicURL)
 	return nil
 }
 
+// lastChars returns the last n characters of s for log identification
+func lastChars(s string, n int) string {
+	if len(s) <= n {
+		return "****"
+	}
+	return s[len(s)-n:]
+}
+
 // IsR2Enabled returns whether R2 upload is enabled
 func IsR2Enabled() bool {
 	return r2Client != nil && r2Client.enabled
 }
 
+// CheckR2 verifies the configured bucket is reachable with the current credentials
+func CheckR2(ctx context.Context) error {
+	if !IsR2Enabled() {
+		return fmt.Errorf("R2 not enabled")
+	}
+	_, err := r2Client.client.HeadBucket(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(r2Client.bucketName),
+	})
+	return err
+}
+
 // UploadToR2 uploads a file to Cloudflare R2 and returns the public URL
 func UploadToR2(file *multipart.FileHeader) (string, error) {
 	if !IsR2Enabled() {
 		return "", fmt.Errorf("R2 client not initialized or disabled")
 	}
 
+	if err := checkUploadSize(file); err != nil {
+		return "", err
+	}
+
+	// Content type comes from the sniffed bytes, not the client's header
+	contentType, err := sniffImageType(file)
+	if err != nil {
+		return "", err
+	}
+
 	// Open the uploaded file
 	src, err := file.Open()
 	if err != nil {
@@ -88,18 +146,25 @@ func UploadToR2(file *multipart.FileHeader) (string, error) {
 	}
 	defer src.Close()
 
+	// Store the downscaled version when it is larger than UPLOAD_MAX_DIMENSION
+	var body io.Reader = src
+	size := file.Size
+	resized, err := downscaleUpload(file, contentType)
+	if err != nil {
+		log.Printf("⚠️ Uploading %s without downscaling: %v", file.Filename, err)
+	}
+	if resized != nil {
+		log.Printf("📐 Downscaled %s: %d -> %d bytes", file.Filename, file.Size, len(resized))
+		body = bytes.NewReader(resized)
+		size = int64(len(resized))
+	}
+
 	// Generate unique filename with timestamp
 	ext := filepath.Ext(file.Filename)
 	timestamp := time.Now().Unix()
 	filename := fmt.Sprintf("gifts/%d_%s%s", timestamp, filepath.Base(file.Filename[:len(file.Filename)-len(ext)]), ext)
 
-	// Detect content type
-	contentType := file.Header.Get("Content-Type")
-	if contentType == "" {
-		contentType = detectContentType(ext)
-	}
-
-	log.Printf("📤 Uploading to R2: bucket=%s, key=%s, size=%d bytes", r2Client.bucketName, filename, file.Size)
+	log.Printf("📤 Uploading to R2: bucket=%s, key=%s, size=%d bytes", r2Client.bucketName, filename, size)
 
 	// Upload to R2
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
@@ -108,9 +173,9 @@ func UploadToR2(file *multipart.FileHeader) (string, error) {
 	_, err = r2Client.client.PutObject(ctx, &s3.PutObjectInput{
 		Bucket:        aws.String(r2Client.bucketName),
 		Key:           aws.String(filename),
-		Body:          src,
+		Body:          body,
 		ContentType:   aws.String(contentType),
-		ContentLength: aws.Int64(file.Size),
+		ContentLength: aws.Int64(size),
 		// Make object publicly readable (if bucket has public access)
 		// ACL: types.ObjectCannedACLPublicRead, // R2 doesn't support ACLs, use bucket settings
 	})
@@ -142,17 +207,20 @@ func detectContentType(ext string) string {
 	}
 }
 
-// DeleteFromR2 deletes a file from R2 (optional cleanup function)
+// DeleteFromR2 deletes a file from R2 by its public URL
 func DeleteFromR2(fileURL string) error {
 	if !IsR2Enabled() {
 		return fmt.Errorf("R2 client not initialized or disabled")
 	}
 
-	// Extract key from URL (assumes format: https://domain/key)
+	// Extract key from URL (assumes format: <public URL>/key)
 	// Example: https://pub-xxx.r2.dev/gifts/1234567890_gift.jpg -> gifts/1234567890_gift.jpg
-	key := filepath.Base(fileURL)
-	if filepath.Dir(fileURL) != "." {
-		key = filepath.Join(filepath.Base(filepath.Dir(fileURL)), key)
+	key := strings.TrimPrefix(fileURL, r2Client.publicURL+"/")
+	if key == fileURL {
+		key = filepath.Base(fileURL)
+		if filepath.Dir(fileURL) != "." {
+			key = filepath.Join(filepath.Base(filepath.Dir(fileURL)), key)
+		}
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -0,0 +1,31 @@
+package admin
+
+import (
+	"html/template"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TemplatesPattern is the glob of the admin and landing page templates
+const TemplatesPattern = "admin/templates/*.html"
+
+// LoadTemplates parses the HTML templates matching pattern into r. Unlike
+// gin's LoadHTMLGlob, which panics, it returns an error when nothing
+// matches or a template is malformed.
+func LoadTemplates(r *gin.Engine, pattern string) error {
+	tmpl, err := template.New("").Funcs(r.FuncMap).ParseGlob(pattern)
+	if err != nil {
+		return err
+	}
+	r.SetHTMLTemplate(tmpl)
+	return nil
+}
+
+// PagesUnavailableHandler answers admin page requests when the templates
+// failed to load; the JSON admin APIs keep working
+func PagesUnavailableHandler(c *gin.Context) {
+	c.JSON(http.StatusServiceUnavailable, gin.H{
+		"error": "Admin pages are unavailable: templates failed to load",
+	})
+}
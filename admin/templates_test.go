@@ -0,0 +1,78 @@
+package admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func writeTemplate(t *testing.T, dir, name, text string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadTemplatesFailuresDoNotPanic(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	broken := t.TempDir()
+	writeTemplate(t, broken, "index.html", `{{ if }}`)
+
+	for name, pattern := range map[string]string{
+		"missing directory": filepath.Join(t.TempDir(), "templates", "*.html"),
+		"no matches":        filepath.Join(t.TempDir(), "*.html"),
+		"malformed":         filepath.Join(broken, "*.html"),
+	} {
+		func() {
+			defer func() {
+				if p := recover(); p != nil {
+					t.Errorf("%s: panicked: %v", name, p)
+				}
+			}()
+			if err := LoadTemplates(gin.New(), pattern); err == nil {
+				t.Errorf("%s: no error", name)
+			}
+		}()
+	}
+}
+
+func TestLoadTemplates(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	writeTemplate(t, dir, "page.html", `<h1>{{ .title }}</h1>`)
+
+	r := gin.New()
+	if err := LoadTemplates(r, filepath.Join(dir, "*.html")); err != nil {
+		t.Fatal(err)
+	}
+	r.GET("/", func(c *gin.Context) { c.HTML(http.StatusOK, "page.html", gin.H{"title": "Gifts"}) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "<h1>Gifts</h1>") {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestShippedTemplatesParse(t *testing.T) {
+	if err := LoadTemplates(gin.New(), filepath.Join("templates", "*.html")); err != nil {
+		t.Fatalf("admin templates: %v", err)
+	}
+}
+
+func TestPagesUnavailableHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/admin", PagesUnavailableHandler)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status %d, want 503", w.Code)
+	}
+}
@@ -0,0 +1,243 @@
+package adminauth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"burma2d/config"
+	"burma2d/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// CookieName is the session cookie set by the login endpoint
+const CookieName = "admin_session"
+
+// ContextUserKey holds the authenticated admin username in the gin context
+const ContextUserKey = "admin_user"
+
+var (
+	username     string
+	passwordHash []byte // bcrypt hash from ADMIN_PASSWORD_HASH
+	staticToken  string // optional ADMIN_TOKEN for scripts
+	sessionKey   []byte // HMAC key for session tokens
+	sessionTTL   time.Duration
+	disabled     bool
+
+	// Tokens revoked by logout, kept until they would have expired anyway
+	revoked      = make(map[string]time.Time)
+	revokedMutex sync.Mutex
+	lastPrune    time.Time
+
+	// Login attempts per client IP
+	loginLimiter *ratelimit.Limiter
+)
+
+// pruneInterval bounds how often logout sweeps expired revocations
+const pruneInterval = time.Minute
+
+var errInvalidToken = errors.New("invalid or expired token")
+
+// Init loads admin credentials from the environment:
+// ADMIN_USERNAME, ADMIN_PASSWORD_HASH (bcrypt), ADMIN_TOKEN, ADMIN_SESSION_SECRET,
+// ADMIN_SESSION_TTL, ADMIN_LOGIN_RATE_LIMIT and ADMIN_LOGIN_RATE_WINDOW.
+// ADMIN_AUTH_DISABLED=true turns checks off for local development.
+func Init() {
+	username = config.String("ADMIN_USERNAME", "admin")
+	passwordHash = []byte(config.Secret("ADMIN_PASSWORD_HASH", ""))
+	staticToken = config.Secret("ADMIN_TOKEN", "")
+	sessionTTL = config.Duration("ADMIN_SESSION_TTL", 24*time.Hour)
+	disabled = config.Bool("ADMIN_AUTH_DISABLED", false)
+	loginLimiter = ratelimit.New(
+		config.Int("ADMIN_LOGIN_RATE_LIMIT", 5),
+		config.Duration("ADMIN_LOGIN_RATE_WINDOW", 15*time.Minute),
+	)
+
+	if secret := config.Secret("ADMIN_SESSION_SECRET", ""); secret != "" {
+		sessionKey = []byte(secret)
+	} else {
+		sessionKey = make([]byte, 32)
+		if _, err := rand.Read(sessionKey); err != nil {
+			log.Fatalf("❌ Failed to generate admin session key: %v", err)
+		}
+		log.Println("⚠️ ADMIN_SESSION_SECRET not set - admin sessions will not survive a restart")
+	}
+
+	switch {
+	case disabled:
+		log.Println("⚠️ Admin authentication DISABLED (ADMIN_AUTH_DISABLED=true) - do not use in production")
+	case len(passwordHash) == 0 && staticToken == "":
+		log.Println("❌ No admin credentials configured (ADMIN_PASSWORD_HASH / ADMIN_TOKEN) - admin routes are locked")
+	default:
+		log.Printf("✅ Admin authentication enabled for user %q", username)
+	}
+}
+
+// Required rejects requests without a valid admin session or token.
+// API requests get 401; HTML pages are redirected to the login page.
+func Required() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if disabled {
+			c.Next()
+			return
+		}
+
+		if user, ok := authenticate(c); ok {
+			c.Set(ContextUserKey, user)
+			c.Next()
+			return
+		}
+
+		if isAPIRequest(c) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Admin authentication required"})
+			return
+		}
+		c.Redirect(http.StatusFound, "/admin/login?next="+url.QueryEscape(c.Request.URL.RequestURI()))
+		c.Abort()
+	}
+}
+
+// IsAdmin reports whether the request carries a valid admin token or
+// session, without rejecting it. It is false when auth is disabled.
+func IsAdmin(c *gin.Context) bool {
+	_, ok := authenticate(c)
+	return ok
+}
+
+// authenticate checks the bearer token first, then the session cookie
+func authenticate(c *gin.Context) (string, bool) {
+	if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		token := strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+		if staticToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(staticToken)) == 1 {
+			return username, true
+		}
+		if user, err := verifyToken(token); err == nil {
+			return user, true
+		}
+		return "", false
+	}
+
+	if cookie, err := c.Cookie(CookieName); err == nil && cookie != "" {
+		if user, err := verifyToken(cookie); err == nil {
+			return user, true
+		}
+	}
+	return "", false
+}
+
+func isAPIRequest(c *gin.Context) bool {
+	return strings.Contains(c.Request.URL.Path, "/api/") ||
+		strings.Contains(c.GetHeader("Accept"), "application/json")
+}
+
+// checkCredentials verifies the username and password against the configured hash
+func checkCredentials(user, password string) bool {
+	if len(passwordHash) == 0 {
+		return false
+	}
+	userOK := subtle.ConstantTimeCompare([]byte(user), []byte(username)) == 1
+	passOK := bcrypt.CompareHashAndPassword(passwordHash, []byte(password)) == nil
+	return userOK && passOK
+}
+
+// issueToken creates a signed token: base64(user|expiry|nonce).hex(hmac)
+func issueToken(user string) (string, time.Time, error) {
+	nonce := make([]byte, 12)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", time.Time{}, err
+	}
+
+	expires := time.Now().Add(sessionTTL)
+	payload := fmt.Sprintf("%s|%d|%s", user, expires.Unix(), hex.EncodeToString(nonce))
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return encoded + "." + sign(encoded), expires, nil
+}
+
+// verifyToken checks a token's signature, expiry and revocation
+func verifyToken(token string) (string, error) {
+	user, sig, _, err := parseToken(token)
+	if err != nil {
+		return "", err
+	}
+
+	revokedMutex.Lock()
+	_, isRevoked := revoked[sig]
+	revokedMutex.Unlock()
+	if isRevoked {
+		return "", errInvalidToken
+	}
+
+	return user, nil
+}
+
+// parseToken checks a token's signature and expiry and returns its user,
+// signature and expiry time
+func parseToken(token string) (string, string, time.Time, error) {
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(sign(encoded))) {
+		return "", "", time.Time{}, errInvalidToken
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", "", time.Time{}, errInvalidToken
+	}
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 {
+		return "", "", time.Time{}, errInvalidToken
+	}
+
+	expiry, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || time.Now().Unix() >= expiry {
+		return "", "", time.Time{}, errInvalidToken
+	}
+
+	return parts[0], sig, time.Unix(expiry, 0), nil
+}
+
+func sign(encoded string) string {
+	mac := hmac.New(sha256.New, sessionKey)
+	mac.Write([]byte(encoded))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// revokeToken invalidates a session token until its expiry. Only tokens we
+// signed that are still live are recorded, so the list cannot be grown by
+// posting arbitrary strings to the logout endpoint.
+func revokeToken(token string) {
+	_, sig, expires, err := parseToken(token)
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	if limit := now.Add(sessionTTL); expires.After(limit) {
+		expires = limit
+	}
+
+	revokedMutex.Lock()
+	defer revokedMutex.Unlock()
+	if now.Sub(lastPrune) >= pruneInterval {
+		for s, exp := range revoked {
+			if now.After(exp) {
+				delete(revoked, s)
+			}
+		}
+		lastPrune = now
+	}
+	revoked[sig] = expires
+}
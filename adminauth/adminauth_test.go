@@ -0,0 +1,111 @@
+package adminauth
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"burma2d/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func setup(t *testing.T) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	sessionKey = []byte("test-session-key")
+	sessionTTL = time.Hour
+	username = "admin"
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	passwordHash = hash
+	loginLimiter = ratelimit.New(3, time.Minute)
+
+	revokedMutex.Lock()
+	revoked = make(map[string]time.Time)
+	lastPrune = time.Time{}
+	revokedMutex.Unlock()
+}
+
+func revokedCount() int {
+	revokedMutex.Lock()
+	defer revokedMutex.Unlock()
+	return len(revoked)
+}
+
+func TestRevokeToken(t *testing.T) {
+	setup(t)
+	token, _, err := issueToken("admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifyToken(token); err != nil {
+		t.Fatalf("fresh token rejected: %v", err)
+	}
+
+	revokeToken(token)
+	if _, err := verifyToken(token); err == nil {
+		t.Fatal("revoked token still accepted")
+	}
+}
+
+func TestRevokeTokenIgnoresUnsignedTokens(t *testing.T) {
+	setup(t)
+	for i := 0; i < 100; i++ {
+		payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("admin|9999999999|%d", i)))
+		revokeToken(payload + "." + strings.Repeat("ab", 32))
+	}
+	revokeToken("not-a-token")
+
+	if n := revokedCount(); n != 0 {
+		t.Fatalf("recorded %d forged tokens", n)
+	}
+}
+
+func TestRevokeTokenCapsExpiry(t *testing.T) {
+	setup(t)
+	// A validly signed token claiming a far-future expiry
+	encoded := base64.RawURLEncoding.EncodeToString([]byte("admin|9999999999|00"))
+	token := encoded + "." + sign(encoded)
+
+	revokeToken(token)
+
+	revokedMutex.Lock()
+	expires := revoked[sign(encoded)]
+	revokedMutex.Unlock()
+	if limit := time.Now().Add(sessionTTL); expires.After(limit) {
+		t.Fatalf("revocation kept until %s, want at most %s", expires, limit)
+	}
+}
+
+func TestLoginThrottled(t *testing.T) {
+	setup(t)
+	r := gin.New()
+	r.POST("/admin/login", LoginHandler)
+
+	login := func(password string) int {
+		body := fmt.Sprintf(`{"username":"admin","password":%q}`, password)
+		req := httptest.NewRequest(http.MethodPost, "/admin/login", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	for i := 0; i < 3; i++ {
+		if code := login("wrong"); code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: status %d, want 401", i+1, code)
+		}
+	}
+	// Even the right password is refused once the budget is spent
+	if code := login("secret"); code != http.StatusTooManyRequests {
+		t.Fatalf("status %d, want 429", code)
+	}
+}
@@ -0,0 +1,118 @@
+package adminauth
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"burma2d/proxy"
+	"burma2d/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+type loginRequest struct {
+	Username string `json:"username" form:"username" binding:"required"`
+	Password string `json:"password" form:"password" binding:"required"`
+}
+
+// LoginPageHandler renders the admin login form
+func LoginPageHandler(c *gin.Context) {
+	c.HTML(http.StatusOK, "login.html", gin.H{
+		"title": "Admin Login - Burma 2D 2025",
+		"next":  safeNext(c.Query("next")),
+		"error": c.Query("error"),
+	})
+}
+
+// LoginHandler checks credentials, sets the session cookie and returns a token.
+// Form posts from the login page are redirected instead of getting JSON.
+// Attempts are throttled per client IP.
+func LoginHandler(c *gin.Context) {
+	isForm := strings.HasPrefix(c.ContentType(), "application/x-www-form-urlencoded")
+	next := safeNext(c.PostForm("next"))
+
+	if ok, wait := loginLimiter.Allow(c.ClientIP()); !ok {
+		log.Printf("⚠️ Admin login rate limit hit for %s", c.ClientIP())
+		if isForm {
+			c.Redirect(http.StatusFound, "/admin/login?error=Too+many+login+attempts,+try+again+later")
+			return
+		}
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":       "Too many login attempts",
+			"retry_after": ratelimit.RetryAfterSeconds(wait),
+		})
+		return
+	}
+
+	var req loginRequest
+	if err := c.ShouldBind(&req); err != nil {
+		if isForm {
+			c.Redirect(http.StatusFound, "/admin/login?error=Username+and+password+required")
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !checkCredentials(req.Username, req.Password) {
+		log.Printf("⚠️ Failed admin login for %q from %s", req.Username, c.ClientIP())
+		if isForm {
+			c.Redirect(http.StatusFound, "/admin/login?error=Invalid+username+or+password")
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
+		return
+	}
+
+	token, expires, err := issueToken(req.Username)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(CookieName, token, int(sessionTTL.Seconds()), "/", "", isSecure(c), true)
+	log.Printf("✅ Admin %q logged in from %s", req.Username, c.ClientIP())
+
+	if isForm {
+		c.Redirect(http.StatusFound, next)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"token":      token,
+		"expires_at": expires,
+	})
+}
+
+// LogoutHandler revokes the current session and clears the cookie
+func LogoutHandler(c *gin.Context) {
+	if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		revokeToken(strings.TrimSpace(strings.TrimPrefix(auth, "Bearer ")))
+	}
+	if cookie, err := c.Cookie(CookieName); err == nil && cookie != "" {
+		revokeToken(cookie)
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(CookieName, "", -1, "/", "", isSecure(c), true)
+
+	if strings.HasPrefix(c.ContentType(), "application/x-www-form-urlencoded") {
+		c.Redirect(http.StatusFound, "/admin/login")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// safeNext only allows redirects back into the admin area
+func safeNext(next string) string {
+	if strings.HasPrefix(next, "/admin") && !strings.HasPrefix(next, "//") {
+		return next
+	}
+	return "/admin"
+}
+
+func isSecure(c *gin.Context) bool {
+	return proxy.IsSecure(c)
+}
@@ -0,0 +1,214 @@
+package appconfig
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"burma2d/jsonutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AppConfig holds the settings the mobile app fetches at startup
+type AppConfig struct {
+	LatestVersion      string    `json:"latest_version"`
+	MinimumVersion     string    `json:"minimum_version"`
+	UpdateURL          string    `json:"update_url"`
+	UpdateMessage      string    `json:"update_message"`
+	MaintenanceMode    bool      `json:"maintenance_mode"`
+	MaintenanceMessage string    `json:"maintenance_message"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+// VersionCheck is the result of comparing an app version with the config
+type VersionCheck struct {
+	CurrentVersion  string `json:"current_version"`
+	LatestVersion   string `json:"latest_version"`
+	MinimumVersion  string `json:"minimum_version"`
+	UpdateAvailable bool   `json:"update_available"`
+	UpdateRequired  bool   `json:"update_required"`
+	UpdateURL       string `json:"update_url"`
+	Message         string `json:"message"`
+}
+
+var db *sql.DB
+
+// ErrInvalidVersion marks errors caused by a malformed or inconsistent
+// version in the request, as opposed to storage failures
+var ErrInvalidVersion = errors.New("invalid version")
+
+// createTableSQL defines the single-row app_config table
+const createTableSQL = `
+	CREATE TABLE IF NOT EXISTS app_config (
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		latest_version TEXT NOT NULL DEFAULT '1.0.0',
+		minimum_version TEXT NOT NULL DEFAULT '1.0.0',
+		update_url TEXT NOT NULL DEFAULT '',
+		update_message TEXT NOT NULL DEFAULT '',
+		maintenance_mode INTEGER NOT NULL DEFAULT 0,
+		maintenance_message TEXT NOT NULL DEFAULT '',
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)
+`
+
+// InitDB initializes the database connection and creates the config row
+func InitDB(database *sql.DB) error {
+	db = database
+
+	if _, err := db.Exec(createTableSQL); err != nil {
+		log.Printf("❌ Error creating app_config table: %v", err)
+		return err
+	}
+
+	if err := insertDefaultConfig(); err != nil {
+		log.Printf("❌ Error inserting default app config: %v", err)
+		return err
+	}
+
+	log.Println("✅ App config table ready")
+	return nil
+}
+
+// insertDefaultConfig creates the config row on first run
+func insertDefaultConfig() error {
+	_, err := db.Exec(`INSERT OR IGNORE INTO app_config (id) VALUES (1)`)
+	return err
+}
+
+// GetConfig returns the current app config
+func GetConfig() (*AppConfig, error) {
+	var cfg AppConfig
+	err := db.QueryRow(`
+		SELECT latest_version, minimum_version, update_url, update_message,
+		       maintenance_mode, maintenance_message, updated_at
+		FROM app_config WHERE id = 1
+	`).Scan(&cfg.LatestVersion, &cfg.MinimumVersion, &cfg.UpdateURL, &cfg.UpdateMessage,
+		&cfg.MaintenanceMode, &cfg.MaintenanceMessage, &cfg.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// UpdateConfig replaces the app config after validating the versions
+func UpdateConfig(cfg AppConfig) error {
+	if _, err := parseVersion(cfg.LatestVersion); err != nil {
+		return fmt.Errorf("latest_version: %w", err)
+	}
+	if _, err := parseVersion(cfg.MinimumVersion); err != nil {
+		return fmt.Errorf("minimum_version: %w", err)
+	}
+	if cmp, _ := compareVersions(cfg.MinimumVersion, cfg.LatestVersion); cmp > 0 {
+		return fmt.Errorf("%w: minimum_version must not be newer than latest_version", ErrInvalidVersion)
+	}
+
+	_, err := db.Exec(`
+		UPDATE app_config SET
+			latest_version = ?, minimum_version = ?, update_url = ?, update_message = ?,
+			maintenance_mode = ?, maintenance_message = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = 1
+	`, cfg.LatestVersion, cfg.MinimumVersion, cfg.UpdateURL, cfg.UpdateMessage,
+		cfg.MaintenanceMode, cfg.MaintenanceMessage)
+	if err != nil {
+		log.Printf("❌ Error updating app config: %v", err)
+		return err
+	}
+
+	log.Printf("✅ App config updated (latest: %s, minimum: %s)", cfg.LatestVersion, cfg.MinimumVersion)
+	return nil
+}
+
+// CheckVersion compares the app's version with the configured versions.
+// Only a malformed currentVersion yields ErrInvalidVersion.
+func CheckVersion(currentVersion string) (*VersionCheck, error) {
+	if _, err := parseVersion(currentVersion); err != nil {
+		return nil, err
+	}
+
+	cfg, err := GetConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	// The stored versions were validated on write; failing here is a server fault
+	belowMinimum, err := compareVersions(currentVersion, cfg.MinimumVersion)
+	if err != nil {
+		return nil, fmt.Errorf("stored minimum_version: %v", err)
+	}
+	belowLatest, err := compareVersions(currentVersion, cfg.LatestVersion)
+	if err != nil {
+		return nil, fmt.Errorf("stored latest_version: %v", err)
+	}
+
+	check := &VersionCheck{
+		CurrentVersion:  currentVersion,
+		LatestVersion:   cfg.LatestVersion,
+		MinimumVersion:  cfg.MinimumVersion,
+		UpdateRequired:  belowMinimum < 0,
+		UpdateAvailable: belowLatest < 0,
+		UpdateURL:       cfg.UpdateURL,
+		Message:         cfg.UpdateMessage,
+	}
+	return check, nil
+}
+
+// GetConfigHandler returns the app config
+func GetConfigHandler(c *gin.Context) {
+	cfg, err := GetConfig()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get app config"})
+		return
+	}
+	c.JSON(http.StatusOK, cfg)
+}
+
+// VersionCheckHandler reports whether the app at ?version= must or can update
+func VersionCheckHandler(c *gin.Context) {
+	version := c.Query("version")
+	if version == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "version required"})
+		return
+	}
+
+	check, err := CheckVersion(version)
+	if errors.Is(err, ErrInvalidVersion) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Error checking app version: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check version"})
+		return
+	}
+	c.JSON(http.StatusOK, check)
+}
+
+// UpdateConfigHandler replaces the app config (admin)
+func UpdateConfigHandler(c *gin.Context) {
+	var cfg AppConfig
+	if err := c.ShouldBindJSON(&cfg); err != nil {
+		jsonutil.BindError(c, err)
+		return
+	}
+
+	err := UpdateConfig(cfg)
+	if errors.Is(err, ErrInvalidVersion) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update app config"})
+		return
+	}
+
+	updated, err := GetConfig()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get app config"})
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
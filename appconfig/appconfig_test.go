@@ -0,0 +1,111 @@
+package appconfig
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	database.SetMaxOpenConns(1)
+	t.Cleanup(func() { database.Close() })
+	if err := InitDB(database); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0.0", "1.0.0", 0},
+		{"1.2", "1.2.0", 0},
+		{"v1.2.3", "1.2.3", 0},
+		{"1.9.0", "1.10.0", -1},
+		{"1.10.0", "1.9.0", 1},
+		{"1.10.0", "1.10.0", 0},
+		{"2.0", "1.99.99", 1},
+	}
+	for _, tt := range tests {
+		got, err := compareVersions(tt.a, tt.b)
+		if err != nil || got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, %v; want %d", tt.a, tt.b, got, err, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "1..0", "1.x", "-1.0"} {
+		if _, err := parseVersion(bad); !errors.Is(err, ErrInvalidVersion) {
+			t.Errorf("parseVersion(%q) error = %v, want ErrInvalidVersion", bad, err)
+		}
+		if _, err := compareVersions("1.0.0", bad); !errors.Is(err, ErrInvalidVersion) {
+			t.Errorf("compareVersions(%q, %q) error = %v, want ErrInvalidVersion", "1.0.0", bad, err)
+		}
+		if _, err := compareVersions(bad, "1.0.0"); !errors.Is(err, ErrInvalidVersion) {
+			t.Errorf("compareVersions(%q, %q) error = %v, want ErrInvalidVersion", bad, "1.0.0", err)
+		}
+	}
+}
+
+func serve(method, target, body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/version-check", VersionCheckHandler)
+	r.PUT("/config", UpdateConfigHandler)
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestVersionCheckHandlerStatus(t *testing.T) {
+	setupTestDB(t)
+
+	if w := serve(http.MethodGet, "/version-check?version=1.0.0", ""); w.Code != http.StatusOK {
+		t.Fatalf("valid version: status %d", w.Code)
+	}
+	if w := serve(http.MethodGet, "/version-check?version=abc", ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("malformed version: status %d, want 400", w.Code)
+	}
+
+	// A storage failure is the server's fault, not the client's
+	db.Exec("DROP TABLE app_config")
+	if w := serve(http.MethodGet, "/version-check?version=1.0.0", ""); w.Code != http.StatusInternalServerError {
+		t.Fatalf("storage failure: status %d, want 500", w.Code)
+	}
+}
+
+func TestUpdateConfigHandlerStatus(t *testing.T) {
+	setupTestDB(t)
+
+	ok := `{"latest_version":"1.2.0","minimum_version":"1.0.0"}`
+	if w := serve(http.MethodPut, "/config", ok); w.Code != http.StatusOK {
+		t.Fatalf("valid config: status %d: %s", w.Code, w.Body.String())
+	}
+	for _, body := range []string{
+		`{"latest_version":"one","minimum_version":"1.0.0"}`,
+		`{"latest_version":"1.0.0","minimum_version":"2.0.0"}`,
+	} {
+		if w := serve(http.MethodPut, "/config", body); w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: status %d, want 400", body, w.Code)
+		}
+	}
+
+	db.Exec("DROP TABLE app_config")
+	if w := serve(http.MethodPut, "/config", ok); w.Code != http.StatusInternalServerError {
+		t.Fatalf("storage failure: status %d, want 500", w.Code)
+	}
+}
@@ -0,0 +1,57 @@
+package appconfig
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// compareVersions compares two dotted numeric versions such as "1.10.0".
+// It returns -1 if a < b, 0 if they are equal and 1 if a > b. Missing
+// segments count as zero, so "1.2" equals "1.2.0". A leading "v" is allowed.
+func compareVersions(a, b string) (int, error) {
+	as, err := parseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	bs, err := parseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+		switch {
+		case x < y:
+			return -1, nil
+		case x > y:
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+// parseVersion splits a version into its numeric segments
+func parseVersion(v string) ([]int, error) {
+	trimmed := strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if trimmed == "" {
+		return nil, fmt.Errorf("%w %q", ErrInvalidVersion, v)
+	}
+
+	parts := strings.Split(trimmed, ".")
+	segments := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%w %q", ErrInvalidVersion, v)
+		}
+		segments[i] = n
+	}
+	return segments, nil
+}
@@ -0,0 +1,52 @@
+package chat
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+
+	"burma2d/adminauth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getMessagesAsUserHandler returns the chat exactly as the given user sees
+// it, with their block list applied, for debugging reports (admin, audited)
+func getMessagesAsUserHandler(c *gin.Context) {
+	userID := c.Param("id")
+	limit := c.DefaultQuery("limit", "30")
+
+	var username string
+	err := db.QueryRow("SELECT username FROM chat_users WHERE id = ?", userID).Scan(&username)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	log.Printf("📝 Admin %q viewed chat as user %s (%s)", c.GetString(adminauth.ContextUserKey), userID, username)
+
+	messages, err := getVisibleMessages(userID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get messages"})
+		return
+	}
+
+	blocked, err := blockedIDs(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get blocked users"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"user_id":     userID,
+		"username":    username,
+		"banned":      isUserBanned(userID),
+		"blocked_ids": blocked,
+		"messages":    messages,
+	})
+}
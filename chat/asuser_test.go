@@ -0,0 +1,67 @@
+package chat
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetMessagesAsUserAppliesBlocks(t *testing.T) {
+	setupTestDB(t)
+	for _, id := range []string{"viewer", "friend", "troll"} {
+		addUser(t, id)
+	}
+	addMessage(t, "friend", "hello", "2026-03-01 09:00:00")
+	addMessage(t, "troll", "spam", "2026-03-01 09:01:00")
+	addMessage(t, "viewer", "hi all", "2026-03-01 09:02:00")
+	if _, err := db.Exec(`INSERT INTO chat_blocks (blocker_id, blocked_id) VALUES ('viewer', 'troll')`); err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/as-user/:id/messages", getMessagesAsUserHandler)
+
+	view := func(userID string) (int, []string, []string) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/as-user/"+userID+"/messages", nil))
+		var body struct {
+			BlockedIDs []string  `json:"blocked_ids"`
+			Messages   []Message `json:"messages"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		var texts []string
+		for _, m := range body.Messages {
+			texts = append(texts, m.Message)
+		}
+		return w.Code, texts, body.BlockedIDs
+	}
+
+	code, texts, blocked := view("viewer")
+	if code != http.StatusOK {
+		t.Fatalf("status %d", code)
+	}
+	if len(blocked) != 1 || blocked[0] != "troll" {
+		t.Errorf("blocked_ids = %v, want [troll]", blocked)
+	}
+	for _, text := range texts {
+		if text == "spam" {
+			t.Errorf("viewer's view includes a blocked user's message: %v", texts)
+		}
+	}
+	if len(texts) != 2 {
+		t.Errorf("viewer sees %v, want the two unblocked messages", texts)
+	}
+
+	// Another user's view is unaffected by the viewer's blocks
+	if _, texts, _ := view("friend"); len(texts) != 3 {
+		t.Errorf("friend sees %v, want all three messages", texts)
+	}
+
+	if code, _, _ := view("nobody"); code != http.StatusNotFound {
+		t.Errorf("unknown user: status %d, want 404", code)
+	}
+}
@@ -0,0 +1,90 @@
+package chat
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"burma2d/jsonutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxBanStatusBatch bounds one lookup (SQLite allows 999 parameters)
+const maxBanStatusBatch = 500
+
+// BanStatus is a user's ban state as returned by the batch lookup
+type BanStatus struct {
+	Banned   bool       `json:"banned"`
+	Reason   string     `json:"reason,omitempty"`
+	BannedBy string     `json:"banned_by,omitempty"`
+	BannedAt *time.Time `json:"banned_at,omitempty"`
+}
+
+// getBanStatuses returns the ban status of every given user in one query;
+// users that are not banned map to Banned=false
+func getBanStatuses(userIDs []string) (map[string]BanStatus, error) {
+	statuses := make(map[string]BanStatus, len(userIDs))
+	placeholders := make([]string, 0, len(userIDs))
+	args := make([]interface{}, 0, len(userIDs))
+	for _, id := range userIDs {
+		if _, seen := statuses[id]; seen {
+			continue
+		}
+		statuses[id] = BanStatus{}
+		placeholders = append(placeholders, "?")
+		args = append(args, id)
+	}
+	if len(args) == 0 {
+		return statuses, nil
+	}
+
+	rows, err := db.Query(`
+		SELECT user_id, banned_by, reason, created_at
+		FROM chat_banned_users
+		WHERE user_id IN (`+strings.Join(placeholders, ",")+`)
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID, bannedBy, reason string
+		var createdAt time.Time
+		if err := rows.Scan(&userID, &bannedBy, &reason, &createdAt); err != nil {
+			continue
+		}
+		statuses[userID] = BanStatus{
+			Banned:   true,
+			Reason:   reason,
+			BannedBy: bannedBy,
+			BannedAt: &createdAt,
+		}
+	}
+	return statuses, rows.Err()
+}
+
+// getBanStatusBatchHandler returns ban info keyed by user ID for up to
+// maxBanStatusBatch users
+func getBanStatusBatchHandler(c *gin.Context) {
+	var req struct {
+		UserIDs []string `json:"user_ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		jsonutil.BindError(c, err)
+		return
+	}
+	if len(req.UserIDs) > maxBanStatusBatch {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many user IDs (max 500)"})
+		return
+	}
+
+	statuses, err := getBanStatuses(req.UserIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get ban status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"statuses": statuses})
+}
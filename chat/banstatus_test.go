@@ -0,0 +1,79 @@
+package chat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func postBanStatusBatch(t *testing.T, body interface{}) (int, map[string]BanStatus) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/ban-status/batch", getBanStatusBatchHandler)
+
+	payload, _ := json.Marshal(body)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/ban-status/batch", bytes.NewReader(payload)))
+
+	var resp struct {
+		Statuses map[string]BanStatus `json:"statuses"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp.Statuses
+}
+
+func TestBanStatusBatch(t *testing.T) {
+	setupTestDB(t)
+	for _, id := range []string{"alice", "bob", "carol"} {
+		addUser(t, id)
+	}
+	if _, err := db.Exec(`
+		INSERT INTO chat_banned_users (user_id, username, banned_by, reason)
+		VALUES ('bob', 'bob', 'mod1', 'spam'), ('carol', 'carol', 'mod2', 'abuse')
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	code, statuses := postBanStatusBatch(t, gin.H{"user_ids": []string{"alice", "bob", "carol", "bob", "nobody"}})
+	if code != http.StatusOK {
+		t.Fatalf("status %d", code)
+	}
+	if len(statuses) != 4 {
+		t.Fatalf("%d statuses, want one per distinct ID: %v", len(statuses), statuses)
+	}
+	if s := statuses["bob"]; !s.Banned || s.Reason != "spam" || s.BannedBy != "mod1" || s.BannedAt == nil {
+		t.Errorf("bob = %+v, want banned by mod1 for spam", s)
+	}
+	if s := statuses["carol"]; !s.Banned || s.Reason != "abuse" {
+		t.Errorf("carol = %+v, want banned for abuse", s)
+	}
+	for _, id := range []string{"alice", "nobody"} {
+		if s, ok := statuses[id]; !ok || s.Banned {
+			t.Errorf("%s = %+v (present %v), want not banned", id, s, ok)
+		}
+	}
+}
+
+func TestBanStatusBatchValidation(t *testing.T) {
+	setupTestDB(t)
+
+	tooMany := make([]string, maxBanStatusBatch+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("user-%d", i)
+	}
+	if code, _ := postBanStatusBatch(t, gin.H{"user_ids": tooMany}); code != http.StatusBadRequest {
+		t.Errorf("%d IDs: status %d, want 400", len(tooMany), code)
+	}
+	if code, _ := postBanStatusBatch(t, gin.H{}); code != http.StatusBadRequest {
+		t.Errorf("missing user_ids: status %d, want 400", code)
+	}
+	if code, statuses := postBanStatusBatch(t, gin.H{"user_ids": []string{}}); code != http.StatusOK || len(statuses) != 0 {
+		t.Errorf("empty list: status %d, %v", code, statuses)
+	}
+}
@@ -4,15 +4,29 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"burma2d/adminauth"
+	"burma2d/chatcore"
+	"burma2d/dbutil"
+	"burma2d/googleauth"
+	"burma2d/jsonutil"
+	"burma2d/pagination"
+	"burma2d/ratelimit"
+	"burma2d/sessionlog"
+	"burma2d/shutdown"
+	"burma2d/tracing"
+	"burma2d/wordfilter"
+
 	"github.com/gin-gonic/gin"
-	"google.golang.org/api/idtoken"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 var db *sql.DB
@@ -23,12 +37,31 @@ var myanmarLocation *time.Location
 // Firebase OAuth Client ID (replace with your actual client ID)
 var googleClientID string
 
+// validateIDToken verifies sign-in tokens; swappable for tests
+var validateIDToken = googleauth.Validate
+
 // SSE clients management
 type SSEClient struct {
 	UserID   string
 	Username string
 	PhotoURL string
 	Channel  chan []byte
+
+	failures int32 // consecutive sends that found Channel full
+	offline  sync.Once
+}
+
+// markOffline records the disconnect once, whether the stream ended or the
+// client was evicted while its handler was still blocked writing
+func (client *SSEClient) markOffline() {
+	client.offline.Do(func() {
+		db.Exec("UPDATE chat_users SET is_online = 0, last_seen = CURRENT_TIMESTAMP WHERE id = ?", client.UserID)
+		sessionlog.Record(client.UserID, sessionlog.StatusOffline, "sse")
+
+		// Broadcasts the online list on both transports
+		chatcore.Disconnect(client.UserID)
+		log.Printf("🔌 SSE client disconnected: %s", client.UserID)
+	})
 }
 
 var (
@@ -49,12 +82,16 @@ type User struct {
 
 // Message represents a chat message
 type Message struct {
-	ID        int64     `json:"id"`
-	UserID    string    `json:"user_id"`
-	Username  string    `json:"username"`
-	PhotoURL  string    `json:"photo_url"`
-	Message   string    `json:"message"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        int64          `json:"id"`
+	UserID    string         `json:"user_id"`
+	Username  string         `json:"username"`
+	PhotoURL  string         `json:"photo_url"`
+	Message   string         `json:"message"`
+	CreatedAt time.Time      `json:"created_at"`
+	Reactions map[string]int `json:"reactions,omitempty"`      // Emoji -> count
+	IsDeleted bool           `json:"is_deleted,omitempty"`     // Admin views only
+	DeletedAt *time.Time     `json:"deleted_at,omitempty"`     // Admin views only
+	Reason    string         `json:"deleted_reason,omitempty"` // Admin views only: "ban" or "admin"
 }
 
 // BlockedUser represents a block relationship
@@ -66,15 +103,11 @@ type BlockedUser struct {
 }
 
 // OnlineUser represents an online user with details
-type OnlineUser struct {
-	UserID   string `json:"user_id"`
-	Username string `json:"username"`
-	PhotoURL string `json:"photo_url"`
-}
+type OnlineUser = chatcore.OnlineUser
 
 // SSE Event types
 type SSEEvent struct {
-	Type string      `json:"type"` // "message", "online", "offline", "count"
+	Type string      `json:"type"` // "message", "online", "offline", "count", "direct_message", "reaction", "server_closing"
 	Data interface{} `json:"data"`
 }
 
@@ -98,7 +131,43 @@ func InitDB(database *sql.DB) error {
 	}
 	log.Printf("✅ Chat timezone set to Myanmar (GMT+6:30)")
 
-	return createTables()
+	if err := initPoints(); err != nil {
+		return fmt.Errorf("failed to create chat_points table: %w", err)
+	}
+	if err := initQuota(); err != nil {
+		return fmt.Errorf("failed to create chat_message_quota table: %w", err)
+	}
+	initEviction()
+	initDirectAuth()
+	if err := initReports(); err != nil {
+		return fmt.Errorf("failed to create chat report tables: %w", err)
+	}
+	chatcore.SetBanChecker(isUserBanned)
+	chatcore.OnBan(disconnectBanned)
+
+	// Relay messages and online changes from either transport to SSE clients
+	chatcore.OnMessage(relayMessage)
+	chatcore.OnConnection(func(chatcore.OnlineUser, bool) { broadcastOnlineStatus() })
+	chatcore.OnPresence(broadcastPresence)
+	shutdown.OnClosing(func(n shutdown.Notice) {
+		broadcastEvent(SSEEvent{Type: shutdown.EventType, Data: n})
+	})
+
+	if err := createTables(); err != nil {
+		return err
+	}
+
+	// No connections exist yet, so any online flag is left over from a crash or restart
+	result, err := db.Exec("UPDATE chat_users SET is_online = 0 WHERE is_online = 1")
+	if err != nil {
+		return fmt.Errorf("failed to reset online flags: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		log.Printf("🧹 Marked %d stale chat users offline", n)
+	}
+
+	startOnlineReaper()
+	return nil
 }
 
 // SetGoogleClientID sets the Google OAuth client ID for token verification
@@ -145,9 +214,31 @@ func createTables() error {
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (user_id) REFERENCES chat_users(id)
 		)`,
+		`CREATE TABLE IF NOT EXISTS chat_direct_messages (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			sender_id TEXT NOT NULL,
+			recipient_id TEXT NOT NULL,
+			message TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			read_at DATETIME,
+			FOREIGN KEY (sender_id) REFERENCES chat_users(id),
+			FOREIGN KEY (recipient_id) REFERENCES chat_users(id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS chat_reactions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			message_id INTEGER NOT NULL,
+			user_id TEXT NOT NULL,
+			emoji TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(message_id, user_id, emoji),
+			FOREIGN KEY (message_id) REFERENCES chat_messages(id),
+			FOREIGN KEY (user_id) REFERENCES chat_users(id)
+		)`,
 		`CREATE INDEX IF NOT EXISTS idx_messages_created ON chat_messages(created_at DESC)`,
 		`CREATE INDEX IF NOT EXISTS idx_users_online ON chat_users(is_online)`,
 		`CREATE INDEX IF NOT EXISTS idx_banned_users ON chat_banned_users(user_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_dm_pair ON chat_direct_messages(sender_id, recipient_id, created_at DESC)`,
+		`CREATE INDEX IF NOT EXISTS idx_dm_unread ON chat_direct_messages(recipient_id, read_at)`,
 	}
 
 	for _, query := range queries {
@@ -156,6 +247,14 @@ func createTables() error {
 		}
 	}
 
+	// Soft delete: messages removed by moderation keep their row
+	if err := dbutil.AddColumnIfMissing(db, "chat_messages", "deleted_at", "DATETIME"); err != nil {
+		return err
+	}
+	if err := dbutil.AddColumnIfMissing(db, "chat_messages", "deleted_reason", "TEXT"); err != nil {
+		return err
+	}
+
 	log.Println("✅ Chat tables created successfully")
 	return nil
 }
@@ -165,23 +264,59 @@ func RegisterRoutes(router *gin.Engine) {
 	chat := router.Group("/api/burma2d/chat")
 	{
 		// Authentication & User Management
-		chat.POST("/auth/google", googleAuthHandler)
+		chat.POST("/auth/google", googleauth.RateLimit(), googleAuthHandler)
 		chat.GET("/users/online", getOnlineUsersHandler)
+		chat.GET("/users/:id", getUserProfileHandler)
 
 		// Messaging
 		chat.POST("/messages", sendMessageHandler)
 		chat.GET("/messages", getMessagesHandler)
+		chat.POST("/messages/:id/react", reactHandler)
+		chat.DELETE("/messages/:id/react", unreactHandler)
+
+		// Reaction Points
+		chat.GET("/points", getPointsHandler)
+		chat.GET("/leaderboard", getLeaderboardHandler)
+
+		// Direct Messages
+		chat.POST("/dm", sendDirectMessageHandler)
+		chat.GET("/dm", getDirectMessagesHandler)
+		chat.POST("/dm/read", markDirectReadHandler)
+		chat.GET("/dm/unread", getUnreadCountsHandler)
 
 		// Blocking
 		chat.POST("/block", blockUserHandler)
 		chat.POST("/unblock", unblockUserHandler)
 		chat.GET("/blocked", getBlockedUsersHandler)
 
+		// Reports (may trigger auto-moderation)
+		chat.POST("/report", reportUserHandler)
+
+		// Admin routes require an admin session or token
+		admin := chat.Group("/admin", adminauth.Required())
+
 		// Admin: Ban Management
-		chat.POST("/admin/ban", banUserHandler)
-		chat.POST("/admin/unban", unbanUserHandler)
-		chat.GET("/admin/banned", getBannedUsersHandler)
-		chat.GET("/admin/messages", getAllMessagesHandler)
+		admin.POST("/ban", banUserHandler)
+		admin.POST("/unban", unbanUserHandler)
+		admin.GET("/banned", getBannedUsersHandler)
+		admin.POST("/ban-status/batch", getBanStatusBatchHandler)
+		admin.GET("/messages", getAllMessagesHandler)
+		admin.GET("/as-user/:id/messages", getMessagesAsUserHandler)
+		admin.DELETE("/messages/:id", deleteMessageHandler)
+		admin.GET("/deleted", getDeletedMessagesHandler)
+		admin.POST("/deleted/:id/restore", restoreMessageHandler)
+		admin.POST("/deleted/restore", restoreUserMessagesHandler)
+		admin.GET("/sessions", sessionlog.GetUserSessionsHandler)
+		admin.POST("/merge-users", mergeUsersHandler)
+		admin.POST("/unmute", unmuteUserHandler)
+		admin.GET("/reports", getReportsHandler)
+		admin.GET("/moderation-log", getModerationLogHandler)
+
+		// Admin: Banned Words
+		admin.GET("/words", wordfilter.ListWordsHandler)
+		admin.POST("/words", wordfilter.AddWordHandler)
+		admin.PUT("/words/:id", wordfilter.UpdateWordHandler)
+		admin.DELETE("/words/:id", wordfilter.DeleteWordHandler)
 
 		// SSE Stream
 		chat.GET("/stream", sseStreamHandler)
@@ -198,7 +333,7 @@ func googleAuthHandler(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		jsonutil.BindError(c, err)
 		return
 	}
 
@@ -208,16 +343,16 @@ func googleAuthHandler(c *gin.Context) {
 	if googleClientID != "" {
 		// Verify token with Google
 		ctx := context.Background()
-		payload, err := idtoken.Validate(ctx, req.IDToken, googleClientID)
+		payload, err := validateIDToken(ctx, req.IDToken, googleClientID)
 		if err != nil {
 			log.Printf("⚠️  Token validation failed: %v", err)
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid ID token"})
 			return
 		}
 
-		// Extract user info from verified token
-		userID = payload.Claims["email"].(string)
-		email = payload.Claims["email"].(string)
+		// The Google subject is the user ID on both transports
+		userID = payload.Subject
+		email, _ = payload.Claims["email"].(string)
 
 		// Get username from token or request
 		if name, ok := payload.Claims["name"].(string); ok && name != "" {
@@ -237,7 +372,8 @@ func googleAuthHandler(c *gin.Context) {
 
 		log.Printf("✅ Token verified for user: %s", email)
 	} else {
-		// Fallback: Development mode without verification
+		// Fallback: Development mode without verification; there is no
+		// verified subject, so the email stands in as the ID
 		log.Println("⚠️  Running without Google OAuth verification (development mode)")
 		userID = req.Email
 		email = req.Email
@@ -250,25 +386,25 @@ func googleAuthHandler(c *gin.Context) {
 		}
 	}
 
-	// Insert or update user with verified data
-	_, err := db.Exec(`
-		INSERT INTO chat_users (id, email, username, photo_url, is_online)
-		VALUES (?, ?, ?, ?, 1)
-		ON CONFLICT(id) DO UPDATE SET
-			username = excluded.username,
-			photo_url = excluded.photo_url,
-			is_online = 1,
-			last_seen = CURRENT_TIMESTAMP
-	`, userID, email, username, photoURL)
+	// Bans recorded before IDs were unified are keyed by email
+	if chatcore.IsBanned(userID, email) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":  chatcore.BanMessage(),
+			"banned": true,
+		})
+		return
+	}
 
-	if err != nil {
+	// Insert or update user with verified data, adopting an email-keyed row
+	if err := chatcore.EnsureUser(userID, email, username, photoURL); err != nil {
+		log.Printf("❌ Error saving chat user %s: %v", userID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save user"})
 		return
 	}
 
 	// Get user data
 	var user User
-	err = db.QueryRow(`
+	err := db.QueryRow(`
 		SELECT id, email, username, photo_url, last_seen, is_online, created_at
 		FROM chat_users WHERE id = ?
 	`, userID).Scan(&user.ID, &user.Email, &user.Username, &user.PhotoURL,
@@ -298,9 +434,16 @@ func sendMessageHandler(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
+		jsonutil.BindError(c, err)
+		return
+	}
+
+	text, err := chatcore.ValidateMessage(req.Message)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	req.Message = text
 
 	// Check if user is banned
 	if isUserBanned(req.UserID) {
@@ -311,9 +454,45 @@ func sendMessageHandler(c *gin.Context) {
 		return
 	}
 
+	if until, muted := mutedUntil(req.UserID); muted {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":       "You are muted",
+			"muted":       true,
+			"muted_until": until,
+		})
+		return
+	}
+
+	// Throttle per user, shared with the WebSocket chat
+	if ok, wait := chatcore.AllowMessage(req.UserID); !ok {
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":       "You are sending messages too fast",
+			"retry_after": ratelimit.RetryAfterSeconds(wait),
+		})
+		return
+	}
+
+	// Banned words are rejected or masked depending on CHAT_BANNED_WORDS_MODE
+	filtered, allowed := wordfilter.Allow(req.Message)
+	if !allowed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Message contains banned words"})
+		return
+	}
+	req.Message = filtered
+
+	// External moderation (CHAT_MODERATION_URL) decides before anything is stored
+	if v := chatcore.Moderate(c.Request.Context(), req.UserID, req.Message, "sse"); v.Verdict == chatcore.VerdictBlock {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":     "Message was blocked by moderation",
+			"reason":    v.Reason,
+			"moderated": true,
+		})
+		return
+	}
+
 	// Get user info
 	var username, photoURL string
-	err := db.QueryRow(`
+	err = db.QueryRow(`
 		SELECT username, photo_url FROM chat_users WHERE id = ?
 	`, req.UserID).Scan(&username, &photoURL)
 
@@ -322,37 +501,56 @@ func sendMessageHandler(c *gin.Context) {
 		return
 	}
 
-	// Insert message
-	result, err := db.Exec(`
-		INSERT INTO chat_messages (user_id, username, photo_url, message)
-		VALUES (?, ?, ?, ?)
-	`, req.UserID, username, photoURL, req.Message)
-
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send message"})
-		return
-	}
-
-	messageID, _ := result.LastInsertId()
-
-	// Create message object with Myanmar time (GMT+6:30)
-	message := Message{
-		ID:        messageID,
+	// Insert message and credit engagement points together
+	message := chatcore.Message{
 		UserID:    req.UserID,
 		Username:  username,
 		PhotoURL:  photoURL,
 		Message:   req.Message,
 		CreatedAt: time.Now().In(myanmarLocation), // Always Myanmar Yangon time
 	}
+	var pointsAwarded int
+	err = dbutil.WithTx(db, func(tx *sql.Tx) error {
+		// Admins are not held to the daily limit
+		if !adminauth.IsAdmin(c) {
+			if err := chatcore.ConsumeQuota(tx, req.UserID, time.Now()); err != nil {
+				return err
+			}
+		}
 
-	// Broadcast to all connected clients
-	broadcastMessage(message, req.UserID)
+		var err error
+		if message, err = chatcore.InsertMessage(tx, message); err != nil {
+			return err
+		}
+
+		pointsAwarded, err = awardMessagePoints(tx, req.UserID, time.Now())
+		return err
+	})
+
+	var qe *chatcore.QuotaError
+	if errors.As(err, &qe) {
+		quotaExceeded(c, qe)
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Error sending message for %s: %v", req.UserID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send message"})
+		return
+	}
+
+	// Deliver to clients on both transports
+	chatcore.PublishMessage(message)
+	chatcore.Touch(req.UserID)
 
 	// Return response matching Android app expectations
-	c.JSON(http.StatusOK, gin.H{
-		"message_id": messageID,
+	response := gin.H{
+		"message_id": message.ID,
 		"message":    req.Message,
-	})
+	}
+	if pointsEnabled {
+		response["points_awarded"] = pointsAwarded
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 // getMessagesHandler gets recent messages
@@ -365,51 +563,54 @@ func getMessagesHandler(c *gin.Context) {
 		return
 	}
 
-	// Get blocked users
-	blockedIDs, err := getBlockedUserIDs(userID)
+	messages, err := getVisibleMessages(userID, limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get blocked users"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get messages"})
 		return
 	}
 
-	// Build query to exclude blocked users
-	query := `
-		SELECT id, user_id, username, photo_url, message, created_at
-		FROM chat_messages
-		WHERE user_id NOT IN (?)
-		ORDER BY created_at DESC
-		LIMIT ?
-	`
+	jsonutil.Write(c, http.StatusOK, gin.H{
+		"success":  true,
+		"messages": messages,
+	})
+}
 
-	rows, err := db.Query(query, blockedIDs, limit)
+// getVisibleMessages returns the latest messages as viewerID sees them:
+// deleted messages and users the viewer blocked are excluded
+func getVisibleMessages(viewerID, limit string) ([]Message, error) {
+	n, err := strconv.Atoi(limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get messages"})
-		return
+		return nil, fmt.Errorf("invalid limit: %q", limit)
+	}
+
+	blocked, err := blockedIDs(viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := chatcore.RecentMessages(n, blocked)
+	if err != nil {
+		return nil, err
 	}
-	defer rows.Close()
 
 	var messages []Message
-	for rows.Next() {
-		var msg Message
-		err := rows.Scan(&msg.ID, &msg.UserID, &msg.Username, &msg.PhotoURL,
-			&msg.Message, &msg.CreatedAt)
-		if err != nil {
-			continue
-		}
+	for _, m := range stored {
 		// Convert to Myanmar timezone (GMT+6:30)
-		msg.CreatedAt = msg.CreatedAt.In(myanmarLocation)
-		messages = append(messages, msg)
+		m.CreatedAt = m.CreatedAt.In(myanmarLocation)
+		messages = append(messages, fromCore(m))
 	}
 
-	// Reverse to get chronological order
-	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
-		messages[i], messages[j] = messages[j], messages[i]
+	// Attach aggregated emoji reactions
+	ids := make([]int64, len(messages))
+	for i, msg := range messages {
+		ids[i] = msg.ID
+	}
+	reactions := getReactions(ids)
+	for i := range messages {
+		messages[i].Reactions = reactions[messages[i].ID]
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success":  true,
-		"messages": messages,
-	})
+	return messages, nil
 }
 
 // blockUserHandler blocks a user
@@ -420,23 +621,63 @@ func blockUserHandler(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		jsonutil.BindError(c, err)
 		return
 	}
 
-	_, err := db.Exec(`
+	// Insert only while the blocker is under the limit, in one statement so
+	// concurrent requests can't overshoot it
+	maxBlocks := chatcore.MaxBlocks()
+	result, err := db.Exec(`
 		INSERT OR IGNORE INTO chat_blocks (blocker_id, blocked_id)
-		VALUES (?, ?)
-	`, req.BlockerID, req.BlockedID)
+		SELECT ?, ?
+		WHERE (SELECT COUNT(*) FROM chat_blocks WHERE blocker_id = ?) < ?
+	`, req.BlockerID, req.BlockedID, req.BlockerID, maxBlocks)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to block user"})
 		return
 	}
 
+	// Nothing inserted: either already blocked (fine) or at the limit
+	if n, _ := result.RowsAffected(); n == 0 && !isBlocked(req.BlockerID, req.BlockedID) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":      fmt.Sprintf("Block list is full (max %d users). Unblock someone first.", maxBlocks),
+			"max_blocks": maxBlocks,
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{"success": true})
 }
 
+// blockedIDs returns the IDs of the users userID has blocked
+func blockedIDs(userID string) ([]string, error) {
+	rows, err := db.Query("SELECT blocked_id FROM chat_blocks WHERE blocker_id = ?", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids, rows.Err()
+}
+
+// isBlocked reports whether blockerID has blocked blockedID
+func isBlocked(blockerID, blockedID string) bool {
+	var count int
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM chat_blocks WHERE blocker_id = ? AND blocked_id = ?
+	`, blockerID, blockedID).Scan(&count)
+	return err == nil && count > 0
+}
+
 // unblockUserHandler unblocks a user
 func unblockUserHandler(c *gin.Context) {
 	var req struct {
@@ -445,7 +686,7 @@ func unblockUserHandler(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		jsonutil.BindError(c, err)
 		return
 	}
 
@@ -500,28 +741,12 @@ func getBlockedUsersHandler(c *gin.Context) {
 func getOnlineUsersHandler(c *gin.Context) {
 	userID := c.Query("user_id")
 
-	// Get blocked users to exclude
-	blockedIDs, _ := getBlockedUserIDs(userID)
-
-	rows, err := db.Query(`
-		SELECT id, username, photo_url
-		FROM chat_users
-		WHERE is_online = 1 AND id NOT IN (?)
-		ORDER BY username ASC
-	`, blockedIDs)
-
+	// Exclude users the viewer blocked
+	online, err := getConnectedOnlineUsers(userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get online users"})
 		return
 	}
-	defer rows.Close()
-
-	var online []OnlineUser
-	for rows.Next() {
-		var user OnlineUser
-		rows.Scan(&user.UserID, &user.Username, &user.PhotoURL)
-		online = append(online, user)
-	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -541,6 +766,14 @@ func sseStreamHandler(c *gin.Context) {
 		return
 	}
 
+	if isUserBanned(userID) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":  chatcore.BanMessage(),
+			"banned": true,
+		})
+		return
+	}
+
 	// Set SSE headers
 	c.Writer.Header().Set("Content-Type", "text/event-stream")
 	c.Writer.Header().Set("Cache-Control", "no-cache")
@@ -561,11 +794,18 @@ func sseStreamHandler(c *gin.Context) {
 	clients[client.Channel] = client
 	clientsMutex.Unlock()
 
-	// Set user online
+	// Set user online; the stored profile wins over query parameters
+	var storedName, storedPhoto sql.NullString
+	db.QueryRow("SELECT username, photo_url FROM chat_users WHERE id = ?", userID).Scan(&storedName, &storedPhoto)
+	if storedName.String != "" {
+		client.Username = storedName.String
+		client.PhotoURL = storedPhoto.String
+	}
 	db.Exec("UPDATE chat_users SET is_online = 1, last_seen = CURRENT_TIMESTAMP WHERE id = ?", userID)
+	sessionlog.Record(userID, sessionlog.StatusOnline, "sse")
 
-	// Broadcast online status
-	broadcastOnlineStatus()
+	// Registers the connection and broadcasts the online list on both transports
+	chatcore.Connect(OnlineUser{UserID: userID, Username: client.Username, PhotoURL: client.PhotoURL})
 
 	// Send initial connection message with online count
 	onlineCount := getOnlineCount()
@@ -586,21 +826,20 @@ func sseStreamHandler(c *gin.Context) {
 	ticker := time.NewTicker(15 * time.Second)
 	defer ticker.Stop()
 
+	// Clean up however the stream ends: client gone, write error or ban
+	defer func() {
+		clientsMutex.Lock()
+		delete(clients, client.Channel)
+		clientsMutex.Unlock()
+
+		client.markOffline()
+	}()
+
 	// Listen for messages
 	for {
 		select {
 		case <-ctx.Done():
 			// Client disconnected or context cancelled
-			clientsMutex.Lock()
-			delete(clients, client.Channel)
-			clientsMutex.Unlock()
-
-			// Set user offline
-			db.Exec("UPDATE chat_users SET is_online = 0, last_seen = CURRENT_TIMESTAMP WHERE id = ?", userID)
-
-			// Broadcast offline status
-			broadcastOnlineStatus()
-			log.Printf("🔌 SSE client disconnected: %s", userID)
 			return
 		case <-ticker.C:
 			// Send heartbeat to keep connection alive
@@ -610,7 +849,12 @@ func sseStreamHandler(c *gin.Context) {
 				return
 			}
 			c.Writer.(http.Flusher).Flush()
-		case msg := <-client.Channel:
+		case msg, ok := <-client.Channel:
+			if !ok {
+				// Closed by disconnectBanned after the banned event was
+				// queued, or by evictStalled
+				return
+			}
 			_, err := c.Writer.Write(msg)
 			if err != nil {
 				log.Printf("❌ SSE write failed for %s: %v", userID, err)
@@ -623,32 +867,21 @@ func sseStreamHandler(c *gin.Context) {
 
 // Helper functions
 
-func getBlockedUserIDs(userID string) (string, error) {
-	if userID == "" {
-		return "''", nil
-	}
-
-	rows, err := db.Query(`
-		SELECT blocked_id FROM chat_blocks WHERE blocker_id = ?
-	`, userID)
-	if err != nil {
-		return "''", err
-	}
-	defer rows.Close()
-
-	var ids []string
-	for rows.Next() {
-		var id string
-		rows.Scan(&id)
-		ids = append(ids, "'"+id+"'")
-	}
-
-	if len(ids) == 0 {
-		return "''", nil
+// fromCore converts a stored message to the SSE representation
+func fromCore(m chatcore.Message) Message {
+	return Message{
+		ID:        m.ID,
+		UserID:    m.UserID,
+		Username:  m.Username,
+		PhotoURL:  m.PhotoURL,
+		Message:   m.Message,
+		CreatedAt: m.CreatedAt,
 	}
+}
 
-	// Return ALL blocked IDs, not just the first one
-	return strings.Join(ids, ","), nil
+// relayMessage delivers a message sent on either transport to SSE clients
+func relayMessage(m chatcore.Message) {
+	broadcastMessage(fromCore(m), m.UserID)
 }
 
 func broadcastMessage(message Message, senderID string) {
@@ -669,6 +902,10 @@ func broadcastMessage(message Message, senderID string) {
 
 	log.Printf("� Broadcasting message from %s: %s", message.Username, message.Message)
 
+	_, span := tracing.Start(context.Background(), "chat.broadcast_message",
+		attribute.Int64("chat.message_id", message.ID))
+	defer span.End()
+
 	// Get list of users who blocked the sender (do this BEFORE locking)
 	blockedByUsers := make(map[string]bool)
 	rows, err := db.Query(`
@@ -686,10 +923,10 @@ func broadcastMessage(message Message, senderID string) {
 
 	// Now broadcast to all clients
 	clientsMutex.RLock()
-	defer clientsMutex.RUnlock()
 
 	sentCount := 0
-	for clientChan, client := range clients {
+	anyStalled := false
+	for _, client := range clients {
 		// Skip if this user blocked the sender
 		if blockedByUsers[client.UserID] {
 			log.Printf("🚫 Skipped user who blocked sender: %s", client.UserID)
@@ -697,62 +934,95 @@ func broadcastMessage(message Message, senderID string) {
 		}
 
 		// Send to client (non-blocking)
-		select {
-		case clientChan <- sseData:
+		sent, stalled := deliver(client, sseData)
+		if sent {
 			sentCount++
-		default:
+		} else {
 			log.Printf("⚠️ Channel full for user: %s", client.UserID)
 		}
+		anyStalled = anyStalled || stalled
 	}
+	total := len(clients)
+	clientsMutex.RUnlock()
 
-	log.Printf("✅ Message broadcast complete: Sent to %d/%d clients", sentCount, len(clients))
+	if anyStalled {
+		evictStalled()
+	}
+
+	span.SetAttributes(attribute.Int("chat.recipients", sentCount))
+	log.Printf("✅ Message broadcast complete: Sent to %d/%d clients", sentCount, total)
 }
 
 func broadcastOnlineStatus() {
 	// Get all online users
-	rows, _ := db.Query(`
-		SELECT id, username, photo_url
-		FROM chat_users
-		WHERE is_online = 1
-		ORDER BY username ASC
-	`)
-	defer rows.Close()
-
-	var online []OnlineUser
-	for rows.Next() {
-		var user OnlineUser
-		rows.Scan(&user.UserID, &user.Username, &user.PhotoURL)
-		online = append(online, user)
-	}
+	online, _ := getConnectedOnlineUsers("")
 
 	status := OnlineStatus{
 		Count: len(online),
 		Users: online,
 	}
 
-	event := SSEEvent{
+	broadcastEvent(SSEEvent{
 		Type: "online",
 		Data: status,
-	}
+	})
+}
+
+// broadcastEvent sends an event to every connected client (non-blocking)
+func broadcastEvent(event SSEEvent) {
+	_, span := tracing.Start(context.Background(), "chat.broadcast_event",
+		attribute.String("chat.event_type", event.Type))
+	defer span.End()
 
 	data, _ := json.Marshal(event)
 	sseData := []byte(fmt.Sprintf("data: %s\n\n", data))
 
 	clientsMutex.RLock()
-	defer clientsMutex.RUnlock()
-
+	anyStalled := false
 	for _, client := range clients {
-		select {
-		case client.Channel <- sseData:
-		default:
-		}
+		_, stalled := deliver(client, sseData)
+		anyStalled = anyStalled || stalled
+	}
+	clientsMutex.RUnlock()
+
+	if anyStalled {
+		evictStalled()
 	}
 }
 
+// ClientCount returns the number of open SSE connections
+func ClientCount() int {
+	clientsMutex.RLock()
+	defer clientsMutex.RUnlock()
+	return len(clients)
+}
+
+// getOnlineCount returns the number of users connected on either transport
 func getOnlineCount() int {
-	var count int
-	db.QueryRow("SELECT COUNT(*) FROM chat_users WHERE is_online = 1").Scan(&count)
-	return count
+	return chatcore.OnlineCount()
+}
+
+// getConnectedOnlineUsers returns users connected on either transport,
+// excluding users viewerID blocked
+func getConnectedOnlineUsers(viewerID string) ([]OnlineUser, error) {
+	blocked := map[string]bool{}
+	if viewerID != "" {
+		ids, err := blockedIDs(viewerID)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			blocked[id] = true
+		}
+	}
+
+	online := []OnlineUser{}
+	for _, user := range chatcore.OnlineUsers() {
+		if !blocked[user.UserID] {
+			online = append(online, user)
+		}
+	}
+	return online, nil
 }
 
 func sendSSE(w http.ResponseWriter, event SSEEvent) {
@@ -765,7 +1035,7 @@ func sendSSE(w http.ResponseWriter, event SSEEvent) {
 // Admin Ban Management Handlers
 // ============================================
 
-// banUserHandler bans a user and deletes all their messages
+// banUserHandler bans a user and soft-deletes all their messages
 func banUserHandler(c *gin.Context) {
 	var req struct {
 		UserID   string `json:"user_id" binding:"required"`
@@ -774,7 +1044,7 @@ func banUserHandler(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		jsonutil.BindError(c, err)
 		return
 	}
 
@@ -788,54 +1058,17 @@ func banUserHandler(c *gin.Context) {
 		req.BannedBy = "admin"
 	}
 
-	// Get username for the user
-	var username string
-	err := db.QueryRow("SELECT username FROM chat_users WHERE id = ?", req.UserID).Scan(&username)
-	if err != nil {
+	username, deletedCount, err := banUser(req.UserID, req.BannedBy, req.Reason)
+	if err == sql.ErrNoRows {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
-
-	// Begin transaction
-	tx, err := db.Begin()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
-		return
-	}
-	defer tx.Rollback()
-
-	// Insert into banned_users table
-	_, err = tx.Exec(`
-		INSERT INTO chat_banned_users (user_id, username, banned_by, reason)
-		VALUES (?, ?, ?, ?)
-		ON CONFLICT(user_id) DO UPDATE SET
-			banned_by = excluded.banned_by,
-			reason = excluded.reason,
-			created_at = CURRENT_TIMESTAMP
-	`, req.UserID, username, req.BannedBy, req.Reason)
-
 	if err != nil {
+		log.Printf("❌ Error banning user %s: %v", req.UserID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to ban user"})
 		return
 	}
 
-	// Delete all messages from this user
-	result, err := tx.Exec("DELETE FROM chat_messages WHERE user_id = ?", req.UserID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user messages"})
-		return
-	}
-
-	deletedCount, _ := result.RowsAffected()
-
-	// Commit transaction
-	if err := tx.Commit(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
-		return
-	}
-
-	log.Printf("✅ User banned: %s (%s) - Deleted %d messages - Reason: %s", username, req.UserID, deletedCount, req.Reason)
-
 	c.JSON(http.StatusOK, gin.H{
 		"message":          "User banned successfully",
 		"user_id":          req.UserID,
@@ -845,6 +1078,53 @@ func banUserHandler(c *gin.Context) {
 	})
 }
 
+// banUser bans userID, soft-deletes their messages and records the action
+// in the moderation log, then disconnects them on every transport. It
+// returns sql.ErrNoRows when the user has no chat profile.
+func banUser(userID, bannedBy, reason string) (string, int64, error) {
+	var username string
+	if err := db.QueryRow("SELECT username FROM chat_users WHERE id = ?", userID).Scan(&username); err != nil {
+		return "", 0, err
+	}
+
+	// Ban and soft-delete messages together
+	var deletedCount int64
+	err := dbutil.WithTx(db, func(tx *sql.Tx) error {
+		// Insert into banned_users table
+		if _, err := tx.Exec(`
+			INSERT INTO chat_banned_users (user_id, username, banned_by, reason)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT(user_id) DO UPDATE SET
+				banned_by = excluded.banned_by,
+				reason = excluded.reason,
+				created_at = CURRENT_TIMESTAMP
+		`, userID, username, bannedBy, reason); err != nil {
+			return fmt.Errorf("failed to ban user: %w", err)
+		}
+
+		// Soft-delete all messages from this user
+		result, err := tx.Exec(`
+			UPDATE chat_messages SET deleted_at = CURRENT_TIMESTAMP, deleted_reason = ?
+			WHERE user_id = ? AND deleted_at IS NULL
+		`, deleteReasonBan, userID)
+		if err != nil {
+			return fmt.Errorf("failed to delete user messages: %w", err)
+		}
+		deletedCount, _ = result.RowsAffected()
+
+		return logModeration(tx, userID, actionBan, reason, bannedBy)
+	})
+	if err != nil {
+		return "", 0, err
+	}
+
+	log.Printf("✅ User banned: %s (%s) - Deleted %d messages - Reason: %s", username, userID, deletedCount, reason)
+
+	// Let every transport disconnect the user's live connections
+	chatcore.NotifyBanned(userID, reason)
+	return username, deletedCount, nil
+}
+
 // unbanUserHandler removes a user from the banned list
 func unbanUserHandler(c *gin.Context) {
 	var req struct {
@@ -852,7 +1132,7 @@ func unbanUserHandler(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		jsonutil.BindError(c, err)
 		return
 	}
 
@@ -868,6 +1148,7 @@ func unbanUserHandler(c *gin.Context) {
 		return
 	}
 
+	logModeration(db, req.UserID, actionUnban, "", c.GetString(adminauth.ContextUserKey))
 	log.Printf("✅ User unbanned: %s", req.UserID)
 
 	c.JSON(http.StatusOK, gin.H{
@@ -876,16 +1157,38 @@ func unbanUserHandler(c *gin.Context) {
 	})
 }
 
-// getAllMessagesHandler gets all messages for admin (no filtering)
+// getAllMessagesHandler gets messages for admin, including soft-deleted ones
+// Optional query params: user_id, from, to (YYYY-MM-DD), q, limit, offset, include_deleted
 func getAllMessagesHandler(c *gin.Context) {
-	limit := c.DefaultQuery("limit", "100")
+	p, err := pagination.Parse(c, pagination.Options{DefaultLimit: 100, MaxLimit: 500})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
+	var where pagination.Where
+	if userID := c.Query("user_id"); userID != "" {
+		where.Add("user_id = ?", userID)
+	}
+	// from/to are Myanmar dates; created_at is stored in UTC
+	where.LocalDateRange("created_at", myanmarLocation, p)
+	where.Contains("message", strings.TrimSpace(c.Query("q")))
+	if c.DefaultQuery("include_deleted", "true") != "true" {
+		where.Add("deleted_at IS NULL")
+	}
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM chat_messages"+where.SQL(), where.Args()...).Scan(&total); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count messages"})
+		return
+	}
+
+	limitSQL, limitArgs := p.LimitOffset()
 	rows, err := db.Query(`
-		SELECT id, user_id, username, photo_url, message, created_at
-		FROM chat_messages
-		ORDER BY created_at DESC
-		LIMIT ?
-	`, limit)
+		SELECT id, user_id, username, photo_url, message, created_at, deleted_at
+		FROM chat_messages`+where.SQL()+`
+		ORDER BY created_at DESC, id DESC`+limitSQL,
+		append(where.Args(), limitArgs...)...)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get messages"})
@@ -896,10 +1199,17 @@ func getAllMessagesHandler(c *gin.Context) {
 	var messages []Message
 	for rows.Next() {
 		var msg Message
-		err := rows.Scan(&msg.ID, &msg.UserID, &msg.Username, &msg.PhotoURL, &msg.Message, &msg.CreatedAt)
+		var deletedAt sql.NullTime
+		err := rows.Scan(&msg.ID, &msg.UserID, &msg.Username, &msg.PhotoURL, &msg.Message, &msg.CreatedAt, &deletedAt)
 		if err != nil {
 			continue
 		}
+		msg.CreatedAt = msg.CreatedAt.In(myanmarLocation)
+		if deletedAt.Valid {
+			t := deletedAt.Time.In(myanmarLocation)
+			msg.IsDeleted = true
+			msg.DeletedAt = &t
+		}
 		messages = append(messages, msg)
 	}
 
@@ -907,9 +1217,12 @@ func getAllMessagesHandler(c *gin.Context) {
 		messages = []Message{}
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	jsonutil.Write(c, http.StatusOK, gin.H{
 		"messages": messages,
 		"count":    len(messages),
+		"total":    total,
+		"limit":    p.Limit,
+		"offset":   p.Offset,
 	})
 }
 
@@ -958,3 +1271,38 @@ func isUserBanned(userID string) bool {
 	err := db.QueryRow("SELECT COUNT(*) FROM chat_banned_users WHERE user_id = ?", userID).Scan(&count)
 	return err == nil && count > 0
 }
+
+// disconnectBanned queues a banned event for every SSE connection of userID
+// and closes its channel, ending the stream; the stream's cleanup then
+// broadcasts the new online list. Removing the client under the write lock
+// guarantees no broadcast is sending on the channel as it closes.
+func disconnectBanned(userID, reason string) {
+	data, _ := json.Marshal(SSEEvent{
+		Type: "banned",
+		Data: gin.H{
+			"message": chatcore.BanMessage(),
+			"reason":  reason,
+		},
+	})
+	msg := []byte(fmt.Sprintf("data: %s\n\n", data))
+
+	clientsMutex.Lock()
+	count := 0
+	for ch, client := range clients {
+		if client.UserID != userID {
+			continue
+		}
+		select {
+		case ch <- msg:
+		default:
+		}
+		delete(clients, ch)
+		close(ch)
+		count++
+	}
+	clientsMutex.Unlock()
+
+	if count > 0 {
+		log.Printf("🚫 Disconnected %d SSE connection(s) of banned user %s", count, userID)
+	}
+}
@@ -0,0 +1,367 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"burma2d/adminauth"
+	"burma2d/chatcore"
+	"burma2d/sessionlog"
+
+	"github.com/gin-gonic/gin"
+)
+
+var hubOnce sync.Once
+
+// startHub initializes the shared chat hub the stream handlers register with
+// and relays published messages to SSE clients
+func startHub() {
+	hubOnce.Do(func() {
+		chatcore.Init()
+		chatcore.OnMessage(relayMessage)
+	})
+}
+
+// openStream connects an SSE client for userID and returns a function that
+// disconnects it and waits for the handler to finish
+func openStream(t *testing.T, userID string) (*httptest.ResponseRecorder, func()) {
+	t.Helper()
+	startHub()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/stream", sseStreamHandler)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/stream?user_id="+userID, nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		r.ServeHTTP(w, req)
+		close(done)
+	}()
+
+	waitFor(t, func() bool { return chatcore.IsOnline(userID) })
+	return w, func() {
+		cancel()
+		<-done
+	}
+}
+
+// configureCore re-reads the chatcore settings with env applied and
+// restores the defaults when the test ends
+func configureCore(t *testing.T, env map[string]string) {
+	t.Helper()
+	startHub()
+	// Registered before Setenv, so it runs after the variables are restored
+	t.Cleanup(chatcore.Init)
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+	chatcore.Init()
+}
+
+// sendMessage posts text as userID to the SSE send handler
+func sendMessage(t *testing.T, userID, text string) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/messages", sendMessageHandler)
+
+	body, _ := json.Marshal(gin.H{"user_id": userID, "message": text})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/messages", bytes.NewReader(body)))
+	return w
+}
+
+// waitFor polls cond until it holds or a second has passed
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within 1s")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func getAllMessages(t *testing.T, query string) (int, []Message) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/messages", getAllMessagesHandler)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/messages?"+query, nil))
+
+	var body struct {
+		Messages []Message `json:"messages"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	return w.Code, body.Messages
+}
+
+func addMessage(t *testing.T, userID, text, createdAt string) {
+	t.Helper()
+	if _, err := db.Exec(`
+		INSERT INTO chat_messages (user_id, username, photo_url, message, created_at)
+		VALUES (?, ?, '', ?, ?)
+	`, userID, userID, text, createdAt); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetAllMessagesFilters(t *testing.T) {
+	setupTestDB(t)
+	// Stored in UTC; the from/to dates are Myanmar days (UTC+6:30)
+	addMessage(t, "alice", "first", "2026-03-01 09:00:00")  // 1 Mar 15:30
+	addMessage(t, "bob", "second", "2026-03-01 17:30:00")   // 2 Mar 00:00
+	addMessage(t, "alice", "third", "2026-03-02 17:29:00")  // 2 Mar 23:59
+	addMessage(t, "alice", "fourth", "2026-03-02 17:30:00") // 3 Mar 00:00
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"fourth", "third", "second", "first"}},
+		{"user_id=alice", []string{"fourth", "third", "first"}},
+		{"from=2026-03-02&to=2026-03-02", []string{"third", "second"}},
+		{"from=2026-03-02", []string{"fourth", "third", "second"}},
+		{"to=2026-03-01", []string{"first"}},
+		{"user_id=alice&from=2026-03-02&to=2026-03-02", []string{"third"}},
+		{"user_id=carol", []string{}},
+	}
+	for _, tt := range tests {
+		code, messages := getAllMessages(t, tt.query)
+		if code != http.StatusOK {
+			t.Errorf("%q: status %d", tt.query, code)
+			continue
+		}
+		got := []string{}
+		for _, m := range messages {
+			got = append(got, m.Message)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%q: got %v, want %v", tt.query, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%q: got %v, want %v", tt.query, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestGetAllMessagesInMyanmarTime(t *testing.T) {
+	setupTestDB(t)
+	addMessage(t, "alice", "removed", "2026-03-01 09:00:00")
+	if _, err := db.Exec("UPDATE chat_messages SET deleted_at = '2026-03-01 10:00:00'"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, messages := getAllMessages(t, "")
+	if len(messages) != 1 || messages[0].DeletedAt == nil {
+		t.Fatalf("messages = %+v, want the deleted message", messages)
+	}
+	m := messages[0]
+	if got := m.CreatedAt.Format("2006-01-02 15:04 -0700"); got != "2026-03-01 15:30 +0630" {
+		t.Errorf("created_at = %s, want Myanmar time", got)
+	}
+	if got := m.DeletedAt.Format("2006-01-02 15:04 -0700"); got != "2026-03-01 16:30 +0630" {
+		t.Errorf("deleted_at = %s, want Myanmar time", got)
+	}
+}
+
+func TestGetAllMessagesRequiresAdmin(t *testing.T) {
+	setupTestDB(t)
+	gin.SetMode(gin.TestMode)
+	// Registered before Setenv, so it runs after the variables are restored
+	t.Cleanup(adminauth.Init)
+	t.Setenv("ADMIN_TOKEN", "admin-token")
+	t.Setenv("ADMIN_SESSION_SECRET", "test-session-key")
+	adminauth.Init()
+
+	r := gin.New()
+	RegisterRoutes(r)
+	for token, want := range map[string]int{
+		"":            http.StatusUnauthorized,
+		"wrong":       http.StatusUnauthorized,
+		"admin-token": http.StatusOK,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/burma2d/chat/admin/messages", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Errorf("token %q: status %d, want %d", token, w.Code, want)
+		}
+	}
+}
+
+func TestGetAllMessagesBadDate(t *testing.T) {
+	setupTestDB(t)
+
+	if code, _ := getAllMessages(t, "from=03/02/2026"); code != http.StatusBadRequest {
+		t.Errorf("status %d, want 400", code)
+	}
+}
+
+func TestStreamRecordsSessionTransitions(t *testing.T) {
+	setupTestDB(t)
+	t.Setenv("CHAT_SESSION_LOG", "true")
+	sessionlog.InitDB(db)
+	addUser(t, "alice")
+
+	_, disconnect := openStream(t, "alice")
+	disconnect()
+
+	transitions, err := sessionlog.GetUserTransitions("alice", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transitions) != 2 ||
+		transitions[0].Status != sessionlog.StatusOffline || transitions[1].Status != sessionlog.StatusOnline ||
+		transitions[0].Transport != "sse" {
+		t.Fatalf("transitions = %+v, want sse online then offline", transitions)
+	}
+}
+
+func TestInitDBMarksEveryoneOffline(t *testing.T) {
+	setupTestDB(t)
+	t.Setenv("CHAT_PRESENCE_REAP_INTERVAL", "0")
+	addUser(t, "alice")
+	addUser(t, "bob")
+	// Flags left behind by a crash
+	if _, err := db.Exec("UPDATE chat_users SET is_online = 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := InitDB(db); err != nil {
+		t.Fatal(err)
+	}
+
+	var online int
+	if err := db.QueryRow("SELECT COUNT(*) FROM chat_users WHERE is_online = 1").Scan(&online); err != nil {
+		t.Fatal(err)
+	}
+	if online != 0 {
+		t.Fatalf("%d users still online after init", online)
+	}
+}
+
+func TestSendMessageModeration(t *testing.T) {
+	setupTestDB(t)
+	addUser(t, "alice")
+	moderation := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		verdict := "allow"
+		if strings.Contains(req.Message, "scam") {
+			verdict = "block"
+		}
+		json.NewEncoder(w).Encode(gin.H{"verdict": verdict, "reason": "test"})
+	}))
+	defer moderation.Close()
+	configureCore(t, map[string]string{"CHAT_MODERATION_URL": moderation.URL})
+	ch := listen(t, "observer")
+
+	w := sendMessage(t, "alice", "cheap scam link")
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"moderated":true`) {
+		t.Fatalf("blocked message: status %d %s", w.Code, w.Body.String())
+	}
+	if len(ch) != 0 {
+		t.Fatal("blocked message was broadcast")
+	}
+	if code, messages := getAllMessages(t, ""); code != http.StatusOK || len(messages) != 0 {
+		t.Fatalf("blocked message was stored: %d messages", len(messages))
+	}
+
+	if w := sendMessage(t, "alice", "hello"); w.Code != http.StatusOK {
+		t.Fatalf("allowed message: status %d %s", w.Code, w.Body.String())
+	}
+	var m Message
+	nextEvent(t, ch, "message", &m)
+	if m.Message != "hello" || m.UserID != "alice" {
+		t.Fatalf("broadcast %+v, want alice's hello", m)
+	}
+}
+
+func TestBlockLimit(t *testing.T) {
+	setupTestDB(t)
+	configureCore(t, map[string]string{"CHAT_MAX_BLOCKS": "3"})
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/block", blockUserHandler)
+	block := func(blocker, blocked string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(gin.H{"blocker_id": blocker, "blocked_id": blocked})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/block", bytes.NewReader(body)))
+		return w
+	}
+
+	for _, id := range []string{"u1", "u2", "u3"} {
+		if w := block("alice", id); w.Code != http.StatusOK {
+			t.Fatalf("block %s: status %d", id, w.Code)
+		}
+	}
+	w := block("alice", "u4")
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), `"max_blocks":3`) {
+		t.Fatalf("block over the limit: status %d %s, want 409", w.Code, w.Body.String())
+	}
+
+	// Re-blocking is not a new block, and other users have their own limit
+	if w := block("alice", "u2"); w.Code != http.StatusOK {
+		t.Errorf("re-block at the limit: status %d", w.Code)
+	}
+	if w := block("bob", "u4"); w.Code != http.StatusOK {
+		t.Errorf("another blocker: status %d", w.Code)
+	}
+
+	var n int
+	db.QueryRow("SELECT COUNT(*) FROM chat_blocks WHERE blocker_id = 'alice'").Scan(&n)
+	if n != 3 {
+		t.Fatalf("alice has %d blocks, want 3", n)
+	}
+}
+
+func TestSendMessageDailyQuota(t *testing.T) {
+	setupTestDB(t)
+	addUser(t, "alice")
+	configureCore(t, map[string]string{"CHAT_DAILY_MESSAGE_LIMIT": "2"})
+
+	for i := 0; i < 2; i++ {
+		if w := sendMessage(t, "alice", "hello"); w.Code != http.StatusOK {
+			t.Fatalf("message %d: status %d %s", i+1, w.Code, w.Body.String())
+		}
+	}
+
+	w := sendMessage(t, "alice", "one more")
+	var resp struct {
+		DailyLimit int       `json:"daily_limit"`
+		ResetAt    time.Time `json:"reset_at"`
+		RetryAfter int       `json:"retry_after"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusTooManyRequests || resp.DailyLimit != 2 {
+		t.Fatalf("over the limit: status %d %s, want 429", w.Code, w.Body.String())
+	}
+	if _, reset := chatcore.QuotaDay(time.Now()); !resp.ResetAt.Equal(reset) || resp.RetryAfter <= 0 {
+		t.Errorf("reset_at %s retry_after %d, want the next Myanmar midnight", resp.ResetAt, resp.RetryAfter)
+	}
+	if _, messages := getAllMessages(t, ""); len(messages) != 2 {
+		t.Errorf("%d messages stored, want the refused one left out", len(messages))
+	}
+}
@@ -0,0 +1,424 @@
+package chat
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"burma2d/adminauth"
+	"burma2d/chatcore"
+	"burma2d/config"
+	"burma2d/dbutil"
+	"burma2d/googleauth"
+	"burma2d/jsonutil"
+	"burma2d/pagination"
+	"burma2d/ratelimit"
+	"burma2d/wordfilter"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DirectMessage represents a one-to-one private message
+type DirectMessage struct {
+	ID          int64      `json:"id"`
+	SenderID    string     `json:"sender_id"`
+	RecipientID string     `json:"recipient_id"`
+	Message     string     `json:"message"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ReadAt      *time.Time `json:"read_at"`
+}
+
+// UnreadCount represents unread direct messages from one peer
+type UnreadCount struct {
+	PeerID   string `json:"peer_id"`
+	Username string `json:"username"`
+	PhotoURL string `json:"photo_url"`
+	Count    int    `json:"count"`
+}
+
+// authenticate verifies the request's Google ID token; swappable for tests
+var authenticate = googleauth.Authenticate
+
+// insecureDirect takes the user from the request when no client ID is
+// configured (CHAT_INSECURE_AUTH, development only)
+var insecureDirect bool
+
+// errDirectSignIn is returned by directUser without a valid ID token
+var errDirectSignIn = errors.New("sign in to use direct messages")
+
+// errDirectNotConfigured is returned by directUser when neither a client ID
+// nor the development opt-out is set
+var errDirectNotConfigured = errors.New("direct messages are not configured")
+
+func initDirectAuth() {
+	insecureDirect = config.Bool("CHAT_INSECURE_AUTH", false)
+	if insecureDirect {
+		log.Println("⚠️ CHAT_INSECURE_AUTH=true: without a client ID, direct messages trust the user ID in the request (development only)")
+	}
+}
+
+// directUser returns the verified Google subject of the request. Only with
+// CHAT_INSECURE_AUTH and no client ID is requestUserID trusted instead.
+func directUser(c *gin.Context, requestUserID string) (string, error) {
+	if googleClientID == "" {
+		if !insecureDirect {
+			return "", errDirectNotConfigured
+		}
+		if requestUserID = strings.TrimSpace(requestUserID); requestUserID == "" {
+			return "", errors.New("user ID required in development mode")
+		}
+		return requestUserID, nil
+	}
+
+	userID, err := authenticate(c, googleClientID)
+	if err != nil {
+		return "", errDirectSignIn
+	}
+	return userID, nil
+}
+
+// directUserOrAbort resolves the requesting user, answering 401 (or 503
+// when auth is not configured) and returning false on failure
+func directUserOrAbort(c *gin.Context, requestUserID string) (string, bool) {
+	userID, err := directUser(c, requestUserID)
+	switch {
+	case errors.Is(err, errDirectNotConfigured):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Direct messages are not configured"})
+		return "", false
+	case errors.Is(err, errDirectSignIn):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Sign in to use direct messages"})
+		return "", false
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return "", false
+	}
+	return userID, true
+}
+
+// sendDirectMessageHandler sends a private message to a single user. The
+// sender is the subject of the request's Google ID token; sender_id is
+// only read with CHAT_INSECURE_AUTH in development.
+func sendDirectMessageHandler(c *gin.Context) {
+	var req struct {
+		SenderID    string `json:"sender_id"` // CHAT_INSECURE_AUTH only
+		RecipientID string `json:"recipient_id" binding:"required"`
+		Message     string `json:"message" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		jsonutil.BindError(c, err)
+		return
+	}
+
+	senderID, ok := directUserOrAbort(c, req.SenderID)
+	if !ok {
+		return
+	}
+	req.SenderID = senderID
+
+	// The same length and character rules as the public chat
+	text, err := chatcore.ValidateMessage(req.Message)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.Message = text
+
+	if req.SenderID == req.RecipientID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot message yourself"})
+		return
+	}
+
+	if isUserBanned(req.SenderID) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":  "You have been banned from the chat",
+			"banned": true,
+		})
+		return
+	}
+	if until, muted := mutedUntil(req.SenderID); muted {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":       "You are muted",
+			"muted":       true,
+			"muted_until": until,
+		})
+		return
+	}
+
+	// DMs share the per-user rate limit with both public transports
+	if ok, wait := chatcore.AllowMessage(req.SenderID); !ok {
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":       "You are sending messages too fast",
+			"retry_after": ratelimit.RetryAfterSeconds(wait),
+		})
+		return
+	}
+
+	filtered, allowed := wordfilter.Allow(req.Message)
+	if !allowed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Message contains banned words"})
+		return
+	}
+	req.Message = filtered
+
+	if v := chatcore.Moderate(c.Request.Context(), req.SenderID, req.Message, "dm"); v.Verdict == chatcore.VerdictBlock {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":     "Message was blocked by moderation",
+			"reason":    v.Reason,
+			"moderated": true,
+		})
+		return
+	}
+
+	// Both users must exist
+	var count int
+	err = db.QueryRow(`
+		SELECT COUNT(*) FROM chat_users WHERE id IN (?, ?)
+	`, req.SenderID, req.RecipientID).Scan(&count)
+	if err != nil || count != 2 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// Blocking applies in both directions
+	if isBlockedEitherWay(req.SenderID, req.RecipientID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot message this user"})
+		return
+	}
+
+	// DMs count toward the daily message limit; admins are exempt
+	var messageID int64
+	err = dbutil.WithTx(db, func(tx *sql.Tx) error {
+		if !adminauth.IsAdmin(c) {
+			if err := chatcore.ConsumeQuota(tx, req.SenderID, time.Now()); err != nil {
+				return err
+			}
+		}
+
+		result, err := tx.Exec(`
+			INSERT INTO chat_direct_messages (sender_id, recipient_id, message)
+			VALUES (?, ?, ?)
+		`, req.SenderID, req.RecipientID, req.Message)
+		if err != nil {
+			return err
+		}
+		messageID, err = result.LastInsertId()
+		return err
+	})
+
+	var qe *chatcore.QuotaError
+	if errors.As(err, &qe) {
+		quotaExceeded(c, qe)
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Error sending direct message for %s: %v", req.SenderID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send message"})
+		return
+	}
+
+	dm := DirectMessage{
+		ID:          messageID,
+		SenderID:    req.SenderID,
+		RecipientID: req.RecipientID,
+		Message:     req.Message,
+		CreatedAt:   time.Now().In(myanmarLocation),
+	}
+
+	// Deliver to the recipient, and echo to the sender's other sessions
+	sendToUsers(SSEEvent{Type: "direct_message", Data: dm}, req.RecipientID, req.SenderID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"message_id": messageID,
+		"message":    dm,
+	})
+}
+
+// getDirectMessagesHandler returns the latest messages between the signed-in
+// user and peer_id (limit, default 50, max 200; cursor pages to messages
+// before that ID). It does not mark anything read; see markDirectReadHandler.
+func getDirectMessagesHandler(c *gin.Context) {
+	userID, ok := directUserOrAbort(c, c.Query("user_id"))
+	if !ok {
+		return
+	}
+	peerID := c.Query("peer_id")
+	if peerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "peer_id required"})
+		return
+	}
+
+	p, err := pagination.Parse(c, pagination.Options{DefaultLimit: 50, MaxLimit: 200})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var where pagination.Where
+	where.Add("((sender_id = ? AND recipient_id = ?) OR (sender_id = ? AND recipient_id = ?))",
+		userID, peerID, peerID, userID)
+	where.Cursor("id", p)
+	limitSQL, limitArgs := p.LimitOffset()
+
+	rows, err := db.Query(`
+		SELECT id, sender_id, recipient_id, message, created_at, read_at
+		FROM chat_direct_messages`+where.SQL()+`
+		ORDER BY created_at DESC, id DESC`+limitSQL,
+		append(where.Args(), limitArgs...)...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get messages"})
+		return
+	}
+	defer rows.Close()
+
+	messages := []DirectMessage{}
+	for rows.Next() {
+		var dm DirectMessage
+		var readAt sql.NullTime
+		if err := rows.Scan(&dm.ID, &dm.SenderID, &dm.RecipientID, &dm.Message,
+			&dm.CreatedAt, &readAt); err != nil {
+			continue
+		}
+		dm.CreatedAt = dm.CreatedAt.In(myanmarLocation)
+		if readAt.Valid {
+			t := readAt.Time.In(myanmarLocation)
+			dm.ReadAt = &t
+		}
+		messages = append(messages, dm)
+	}
+
+	// Reverse to get chronological order
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"messages": messages,
+	})
+}
+
+// markDirectReadHandler marks peer_id's messages to the signed-in user as read
+func markDirectReadHandler(c *gin.Context) {
+	var req struct {
+		UserID string `json:"user_id"` // CHAT_INSECURE_AUTH only
+		PeerID string `json:"peer_id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		jsonutil.BindError(c, err)
+		return
+	}
+
+	userID, ok := directUserOrAbort(c, req.UserID)
+	if !ok {
+		return
+	}
+
+	result, err := db.Exec(`
+		UPDATE chat_direct_messages SET read_at = CURRENT_TIMESTAMP
+		WHERE sender_id = ? AND recipient_id = ? AND read_at IS NULL
+	`, req.PeerID, userID)
+	if err != nil {
+		log.Printf("⚠️ Failed to mark direct messages read: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark messages read"})
+		return
+	}
+	marked, _ := result.RowsAffected()
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"marked":  marked,
+	})
+}
+
+// getUnreadCountsHandler returns the signed-in user's unread direct message
+// counts per peer
+func getUnreadCountsHandler(c *gin.Context) {
+	userID, ok := directUserOrAbort(c, c.Query("user_id"))
+	if !ok {
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT dm.sender_id, u.username, u.photo_url, COUNT(*)
+		FROM chat_direct_messages dm
+		JOIN chat_users u ON dm.sender_id = u.id
+		WHERE dm.recipient_id = ? AND dm.read_at IS NULL
+		  AND dm.sender_id NOT IN (SELECT blocked_id FROM chat_blocks WHERE blocker_id = ?)
+		GROUP BY dm.sender_id, u.username, u.photo_url
+		ORDER BY MAX(dm.created_at) DESC
+	`, userID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get unread counts"})
+		return
+	}
+	defer rows.Close()
+
+	unread := []UnreadCount{}
+	total := 0
+	for rows.Next() {
+		var u UnreadCount
+		if err := rows.Scan(&u.PeerID, &u.Username, &u.PhotoURL, &u.Count); err != nil {
+			continue
+		}
+		total += u.Count
+		unread = append(unread, u)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"total":   total,
+		"unread":  unread,
+	})
+}
+
+// isBlockedEitherWay reports whether either user has blocked the other
+func isBlockedEitherWay(userA, userB string) bool {
+	var count int
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM chat_blocks
+		WHERE (blocker_id = ? AND blocked_id = ?)
+		   OR (blocker_id = ? AND blocked_id = ?)
+	`, userA, userB, userB, userA).Scan(&count)
+	return err == nil && count > 0
+}
+
+// sendToUsers delivers an SSE event only to the connections of the given users
+func sendToUsers(event SSEEvent, userIDs ...string) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("❌ Failed to marshal %s event: %v", event.Type, err)
+		return
+	}
+	sseData := []byte(fmt.Sprintf("data: %s\n\n", data))
+
+	targets := make(map[string]bool, len(userIDs))
+	for _, id := range userIDs {
+		targets[id] = true
+	}
+
+	clientsMutex.RLock()
+	anyStalled := false
+	for _, client := range clients {
+		if !targets[client.UserID] {
+			continue
+		}
+		sent, stalled := deliver(client, sseData)
+		if !sent {
+			log.Printf("⚠️ Channel full for user: %s", client.UserID)
+		}
+		anyStalled = anyStalled || stalled
+	}
+	clientsMutex.RUnlock()
+
+	if anyStalled {
+		evictStalled()
+	}
+}
@@ -0,0 +1,345 @@
+package chat
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"burma2d/googleauth"
+	"burma2d/wordfilter"
+
+	"github.com/gin-gonic/gin"
+)
+
+// useInsecureDirect trusts user IDs in DM requests, as in development
+func useInsecureDirect(t *testing.T) {
+	t.Helper()
+	old := insecureDirect
+	insecureDirect = true
+	t.Cleanup(func() { insecureDirect = old })
+}
+
+// useDirectTokens requires a bearer token naming the user: "Bearer alice"
+func useDirectTokens(t *testing.T) {
+	t.Helper()
+	oldAuth, oldClientID := authenticate, googleClientID
+	authenticate = func(c *gin.Context, audience string) (string, error) {
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if token == "" || audience != "client-id" {
+			return "", googleauth.ErrNoToken
+		}
+		return token, nil
+	}
+	googleClientID = "client-id"
+	t.Cleanup(func() { authenticate, googleClientID = oldAuth, oldClientID })
+}
+
+// directRequest serves one DM request as the user named by token ("" for none)
+func directRequest(t *testing.T, method, target, token string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/dm", sendDirectMessageHandler)
+	r.GET("/dm", getDirectMessagesHandler)
+	r.POST("/dm/read", markDirectReadHandler)
+	r.GET("/dm/unread", getUnreadCountsHandler)
+
+	var data []byte
+	if body != nil {
+		data, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(method, target, bytes.NewReader(data))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func unreadTotal(t *testing.T, token string) int {
+	t.Helper()
+	w := directRequest(t, http.MethodGet, "/dm/unread", token, nil)
+	var body struct {
+		Total int `json:"total"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unread: status %d %s", w.Code, w.Body.String())
+	}
+	return body.Total
+}
+
+func getDirectMessages(t *testing.T, query string) (int, []DirectMessage) {
+	t.Helper()
+	useInsecureDirect(t)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/dm", getDirectMessagesHandler)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/dm?user_id=a&peer_id=b&"+query, nil))
+
+	var body struct {
+		Messages []DirectMessage `json:"messages"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	return w.Code, body.Messages
+}
+
+func TestGetDirectMessagesPagination(t *testing.T) {
+	setupTestDB(t)
+	for i := 0; i < 300; i++ {
+		sender, recipient := "a", "b"
+		if i%2 == 1 {
+			sender, recipient = recipient, sender
+		}
+		if _, err := db.Exec(`INSERT INTO chat_direct_messages (sender_id, recipient_id, message) VALUES (?, ?, 'hi')`,
+			sender, recipient); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A message in another conversation is never returned
+	db.Exec(`INSERT INTO chat_direct_messages (sender_id, recipient_id, message) VALUES ('a', 'c', 'hi')`)
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"", 50},
+		{"limit=10", 10},
+		{"limit=-1", 50},    // no way around the cap
+		{"limit=0", 50},     // likewise
+		{"limit=5000", 200}, // clamped to the maximum
+	}
+	for _, tt := range tests {
+		code, messages := getDirectMessages(t, tt.query)
+		if code != http.StatusOK || len(messages) != tt.want {
+			t.Errorf("%q: status %d, %d messages; want 200 and %d", tt.query, code, len(messages), tt.want)
+		}
+	}
+
+	if code, _ := getDirectMessages(t, "limit=abc"); code != http.StatusBadRequest {
+		t.Errorf("limit=abc: status %d, want 400", code)
+	}
+}
+
+func TestGetDirectMessagesCursor(t *testing.T) {
+	setupTestDB(t)
+	for i := 0; i < 5; i++ {
+		db.Exec(`INSERT INTO chat_direct_messages (sender_id, recipient_id, message) VALUES ('a', 'b', 'hi')`)
+	}
+
+	_, latest := getDirectMessages(t, "limit=2")
+	if len(latest) != 2 || latest[0].ID != 4 || latest[1].ID != 5 {
+		t.Fatalf("latest page = %+v, want IDs 4 and 5 in order", latest)
+	}
+
+	_, older := getDirectMessages(t, "limit=2&cursor=4")
+	if len(older) != 2 || older[0].ID != 2 || older[1].ID != 3 {
+		t.Fatalf("older page = %+v, want IDs 2 and 3", older)
+	}
+}
+
+func TestDirectMessagesUseVerifiedIdentity(t *testing.T) {
+	setupTestDB(t)
+	startHub()
+	useDirectTokens(t)
+	addUser(t, "alice")
+	addUser(t, "bob")
+
+	// sender_id in the body is ignored; the token decides
+	w := directRequest(t, http.MethodPost, "/dm", "alice",
+		gin.H{"sender_id": "bob", "recipient_id": "bob", "message": "hi bob"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("send: status %d %s", w.Code, w.Body.String())
+	}
+	var sender string
+	db.QueryRow("SELECT sender_id FROM chat_direct_messages").Scan(&sender)
+	if sender != "alice" {
+		t.Fatalf("stored sender %q, want the signed-in user", sender)
+	}
+
+	// Without a token nothing can be sent or read, whatever the query says
+	for _, tt := range []struct{ method, target string }{
+		{http.MethodPost, "/dm"},
+		{http.MethodGet, "/dm?user_id=bob&peer_id=alice"},
+		{http.MethodGet, "/dm/unread?user_id=bob"},
+		{http.MethodPost, "/dm/read"},
+	} {
+		var body interface{} = gin.H{"sender_id": "bob", "recipient_id": "alice", "message": "hi", "user_id": "bob", "peer_id": "alice"}
+		if tt.method == http.MethodGet {
+			body = nil
+		}
+		if w := directRequest(t, tt.method, tt.target, "", body); w.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s without a token: status %d, want 401", tt.method, tt.target, w.Code)
+		}
+	}
+
+	// A third user asking about bob's conversation sees only their own
+	w = directRequest(t, http.MethodGet, "/dm?user_id=bob&peer_id=alice", "mallory", nil)
+	var body struct {
+		Messages []DirectMessage `json:"messages"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if w.Code != http.StatusOK || len(body.Messages) != 0 {
+		t.Errorf("mallory read %d messages of alice and bob", len(body.Messages))
+	}
+	if n := unreadTotal(t, "bob"); n != 1 {
+		t.Errorf("bob has %d unread, want 1", n)
+	}
+}
+
+func TestDirectMessagesNeedAuthConfigured(t *testing.T) {
+	setupTestDB(t)
+	googleClientID, insecureDirect = "", false
+
+	if w := directRequest(t, http.MethodGet, "/dm?user_id=a&peer_id=b", "", nil); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status %d, want 503 without a client ID or CHAT_INSECURE_AUTH", w.Code)
+	}
+}
+
+func TestReadingDirectMessagesDoesNotMarkThemRead(t *testing.T) {
+	setupTestDB(t)
+	useDirectTokens(t)
+	addUser(t, "alice")
+	addUser(t, "bob")
+	db.Exec(`INSERT INTO chat_direct_messages (sender_id, recipient_id, message) VALUES ('alice', 'bob', 'one'), ('alice', 'bob', 'two'), ('bob', 'alice', 'reply')`)
+
+	if w := directRequest(t, http.MethodGet, "/dm?peer_id=alice", "bob", nil); w.Code != http.StatusOK {
+		t.Fatalf("get: status %d", w.Code)
+	}
+	if n := unreadTotal(t, "bob"); n != 2 {
+		t.Fatalf("after reading the conversation bob has %d unread, want 2", n)
+	}
+
+	w := directRequest(t, http.MethodPost, "/dm/read", "bob", gin.H{"peer_id": "alice"})
+	var resp struct {
+		Marked int `json:"marked"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || resp.Marked != 2 {
+		t.Fatalf("mark read: status %d %s, want 2 marked", w.Code, w.Body.String())
+	}
+	if n := unreadTotal(t, "bob"); n != 0 {
+		t.Errorf("bob has %d unread after marking, want 0", n)
+	}
+	// Only messages to the caller are marked
+	if n := unreadTotal(t, "alice"); n != 1 {
+		t.Errorf("alice has %d unread, want her reply from bob left alone", n)
+	}
+}
+
+// sendDirect sends text from alice to bob
+func sendDirect(t *testing.T, text string) *httptest.ResponseRecorder {
+	t.Helper()
+	return directRequest(t, http.MethodPost, "/dm", "alice", gin.H{"recipient_id": "bob", "message": text})
+}
+
+func directCount(t *testing.T) int {
+	t.Helper()
+	var n int
+	db.QueryRow("SELECT COUNT(*) FROM chat_direct_messages").Scan(&n)
+	return n
+}
+
+func setupDirect(t *testing.T, env map[string]string) {
+	t.Helper()
+	setupTestDB(t)
+	useDirectTokens(t)
+	addUser(t, "alice")
+	addUser(t, "bob")
+	configureCore(t, env)
+}
+
+func TestSendDirectMessageRateLimitAndQuota(t *testing.T) {
+	setupDirect(t, map[string]string{"CHAT_RATE_LIMIT_MESSAGES": "2"})
+	for i := 0; i < 2; i++ {
+		if w := sendDirect(t, "hi"); w.Code != http.StatusOK {
+			t.Fatalf("message %d: status %d %s", i+1, w.Code, w.Body.String())
+		}
+	}
+	if w := sendDirect(t, "hi"); w.Code != http.StatusTooManyRequests || !strings.Contains(w.Body.String(), "retry_after") {
+		t.Fatalf("over the rate limit: status %d %s, want 429", w.Code, w.Body.String())
+	}
+
+	setupDirect(t, map[string]string{"CHAT_DAILY_MESSAGE_LIMIT": "1"})
+	sendDirect(t, "hi")
+	if w := sendDirect(t, "again"); w.Code != http.StatusTooManyRequests || !strings.Contains(w.Body.String(), `"daily_limit":1`) {
+		t.Fatalf("over the daily limit: status %d %s, want 429", w.Code, w.Body.String())
+	}
+	if n := directCount(t); n != 1 {
+		t.Errorf("%d DMs stored, want the refused one left out", n)
+	}
+}
+
+func TestSendDirectMessageFilters(t *testing.T) {
+	moderation := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Message   string `json:"message"`
+			Transport string `json:"transport"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		verdict := "allow"
+		if strings.Contains(req.Message, "scam") && req.Transport == "dm" {
+			verdict = "block"
+		}
+		json.NewEncoder(w).Encode(gin.H{"verdict": verdict, "reason": "test"})
+	}))
+	defer moderation.Close()
+	setupDirect(t, map[string]string{"CHAT_MODERATION_URL": moderation.URL})
+
+	// Banned words, rejected outright
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM chat_banned_words")
+		wordfilter.InitDB(db)
+	})
+	t.Setenv("CHAT_BANNED_WORDS_MODE", "reject")
+	wordfilter.InitDB(db)
+	db.Exec("INSERT INTO chat_banned_words (word) VALUES ('badword')")
+	wordfilter.InitDB(db)
+
+	if w := sendDirect(t, "you badword"); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "banned words") {
+		t.Errorf("banned word: status %d %s, want 400", w.Code, w.Body.String())
+	}
+	if w := sendDirect(t, "cheap scam link"); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"moderated":true`) {
+		t.Errorf("moderated: status %d %s, want 400", w.Code, w.Body.String())
+	}
+	if w := sendDirect(t, "hello"); w.Code != http.StatusOK {
+		t.Errorf("clean message: status %d %s", w.Code, w.Body.String())
+	}
+	if n := directCount(t); n != 1 {
+		t.Errorf("%d DMs stored, want only the clean one", n)
+	}
+}
+
+func TestSendDirectMessageValidation(t *testing.T) {
+	setupDirect(t, map[string]string{"CHAT_MAX_MESSAGE_RUNES": "10"})
+
+	for name, text := range map[string]string{
+		"oversize":        strings.Repeat("မ", 11),
+		"control only":    "\x00\x07\x1b",
+		"zero-width only": "\u200b\u200b",
+		"whitespace only": " \n\t ",
+	} {
+		if w := sendDirect(t, text); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d %s, want 400", name, w.Code, w.Body.String())
+		}
+	}
+	if n := directCount(t); n != 0 {
+		t.Fatalf("%d invalid DMs stored", n)
+	}
+
+	// Exactly at the limit, counted in characters rather than bytes; stored trimmed
+	if w := sendDirect(t, "  "+strings.Repeat("မ", 10)+"  "); w.Code != http.StatusOK {
+		t.Fatalf("at the limit: status %d %s", w.Code, w.Body.String())
+	}
+	var stored string
+	db.QueryRow("SELECT message FROM chat_direct_messages").Scan(&stored)
+	if stored != strings.Repeat("မ", 10) {
+		t.Errorf("stored %q, want the trimmed message", stored)
+	}
+}
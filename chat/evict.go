@@ -0,0 +1,69 @@
+package chat
+
+import (
+	"log"
+	"sync/atomic"
+
+	"burma2d/config"
+)
+
+var (
+	// maxSendFailures is how many sends in a row may find a client's buffer
+	// full before it is evicted (CHAT_SSE_MAX_SEND_FAILURES, 0 disables)
+	maxSendFailures int32
+
+	// debugLogging enables verbose logs (CHAT_DEBUG_LOG)
+	debugLogging bool
+)
+
+func initEviction() {
+	maxSendFailures = int32(config.Int("CHAT_SSE_MAX_SEND_FAILURES", 5))
+	debugLogging = config.Bool("CHAT_DEBUG_LOG", false)
+}
+
+// debugf logs only when CHAT_DEBUG_LOG is enabled
+func debugf(format string, args ...interface{}) {
+	if debugLogging {
+		log.Printf("🐛 "+format, args...)
+	}
+}
+
+// deliver queues data for a client without blocking. A full buffer counts
+// as a failed send; a successful one resets the count. It reports whether
+// the client has now failed often enough to be evicted.
+func deliver(client *SSEClient, data []byte) (sent, stalled bool) {
+	select {
+	case client.Channel <- data:
+		atomic.StoreInt32(&client.failures, 0)
+		return true, false
+	default:
+		n := atomic.AddInt32(&client.failures, 1)
+		return false, maxSendFailures > 0 && n >= maxSendFailures
+	}
+}
+
+// evictStalled removes clients whose buffers stayed full, closes their
+// channels so the stream handler exits once unblocked, and marks them
+// offline straight away. Call it without holding clientsMutex.
+func evictStalled() {
+	if maxSendFailures <= 0 {
+		return
+	}
+
+	var evicted []*SSEClient
+	clientsMutex.Lock()
+	for ch, client := range clients {
+		if atomic.LoadInt32(&client.failures) < maxSendFailures {
+			continue
+		}
+		delete(clients, ch)
+		close(ch)
+		evicted = append(evicted, client)
+	}
+	clientsMutex.Unlock()
+
+	for _, client := range evicted {
+		debugf("Evicted stalled SSE client %s after %d failed sends", client.UserID, maxSendFailures)
+		client.markOffline()
+	}
+}
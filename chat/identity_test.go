@@ -0,0 +1,124 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"burma2d/chatcore"
+	"burma2d/chatws"
+	"burma2d/sessionlog"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"google.golang.org/api/idtoken"
+)
+
+// useFakeTokens accepts any token as the Google account it names: "sub|email"
+func useFakeTokens(t *testing.T) {
+	t.Helper()
+	oldValidate, oldClientID := validateIDToken, googleClientID
+	validateIDToken = func(_ context.Context, token, _ string) (*idtoken.Payload, error) {
+		sub, email, _ := strings.Cut(token, "|")
+		return &idtoken.Payload{Subject: sub, Claims: map[string]interface{}{"email": email, "name": "Ann"}}, nil
+	}
+	googleClientID = "client-id"
+	t.Cleanup(func() { validateIDToken, googleClientID = oldValidate, oldClientID })
+}
+
+// signInSSE signs in through the SSE auth handler and returns the user ID
+func signInSSE(t *testing.T, token string) string {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/auth/google", googleAuthHandler)
+
+	body, _ := json.Marshal(gin.H{"id_token": token})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/auth/google", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("SSE sign-in: status %d %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		UserID string `json:"user_id"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return resp.UserID
+}
+
+// dialWS connects to the WebSocket chat with an unverified token carrying claims
+func dialWS(t *testing.T, claims map[string]string) *websocket.Conn {
+	t.Helper()
+	t.Setenv("CHAT_INSECURE_AUTH", "true")
+	if err := chatws.InitDB(db); err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.GET("/ws", chatws.HandleWebSocket)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	payload, _ := json.Marshal(claims)
+	token := "header." + base64.RawURLEncoding.EncodeToString(payload) + ".signature"
+	u := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws?idtoken=" + url.QueryEscape(token)
+	conn, _, err := websocket.DefaultDialer.Dial(u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestSameAccountSameIDOnBothTransports(t *testing.T) {
+	setupTestDB(t)
+	startHub()
+	useFakeTokens(t)
+	// Adopting an old row also moves session log entries and redemptions
+	sessionlog.InitDB(db)
+	if _, err := db.Exec(`CREATE TABLE gift_redemptions (id INTEGER PRIMARY KEY, user_id TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+	// A row from before IDs were unified, keyed by email
+	if _, err := db.Exec(`INSERT INTO chat_users (id, email, username, photo_url) VALUES ('ann@example.com', 'ann@example.com', 'Ann', '')`); err != nil {
+		t.Fatal(err)
+	}
+	addMessage(t, "ann@example.com", "old message", "2026-03-01 09:00:00")
+
+	sseID := signInSSE(t, "sub-ann|ann@example.com")
+	if sseID != "sub-ann" {
+		t.Fatalf("SSE user ID = %q, want the Google subject", sseID)
+	}
+
+	conn := dialWS(t, map[string]string{"sub": "sub-ann", "email": "ann@example.com", "name": "Ann"})
+	if err := conn.WriteJSON(map[string]string{"type": "message", "message": "from ws"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The WebSocket message is stored under the same ID
+	waitFor(t, func() bool {
+		var n int
+		db.QueryRow("SELECT COUNT(*) FROM chat_messages WHERE message = 'from ws'").Scan(&n)
+		return n == 1
+	})
+	var wsID string
+	db.QueryRow("SELECT user_id FROM chat_messages WHERE message = 'from ws'").Scan(&wsID)
+	if wsID != sseID {
+		t.Fatalf("WebSocket user ID = %q, SSE user ID = %q", wsID, sseID)
+	}
+
+	var users, legacy int
+	db.QueryRow("SELECT COUNT(*) FROM chat_users WHERE email = 'ann@example.com'").Scan(&users)
+	db.QueryRow("SELECT COUNT(*) FROM chat_messages WHERE user_id = 'sub-ann' AND message = 'old message'").Scan(&legacy)
+	if users != 1 || legacy != 1 {
+		t.Fatalf("%d profiles for the account, old message adopted: %v", users, legacy == 1)
+	}
+	if !chatcore.IsOnline("sub-ann") {
+		t.Fatal("WebSocket session not online under the subject")
+	}
+}
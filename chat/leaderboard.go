@@ -0,0 +1,110 @@
+package chat
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Leaderboard size bounds
+const (
+	defaultLeaderboardLimit = 10
+	maxLeaderboardLimit     = 100
+)
+
+// LeaderboardEntry is one ranked user on the top chatters board
+type LeaderboardEntry struct {
+	Rank         int    `json:"rank"`
+	UserID       string `json:"user_id"`
+	Username     string `json:"username"`
+	PhotoURL     string `json:"photo_url"`
+	MessageCount int    `json:"message_count"`
+}
+
+// leaderboardSince returns the start of period in Myanmar time: today for
+// "day", the last seven calendar days for "week", and zero for "all"
+func leaderboardSince(period string, now time.Time) (time.Time, bool) {
+	local := now.In(myanmarLocation)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, myanmarLocation)
+
+	switch period {
+	case "day":
+		return midnight, true
+	case "week":
+		return midnight.AddDate(0, 0, -6), true
+	case "all":
+		return time.Time{}, true
+	}
+	return time.Time{}, false
+}
+
+// getLeaderboard ranks users by visible messages sent since since (zero =
+// all time), leaving out banned users. Ties go to whoever posted first.
+func getLeaderboard(since time.Time, limit int) ([]LeaderboardEntry, error) {
+	rows, err := db.Query(`
+		SELECT m.user_id, u.username, COALESCE(u.photo_url, ''), COUNT(*) AS messages
+		FROM chat_messages m
+		JOIN chat_users u ON u.id = m.user_id
+		WHERE m.deleted_at IS NULL
+		  AND m.created_at >= ?
+		  AND m.user_id NOT IN (SELECT user_id FROM chat_banned_users)
+		GROUP BY m.user_id
+		ORDER BY messages DESC, MIN(m.id) ASC
+		LIMIT ?
+	`, since.UTC().Format("2006-01-02 15:04:05"), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []LeaderboardEntry{}
+	for rows.Next() {
+		var e LeaderboardEntry
+		if err := rows.Scan(&e.UserID, &e.Username, &e.PhotoURL, &e.MessageCount); err != nil {
+			continue
+		}
+		e.Rank = len(entries) + 1
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// getLeaderboardHandler returns the top chatters for period=day|week|all
+func getLeaderboardHandler(c *gin.Context) {
+	period := c.DefaultQuery("period", "week")
+	since, ok := leaderboardSince(period, time.Now())
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "period must be day, week or all"})
+		return
+	}
+
+	limit := defaultLeaderboardLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		limit = n
+	}
+	if limit > maxLeaderboardLimit {
+		limit = maxLeaderboardLimit
+	}
+
+	entries, err := getLeaderboard(since, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get leaderboard"})
+		return
+	}
+
+	response := gin.H{
+		"period":  period,
+		"entries": entries,
+	}
+	if !since.IsZero() {
+		response["since"] = since
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,103 @@
+package chat
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func getLeaderboardRanks(t *testing.T, query string) (int, []string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/leaderboard", getLeaderboardHandler)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/leaderboard?"+query, nil))
+	var body struct {
+		Entries []LeaderboardEntry `json:"entries"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+
+	ranked := []string{}
+	for i, e := range body.Entries {
+		if e.Rank != i+1 {
+			t.Errorf("%q: %s has rank %d at position %d", query, e.UserID, e.Rank, i+1)
+		}
+		ranked = append(ranked, e.UserID)
+	}
+	return w.Code, ranked
+}
+
+func TestLeaderboardPeriods(t *testing.T) {
+	setupTestDB(t)
+	for _, id := range []string{"alice", "bob", "carol", "dave", "erin"} {
+		addUser(t, id)
+	}
+
+	now := time.Now()
+	midnight, _ := leaderboardSince("day", now)
+	stamp := func(t time.Time) string { return t.UTC().Format("2006-01-02 15:04:05") }
+	today, yesterday, lastMonth := stamp(now), stamp(midnight.Add(-time.Hour)), stamp(midnight.AddDate(0, 0, -30))
+
+	seed := []struct {
+		user, at string
+		n        int
+	}{
+		{"alice", today, 1}, {"alice", yesterday, 3},
+		{"bob", today, 2},
+		{"carol", lastMonth, 6},
+		{"dave", today, 5}, // banned below
+		{"erin", today, 1},
+	}
+	for _, s := range seed {
+		for i := 0; i < s.n; i++ {
+			addMessage(t, s.user, "hi", s.at)
+		}
+	}
+	// Deleted messages don't count
+	addMessage(t, "erin", "removed", today)
+	addMessage(t, "erin", "removed", today)
+	db.Exec("UPDATE chat_messages SET deleted_at = CURRENT_TIMESTAMP WHERE message = 'removed'")
+	db.Exec("INSERT INTO chat_banned_users (user_id, username) VALUES ('dave', 'dave')")
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		// alice and erin tie on one message; alice posted first
+		{"period=day", []string{"bob", "alice", "erin"}},
+		{"period=week", []string{"alice", "bob", "erin"}},
+		{"", []string{"alice", "bob", "erin"}},
+		{"period=all", []string{"carol", "alice", "bob", "erin"}},
+		{"period=all&limit=2", []string{"carol", "alice"}},
+	}
+	for _, tt := range tests {
+		code, got := getLeaderboardRanks(t, tt.query)
+		if code != http.StatusOK || !sameIDs(got, tt.want) {
+			t.Errorf("%q: status %d, ranking %v, want %v", tt.query, code, got, tt.want)
+		}
+	}
+
+	for _, query := range []string{"period=month", "limit=0", "limit=x"} {
+		if code, _ := getLeaderboardRanks(t, query); code != http.StatusBadRequest {
+			t.Errorf("%q: status %d, want 400", query, code)
+		}
+	}
+}
+
+func sameIDs(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,52 @@
+package chat
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"burma2d/chatcore"
+	"burma2d/jsonutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mergeUsersHandler merges a duplicate chat user into another (admin)
+func mergeUsersHandler(c *gin.Context) {
+	var req struct {
+		FromID string `json:"from_id" binding:"required"`
+		IntoID string `json:"into_id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		jsonutil.BindError(c, err)
+		return
+	}
+	if req.FromID == req.IntoID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from_id and into_id must differ"})
+		return
+	}
+
+	affected, newlyBanned, err := chatcore.MergeUsers(req.FromID, req.IntoID)
+	if errors.Is(err, chatcore.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Error merging user %s into %s: %v", req.FromID, req.IntoID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge users"})
+		return
+	}
+
+	if newlyBanned {
+		chatcore.NotifyBanned(req.IntoID, "Account merged with a banned account")
+	}
+
+	log.Printf("🔀 Merged chat user %s into %s: %v", req.FromID, req.IntoID, affected)
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"from_id":  req.FromID,
+		"into_id":  req.IntoID,
+		"affected": affected,
+	})
+}
@@ -0,0 +1,135 @@
+package chat
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+
+	"burma2d/config"
+	"burma2d/userdata"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Chat engagement points, read in initPoints. Off unless CHAT_POINTS_ENABLED=true.
+var (
+	pointsEnabled    bool
+	pointsPerMessage int
+	pointsDailyCap   int
+	pointsCooldown   time.Duration
+)
+
+// initPoints loads the engagement reward settings: CHAT_POINTS_PER_MESSAGE
+// (default 1), CHAT_POINTS_DAILY_CAP (default 20, per Myanmar calendar day)
+// and CHAT_POINTS_COOLDOWN (default 30s between rewarded messages)
+func initPoints() error {
+	pointsEnabled = config.Bool("CHAT_POINTS_ENABLED", false)
+	pointsPerMessage = config.Int("CHAT_POINTS_PER_MESSAGE", 1)
+	pointsDailyCap = config.Int("CHAT_POINTS_DAILY_CAP", 20)
+	pointsCooldown = config.Duration("CHAT_POINTS_COOLDOWN", 30*time.Second)
+
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS chat_points (
+			user_id TEXT NOT NULL,
+			day TEXT NOT NULL,
+			points INTEGER NOT NULL DEFAULT 0,
+			last_awarded_at INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (user_id, day)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+	userdata.Register(userdata.Table{
+		Section: "points",
+		Export:  "SELECT * FROM chat_points WHERE user_id = ? ORDER BY day",
+		Delete:  "DELETE FROM chat_points WHERE user_id = ?",
+	})
+
+	if pointsEnabled && pointsPerMessage > 0 && pointsDailyCap > 0 {
+		log.Printf("✅ Chat points: %d per message, %d per day, %s cooldown", pointsPerMessage, pointsDailyCap, pointsCooldown)
+	} else {
+		pointsEnabled = false
+		log.Println("ℹ️  Chat points disabled (set CHAT_POINTS_ENABLED=true to enable)")
+	}
+	return nil
+}
+
+// pointsDay is the calendar day points are capped by
+func pointsDay(now time.Time) string {
+	return now.In(myanmarLocation).Format("2006-01-02")
+}
+
+// awardMessagePoints credits points for a sent message inside tx and returns
+// how many were awarded: none when disabled, during the cooldown, or once
+// the daily cap is reached (the last award is trimmed to fit the cap)
+func awardMessagePoints(tx *sql.Tx, userID string, now time.Time) (int, error) {
+	if !pointsEnabled {
+		return 0, nil
+	}
+
+	day := pointsDay(now)
+	var today int
+	var lastAwarded int64
+	err := tx.QueryRow(`
+		SELECT points, last_awarded_at FROM chat_points WHERE user_id = ? AND day = ?
+	`, userID, day).Scan(&today, &lastAwarded)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	if now.Sub(time.Unix(lastAwarded, 0)) < pointsCooldown {
+		return 0, nil
+	}
+	award := pointsPerMessage
+	if remaining := pointsDailyCap - today; award > remaining {
+		award = remaining
+	}
+	if award <= 0 {
+		return 0, nil
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO chat_points (user_id, day, points, last_awarded_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(user_id, day) DO UPDATE SET
+			points = points + excluded.points,
+			last_awarded_at = excluded.last_awarded_at
+	`, userID, day, award, now.Unix())
+	if err != nil {
+		return 0, err
+	}
+	return award, nil
+}
+
+// getPointsHandler returns a user's chat points: lifetime total, the balance
+// left after gift redemptions and today's progress toward the daily cap
+func getPointsHandler(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id required"})
+		return
+	}
+
+	var total, today, spent int
+	err := db.QueryRow(`
+		SELECT COALESCE(SUM(points), 0),
+		       COALESCE(SUM(CASE WHEN day = ? THEN points END), 0),
+		       (SELECT COALESCE(SUM(points_spent), 0) FROM gift_redemptions WHERE user_id = ?)
+		FROM chat_points WHERE user_id = ?
+	`, pointsDay(time.Now()), userID, userID).Scan(&total, &today, &spent)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get points"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":   pointsEnabled,
+		"user_id":   userID,
+		"total":     total,
+		"balance":   total - spent,
+		"today":     today,
+		"daily_cap": pointsDailyCap,
+	})
+}
@@ -0,0 +1,97 @@
+package chat
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"burma2d/dbutil"
+)
+
+// award runs awardMessagePoints in its own transaction
+func award(t *testing.T, userID string, now time.Time) int {
+	t.Helper()
+	var n int
+	err := dbutil.WithTx(db, func(tx *sql.Tx) error {
+		var err error
+		n, err = awardMessagePoints(tx, userID, now)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestMessagesAwardPointsUpToDailyCap(t *testing.T) {
+	t.Setenv("CHAT_POINTS_ENABLED", "true")
+	t.Setenv("CHAT_POINTS_PER_MESSAGE", "2")
+	t.Setenv("CHAT_POINTS_DAILY_CAP", "5")
+	t.Setenv("CHAT_POINTS_COOLDOWN", "0s")
+	setupTestDB(t)
+	configureCore(t, map[string]string{"CHAT_RATE_LIMIT_MESSAGES": "100"})
+	addUser(t, "alice")
+
+	var awarded []int
+	for i := 0; i < 5; i++ {
+		w := sendMessage(t, "alice", "hello")
+		if w.Code != http.StatusOK {
+			t.Fatalf("send %d: status %d %s", i+1, w.Code, w.Body.String())
+		}
+		var body struct {
+			PointsAwarded int `json:"points_awarded"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		awarded = append(awarded, body.PointsAwarded)
+	}
+
+	// The third award is trimmed to fit the cap; nothing after it
+	want := []int{2, 2, 1, 0, 0}
+	for i := range want {
+		if awarded[i] != want[i] {
+			t.Fatalf("points awarded = %v, want %v", awarded, want)
+		}
+	}
+	var total int
+	db.QueryRow("SELECT SUM(points) FROM chat_points WHERE user_id = 'alice'").Scan(&total)
+	if total != 5 {
+		t.Fatalf("stored points = %d, want the cap of 5", total)
+	}
+}
+
+func TestAwardMessagePointsCooldownAndNewDay(t *testing.T) {
+	t.Setenv("CHAT_POINTS_ENABLED", "true")
+	t.Setenv("CHAT_POINTS_DAILY_CAP", "2")
+	t.Setenv("CHAT_POINTS_COOLDOWN", "30s")
+	setupTestDB(t)
+
+	// 23:00 in Yangon
+	now := time.Date(2026, 3, 1, 16, 30, 0, 0, time.UTC)
+	steps := []struct {
+		after time.Duration
+		want  int
+	}{
+		{0, 1},
+		{10 * time.Second, 0}, // cooldown
+		{30 * time.Second, 1},
+		{time.Minute, 0},      // cap
+		{59 * time.Minute, 1}, // 00:30 the next day
+	}
+	for i, s := range steps {
+		now = now.Add(s.after)
+		if got := award(t, "alice", now); got != s.want {
+			t.Errorf("step %d at %s: awarded %d, want %d", i+1, now.In(myanmarLocation).Format("15:04:05"), got, s.want)
+		}
+	}
+}
+
+func TestAwardMessagePointsDisabled(t *testing.T) {
+	t.Setenv("CHAT_POINTS_ENABLED", "false")
+	setupTestDB(t)
+
+	if got := award(t, "alice", time.Now()); got != 0 {
+		t.Fatalf("awarded %d while disabled", got)
+	}
+}
@@ -0,0 +1,86 @@
+package chat
+
+import (
+	"log"
+	"time"
+
+	"burma2d/chatcore"
+	"burma2d/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// broadcastPresence tells every SSE client that a user on either transport
+// became active or away (CHAT_AWAY_AFTER, default 5m)
+func broadcastPresence(userID, state string) {
+	broadcastEvent(SSEEvent{
+		Type: "presence",
+		Data: gin.H{
+			"user_id": userID,
+			"state":   state,
+		},
+	})
+}
+
+// startOnlineReaper clears stale is_online flags every
+// CHAT_PRESENCE_REAP_INTERVAL (default 1m) for users not seen for
+// CHAT_PRESENCE_STALE_AFTER (default 2m) and no longer connected
+func startOnlineReaper() {
+	interval := config.Duration("CHAT_PRESENCE_REAP_INTERVAL", time.Minute)
+	staleAfter := config.Duration("CHAT_PRESENCE_STALE_AFTER", 2*time.Minute)
+	if interval <= 0 || staleAfter <= 0 {
+		log.Println("ℹ️  Chat online reaper disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if n := reapStaleOnline(time.Now().Add(-staleAfter)); n > 0 {
+				broadcastOnlineStatus()
+			}
+		}
+	}()
+}
+
+// reapStaleOnline marks users offline whose flag is set but who were last
+// seen before cutoff and have no live connection on either transport. It
+// returns how many were marked.
+func reapStaleOnline(cutoff time.Time) int {
+	rows, err := db.Query(`
+		SELECT id FROM chat_users WHERE is_online = 1 AND last_seen < ?
+	`, cutoff.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		log.Printf("⚠️ Failed to look up stale online users: %v", err)
+		return 0
+	}
+
+	var stale []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err == nil && !chatcore.IsOnline(id) {
+			stale = append(stale, id)
+		}
+	}
+	rows.Close()
+
+	reaped := 0
+	for _, id := range stale {
+		// Re-check the flag so a user who just reconnected isn't cleared
+		result, err := db.Exec("UPDATE chat_users SET is_online = 0 WHERE id = ? AND is_online = 1 AND last_seen < ?",
+			id, cutoff.UTC().Format("2006-01-02 15:04:05"))
+		if err != nil {
+			continue
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			reaped++
+		}
+	}
+
+	if reaped > 0 {
+		log.Printf("🧹 Marked %d stale chat users offline", reaped)
+	}
+	return reaped
+}
@@ -0,0 +1,103 @@
+package chat
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listen registers a bare SSE client and returns its channel
+func listen(t *testing.T, userID string) chan []byte {
+	t.Helper()
+	client := &SSEClient{UserID: userID, Channel: make(chan []byte, 10)}
+	clientsMutex.Lock()
+	clients[client.Channel] = client
+	clientsMutex.Unlock()
+	t.Cleanup(func() {
+		clientsMutex.Lock()
+		delete(clients, client.Channel)
+		clientsMutex.Unlock()
+	})
+	return client.Channel
+}
+
+// nextEvent decodes the next queued SSE event of type typ
+func nextEvent(t *testing.T, ch chan []byte, typ string, v interface{}) {
+	t.Helper()
+	for {
+		select {
+		case raw := <-ch:
+			var event struct {
+				Type string          `json:"type"`
+				Data json.RawMessage `json:"data"`
+			}
+			payload := strings.TrimSuffix(strings.TrimPrefix(string(raw), "data: "), "\n\n")
+			if err := json.Unmarshal([]byte(payload), &event); err != nil {
+				t.Fatalf("bad event %q: %v", raw, err)
+			}
+			if event.Type != typ {
+				continue
+			}
+			if err := json.Unmarshal(event.Data, v); err != nil {
+				t.Fatal(err)
+			}
+			return
+		default:
+			t.Fatalf("no %q event queued", typ)
+		}
+	}
+}
+
+func TestOnlineListExcludesStaleRows(t *testing.T) {
+	setupTestDB(t)
+	addUser(t, "alice")
+	addUser(t, "ghost")
+	// Left online by a crash, with no connection behind it
+	db.Exec("UPDATE chat_users SET is_online = 1 WHERE id = 'ghost'")
+
+	_, disconnect := openStream(t, "alice")
+	defer disconnect()
+
+	ch := listen(t, "observer")
+	broadcastOnlineStatus()
+
+	var status OnlineStatus
+	nextEvent(t, ch, "online", &status)
+	if status.Count != 1 || len(status.Users) != 1 || status.Users[0].UserID != "alice" {
+		t.Fatalf("broadcast online list = %+v, want only alice", status)
+	}
+}
+
+func TestReapStaleOnline(t *testing.T) {
+	setupTestDB(t)
+	for _, id := range []string{"ghost", "recent", "connected"} {
+		addUser(t, id)
+	}
+	_, disconnect := openStream(t, "connected")
+	defer disconnect()
+
+	// ghost crashed ten minutes ago; connected is idle but still has a stream
+	db.Exec("UPDATE chat_users SET is_online = 1, last_seen = datetime('now', '-10 minutes') WHERE id IN ('ghost', 'connected')")
+	db.Exec("UPDATE chat_users SET is_online = 1, last_seen = datetime('now') WHERE id = 'recent'")
+
+	if n := reapStaleOnline(time.Now().Add(-2 * time.Minute)); n != 1 {
+		t.Fatalf("reaped %d, want 1", n)
+	}
+	online := map[string]bool{}
+	rows, _ := db.Query("SELECT id FROM chat_users WHERE is_online = 1")
+	for rows.Next() {
+		var id string
+		rows.Scan(&id)
+		online[id] = true
+	}
+	rows.Close()
+	if online["ghost"] || !online["recent"] || !online["connected"] {
+		t.Fatalf("online after reaping = %v, want recent and connected", online)
+	}
+
+	// Nothing left to reap
+	if n := reapStaleOnline(time.Now().Add(-2 * time.Minute)); n != 0 {
+		t.Fatalf("second pass reaped %d", n)
+	}
+}
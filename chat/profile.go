@@ -0,0 +1,66 @@
+package chat
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"burma2d/chatcore"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserProfile is the public view of a chat user; email is never included
+type UserProfile struct {
+	ID              string    `json:"id"`
+	Username        string    `json:"username"`
+	PhotoURL        string    `json:"photo_url"`
+	JoinedAt        time.Time `json:"joined_at"`
+	LastSeen        time.Time `json:"last_seen"`
+	LastSeenSeconds int64     `json:"last_seen_seconds_ago"`
+	Online          bool      `json:"online"`
+	State           string    `json:"state,omitempty"` // active or away while online
+	MessageCount    int       `json:"message_count"`
+}
+
+// getUserProfileHandler returns a user's public profile with their count
+// of visible messages; times are in Myanmar time
+func getUserProfileHandler(c *gin.Context) {
+	userID := c.Param("id")
+
+	var p UserProfile
+	var photoURL sql.NullString
+	err := db.QueryRow(`
+		SELECT u.id, u.username, u.photo_url, u.created_at, u.last_seen,
+			(SELECT COUNT(*) FROM chat_messages m WHERE m.user_id = u.id AND m.deleted_at IS NULL)
+		FROM chat_users u
+		WHERE u.id = ?
+	`, userID).Scan(&p.ID, &p.Username, &photoURL, &p.JoinedAt, &p.LastSeen, &p.MessageCount)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	p.PhotoURL = photoURL.String
+	p.Online = chatcore.IsOnline(p.ID)
+	if p.Online {
+		// Connected users are seen now, whatever the stored value says
+		p.LastSeen = time.Now()
+		p.State = chatcore.PresenceState(p.ID)
+	}
+	p.LastSeenSeconds = int64(time.Since(p.LastSeen).Seconds())
+	if p.LastSeenSeconds < 0 {
+		p.LastSeenSeconds = 0
+	}
+	p.JoinedAt = p.JoinedAt.In(myanmarLocation)
+	p.LastSeen = p.LastSeen.In(myanmarLocation)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"user":    p,
+	})
+}
@@ -0,0 +1,54 @@
+package chat
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"burma2d/chatcore"
+	"burma2d/userdata"
+
+	"github.com/gin-gonic/gin"
+)
+
+// quotaRetention is how many days of daily message counts are kept
+const quotaRetention = 7
+
+// initQuota creates the per-user daily message counters used by
+// chatcore.ConsumeQuota for both transports and drops old days
+func initQuota() error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS chat_message_quota (
+			user_id TEXT NOT NULL,
+			day TEXT NOT NULL,
+			count INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (user_id, day)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+	userdata.Register(userdata.Table{
+		Section: "message_quota",
+		Export:  "SELECT * FROM chat_message_quota WHERE user_id = ? ORDER BY day",
+		Delete:  "DELETE FROM chat_message_quota WHERE user_id = ?",
+	})
+
+	oldest, _ := chatcore.QuotaDay(time.Now().AddDate(0, 0, -quotaRetention))
+	if result, err := db.Exec("DELETE FROM chat_message_quota WHERE day < ?", oldest); err != nil {
+		log.Printf("⚠️ Failed to prune chat message quota: %v", err)
+	} else if n, _ := result.RowsAffected(); n > 0 {
+		log.Printf("🧹 Pruned %d chat message quota rows", n)
+	}
+	return nil
+}
+
+// quotaExceeded answers a send over the daily limit with 429 and the reset time
+func quotaExceeded(c *gin.Context, qe *chatcore.QuotaError) {
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "You have reached your daily message limit",
+		"daily_limit": qe.Limit,
+		"reset_at":    qe.ResetAt.In(myanmarLocation),
+		"retry_after": int(time.Until(qe.ResetAt).Seconds()) + 1,
+	})
+}
@@ -0,0 +1,153 @@
+package chat
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"burma2d/jsonutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxEmojiLength bounds the reaction string (emoji with modifiers/ZWJ sequences)
+const maxEmojiLength = 16
+
+type reactionRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+	Emoji  string `json:"emoji" binding:"required"`
+}
+
+// reactHandler toggles a user's emoji reaction on a message
+func reactHandler(c *gin.Context) {
+	updateReaction(c, true)
+}
+
+// unreactHandler removes a user's emoji reaction from a message
+func unreactHandler(c *gin.Context) {
+	updateReaction(c, false)
+}
+
+func updateReaction(c *gin.Context, toggle bool) {
+	messageID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	var req reactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		jsonutil.BindError(c, err)
+		return
+	}
+
+	req.Emoji = strings.TrimSpace(req.Emoji)
+	if req.Emoji == "" || utf8.RuneCountInString(req.Emoji) > maxEmojiLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid emoji"})
+		return
+	}
+
+	if isUserBanned(req.UserID) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":  "You have been banned from the chat",
+			"banned": true,
+		})
+		return
+	}
+
+	var exists int
+	err = db.QueryRow(`
+		SELECT COUNT(*) FROM chat_messages WHERE id = ? AND deleted_at IS NULL
+	`, messageID).Scan(&exists)
+	if err != nil || exists == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return
+	}
+
+	// Remove first; when toggling and nothing was removed, add the reaction
+	result, err := db.Exec(`
+		DELETE FROM chat_reactions WHERE message_id = ? AND user_id = ? AND emoji = ?
+	`, messageID, req.UserID, req.Emoji)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reaction"})
+		return
+	}
+
+	removed, _ := result.RowsAffected()
+	reacted := false
+	if toggle && removed == 0 {
+		_, err = db.Exec(`
+			INSERT OR IGNORE INTO chat_reactions (message_id, user_id, emoji)
+			VALUES (?, ?, ?)
+		`, messageID, req.UserID, req.Emoji)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reaction"})
+			return
+		}
+		reacted = true
+	}
+
+	reactions := getReactions([]int64{messageID})[messageID]
+	if reactions == nil {
+		reactions = map[string]int{}
+	}
+
+	if reacted || removed > 0 {
+		broadcastEvent(SSEEvent{
+			Type: "reaction",
+			Data: gin.H{
+				"message_id": messageID,
+				"reactions":  reactions,
+			},
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"message_id": messageID,
+		"reacted":    reacted,
+		"reactions":  reactions,
+	})
+}
+
+// getReactions returns aggregated emoji counts keyed by message ID
+func getReactions(messageIDs []int64) map[int64]map[string]int {
+	result := make(map[int64]map[string]int)
+	if len(messageIDs) == 0 {
+		return result
+	}
+
+	placeholders := make([]string, len(messageIDs))
+	args := make([]interface{}, len(messageIDs))
+	for i, id := range messageIDs {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+
+	rows, err := db.Query(`
+		SELECT message_id, emoji, COUNT(*)
+		FROM chat_reactions
+		WHERE message_id IN (`+strings.Join(placeholders, ",")+`)
+		GROUP BY message_id, emoji
+	`, args...)
+	if err != nil {
+		return result
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var messageID int64
+		var emoji string
+		var count int
+		if err := rows.Scan(&messageID, &emoji, &count); err != nil {
+			continue
+		}
+		if result[messageID] == nil {
+			result[messageID] = make(map[string]int)
+		}
+		result[messageID][emoji] = count
+	}
+
+	return result
+}
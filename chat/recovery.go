@@ -0,0 +1,186 @@
+package chat
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"burma2d/config"
+	"burma2d/jsonutil"
+	"burma2d/pagination"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Reasons recorded when a message is soft-deleted
+const (
+	deleteReasonBan   = "ban"
+	deleteReasonAdmin = "admin"
+)
+
+// deletedRetention is how long soft-deleted messages stay recoverable
+func deletedRetention() time.Duration {
+	return time.Duration(config.Int("CHAT_DELETED_RETENTION_DAYS", 30)) * 24 * time.Hour
+}
+
+// deletedCutoff formats the oldest recoverable deleted_at in SQLite's
+// CURRENT_TIMESTAMP layout so string comparison stays correct
+func deletedCutoff() string {
+	return time.Now().UTC().Add(-deletedRetention()).Format("2006-01-02 15:04:05")
+}
+
+// deleteMessageHandler soft-deletes a single message (admin)
+func deleteMessageHandler(c *gin.Context) {
+	messageID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	result, err := db.Exec(`
+		UPDATE chat_messages SET deleted_at = CURRENT_TIMESTAMP, deleted_reason = ?
+		WHERE id = ? AND deleted_at IS NULL
+	`, deleteReasonAdmin, messageID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete message"})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return
+	}
+
+	broadcastEvent(SSEEvent{Type: "message_deleted", Data: gin.H{"message_id": messageID}})
+	log.Printf("🗑️ Message %d deleted by admin", messageID)
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message_id": messageID})
+}
+
+// getDeletedMessagesHandler lists recoverable soft-deleted messages (admin)
+// Optional query params: user_id, reason (ban|admin), limit, offset
+func getDeletedMessagesHandler(c *gin.Context) {
+	p, err := pagination.Parse(c, pagination.Options{DefaultLimit: 100, MaxLimit: 500})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var where pagination.Where
+	where.Add("deleted_at IS NOT NULL")
+	where.Add("deleted_at >= ?", deletedCutoff())
+	if userID := c.Query("user_id"); userID != "" {
+		where.Add("user_id = ?", userID)
+	}
+	if reason := c.Query("reason"); reason != "" {
+		where.Add("deleted_reason = ?", reason)
+	}
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM chat_messages"+where.SQL(), where.Args()...).Scan(&total); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count messages"})
+		return
+	}
+
+	limitSQL, limitArgs := p.LimitOffset()
+	rows, err := db.Query(`
+		SELECT id, user_id, username, photo_url, message, created_at, deleted_at, deleted_reason
+		FROM chat_messages`+where.SQL()+`
+		ORDER BY deleted_at DESC, id DESC`+limitSQL,
+		append(where.Args(), limitArgs...)...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get messages"})
+		return
+	}
+	defer rows.Close()
+
+	messages := []Message{}
+	for rows.Next() {
+		var msg Message
+		var deletedAt time.Time
+		var reason sql.NullString
+		if err := rows.Scan(&msg.ID, &msg.UserID, &msg.Username, &msg.PhotoURL, &msg.Message,
+			&msg.CreatedAt, &deletedAt, &reason); err != nil {
+			continue
+		}
+		msg.CreatedAt = msg.CreatedAt.In(myanmarLocation)
+		deletedAt = deletedAt.In(myanmarLocation)
+		msg.IsDeleted = true
+		msg.DeletedAt = &deletedAt
+		msg.Reason = reason.String
+		messages = append(messages, msg)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"messages":       messages,
+		"count":          len(messages),
+		"total":          total,
+		"limit":          p.Limit,
+		"offset":         p.Offset,
+		"retention_days": int(deletedRetention().Hours() / 24),
+	})
+}
+
+// restoreMessageHandler restores one soft-deleted message (admin)
+func restoreMessageHandler(c *gin.Context) {
+	messageID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	result, err := db.Exec(`
+		UPDATE chat_messages SET deleted_at = NULL, deleted_reason = NULL
+		WHERE id = ? AND deleted_at IS NOT NULL AND deleted_at >= ?
+	`, messageID, deletedCutoff())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore message"})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No recoverable message with this ID"})
+		return
+	}
+
+	log.Printf("♻️ Message %d restored by admin", messageID)
+	c.JSON(http.StatusOK, gin.H{"success": true, "message_id": messageID})
+}
+
+// restoreUserMessagesHandler restores all recoverable messages of a user,
+// e.g. after a mistaken ban (admin). Unban separately via /admin/unban.
+func restoreUserMessagesHandler(c *gin.Context) {
+	var req struct {
+		UserID string `json:"user_id" binding:"required"`
+		Reason string `json:"reason"` // optional: only restore "ban" or "admin" deletions
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		jsonutil.BindError(c, err)
+		return
+	}
+
+	var where pagination.Where
+	where.Add("user_id = ?", req.UserID)
+	where.Add("deleted_at IS NOT NULL")
+	where.Add("deleted_at >= ?", deletedCutoff())
+	if req.Reason != "" {
+		where.Add("deleted_reason = ?", req.Reason)
+	}
+
+	result, err := db.Exec(
+		"UPDATE chat_messages SET deleted_at = NULL, deleted_reason = NULL"+where.SQL(),
+		where.Args()...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore messages"})
+		return
+	}
+
+	restored, _ := result.RowsAffected()
+	log.Printf("♻️ Restored %d messages for user %s", restored, req.UserID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"user_id":  req.UserID,
+		"restored": restored,
+	})
+}
@@ -0,0 +1,151 @@
+package chat
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recoveryRequest sends a request to the admin recovery routes
+func recoveryRequest(t *testing.T, method, target string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	startHub()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.DELETE("/messages/:id", deleteMessageHandler)
+	r.GET("/deleted", getDeletedMessagesHandler)
+	r.POST("/deleted/:id/restore", restoreMessageHandler)
+	r.POST("/deleted/restore", restoreUserMessagesHandler)
+
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(method, target, &buf))
+	return w
+}
+
+func deletedMessages(t *testing.T, query string) []Message {
+	t.Helper()
+	w := recoveryRequest(t, http.MethodGet, "/deleted?"+query, nil)
+	var resp struct {
+		Messages []Message `json:"messages"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	return resp.Messages
+}
+
+func visibleCount(t *testing.T, userID string) int {
+	t.Helper()
+	var n int
+	db.QueryRow("SELECT COUNT(*) FROM chat_messages WHERE user_id = ? AND deleted_at IS NULL", userID).Scan(&n)
+	return n
+}
+
+func TestDeleteAndRestoreMessage(t *testing.T) {
+	setupTestDB(t)
+	addMessage(t, "alice", "oops", "2026-03-02 09:00:00")
+	addMessage(t, "alice", "fine", "2026-03-02 09:01:00")
+
+	if w := recoveryRequest(t, http.MethodDelete, "/messages/1", nil); w.Code != http.StatusOK {
+		t.Fatalf("delete: status %d %s", w.Code, w.Body.String())
+	}
+	if w := recoveryRequest(t, http.MethodDelete, "/messages/1", nil); w.Code != http.StatusNotFound {
+		t.Errorf("second delete: status %d, want 404", w.Code)
+	}
+
+	deleted := deletedMessages(t, "")
+	if len(deleted) != 1 || deleted[0].ID != 1 || deleted[0].Reason != deleteReasonAdmin || deleted[0].DeletedAt == nil {
+		t.Fatalf("deleted = %+v, want message 1 deleted by an admin", deleted)
+	}
+	if _, offset := deleted[0].DeletedAt.Zone(); offset != 6*3600+30*60 {
+		t.Errorf("deleted_at offset %d, want Myanmar time", offset)
+	}
+
+	if w := recoveryRequest(t, http.MethodPost, "/deleted/1/restore", nil); w.Code != http.StatusOK {
+		t.Fatalf("restore: status %d %s", w.Code, w.Body.String())
+	}
+	if n := visibleCount(t, "alice"); n != 2 {
+		t.Errorf("%d visible messages after the restore, want 2", n)
+	}
+	if deleted := deletedMessages(t, ""); len(deleted) != 0 {
+		t.Errorf("still listed as deleted: %+v", deleted)
+	}
+	if w := recoveryRequest(t, http.MethodPost, "/deleted/1/restore", nil); w.Code != http.StatusNotFound {
+		t.Errorf("restoring a visible message: status %d, want 404", w.Code)
+	}
+}
+
+func TestRestoreUserMessages(t *testing.T) {
+	setupTestDB(t)
+	addUser(t, "alice")
+	for _, text := range []string{"one", "two", "three"} {
+		addMessage(t, "alice", text, "2026-03-02 09:00:00")
+	}
+	addMessage(t, "bob", "other user", "2026-03-02 09:00:00")
+	if _, _, err := banUser("alice", "admin", "spam"); err != nil {
+		t.Fatal(err)
+	}
+	recoveryRequest(t, http.MethodDelete, "/messages/4", nil)
+
+	// Only the ban deletions, and only alice's
+	if n := len(deletedMessages(t, "user_id=alice&reason=ban")); n != 3 {
+		t.Fatalf("%d of alice's messages deleted by the ban, want 3", n)
+	}
+	w := recoveryRequest(t, http.MethodPost, "/deleted/restore", gin.H{"user_id": "alice", "reason": deleteReasonBan})
+	var resp struct {
+		Restored int `json:"restored"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || resp.Restored != 3 {
+		t.Fatalf("restore: status %d %s, want 3 restored", w.Code, w.Body.String())
+	}
+	if n := visibleCount(t, "alice"); n != 3 {
+		t.Errorf("alice has %d visible messages, want 3", n)
+	}
+	if n := visibleCount(t, "bob"); n != 0 {
+		t.Errorf("bob's admin deletion was restored too")
+	}
+
+	if w := recoveryRequest(t, http.MethodPost, "/deleted/restore", gin.H{}); w.Code != http.StatusBadRequest {
+		t.Errorf("missing user_id: status %d, want 400", w.Code)
+	}
+}
+
+func TestRecoveryRetentionWindow(t *testing.T) {
+	setupTestDB(t)
+	t.Setenv("CHAT_DELETED_RETENTION_DAYS", "30")
+	addMessage(t, "alice", "expired", "2026-01-01 09:00:00")
+	addMessage(t, "alice", "recent", "2026-01-01 09:00:00")
+	db.Exec("UPDATE chat_messages SET deleted_at = datetime('now', '-31 days'), deleted_reason = 'admin' WHERE id = 1")
+	db.Exec("UPDATE chat_messages SET deleted_at = datetime('now', '-29 days'), deleted_reason = 'admin' WHERE id = 2")
+
+	deleted := deletedMessages(t, "")
+	if len(deleted) != 1 || deleted[0].Message != "recent" {
+		t.Fatalf("deleted = %+v, want only the one inside the window", deleted)
+	}
+
+	if w := recoveryRequest(t, http.MethodPost, "/deleted/1/restore", nil); w.Code != http.StatusNotFound {
+		t.Errorf("restoring past the window: status %d, want 404", w.Code)
+	}
+	w := recoveryRequest(t, http.MethodPost, "/deleted/restore", gin.H{"user_id": "alice"})
+	var resp struct {
+		Restored int `json:"restored"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Restored != 1 {
+		t.Errorf("restored %d for the user, want only the recent one", resp.Restored)
+	}
+	var expiredDeleted bool
+	db.QueryRow("SELECT deleted_at IS NOT NULL FROM chat_messages WHERE id = 1").Scan(&expiredDeleted)
+	if !expiredDeleted {
+		t.Error("a message past the window was restored")
+	}
+}
@@ -0,0 +1,466 @@
+package chat
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"burma2d/adminauth"
+	"burma2d/chatcore"
+	"burma2d/config"
+	"burma2d/dbutil"
+	"burma2d/fcm"
+	"burma2d/googleauth"
+	"burma2d/jsonutil"
+	"burma2d/userdata"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Moderation log actions
+const (
+	actionBan    = "ban"
+	actionUnban  = "unban"
+	actionMute   = "mute"
+	actionUnmute = "unmute"
+)
+
+// autoModActor is recorded as the actor of actions taken on reports
+const autoModActor = "auto-moderation"
+
+// maxReportReasonRunes bounds the free-text reason on a report
+const maxReportReasonRunes = 500
+
+// Auto-moderation settings, loaded by initReports
+var (
+	autoModEnabled   bool
+	autoModThreshold int
+	autoModWindow    time.Duration
+	autoModAction    string
+	autoModMuteFor   time.Duration
+	autoModExempt    map[string]bool
+	adminAlertTopic  string
+
+	// autoModMu serializes threshold checks so one burst of reports acts once
+	autoModMu sync.Mutex
+)
+
+// initReports creates the report, mute and moderation log tables and reads
+// CHAT_AUTOMOD_ENABLED (default false), CHAT_AUTOMOD_THRESHOLD (distinct
+// reporters, default 5), CHAT_AUTOMOD_WINDOW (default 24h),
+// CHAT_AUTOMOD_ACTION (mute or ban, default mute), CHAT_AUTOMOD_MUTE_DURATION
+// (default 24h), CHAT_AUTOMOD_EXEMPT (comma-separated user IDs) and
+// CHAT_ADMIN_ALERT_TOPIC (FCM topic for admin alerts; empty = log only)
+func initReports() error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS chat_reports (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			reporter_id TEXT NOT NULL,
+			reported_id TEXT NOT NULL,
+			message_id INTEGER,
+			reason TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_reports_reported ON chat_reports(reported_id, created_at);
+		CREATE TABLE IF NOT EXISTS chat_mutes (
+			user_id TEXT PRIMARY KEY,
+			muted_until DATETIME NOT NULL,
+			reason TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE IF NOT EXISTS chat_moderation_log (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id TEXT NOT NULL,
+			action TEXT NOT NULL,
+			reason TEXT NOT NULL DEFAULT '',
+			actor TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_moderation_log_user ON chat_moderation_log(user_id, created_at DESC);
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Only reports whose reporter proved their identity count toward
+	// auto-moderation; anyone can name another user's ID in a body
+	if err := dbutil.AddColumnIfMissing(db, "chat_reports", "verified", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+
+	// Reports against the user are exported without the reporter's ID
+	userdata.Register(userdata.Table{
+		Section: "reports_made",
+		Export:  "SELECT * FROM chat_reports WHERE reporter_id = ? ORDER BY id",
+		Delete:  "DELETE FROM chat_reports WHERE reporter_id = ?",
+	})
+	userdata.Register(userdata.Table{
+		Section: "reports_received",
+		Export:  "SELECT id, message_id, reason, created_at FROM chat_reports WHERE reported_id = ? ORDER BY id",
+		Delete:  "DELETE FROM chat_reports WHERE reported_id = ?",
+	})
+	userdata.Register(userdata.Table{
+		Section: "mutes",
+		Export:  "SELECT * FROM chat_mutes WHERE user_id = ?",
+		Delete:  "DELETE FROM chat_mutes WHERE user_id = ?",
+	})
+	userdata.Register(userdata.Table{
+		Section: "moderation_log",
+		Export:  "SELECT * FROM chat_moderation_log WHERE user_id = ? ORDER BY id",
+		Delete:  "DELETE FROM chat_moderation_log WHERE user_id = ?",
+	})
+
+	autoModEnabled = config.Bool("CHAT_AUTOMOD_ENABLED", false)
+	autoModThreshold = config.Int("CHAT_AUTOMOD_THRESHOLD", 5)
+	if autoModThreshold < 2 {
+		autoModThreshold = 2
+	}
+	autoModWindow = config.Duration("CHAT_AUTOMOD_WINDOW", 24*time.Hour)
+	autoModMuteFor = config.Duration("CHAT_AUTOMOD_MUTE_DURATION", 24*time.Hour)
+	autoModAction = strings.ToLower(config.String("CHAT_AUTOMOD_ACTION", actionMute))
+	if autoModAction != actionMute && autoModAction != actionBan {
+		log.Printf("⚠️ Unknown CHAT_AUTOMOD_ACTION %q, using %s", autoModAction, actionMute)
+		autoModAction = actionMute
+	}
+	autoModExempt = make(map[string]bool)
+	for _, id := range strings.Split(config.String("CHAT_AUTOMOD_EXEMPT", ""), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			autoModExempt[id] = true
+		}
+	}
+	adminAlertTopic = config.String("CHAT_ADMIN_ALERT_TOPIC", "")
+
+	chatcore.SetMuteChecker(mutedUntil)
+
+	if autoModEnabled {
+		log.Printf("✅ Chat auto-moderation: %s after %d reports in %s (%d exempt)",
+			autoModAction, autoModThreshold, autoModWindow, len(autoModExempt))
+	} else {
+		log.Println("ℹ️  Chat auto-moderation disabled (set CHAT_AUTOMOD_ENABLED=true to enable)")
+	}
+	return nil
+}
+
+// logModeration appends an entry to the moderation log
+func logModeration(q chatcore.Execer, userID, action, reason, actor string) error {
+	_, err := q.Exec(`
+		INSERT INTO chat_moderation_log (user_id, action, reason, actor)
+		VALUES (?, ?, ?, ?)
+	`, userID, action, reason, actor)
+	if err != nil {
+		log.Printf("⚠️ Failed to log %s of %s: %v", action, userID, err)
+	}
+	return err
+}
+
+// mutedUntil returns when userID's mute ends, if it is still running
+func mutedUntil(userID string) (time.Time, bool) {
+	var until time.Time
+	err := db.QueryRow("SELECT muted_until FROM chat_mutes WHERE user_id = ?", userID).Scan(&until)
+	if err != nil || !until.After(time.Now()) {
+		return time.Time{}, false
+	}
+	return until, true
+}
+
+// reportUserHandler records a user's report of another user (or one of
+// their messages) and applies auto-moderation when it is enabled. The
+// reporter is the subject of the request's Google ID token; only in
+// development mode (no GOOGLE_OAUTH_CLIENT_ID) is reporter_id taken from
+// the body, and such reports never count toward auto-moderation.
+func reportUserHandler(c *gin.Context) {
+	var req struct {
+		ReporterID string `json:"reporter_id"`
+		ReportedID string `json:"reported_id" binding:"required"`
+		MessageID  *int64 `json:"message_id"`
+		Reason     string `json:"reason"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		jsonutil.BindError(c, err)
+		return
+	}
+
+	verified := googleClientID != ""
+	if verified {
+		reporterID, err := googleauth.Authenticate(c, googleClientID)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Sign in to report users"})
+			return
+		}
+		req.ReporterID = reporterID
+	} else if req.ReporterID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reporter_id required in development mode"})
+		return
+	}
+	if req.ReporterID == req.ReportedID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot report yourself"})
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if len([]rune(req.Reason)) > maxReportReasonRunes {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Reason too long (max %d characters)", maxReportReasonRunes)})
+		return
+	}
+
+	if isUserBanned(req.ReporterID) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":  chatcore.BanMessage(),
+			"banned": true,
+		})
+		return
+	}
+
+	var users int
+	err := db.QueryRow("SELECT COUNT(*) FROM chat_users WHERE id IN (?, ?)", req.ReporterID, req.ReportedID).Scan(&users)
+	if err != nil || users < 2 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if _, err := db.Exec(`
+		INSERT INTO chat_reports (reporter_id, reported_id, message_id, reason, verified)
+		VALUES (?, ?, ?, ?, ?)
+	`, req.ReporterID, req.ReportedID, req.MessageID, req.Reason, verified); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save report"})
+		return
+	}
+	log.Printf("🚩 %s reported %s: %s", req.ReporterID, req.ReportedID, req.Reason)
+
+	// The reporter only learns that the report was received
+	if _, err := applyAutoModeration(req.ReportedID); err != nil {
+		log.Printf("❌ Auto-moderation failed for %s: %v", req.ReportedID, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// applyAutoModeration takes the configured action against userID once the
+// number of distinct verified reporters within the window reaches the threshold.
+// Reports made before the user's last moderation action don't count again,
+// so one set of reports triggers at most one action. It returns the action
+// taken, or "" when none was.
+func applyAutoModeration(userID string) (string, error) {
+	if !autoModEnabled || autoModExempt[userID] {
+		return "", nil
+	}
+
+	autoModMu.Lock()
+	defer autoModMu.Unlock()
+
+	if isUserBanned(userID) {
+		return "", nil
+	}
+	if _, muted := mutedUntil(userID); muted {
+		return "", nil
+	}
+
+	since := time.Now().UTC().Add(-autoModWindow).Format("2006-01-02 15:04:05")
+	var reporters int
+	err := db.QueryRow(`
+		SELECT COUNT(DISTINCT reporter_id) FROM chat_reports
+		WHERE reported_id = ?
+		  AND verified = 1
+		  AND created_at >= ?
+		  AND created_at > COALESCE((
+		      SELECT MAX(created_at) FROM chat_moderation_log
+		      WHERE user_id = ? AND action IN (?, ?, ?, ?)
+		  ), '')
+	`, userID, since, userID, actionBan, actionUnban, actionMute, actionUnmute).Scan(&reporters)
+	if err != nil {
+		return "", err
+	}
+	if reporters < autoModThreshold {
+		return "", nil
+	}
+
+	reason := fmt.Sprintf("Reported by %d users within %s", reporters, autoModWindow)
+	switch autoModAction {
+	case actionBan:
+		if _, _, err := banUser(userID, autoModActor, reason); err != nil {
+			return "", err
+		}
+	default:
+		if err := muteUser(userID, autoModMuteFor, reason, autoModActor); err != nil {
+			return "", err
+		}
+	}
+
+	alertAdmins("Chat auto-moderation", fmt.Sprintf("User %s was %s: %s", userID, pastTense(autoModAction), reason))
+	return autoModAction, nil
+}
+
+// muteUser stops userID from sending for d and records it
+func muteUser(userID string, d time.Duration, reason, actor string) error {
+	until := time.Now().UTC().Add(d)
+	if _, err := db.Exec(`
+		INSERT INTO chat_mutes (user_id, muted_until, reason)
+		VALUES (?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET
+			muted_until = excluded.muted_until,
+			reason = excluded.reason,
+			created_at = CURRENT_TIMESTAMP
+	`, userID, until, reason); err != nil {
+		return err
+	}
+	logModeration(db, userID, actionMute, reason, actor)
+	log.Printf("🔇 User muted until %s: %s - %s", until.Format(time.RFC3339), userID, reason)
+	return nil
+}
+
+func pastTense(action string) string {
+	if action == actionBan {
+		return "banned"
+	}
+	return "muted"
+}
+
+// alertAdmins logs an alert and, when CHAT_ADMIN_ALERT_TOPIC is set, pushes
+// it to that FCM topic
+func alertAdmins(title, body string) {
+	log.Printf("📣 %s: %s", title, body)
+	if adminAlertTopic == "" || !fcm.IsInitialized() {
+		return
+	}
+	go func() {
+		if err := fcm.SendNotificationToTopic(adminAlertTopic, title, body); err != nil {
+			log.Printf("⚠️ Failed to alert admins: %v", err)
+		}
+	}()
+}
+
+// unmuteUserHandler lifts a mute early (admin)
+func unmuteUserHandler(c *gin.Context) {
+	var req struct {
+		UserID string `json:"user_id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		jsonutil.BindError(c, err)
+		return
+	}
+
+	result, err := db.Exec("DELETE FROM chat_mutes WHERE user_id = ?", req.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unmute user"})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User is not muted"})
+		return
+	}
+
+	logModeration(db, req.UserID, actionUnmute, "", c.GetString(adminauth.ContextUserKey))
+	c.JSON(http.StatusOK, gin.H{"success": true, "user_id": req.UserID})
+}
+
+// ModerationEntry is one row of the moderation log
+type ModerationEntry struct {
+	ID        int64     `json:"id"`
+	UserID    string    `json:"user_id"`
+	Action    string    `json:"action"`
+	Reason    string    `json:"reason"`
+	Actor     string    `json:"actor"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// getModerationLogHandler returns recent moderation actions, optionally
+// for one ?user_id= (admin)
+func getModerationLogHandler(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+	if limit > 500 {
+		limit = 500
+	}
+
+	query := "SELECT id, user_id, action, reason, actor, created_at FROM chat_moderation_log"
+	args := []interface{}{}
+	if userID := c.Query("user_id"); userID != "" {
+		query += " WHERE user_id = ?"
+		args = append(args, userID)
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get moderation log"})
+		return
+	}
+	defer rows.Close()
+
+	entries := []ModerationEntry{}
+	for rows.Next() {
+		var e ModerationEntry
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Action, &e.Reason, &e.Actor, &e.CreatedAt); err != nil {
+			continue
+		}
+		entries = append(entries, e)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"entries": entries})
+}
+
+// getReportsHandler returns reports against a user with the distinct
+// reporter count inside the auto-moderation window (admin)
+func getReportsHandler(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id required"})
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT id, reporter_id, message_id, reason, created_at
+		FROM chat_reports WHERE reported_id = ?
+		ORDER BY id DESC LIMIT 200
+	`, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get reports"})
+		return
+	}
+	defer rows.Close()
+
+	reports := []gin.H{}
+	windowStart := time.Now().Add(-autoModWindow)
+	recent := map[string]bool{}
+	for rows.Next() {
+		var id int64
+		var reporterID, reason string
+		var messageID sql.NullInt64
+		var createdAt time.Time
+		if err := rows.Scan(&id, &reporterID, &messageID, &reason, &createdAt); err != nil {
+			continue
+		}
+		if createdAt.After(windowStart) {
+			recent[reporterID] = true
+		}
+		report := gin.H{"id": id, "reporter_id": reporterID, "reason": reason, "created_at": createdAt}
+		if messageID.Valid {
+			report["message_id"] = messageID.Int64
+		}
+		reports = append(reports, report)
+	}
+
+	until, muted := mutedUntil(userID)
+	response := gin.H{
+		"user_id":          userID,
+		"reports":          reports,
+		"recent_reporters": len(recent),
+		"threshold":        autoModThreshold,
+		"banned":           isUserBanned(userID),
+		"muted":            muted,
+	}
+	if muted {
+		response["muted_until"] = until
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,205 @@
+package chat
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"burma2d/chatcore"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// setupTestDB points the package at a fresh in-memory database with the
+// chat and report tables and auto-moderation muting at two reporters
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each :memory: connection is its own database
+	database.SetMaxOpenConns(1)
+	t.Cleanup(func() { database.Close() })
+
+	db = database
+	chatcore.InitDB(database)
+	myanmarLocation = time.FixedZone("Myanmar", 6*3600+30*60)
+	if err := createTables(); err != nil {
+		t.Fatal(err)
+	}
+	if err := initReports(); err != nil {
+		t.Fatal(err)
+	}
+	if err := initPoints(); err != nil {
+		t.Fatal(err)
+	}
+	if err := initQuota(); err != nil {
+		t.Fatal(err)
+	}
+
+	autoModEnabled = true
+	autoModThreshold = 2
+	autoModWindow = time.Hour
+	autoModAction = actionMute
+	autoModMuteFor = time.Hour
+	autoModExempt = map[string]bool{}
+	adminAlertTopic = ""
+}
+
+func addReport(t *testing.T, reporter, reported string, verified bool) {
+	t.Helper()
+	if _, err := db.Exec(`
+		INSERT INTO chat_reports (reporter_id, reported_id, reason, verified)
+		VALUES (?, ?, 'spam', ?)
+	`, reporter, reported, verified); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func addUser(t *testing.T, id string) {
+	t.Helper()
+	if _, err := db.Exec(`INSERT INTO chat_users (id, email, username, photo_url) VALUES (?, ?, ?, '')`,
+		id, id+"@example.com", id); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func moderationCount(t *testing.T, userID string) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM chat_moderation_log WHERE user_id = ? AND action = ?`,
+		userID, actionMute).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestAutoModerationActsOnceAtThreshold(t *testing.T) {
+	setupTestDB(t)
+
+	addReport(t, "reporter-1", "target", true)
+	if action, err := applyAutoModeration("target"); err != nil || action != "" {
+		t.Fatalf("below threshold: action %q, err %v", action, err)
+	}
+
+	addReport(t, "reporter-2", "target", true)
+	if action, err := applyAutoModeration("target"); err != nil || action != actionMute {
+		t.Fatalf("at threshold: action %q, err %v", action, err)
+	}
+
+	addReport(t, "reporter-3", "target", true)
+	if action, err := applyAutoModeration("target"); err != nil || action != "" {
+		t.Fatalf("already muted: action %q, err %v", action, err)
+	}
+
+	if n := moderationCount(t, "target"); n != 1 {
+		t.Fatalf("mutes logged = %d, want 1", n)
+	}
+	if _, muted := mutedUntil("target"); !muted {
+		t.Fatal("target not muted")
+	}
+}
+
+func TestAutoModerationConcurrentReportsActOnce(t *testing.T) {
+	setupTestDB(t)
+	for i := 0; i < 5; i++ {
+		addReport(t, fmt.Sprintf("reporter-%d", i), "target", true)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	acted := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			action, err := applyAutoModeration("target")
+			if err != nil {
+				t.Error(err)
+			}
+			if action != "" {
+				mu.Lock()
+				acted++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if acted != 1 {
+		t.Fatalf("acted %d times, want 1", acted)
+	}
+	if n := moderationCount(t, "target"); n != 1 {
+		t.Fatalf("mutes logged = %d, want 1", n)
+	}
+}
+
+func TestAutoModerationIgnoresUnverifiedReports(t *testing.T) {
+	setupTestDB(t)
+	for i := 0; i < 5; i++ {
+		addReport(t, fmt.Sprintf("claimed-%d", i), "target", false)
+	}
+
+	if action, err := applyAutoModeration("target"); err != nil || action != "" {
+		t.Fatalf("action %q, err %v", action, err)
+	}
+	if _, muted := mutedUntil("target"); muted {
+		t.Fatal("unverified reports muted the target")
+	}
+}
+
+func TestReportUserRequiresToken(t *testing.T) {
+	setupTestDB(t)
+	gin.SetMode(gin.TestMode)
+
+	googleClientID = "client-id"
+	t.Cleanup(func() { googleClientID = "" })
+
+	r := gin.New()
+	r.POST("/report", reportUserHandler)
+
+	body := `{"reporter_id":"someone-else","reported_id":"target"}`
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/report", strings.NewReader(body)))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", w.Code)
+	}
+
+	var n int
+	db.QueryRow(`SELECT COUNT(*) FROM chat_reports`).Scan(&n)
+	if n != 0 {
+		t.Fatalf("stored %d reports without a token", n)
+	}
+}
+
+func TestReportUserDevModeIsUnverified(t *testing.T) {
+	setupTestDB(t)
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.POST("/report", reportUserHandler)
+
+	addUser(t, "target")
+	for i := 0; i < 3; i++ {
+		addUser(t, fmt.Sprintf("dev-%d", i))
+	}
+	for i := 0; i < 3; i++ {
+		body := fmt.Sprintf(`{"reporter_id":"dev-%d","reported_id":"target"}`, i)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/report", strings.NewReader(body)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+		}
+	}
+
+	if _, muted := mutedUntil("target"); muted {
+		t.Fatal("development-mode reports muted the target")
+	}
+}
@@ -0,0 +1,63 @@
+package chatcore
+
+import "sync"
+
+// DefaultBanMessage is shown to banned users when CHAT_BAN_MESSAGE is unset
+const DefaultBanMessage = "You have been banned from the chat"
+
+// BanListener is called after a user has been banned
+type BanListener func(userID, reason string)
+
+// BanChecker reports whether a user ID is banned
+type BanChecker func(userID string) bool
+
+var (
+	banMessage = DefaultBanMessage
+	banChecker BanChecker
+
+	banListenersMu sync.RWMutex
+	banListeners   []BanListener
+)
+
+// BanMessage returns the notice sent to a user when they are banned
+func BanMessage() string {
+	return banMessage
+}
+
+// OnBan registers a listener so each transport can disconnect banned users
+func OnBan(fn BanListener) {
+	banListenersMu.Lock()
+	banListeners = append(banListeners, fn)
+	banListenersMu.Unlock()
+}
+
+// NotifyBanned tells every registered transport that userID was banned
+func NotifyBanned(userID, reason string) {
+	banListenersMu.RLock()
+	listeners := append([]BanListener(nil), banListeners...)
+	banListenersMu.RUnlock()
+
+	for _, fn := range listeners {
+		fn(userID, reason)
+	}
+}
+
+// SetBanChecker installs the ban lookup used by IsBanned; the chat package
+// owns the ban list and registers it at startup
+func SetBanChecker(fn BanChecker) {
+	banChecker = fn
+}
+
+// IsBanned reports whether any of the user's IDs is banned, so transports
+// that key users differently can still honour the shared ban list
+func IsBanned(ids ...string) bool {
+	if banChecker == nil {
+		return false
+	}
+	for _, id := range ids {
+		if id != "" && banChecker(id) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package chatcore
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"burma2d/config"
+)
+
+// DefaultMaxMessageRunes is the message length limit when CHAT_MAX_MESSAGE_RUNES is unset
+const DefaultMaxMessageRunes = 1000
+
+// DefaultMaxBlocks is the per-user block-list limit when CHAT_MAX_BLOCKS is unset
+const DefaultMaxBlocks = 1000
+
+// ErrEmptyMessage is returned for messages with no visible content
+var ErrEmptyMessage = errors.New("message cannot be empty")
+
+var (
+	maxMessageRunes = DefaultMaxMessageRunes
+	maxBlocks       = DefaultMaxBlocks
+)
+
+// Init loads shared chat settings and starts presence tracking
+func Init() {
+	maxMessageRunes = config.Int("CHAT_MAX_MESSAGE_RUNES", DefaultMaxMessageRunes)
+	if maxMessageRunes < 1 {
+		maxMessageRunes = DefaultMaxMessageRunes
+	}
+	maxBlocks = config.Int("CHAT_MAX_BLOCKS", DefaultMaxBlocks)
+	if maxBlocks < 1 {
+		maxBlocks = DefaultMaxBlocks
+	}
+	banMessage = config.String("CHAT_BAN_MESSAGE", DefaultBanMessage)
+	loadModerationConfig()
+	loadQuotaConfig()
+	loadRateLimitConfig()
+	startPresence()
+}
+
+// MaxBlocks returns how many users one user may block (CHAT_MAX_BLOCKS)
+func MaxBlocks() int {
+	return maxBlocks
+}
+
+// ValidateMessage trims text and checks it is non-empty, has visible content
+// and is within the length limit. Both chat transports use the same rules.
+func ValidateMessage(text string) (string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" || !hasVisibleRune(text) {
+		return "", ErrEmptyMessage
+	}
+
+	if n := utf8.RuneCountInString(text); n > maxMessageRunes {
+		return "", fmt.Errorf("message too long: %d characters (max %d)", n, maxMessageRunes)
+	}
+
+	return text, nil
+}
+
+// hasVisibleRune reports whether text contains anything besides whitespace,
+// control and invisible formatting characters (e.g. zero-width space)
+func hasVisibleRune(text string) bool {
+	for _, r := range text {
+		if !unicode.IsSpace(r) && !unicode.IsControl(r) && !unicode.Is(unicode.Cf, r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,179 @@
+package chatcore
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// OnlineUser is a connected user as shown in online lists
+type OnlineUser struct {
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+	PhotoURL string `json:"photo_url"`
+	State    string `json:"state,omitempty"` // "active" or "away" (online lists only)
+}
+
+// Listeners let each transport relay events that happened on either one
+type (
+	// MessageListener is called for every new chat message
+	MessageListener func(m Message)
+	// ConnectionListener is called when a connection opens or closes
+	ConnectionListener func(user OnlineUser, connected bool)
+	// PresenceListener is called when a user becomes active or away
+	PresenceListener func(userID, state string)
+)
+
+// connection is one user's open connections across both transports
+type connection struct {
+	user  OnlineUser
+	conns int
+}
+
+var (
+	hubMu       sync.RWMutex
+	connections = make(map[string]*connection)
+
+	// presence tracks active/away state for users on either transport
+	presence *Presence
+
+	listenersMu         sync.RWMutex
+	messageListeners    []MessageListener
+	connectionListeners []ConnectionListener
+	presenceListeners   []PresenceListener
+)
+
+// startPresence creates the shared away tracker (CHAT_AWAY_AFTER)
+func startPresence() {
+	presence = NewPresence(AwayAfter(), notifyPresence)
+	go presence.Run()
+}
+
+// OnMessage registers a listener for new messages
+func OnMessage(fn MessageListener) {
+	listenersMu.Lock()
+	messageListeners = append(messageListeners, fn)
+	listenersMu.Unlock()
+}
+
+// OnConnection registers a listener for connections opening and closing
+func OnConnection(fn ConnectionListener) {
+	listenersMu.Lock()
+	connectionListeners = append(connectionListeners, fn)
+	listenersMu.Unlock()
+}
+
+// OnPresence registers a listener for active/away transitions
+func OnPresence(fn PresenceListener) {
+	listenersMu.Lock()
+	presenceListeners = append(presenceListeners, fn)
+	listenersMu.Unlock()
+}
+
+// PublishMessage delivers a stored message to every transport
+func PublishMessage(m Message) {
+	listenersMu.RLock()
+	listeners := append([]MessageListener(nil), messageListeners...)
+	listenersMu.RUnlock()
+
+	for _, fn := range listeners {
+		fn(m)
+	}
+}
+
+// Connect records an open connection for user on either transport
+func Connect(user OnlineUser) {
+	hubMu.Lock()
+	c := connections[user.UserID]
+	if c == nil {
+		c = &connection{}
+		connections[user.UserID] = c
+	}
+	c.user = user
+	c.conns++
+	hubMu.Unlock()
+
+	presence.Connect(user.UserID)
+	notifyConnection(user, true)
+}
+
+// Disconnect records that one of userID's connections closed
+func Disconnect(userID string) {
+	hubMu.Lock()
+	c := connections[userID]
+	if c == nil {
+		hubMu.Unlock()
+		return
+	}
+	user := c.user
+	c.conns--
+	if c.conns <= 0 {
+		delete(connections, userID)
+	}
+	hubMu.Unlock()
+
+	presence.Disconnect(userID)
+	notifyConnection(user, false)
+}
+
+// Touch records activity (a message or typing) for userID
+func Touch(userID string) {
+	presence.Touch(userID)
+}
+
+// PresenceState returns the user's presence state
+func PresenceState(userID string) string {
+	return presence.State(userID)
+}
+
+// IsOnline reports whether userID has an open connection on either transport
+func IsOnline(userID string) bool {
+	hubMu.RLock()
+	defer hubMu.RUnlock()
+	return connections[userID] != nil
+}
+
+// OnlineCount returns the number of connected users across both transports
+func OnlineCount() int {
+	hubMu.RLock()
+	defer hubMu.RUnlock()
+	return len(connections)
+}
+
+// OnlineUsers returns connected users sorted by username, with their state
+func OnlineUsers() []OnlineUser {
+	hubMu.RLock()
+	users := make([]OnlineUser, 0, len(connections))
+	for _, c := range connections {
+		users = append(users, c.user)
+	}
+	hubMu.RUnlock()
+
+	for i := range users {
+		users[i].State = presence.State(users[i].UserID)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return strings.ToLower(users[i].Username) < strings.ToLower(users[j].Username)
+	})
+	return users
+}
+
+func notifyConnection(user OnlineUser, connected bool) {
+	listenersMu.RLock()
+	listeners := append([]ConnectionListener(nil), connectionListeners...)
+	listenersMu.RUnlock()
+
+	for _, fn := range listeners {
+		fn(user, connected)
+	}
+}
+
+func notifyPresence(userID, state string) {
+	listenersMu.RLock()
+	listeners := append([]PresenceListener(nil), presenceListeners...)
+	listenersMu.RUnlock()
+
+	for _, fn := range listeners {
+		fn(userID, state)
+	}
+}
@@ -0,0 +1,140 @@
+package chatcore
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"burma2d/dbutil"
+)
+
+// ErrUserNotFound is returned by MergeUsers when either user has no profile
+var ErrUserNotFound = errors.New("user not found")
+
+// mergeStep is one statement of a merge, counted under label
+type mergeStep struct {
+	label string
+	query string
+	args  []interface{}
+}
+
+// mergeSteps lists the statements that move fromID's records onto intoID.
+// Rows that would collide with a unique key intoID already holds are
+// dropped, except points and message quota, which are added together per
+// day, and mutes, where the later expiry is kept.
+func mergeSteps(fromID, intoID string) []mergeStep {
+	return []mergeStep{
+		{"messages", "UPDATE chat_messages SET user_id = ? WHERE user_id = ?", []interface{}{intoID, fromID}},
+		{"direct_messages", "UPDATE chat_direct_messages SET sender_id = ? WHERE sender_id = ?", []interface{}{intoID, fromID}},
+		{"direct_messages", "UPDATE chat_direct_messages SET recipient_id = ? WHERE recipient_id = ?", []interface{}{intoID, fromID}},
+		{"reactions", "UPDATE OR IGNORE chat_reactions SET user_id = ? WHERE user_id = ?", []interface{}{intoID, fromID}},
+		{"reactions_dropped", "DELETE FROM chat_reactions WHERE user_id = ?", []interface{}{fromID}},
+
+		// Blocks between the two accounts would become self-blocks
+		{"blocks_dropped", "DELETE FROM chat_blocks WHERE (blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)",
+			[]interface{}{fromID, intoID, intoID, fromID}},
+		{"blocks", "UPDATE OR IGNORE chat_blocks SET blocker_id = ? WHERE blocker_id = ?", []interface{}{intoID, fromID}},
+		{"blocks", "UPDATE OR IGNORE chat_blocks SET blocked_id = ? WHERE blocked_id = ?", []interface{}{intoID, fromID}},
+		{"blocks_dropped", "DELETE FROM chat_blocks WHERE blocker_id = ? OR blocked_id = ?", []interface{}{fromID, fromID}},
+
+		// A ban on either account applies to the merged one
+		{"bans", "UPDATE OR IGNORE chat_banned_users SET user_id = ? WHERE user_id = ?", []interface{}{intoID, fromID}},
+		{"bans_dropped", "DELETE FROM chat_banned_users WHERE user_id = ?", []interface{}{fromID}},
+
+		{"points", `
+			INSERT INTO chat_points (user_id, day, points, last_awarded_at)
+			SELECT ?, day, points, last_awarded_at FROM chat_points WHERE user_id = ?
+			ON CONFLICT(user_id, day) DO UPDATE SET
+				points = points + excluded.points,
+				last_awarded_at = MAX(last_awarded_at, excluded.last_awarded_at)
+		`, []interface{}{intoID, fromID}},
+		{"points_merged", "DELETE FROM chat_points WHERE user_id = ?", []interface{}{fromID}},
+
+		{"sessions", "UPDATE chat_session_log SET user_id = ? WHERE user_id = ?", []interface{}{intoID, fromID}},
+
+		// Redemptions debit points, so they follow the points they spent
+		{"redemptions", "UPDATE gift_redemptions SET user_id = ? WHERE user_id = ?", []interface{}{intoID, fromID}},
+
+		// The later of the two mutes wins, so signing in under a new ID
+		// never lifts one
+		{"mutes", `
+			INSERT INTO chat_mutes (user_id, muted_until, reason, created_at)
+			SELECT ?, muted_until, reason, created_at FROM chat_mutes WHERE user_id = ?
+			ON CONFLICT(user_id) DO UPDATE SET
+				muted_until = MAX(muted_until, excluded.muted_until),
+				reason = CASE WHEN excluded.muted_until > muted_until THEN excluded.reason ELSE reason END
+		`, []interface{}{intoID, fromID}},
+		{"mutes_merged", "DELETE FROM chat_mutes WHERE user_id = ?", []interface{}{fromID}},
+
+		// Reports between the two accounts would become self-reports
+		{"reports_dropped", "DELETE FROM chat_reports WHERE (reporter_id = ? AND reported_id = ?) OR (reporter_id = ? AND reported_id = ?)",
+			[]interface{}{fromID, intoID, intoID, fromID}},
+		{"reports", "UPDATE chat_reports SET reporter_id = ? WHERE reporter_id = ?", []interface{}{intoID, fromID}},
+		{"reports", "UPDATE chat_reports SET reported_id = ? WHERE reported_id = ?", []interface{}{intoID, fromID}},
+		{"moderation_log", "UPDATE chat_moderation_log SET user_id = ? WHERE user_id = ?", []interface{}{intoID, fromID}},
+
+		{"message_quota", `
+			INSERT INTO chat_message_quota (user_id, day, count)
+			SELECT ?, day, count FROM chat_message_quota WHERE user_id = ?
+			ON CONFLICT(user_id, day) DO UPDATE SET count = count + excluded.count
+		`, []interface{}{intoID, fromID}},
+		{"message_quota_merged", "DELETE FROM chat_message_quota WHERE user_id = ?", []interface{}{fromID}},
+
+		{"profile", `
+			UPDATE chat_users SET
+				last_seen = MAX(last_seen, (SELECT last_seen FROM chat_users WHERE id = ?)),
+				created_at = MIN(created_at, (SELECT created_at FROM chat_users WHERE id = ?))
+			WHERE id = ?
+		`, []interface{}{fromID, fromID, intoID}},
+		{"profile_removed", "DELETE FROM chat_users WHERE id = ?", []interface{}{fromID}},
+	}
+}
+
+// MergeUsers moves everything fromID owns onto intoID and deletes fromID,
+// in one transaction. It returns the rows affected per step label and
+// whether intoID became banned through the merge.
+func MergeUsers(fromID, intoID string) (map[string]int64, bool, error) {
+	var affected map[string]int64
+	var newlyBanned bool
+
+	err := dbutil.WithTx(db, func(tx *sql.Tx) error {
+		var users int
+		err := tx.QueryRow("SELECT COUNT(*) FROM chat_users WHERE id IN (?, ?)", fromID, intoID).Scan(&users)
+		if err != nil {
+			return fmt.Errorf("look up users: %w", err)
+		}
+		if users < 2 {
+			return ErrUserNotFound
+		}
+
+		affected, newlyBanned, err = mergeUsersTx(tx, fromID, intoID)
+		return err
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return affected, newlyBanned, nil
+}
+
+// mergeUsersTx runs the merge steps inside tx
+func mergeUsersTx(tx *sql.Tx, fromID, intoID string) (map[string]int64, bool, error) {
+	var fromBanned, intoBanned int
+	err := tx.QueryRow(`
+		SELECT (SELECT COUNT(*) FROM chat_banned_users WHERE user_id = ?),
+		       (SELECT COUNT(*) FROM chat_banned_users WHERE user_id = ?)
+	`, fromID, intoID).Scan(&fromBanned, &intoBanned)
+	if err != nil {
+		return nil, false, fmt.Errorf("look up bans: %w", err)
+	}
+
+	affected := make(map[string]int64)
+	for _, step := range mergeSteps(fromID, intoID) {
+		result, err := tx.Exec(step.query, step.args...)
+		if err != nil {
+			return nil, false, fmt.Errorf("%s: %w", step.label, err)
+		}
+		n, _ := result.RowsAffected()
+		affected[step.label] += n
+	}
+	return affected, fromBanned > 0 && intoBanned == 0, nil
+}
@@ -0,0 +1,243 @@
+package chatcore
+
+import (
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// setupTestDB creates the tables the merge touches with the columns and
+// unique keys it relies on
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	database.SetMaxOpenConns(1)
+	t.Cleanup(func() { database.Close() })
+
+	_, err = database.Exec(`
+		CREATE TABLE chat_users (
+			id TEXT PRIMARY KEY, email TEXT UNIQUE NOT NULL, username TEXT NOT NULL, photo_url TEXT,
+			last_seen DATETIME DEFAULT CURRENT_TIMESTAMP, is_online BOOLEAN DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE chat_messages (id INTEGER PRIMARY KEY, user_id TEXT);
+		CREATE TABLE chat_direct_messages (id INTEGER PRIMARY KEY, sender_id TEXT, recipient_id TEXT);
+		CREATE TABLE chat_reactions (id INTEGER PRIMARY KEY, message_id INTEGER, user_id TEXT, emoji TEXT, UNIQUE(message_id, user_id, emoji));
+		CREATE TABLE chat_blocks (id INTEGER PRIMARY KEY, blocker_id TEXT, blocked_id TEXT, UNIQUE(blocker_id, blocked_id));
+		CREATE TABLE chat_banned_users (user_id TEXT PRIMARY KEY, username TEXT);
+		CREATE TABLE chat_points (user_id TEXT, day TEXT, points INTEGER, last_awarded_at INTEGER, PRIMARY KEY (user_id, day));
+		CREATE TABLE chat_session_log (id INTEGER PRIMARY KEY, user_id TEXT);
+		CREATE TABLE chat_mutes (user_id TEXT PRIMARY KEY, muted_until DATETIME NOT NULL, reason TEXT NOT NULL DEFAULT '', created_at DATETIME DEFAULT CURRENT_TIMESTAMP);
+		CREATE TABLE chat_reports (id INTEGER PRIMARY KEY, reporter_id TEXT, reported_id TEXT, reason TEXT, created_at DATETIME DEFAULT CURRENT_TIMESTAMP);
+		CREATE TABLE chat_moderation_log (id INTEGER PRIMARY KEY, user_id TEXT, action TEXT, created_at DATETIME DEFAULT CURRENT_TIMESTAMP);
+		CREATE TABLE chat_message_quota (user_id TEXT, day TEXT, count INTEGER, PRIMARY KEY (user_id, day));
+		CREATE TABLE gift_redemptions (id INTEGER PRIMARY KEY, gift_id INTEGER, user_id TEXT, points_spent INTEGER);
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db = database
+}
+
+func seed(t *testing.T, stmts ...string) {
+	t.Helper()
+	for _, s := range stmts {
+		if _, err := db.Exec(s); err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+	}
+}
+
+func queryInt(t *testing.T, query string, args ...interface{}) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	return n
+}
+
+func TestEnsureUserKeepsModerationState(t *testing.T) {
+	setupTestDB(t)
+	// An email-keyed record from an earlier release, muted and reported
+	seed(t,
+		`INSERT INTO chat_users (id, email, username) VALUES ('old@example.com', 'old@example.com', 'Old')`,
+		`INSERT INTO chat_users (id, email, username) VALUES ('reporter', 'r@example.com', 'R')`,
+		`INSERT INTO chat_mutes (user_id, muted_until, reason) VALUES ('old@example.com', '2999-01-01 00:00:00', 'spam')`,
+		`INSERT INTO chat_reports (reporter_id, reported_id, reason) VALUES ('reporter', 'old@example.com', 'spam')`,
+		`INSERT INTO chat_reports (reporter_id, reported_id, reason) VALUES ('old@example.com', 'reporter', 'rude')`,
+		`INSERT INTO chat_moderation_log (user_id, action) VALUES ('old@example.com', 'mute')`,
+		`INSERT INTO chat_message_quota (user_id, day, count) VALUES ('old@example.com', '2025-01-01', 3)`,
+	)
+
+	if err := EnsureUser("sub-123", "old@example.com", "New", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, muted := mutedUntil(t, "sub-123"); !muted {
+		t.Fatal("mute was lost when the user signed in under their subject")
+	}
+	if n := queryInt(t, "SELECT COUNT(*) FROM chat_reports WHERE reported_id = 'sub-123'"); n != 1 {
+		t.Errorf("reports against the user = %d, want 1", n)
+	}
+	if n := queryInt(t, "SELECT COUNT(*) FROM chat_reports WHERE reporter_id = 'sub-123'"); n != 1 {
+		t.Errorf("reports by the user = %d, want 1", n)
+	}
+	if n := queryInt(t, "SELECT COUNT(*) FROM chat_moderation_log WHERE user_id = 'sub-123'"); n != 1 {
+		t.Errorf("moderation log entries = %d, want 1", n)
+	}
+	if n := queryInt(t, "SELECT count FROM chat_message_quota WHERE user_id = 'sub-123' AND day = '2025-01-01'"); n != 3 {
+		t.Errorf("quota count = %d, want 3", n)
+	}
+	for _, table := range []string{"chat_mutes", "chat_message_quota", "chat_moderation_log"} {
+		if n := queryInt(t, "SELECT COUNT(*) FROM "+table+" WHERE user_id = 'old@example.com'"); n != 0 {
+			t.Errorf("%s still has %d rows for the old ID", table, n)
+		}
+	}
+}
+
+// mutedUntil reads the stored mute expiry for userID
+func mutedUntil(t *testing.T, userID string) (string, bool) {
+	t.Helper()
+	var until string
+	err := db.QueryRow("SELECT muted_until FROM chat_mutes WHERE user_id = ?", userID).Scan(&until)
+	if err == sql.ErrNoRows {
+		return "", false
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return until, true
+}
+
+func TestMergeUsersCombinesMutesAndQuota(t *testing.T) {
+	setupTestDB(t)
+	seed(t,
+		`INSERT INTO chat_users (id, email, username) VALUES ('a', 'a@example.com', 'A'), ('b', 'b@example.com', 'B')`,
+		`INSERT INTO chat_mutes (user_id, muted_until, reason) VALUES ('a', '2999-01-01 00:00:00', 'later')`,
+		`INSERT INTO chat_mutes (user_id, muted_until, reason) VALUES ('b', '2000-01-01 00:00:00', 'earlier')`,
+		`INSERT INTO chat_message_quota (user_id, day, count) VALUES ('a', '2025-01-01', 2), ('b', '2025-01-01', 5)`,
+		// A report between the two accounts would become a self-report
+		`INSERT INTO chat_reports (reporter_id, reported_id, reason) VALUES ('a', 'b', 'x')`,
+	)
+
+	affected, _, err := MergeUsers("a", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if affected["reports_dropped"] != 1 {
+		t.Errorf("reports_dropped = %d, want 1", affected["reports_dropped"])
+	}
+
+	until, _ := mutedUntil(t, "b")
+	if until[:4] != "2999" {
+		t.Errorf("muted_until = %s, want the later mute", until)
+	}
+	var reason string
+	db.QueryRow("SELECT reason FROM chat_mutes WHERE user_id = 'b'").Scan(&reason)
+	if reason != "later" {
+		t.Errorf("reason = %q, want the later mute's reason", reason)
+	}
+	if n := queryInt(t, "SELECT count FROM chat_message_quota WHERE user_id = 'b' AND day = '2025-01-01'"); n != 7 {
+		t.Errorf("quota count = %d, want 7", n)
+	}
+}
+
+func TestMergeUsersNotFound(t *testing.T) {
+	setupTestDB(t)
+	seed(t, `INSERT INTO chat_users (id, email, username) VALUES ('a', 'a@example.com', 'A')`)
+	if _, _, err := MergeUsers("a", "missing"); err != ErrUserNotFound {
+		t.Fatalf("err = %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestMergeUsersReassignsMessagesAndBlocks(t *testing.T) {
+	setupTestDB(t)
+	seed(t,
+		`INSERT INTO chat_users (id, email, username, last_seen, created_at) VALUES
+			('dup', 'dup@example.com', 'Dup', '2026-03-05 00:00:00', '2026-01-01 00:00:00'),
+			('main', 'main@example.com', 'Main', '2026-03-01 00:00:00', '2026-02-01 00:00:00'),
+			('x', 'x@example.com', 'X', '2026-03-01 00:00:00', '2026-01-01 00:00:00'),
+			('y', 'y@example.com', 'Y', '2026-03-01 00:00:00', '2026-01-01 00:00:00')`,
+		`INSERT INTO chat_messages (user_id) VALUES ('dup'), ('dup'), ('main'), ('x')`,
+		`INSERT INTO chat_direct_messages (sender_id, recipient_id) VALUES ('dup', 'x'), ('x', 'dup')`,
+		`INSERT INTO chat_blocks (blocker_id, blocked_id) VALUES
+			('dup', 'x'), ('main', 'x'), ('dup', 'y'), ('y', 'dup'), ('dup', 'main')`,
+		`INSERT INTO chat_banned_users (user_id, username) VALUES ('dup', 'Dup')`,
+	)
+
+	affected, newlyBanned, err := MergeUsers("dup", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !newlyBanned {
+		t.Error("the ban on the duplicate did not carry over")
+	}
+
+	// No message is lost
+	if n := queryInt(t, "SELECT COUNT(*) FROM chat_messages WHERE user_id = 'main'"); n != 3 {
+		t.Errorf("main has %d messages, want 3", n)
+	}
+	if n := queryInt(t, "SELECT COUNT(*) FROM chat_messages"); n != 4 {
+		t.Errorf("%d messages in total, want 4", n)
+	}
+	if n := queryInt(t, "SELECT COUNT(*) FROM chat_direct_messages WHERE sender_id = 'main' OR recipient_id = 'main'"); n != 2 {
+		t.Errorf("main has %d direct messages, want 2", n)
+	}
+
+	// Blocks move over; the duplicate entry and the self-block go
+	want := map[string]bool{"main>x": true, "main>y": true, "y>main": true}
+	rows, _ := db.Query("SELECT blocker_id || '>' || blocked_id FROM chat_blocks")
+	got := map[string]bool{}
+	for rows.Next() {
+		var b string
+		rows.Scan(&b)
+		got[b] = true
+	}
+	rows.Close()
+	if len(got) != len(want) {
+		t.Errorf("blocks = %v, want %v", got, want)
+	}
+	for b := range want {
+		if !got[b] {
+			t.Errorf("block %s missing, have %v", b, got)
+		}
+	}
+	if affected["messages"] != 2 || affected["blocks_dropped"] != 2 {
+		t.Errorf("affected = %v", affected)
+	}
+
+	// The duplicate's profile is gone and its history widens the kept one
+	if n := queryInt(t, "SELECT COUNT(*) FROM chat_users WHERE id = 'dup'"); n != 0 {
+		t.Error("duplicate profile was not removed")
+	}
+	var lastSeen, createdAt string
+	db.QueryRow("SELECT last_seen, created_at FROM chat_users WHERE id = 'main'").Scan(&lastSeen, &createdAt)
+	if lastSeen[:10] != "2026-03-05" || createdAt[:10] != "2026-01-01" {
+		t.Errorf("main last_seen %s, created_at %s; want the later and the earlier", lastSeen, createdAt)
+	}
+}
+
+func TestMergeUsersRollsBackOnError(t *testing.T) {
+	setupTestDB(t)
+	seed(t,
+		`INSERT INTO chat_users (id, email, username) VALUES ('a', 'a@example.com', 'A'), ('b', 'b@example.com', 'B')`,
+		`INSERT INTO chat_messages (user_id) VALUES ('a')`,
+		// A later step fails half way through the merge
+		`DROP TABLE chat_session_log`,
+	)
+
+	if _, _, err := MergeUsers("a", "b"); err == nil {
+		t.Fatal("merge succeeded without chat_session_log")
+	}
+	if n := queryInt(t, "SELECT COUNT(*) FROM chat_messages WHERE user_id = 'a'"); n != 1 {
+		t.Error("messages moved despite the failed merge")
+	}
+	if n := queryInt(t, "SELECT COUNT(*) FROM chat_users"); n != 2 {
+		t.Error("a profile was removed despite the failed merge")
+	}
+}
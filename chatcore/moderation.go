@@ -0,0 +1,110 @@
+package chatcore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"burma2d/config"
+)
+
+// Moderation verdicts returned by the webhook
+const (
+	VerdictAllow = "allow"
+	VerdictFlag  = "flag"
+	VerdictBlock = "block"
+)
+
+// Verdict is the moderation service's decision on a message
+type Verdict struct {
+	Verdict string `json:"verdict"`
+	Reason  string `json:"reason"`
+}
+
+// moderationRequest is the body posted to the webhook
+type moderationRequest struct {
+	UserID    string `json:"user_id"`
+	Message   string `json:"message"`
+	Transport string `json:"transport"` // "sse", "ws" or "dm"
+}
+
+var (
+	moderationURL      string
+	moderationFailOpen = true
+	moderationClient   = &http.Client{Timeout: 2 * time.Second}
+)
+
+// loadModerationConfig reads CHAT_MODERATION_URL (empty = disabled; redacted
+// in the config snapshot as webhook URLs often carry a token),
+// CHAT_MODERATION_TIMEOUT (default 2s) and CHAT_MODERATION_FAIL_OPEN
+// (default true: allow messages when the service is unreachable)
+func loadModerationConfig() {
+	moderationURL = config.Secret("CHAT_MODERATION_URL", "")
+	moderationFailOpen = config.Bool("CHAT_MODERATION_FAIL_OPEN", true)
+	moderationClient = &http.Client{Timeout: config.Duration("CHAT_MODERATION_TIMEOUT", 2*time.Second)}
+}
+
+// Moderate asks the moderation webhook for a verdict on a message before
+// it is broadcast. Allows everything when no webhook is configured; on
+// errors or timeouts the fail-open policy decides between allow and block.
+func Moderate(ctx context.Context, userID, text, transport string) Verdict {
+	if moderationURL == "" {
+		return Verdict{Verdict: VerdictAllow}
+	}
+
+	v, err := callModeration(ctx, moderationRequest{UserID: userID, Message: text, Transport: transport})
+	if err != nil {
+		if moderationFailOpen {
+			log.Printf("⚠️ Moderation unavailable, allowing message from %s: %v", userID, err)
+			return Verdict{Verdict: VerdictAllow}
+		}
+		log.Printf("⚠️ Moderation unavailable, blocking message from %s: %v", userID, err)
+		return Verdict{Verdict: VerdictBlock, Reason: "Moderation unavailable, try again later"}
+	}
+
+	switch v.Verdict {
+	case VerdictBlock:
+		log.Printf("🚫 Moderation blocked message from %s: %s", userID, v.Reason)
+	case VerdictFlag:
+		log.Printf("🚩 Moderation flagged message from %s: %s", userID, v.Reason)
+	}
+	return v
+}
+
+func callModeration(ctx context.Context, body moderationRequest) (Verdict, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return Verdict{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, moderationURL, bytes.NewReader(payload))
+	if err != nil {
+		return Verdict{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := moderationClient.Do(req)
+	if err != nil {
+		return Verdict{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Verdict{}, fmt.Errorf("moderation webhook returned %d", resp.StatusCode)
+	}
+
+	var v Verdict
+	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+		return Verdict{}, fmt.Errorf("invalid moderation response: %w", err)
+	}
+	switch v.Verdict {
+	case VerdictAllow, VerdictFlag, VerdictBlock:
+		return v, nil
+	default:
+		return Verdict{}, fmt.Errorf("unknown moderation verdict %q", v.Verdict)
+	}
+}
@@ -0,0 +1,100 @@
+package chatcore
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"burma2d/config"
+)
+
+// useModeration points the webhook at a mock service for the test
+func useModeration(t *testing.T, handler http.HandlerFunc, failOpen bool, timeout time.Duration) {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	oldURL, oldFailOpen, oldClient := moderationURL, moderationFailOpen, moderationClient
+	moderationURL, moderationFailOpen = srv.URL, failOpen
+	moderationClient = &http.Client{Timeout: timeout}
+	t.Cleanup(func() {
+		srv.Close()
+		moderationURL, moderationFailOpen, moderationClient = oldURL, oldFailOpen, oldClient
+	})
+}
+
+// verdictFor answers with a fixed verdict and records the last request
+func verdictFor(verdict string, got *moderationRequest) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(got)
+		json.NewEncoder(w).Encode(Verdict{Verdict: verdict, Reason: "test"})
+	}
+}
+
+func TestModerateVerdicts(t *testing.T) {
+	for _, verdict := range []string{VerdictAllow, VerdictFlag, VerdictBlock} {
+		var got moderationRequest
+		useModeration(t, verdictFor(verdict, &got), true, time.Second)
+
+		v := Moderate(context.Background(), "alice", "hello", "ws")
+		if v.Verdict != verdict {
+			t.Errorf("verdict %q, want %q", v.Verdict, verdict)
+		}
+		if got.UserID != "alice" || got.Message != "hello" || got.Transport != "ws" {
+			t.Errorf("webhook received %+v", got)
+		}
+	}
+}
+
+func TestModerateDisabled(t *testing.T) {
+	old := moderationURL
+	moderationURL = ""
+	t.Cleanup(func() { moderationURL = old })
+
+	if v := Moderate(context.Background(), "alice", "hello", "sse"); v.Verdict != VerdictAllow {
+		t.Fatalf("verdict %q with no webhook, want allow", v.Verdict)
+	}
+}
+
+func TestModerateUnavailable(t *testing.T) {
+	slow := func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}
+	failing := func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusBadGateway)
+	}
+	unknown := verdictFor("maybe", &moderationRequest{})
+
+	for name, handler := range map[string]http.HandlerFunc{"timeout": slow, "error status": failing, "unknown verdict": unknown} {
+		useModeration(t, handler, true, 50*time.Millisecond)
+		start := time.Now()
+		if v := Moderate(context.Background(), "alice", "hello", "sse"); v.Verdict != VerdictAllow {
+			t.Errorf("%s, fail-open: verdict %q, want allow", name, v.Verdict)
+		}
+		if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+			t.Errorf("%s: took %s, the timeout was not applied", name, elapsed)
+		}
+
+		moderationFailOpen = false
+		if v := Moderate(context.Background(), "alice", "hello", "sse"); v.Verdict != VerdictBlock {
+			t.Errorf("%s, fail-closed: verdict %q, want block", name, v.Verdict)
+		}
+	}
+}
+
+func TestModerationURLIsRedacted(t *testing.T) {
+	t.Cleanup(loadModerationConfig)
+	t.Setenv("CHAT_MODERATION_URL", "https://moderation.example/hook?key=secret")
+	loadModerationConfig()
+
+	if moderationURL != "https://moderation.example/hook?key=secret" {
+		t.Fatalf("moderationURL = %q", moderationURL)
+	}
+	if got := config.Snapshot()["CHAT_MODERATION_URL"]; got != "[REDACTED]" {
+		t.Errorf("snapshot shows %q, want it redacted", got)
+	}
+}
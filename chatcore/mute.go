@@ -0,0 +1,31 @@
+package chatcore
+
+import "time"
+
+// MuteChecker returns when a user's mute ends, if they are muted
+type MuteChecker func(userID string) (time.Time, bool)
+
+var muteChecker MuteChecker
+
+// SetMuteChecker installs the mute lookup used by MutedUntil; the chat
+// package owns the mute list and registers it at startup
+func SetMuteChecker(fn MuteChecker) {
+	muteChecker = fn
+}
+
+// MutedUntil reports whether any of the user's IDs is muted and until when.
+// Muted users stay connected and can read but not send.
+func MutedUntil(ids ...string) (time.Time, bool) {
+	if muteChecker == nil {
+		return time.Time{}, false
+	}
+	for _, id := range ids {
+		if id == "" {
+			continue
+		}
+		if until, ok := muteChecker(id); ok {
+			return until, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,150 @@
+package chatcore
+
+import (
+	"sync"
+	"time"
+
+	"burma2d/config"
+)
+
+// Presence states for connected users
+const (
+	PresenceActive = "active"
+	PresenceAway   = "away"
+)
+
+// DefaultAwayAfter is the idle period before a user is away when CHAT_AWAY_AFTER is unset
+const DefaultAwayAfter = 5 * time.Minute
+
+// AwayAfter returns the configured idle period (CHAT_AWAY_AFTER)
+func AwayAfter() time.Duration {
+	d := config.Duration("CHAT_AWAY_AFTER", DefaultAwayAfter)
+	if d <= 0 {
+		return DefaultAwayAfter
+	}
+	return d
+}
+
+// presenceEntry is one connected user's activity
+type presenceEntry struct {
+	conns      int // open connections; the entry is dropped at zero
+	lastActive time.Time
+	away       bool
+}
+
+// Presence tracks activity of connected users and reports when they move
+// between active and away. The hub keeps one tracker for both transports.
+type Presence struct {
+	mu       sync.Mutex
+	idle     time.Duration
+	users    map[string]*presenceEntry
+	onChange func(userID, state string)
+	now      func() time.Time
+}
+
+// NewPresence returns a tracker that marks users away after idle and calls
+// onChange (outside the lock) on every transition
+func NewPresence(idle time.Duration, onChange func(userID, state string)) *Presence {
+	return &Presence{
+		idle:     idle,
+		users:    make(map[string]*presenceEntry),
+		onChange: onChange,
+		now:      time.Now,
+	}
+}
+
+// Connect records a new connection for userID; connecting counts as activity
+func (p *Presence) Connect(userID string) {
+	p.mu.Lock()
+	e := p.users[userID]
+	if e == nil {
+		e = &presenceEntry{}
+		p.users[userID] = e
+	}
+	e.conns++
+	p.mu.Unlock()
+
+	p.Touch(userID)
+}
+
+// Disconnect drops one connection; the user is forgotten with the last one
+func (p *Presence) Disconnect(userID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if e := p.users[userID]; e != nil {
+		e.conns--
+		if e.conns <= 0 {
+			delete(p.users, userID)
+		}
+	}
+}
+
+// Touch records activity (a message or typing) and brings an away user back
+func (p *Presence) Touch(userID string) {
+	p.mu.Lock()
+	e := p.users[userID]
+	if e == nil {
+		p.mu.Unlock()
+		return
+	}
+	e.lastActive = p.now()
+	wasAway := e.away
+	e.away = false
+	p.mu.Unlock()
+
+	if wasAway && p.onChange != nil {
+		p.onChange(userID, PresenceActive)
+	}
+}
+
+// State returns the user's presence state (active for unknown users)
+func (p *Presence) State(userID string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if e := p.users[userID]; e != nil && e.away {
+		return PresenceAway
+	}
+	return PresenceActive
+}
+
+// Sweep marks users idle for longer than the away period as away
+func (p *Presence) Sweep() {
+	now := p.now()
+
+	var newlyAway []string
+	p.mu.Lock()
+	for userID, e := range p.users {
+		if !e.away && now.Sub(e.lastActive) >= p.idle {
+			e.away = true
+			newlyAway = append(newlyAway, userID)
+		}
+	}
+	p.mu.Unlock()
+
+	if p.onChange == nil {
+		return
+	}
+	for _, userID := range newlyAway {
+		p.onChange(userID, PresenceAway)
+	}
+}
+
+// Run sweeps periodically; start it in its own goroutine
+func (p *Presence) Run() {
+	interval := p.idle / 10
+	if interval < time.Second {
+		interval = time.Second
+	}
+	if interval > 30*time.Second {
+		interval = 30 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		p.Sweep()
+	}
+}
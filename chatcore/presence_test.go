@@ -0,0 +1,111 @@
+package chatcore
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// presenceClock is a manual clock with a log of presence changes
+type presenceClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	changes []string
+}
+
+func newTestPresence(idle time.Duration) (*Presence, *presenceClock) {
+	clock := &presenceClock{now: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}
+	p := NewPresence(idle, func(userID, state string) {
+		clock.mu.Lock()
+		clock.changes = append(clock.changes, userID+":"+state)
+		clock.mu.Unlock()
+	})
+	p.now = func() time.Time {
+		clock.mu.Lock()
+		defer clock.mu.Unlock()
+		return clock.now
+	}
+	return p, clock
+}
+
+func (c *presenceClock) advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+func (c *presenceClock) log() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.changes...)
+}
+
+func TestPresenceIdleUserGoesAwayAndReturns(t *testing.T) {
+	p, clock := newTestPresence(5 * time.Minute)
+	p.Connect("alice")
+
+	clock.advance(4 * time.Minute)
+	p.Sweep()
+	if state := p.State("alice"); state != PresenceActive {
+		t.Fatalf("after 4m: %s, want active", state)
+	}
+
+	clock.advance(time.Minute)
+	p.Sweep()
+	if state := p.State("alice"); state != PresenceAway {
+		t.Fatalf("after 5m: %s, want away", state)
+	}
+
+	// Another sweep doesn't announce away again
+	p.Sweep()
+
+	// Sending a message brings the user back
+	p.Touch("alice")
+	if state := p.State("alice"); state != PresenceActive {
+		t.Fatalf("after a message: %s, want active", state)
+	}
+
+	if got := clock.log(); len(got) != 2 || got[0] != "alice:away" || got[1] != "alice:active" {
+		t.Fatalf("changes = %v, want [alice:away alice:active]", got)
+	}
+}
+
+func TestPresenceActivityResetsIdleTimer(t *testing.T) {
+	p, clock := newTestPresence(5 * time.Minute)
+	p.Connect("alice")
+
+	clock.advance(4 * time.Minute)
+	p.Touch("alice")
+	clock.advance(4 * time.Minute)
+	p.Sweep()
+
+	if state := p.State("alice"); state != PresenceActive {
+		t.Fatalf("%s, want active 4m after the last message", state)
+	}
+	if got := clock.log(); len(got) != 0 {
+		t.Fatalf("changes = %v, want none", got)
+	}
+}
+
+func TestPresenceForgetsDisconnectedUsers(t *testing.T) {
+	p, clock := newTestPresence(time.Minute)
+	p.Connect("alice")
+	p.Connect("alice") // second tab
+	p.Disconnect("alice")
+
+	clock.advance(time.Minute)
+	p.Sweep()
+	if state := p.State("alice"); state != PresenceAway {
+		t.Fatalf("with one tab open: %s, want away", state)
+	}
+
+	p.Disconnect("alice")
+	if state := p.State("alice"); state != PresenceActive {
+		t.Fatalf("after the last tab closed: %s, want the default", state)
+	}
+	// Activity from a user who isn't connected is ignored
+	p.Touch("alice")
+	if got := clock.log(); len(got) != 1 {
+		t.Fatalf("changes = %v, want only the away change", got)
+	}
+}
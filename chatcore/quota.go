@@ -0,0 +1,84 @@
+package chatcore
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"burma2d/config"
+)
+
+// Daily message quota, read in loadQuotaConfig. Off unless
+// CHAT_DAILY_MESSAGE_LIMIT is positive.
+var (
+	dailyMessageLimit int
+	quotaExempt       map[string]bool
+	quotaLocation     = time.FixedZone("Myanmar", 6*3600+30*60)
+)
+
+// QuotaError is returned by ConsumeQuota once a user has sent their daily limit
+type QuotaError struct {
+	Limit   int
+	ResetAt time.Time // Next Myanmar midnight
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("daily limit of %d messages reached", e.Limit)
+}
+
+// loadQuotaConfig reads CHAT_DAILY_MESSAGE_LIMIT (messages per user per
+// Myanmar calendar day, 0 = unlimited) and CHAT_QUOTA_EXEMPT_USERS
+// (comma-separated chat user IDs that are never limited)
+func loadQuotaConfig() {
+	if loc, err := time.LoadLocation("Asia/Yangon"); err == nil {
+		quotaLocation = loc
+	}
+
+	dailyMessageLimit = config.Int("CHAT_DAILY_MESSAGE_LIMIT", 0)
+	quotaExempt = make(map[string]bool)
+	for _, id := range strings.Split(config.String("CHAT_QUOTA_EXEMPT_USERS", ""), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			quotaExempt[id] = true
+		}
+	}
+
+	if dailyMessageLimit > 0 {
+		log.Printf("✅ Chat daily message limit: %d per user (%d exempt users)", dailyMessageLimit, len(quotaExempt))
+	} else {
+		dailyMessageLimit = 0
+		log.Println("ℹ️  Chat daily message limit disabled (set CHAT_DAILY_MESSAGE_LIMIT to enable)")
+	}
+}
+
+// QuotaDay returns the Myanmar calendar day of now and the midnight ending it
+func QuotaDay(now time.Time) (string, time.Time) {
+	local := now.In(quotaLocation)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, quotaLocation)
+	return local.Format("2006-01-02"), midnight.AddDate(0, 0, 1)
+}
+
+// ConsumeQuota counts one message against userID's daily limit through q,
+// or returns a *QuotaError without counting once the limit is reached. Pass
+// the transaction storing the message so a failed send is not counted.
+func ConsumeQuota(q Execer, userID string, now time.Time) error {
+	if dailyMessageLimit <= 0 || quotaExempt[userID] {
+		return nil
+	}
+
+	day, resetAt := QuotaDay(now)
+	result, err := q.Exec(`
+		INSERT INTO chat_message_quota (user_id, day, count)
+		VALUES (?, ?, 1)
+		ON CONFLICT(user_id, day) DO UPDATE SET count = count + 1
+		WHERE count < ?
+	`, userID, day, dailyMessageLimit)
+	if err != nil {
+		return err
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return &QuotaError{Limit: dailyMessageLimit, ResetAt: resetAt}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package chatcore
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func useQuota(t *testing.T, limit, exempt string) {
+	t.Helper()
+	setupTestDB(t)
+	t.Setenv("CHAT_DAILY_MESSAGE_LIMIT", limit)
+	t.Setenv("CHAT_QUOTA_EXEMPT_USERS", exempt)
+	loadQuotaConfig()
+	t.Cleanup(func() { dailyMessageLimit, quotaExempt = 0, nil })
+}
+
+func TestDailyQuotaBlocksUntilReset(t *testing.T) {
+	useQuota(t, "3", "")
+	// 23:00 in Myanmar, half an hour before the day resets
+	now := time.Date(2026, 3, 2, 16, 30, 0, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		if err := ConsumeQuota(db, "alice", now); err != nil {
+			t.Fatalf("message %d: %v", i+1, err)
+		}
+	}
+
+	err := ConsumeQuota(db, "alice", now.Add(20*time.Minute))
+	var qe *QuotaError
+	if !errors.As(err, &qe) {
+		t.Fatalf("4th message: %v, want a QuotaError", err)
+	}
+	wantReset := time.Date(2026, 3, 2, 17, 30, 0, 0, time.UTC) // Myanmar midnight
+	if qe.Limit != 3 || !qe.ResetAt.Equal(wantReset) {
+		t.Errorf("QuotaError = %d, %s; want 3, %s", qe.Limit, qe.ResetAt.UTC(), wantReset)
+	}
+	// A refused message is not counted
+	if n := queryInt(t, "SELECT count FROM chat_message_quota WHERE user_id = 'alice'"); n != 3 {
+		t.Errorf("count = %d, want 3", n)
+	}
+
+	// Other users have their own quota
+	if err := ConsumeQuota(db, "bob", now); err != nil {
+		t.Errorf("bob: %v", err)
+	}
+
+	// Still blocked a second before midnight, allowed again after it
+	if err := ConsumeQuota(db, "alice", wantReset.Add(-time.Second)); err == nil {
+		t.Errorf("allowed before the reset")
+	}
+	if err := ConsumeQuota(db, "alice", wantReset); err != nil {
+		t.Errorf("after the reset: %v", err)
+	}
+}
+
+func TestDailyQuotaExemptAndDisabled(t *testing.T) {
+	useQuota(t, "1", "admin-1, admin-2")
+	now := time.Date(2026, 3, 2, 6, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 5; i++ {
+		if err := ConsumeQuota(db, "admin-2", now); err != nil {
+			t.Fatalf("exempt user blocked: %v", err)
+		}
+	}
+
+	useQuota(t, "0", "")
+	for i := 0; i < 5; i++ {
+		if err := ConsumeQuota(db, "alice", now); err != nil {
+			t.Fatalf("blocked with the limit disabled: %v", err)
+		}
+	}
+}
+
+func TestQuotaDay(t *testing.T) {
+	loadQuotaConfig()
+	for _, tt := range []struct {
+		now        time.Time
+		day, reset string
+	}{
+		{time.Date(2026, 3, 2, 17, 29, 0, 0, time.UTC), "2026-03-02", "2026-03-02T17:30:00Z"},
+		{time.Date(2026, 3, 2, 17, 30, 0, 0, time.UTC), "2026-03-03", "2026-03-03T17:30:00Z"},
+	} {
+		day, reset := QuotaDay(tt.now)
+		if day != tt.day || reset.UTC().Format(time.RFC3339) != tt.reset {
+			t.Errorf("QuotaDay(%s) = %s, %s; want %s, %s", tt.now, day, reset.UTC().Format(time.RFC3339), tt.day, tt.reset)
+		}
+	}
+}
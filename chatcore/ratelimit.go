@@ -0,0 +1,30 @@
+package chatcore
+
+import (
+	"log"
+	"time"
+
+	"burma2d/config"
+	"burma2d/ratelimit"
+)
+
+// messageLimiter throttles sends per user across both transports, so using
+// SSE and WebSocket together does not double the allowance
+var messageLimiter *ratelimit.Limiter
+
+// loadRateLimitConfig reads CHAT_RATE_LIMIT_MESSAGES per CHAT_RATE_LIMIT_WINDOW
+func loadRateLimitConfig() {
+	limit := config.Int("CHAT_RATE_LIMIT_MESSAGES", 5)
+	window := config.Duration("CHAT_RATE_LIMIT_WINDOW", 10*time.Second)
+	messageLimiter = ratelimit.New(limit, window)
+	log.Printf("✅ Chat rate limit: %d messages per %s", limit, window)
+}
+
+// AllowMessage consumes one send for userID from the shared limiter. When
+// it returns false, wait is how long until the next send is allowed.
+func AllowMessage(userID string) (ok bool, wait time.Duration) {
+	if messageLimiter == nil {
+		return true, 0
+	}
+	return messageLimiter.Allow(userID)
+}
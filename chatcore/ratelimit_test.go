@@ -0,0 +1,39 @@
+package chatcore
+
+import (
+	"testing"
+	"time"
+
+	"burma2d/ratelimit"
+)
+
+func TestAllowMessageSharedBudget(t *testing.T) {
+	old := messageLimiter
+	messageLimiter = ratelimit.New(2, time.Minute)
+	t.Cleanup(func() { messageLimiter = old })
+
+	// Both transports draw from the same per-user budget
+	for i := 0; i < 2; i++ {
+		if ok, _ := AllowMessage("u1"); !ok {
+			t.Fatalf("send %d refused", i+1)
+		}
+	}
+	ok, wait := AllowMessage("u1")
+	if ok || wait <= 0 {
+		t.Fatalf("third send: ok %v, wait %s; want refused with a wait", ok, wait)
+	}
+
+	if ok, _ := AllowMessage("u2"); !ok {
+		t.Fatal("another user's budget was affected")
+	}
+}
+
+func TestAllowMessageBeforeInit(t *testing.T) {
+	old := messageLimiter
+	messageLimiter = nil
+	t.Cleanup(func() { messageLimiter = old })
+
+	if ok, _ := AllowMessage("u1"); !ok {
+		t.Fatal("sends refused before the limiter is configured")
+	}
+}
@@ -0,0 +1,153 @@
+package chatcore
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"burma2d/dbutil"
+)
+
+// Both transports store messages in chat_messages and users in chat_users.
+// The tables are created and migrated by the chat package.
+var db *sql.DB
+
+// InitDB sets the database holding the shared message store
+func InitDB(database *sql.DB) {
+	db = database
+}
+
+// Message is a chat message as stored and sent to clients
+type Message struct {
+	ID        int64     `json:"id"`
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username"`
+	PhotoURL  string    `json:"photo_url"`
+	Message   string    `json:"message"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Execer runs a statement; *sql.DB and *sql.Tx both satisfy it
+type Execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// InsertMessage stores m through q and returns it with its ID set. Pass a
+// transaction to store the message together with other writes.
+func InsertMessage(q Execer, m Message) (Message, error) {
+	result, err := q.Exec(`
+		INSERT INTO chat_messages (user_id, username, photo_url, message)
+		VALUES (?, ?, ?, ?)
+	`, m.UserID, m.Username, m.PhotoURL, m.Message)
+	if err != nil {
+		return m, err
+	}
+
+	m.ID, _ = result.LastInsertId()
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = time.Now()
+	}
+	return m, nil
+}
+
+// SaveMessage stores m on its own
+func SaveMessage(m Message) (Message, error) {
+	return InsertMessage(db, m)
+}
+
+// RecentMessages returns up to limit of the latest messages in chronological
+// order, leaving out deleted messages and messages from hidden users
+func RecentMessages(limit int, hidden []string) ([]Message, error) {
+	query := `
+		SELECT id, user_id, username, photo_url, message, created_at
+		FROM chat_messages
+		WHERE deleted_at IS NULL`
+	args := make([]interface{}, 0, len(hidden)+1)
+	if len(hidden) > 0 {
+		query += " AND user_id NOT IN (?" + strings.Repeat(",?", len(hidden)-1) + ")"
+		for _, id := range hidden {
+			args = append(args, id)
+		}
+	}
+	query += " ORDER BY created_at DESC, id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []Message{}
+	for rows.Next() {
+		var m Message
+		var photoURL sql.NullString
+		if err := rows.Scan(&m.ID, &m.UserID, &m.Username, &photoURL, &m.Message, &m.CreatedAt); err != nil {
+			continue
+		}
+		m.PhotoURL = photoURL.String
+		messages = append(messages, m)
+	}
+
+	// Reverse to chronological order
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages, rows.Err()
+}
+
+// EnsureUser creates or refreshes the chat_users row of a signed-in user.
+// id is the Google subject on both transports; email is kept as a separate
+// attribute. Any other row holding the same email is an older record of
+// this account (earlier releases keyed SSE users by email) and is merged
+// into id, so the user keeps their messages, blocks and bans.
+func EnsureUser(id, email, username, photoURL string) error {
+	return dbutil.WithTx(db, func(tx *sql.Tx) error {
+		if email == "" {
+			// email is required and unique; the ID is a stable stand-in
+			email = id + "@users.invalid"
+		}
+
+		var holder string
+		err := tx.QueryRow("SELECT id FROM chat_users WHERE email = ?", email).Scan(&holder)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+
+		// Until the older row is merged away its email can't be reused
+		rowEmail := email
+		if holder != "" && holder != id {
+			rowEmail = id + "@users.invalid"
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO chat_users (id, email, username, photo_url, is_online)
+			VALUES (?, ?, ?, ?, 1)
+			ON CONFLICT(id) DO UPDATE SET
+				email = excluded.email,
+				username = excluded.username,
+				photo_url = excluded.photo_url,
+				is_online = 1,
+				last_seen = CURRENT_TIMESTAMP
+		`, id, rowEmail, username, photoURL)
+		if err != nil {
+			return err
+		}
+
+		if holder == "" || holder == id {
+			return nil
+		}
+
+		affected, _, err := mergeUsersTx(tx, holder, id)
+		if err != nil {
+			return fmt.Errorf("merge %s into %s: %w", holder, id, err)
+		}
+		if _, err := tx.Exec("UPDATE chat_users SET email = ? WHERE id = ?", email, id); err != nil {
+			return err
+		}
+		log.Printf("🔀 Moved chat user %s to ID %s: %v", holder, id, affected)
+		return nil
+	})
+}
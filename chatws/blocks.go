@@ -0,0 +1,179 @@
+package chatws
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+
+	"burma2d/chatcore"
+	"burma2d/jsonutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// blocks caches chatws_blocked_users as blocker -> blocked set so the
+// broadcast loop can filter without a query per recipient. Every write to
+// the table goes through addBlock/removeBlock, which keep it in sync.
+var (
+	blocks   = make(map[string]map[string]bool)
+	blocksMu sync.RWMutex
+)
+
+// loadBlocks fills the cache from the database
+func loadBlocks() error {
+	rows, err := db.Query("SELECT blocker_id, blocked_id FROM chatws_blocked_users")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	loaded := make(map[string]map[string]bool)
+	count := 0
+	for rows.Next() {
+		var blocker, blocked string
+		if err := rows.Scan(&blocker, &blocked); err != nil {
+			return err
+		}
+		if loaded[blocker] == nil {
+			loaded[blocker] = make(map[string]bool)
+		}
+		loaded[blocker][blocked] = true
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	blocksMu.Lock()
+	blocks = loaded
+	blocksMu.Unlock()
+
+	log.Printf("✅ Loaded %d WebSocket chat blocks", count)
+	return nil
+}
+
+// isBlocking reports whether blocker has blocked sender
+func isBlocking(blocker, sender string) bool {
+	blocksMu.RLock()
+	defer blocksMu.RUnlock()
+	return blocks[blocker][sender]
+}
+
+// blockedBy returns the IDs blocker has blocked
+func blockedBy(blocker string) []string {
+	blocksMu.RLock()
+	defer blocksMu.RUnlock()
+
+	ids := make([]string, 0, len(blocks[blocker]))
+	for id := range blocks[blocker] {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// addBlock stores a block, enforcing the shared per-user limit
+func addBlock(blocker, blocked string) error {
+	blocksMu.Lock()
+	defer blocksMu.Unlock()
+
+	if blocks[blocker][blocked] {
+		return nil
+	}
+	if max := chatcore.MaxBlocks(); len(blocks[blocker]) >= max {
+		return fmt.Errorf("block list is full (max %d users)", max)
+	}
+
+	_, err := db.Exec(`
+		INSERT OR IGNORE INTO chatws_blocked_users (blocker_id, blocked_id)
+		VALUES (?, ?)
+	`, blocker, blocked)
+	if err != nil {
+		return err
+	}
+
+	if blocks[blocker] == nil {
+		blocks[blocker] = make(map[string]bool)
+	}
+	blocks[blocker][blocked] = true
+	return nil
+}
+
+// removeBlock deletes a block
+func removeBlock(blocker, blocked string) error {
+	blocksMu.Lock()
+	defer blocksMu.Unlock()
+
+	_, err := db.Exec(`
+		DELETE FROM chatws_blocked_users WHERE blocker_id = ? AND blocked_id = ?
+	`, blocker, blocked)
+	if err != nil {
+		return err
+	}
+
+	delete(blocks[blocker], blocked)
+	if len(blocks[blocker]) == 0 {
+		delete(blocks, blocker)
+	}
+	return nil
+}
+
+type blockRequest struct {
+	BlockerID string `json:"blocker_id" binding:"required"`
+	BlockedID string `json:"blocked_id" binding:"required"`
+}
+
+// BlockUserHandler blocks a user for POST /api/burma2d/chatws/block
+func BlockUserHandler(c *gin.Context) {
+	var req blockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		jsonutil.BindError(c, err)
+		return
+	}
+	if req.BlockerID == req.BlockedID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot block yourself"})
+		return
+	}
+
+	var exists int
+	db.QueryRow("SELECT COUNT(*) FROM chatws_users WHERE id IN (?, ?)", req.BlockerID, req.BlockedID).Scan(&exists)
+	if exists < 2 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := addBlock(req.BlockerID, req.BlockedID); err != nil {
+		log.Printf("⚠️ Failed to block %s for %s: %v", req.BlockedID, req.BlockerID, err)
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// UnblockUserHandler removes a block for DELETE /api/burma2d/chatws/block
+func UnblockUserHandler(c *gin.Context) {
+	var req blockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		jsonutil.BindError(c, err)
+		return
+	}
+
+	if err := removeBlock(req.BlockerID, req.BlockedID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unblock user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// GetBlockedUsersHandler lists the users a user has blocked
+func GetBlockedUsersHandler(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id required"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"blocked_user_ids": blockedBy(userID)})
+}
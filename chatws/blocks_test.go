@@ -0,0 +1,87 @@
+package chatws
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// setBlock calls the block or unblock endpoint and returns the status
+func setBlock(t *testing.T, method, blocker, blocked string) int {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/block", BlockUserHandler)
+	r.DELETE("/block", UnblockUserHandler)
+
+	body, _ := json.Marshal(gin.H{"blocker_id": blocker, "blocked_id": blocked})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(method, "/block", bytes.NewReader(body)))
+	return w.Code
+}
+
+func say(t *testing.T, conn *websocket.Conn, text string) {
+	t.Helper()
+	if err := conn.WriteJSON(map[string]string{"type": "message", "message": text}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBlockerNeverReceivesBlockedSender(t *testing.T) {
+	srv := startServer(t)
+	alice := dial(t, srv, "alice")
+	bob := dial(t, srv, "bob")
+	carol := dial(t, srv, "carol")
+
+	if code := setBlock(t, http.MethodPost, "carol", "bob"); code != http.StatusOK {
+		t.Fatalf("block: status %d", code)
+	}
+
+	say(t, bob, "spam")
+	if data := readEvent(t, alice, "message"); data["user_id"] != "bob" {
+		t.Fatalf("alice got %v, want bob's message", data)
+	}
+
+	// Bob's message went out first, so carol's next message would be his
+	say(t, alice, "hello")
+	if data := readEvent(t, carol, "message"); data["user_id"] != "alice" || data["message"] != "hello" {
+		t.Fatalf("carol got %v, want alice's message and nothing from bob", data)
+	}
+
+	// Unblocking restores delivery
+	if code := setBlock(t, http.MethodDelete, "carol", "bob"); code != http.StatusOK {
+		t.Fatalf("unblock: status %d", code)
+	}
+	say(t, bob, "back again")
+	if data := readEvent(t, carol, "message"); data["user_id"] != "bob" {
+		t.Fatalf("carol got %v after unblocking, want bob's message", data)
+	}
+}
+
+func TestBlockSurvivesReload(t *testing.T) {
+	srv := startServer(t)
+	dial(t, srv, "alice")
+	dial(t, srv, "bob")
+
+	if code := setBlock(t, http.MethodPost, "alice", "bob"); code != http.StatusOK {
+		t.Fatalf("block: status %d", code)
+	}
+	if code := setBlock(t, http.MethodPost, "alice", "alice"); code != http.StatusBadRequest {
+		t.Errorf("self block: status %d, want 400", code)
+	}
+	if code := setBlock(t, http.MethodPost, "alice", "nobody"); code != http.StatusNotFound {
+		t.Errorf("unknown user: status %d, want 404", code)
+	}
+
+	if err := loadBlocks(); err != nil {
+		t.Fatal(err)
+	}
+	if !isBlocking("alice", "bob") || isBlocking("bob", "alice") {
+		t.Fatal("cache does not match the stored block after reload")
+	}
+}
@@ -5,16 +5,28 @@ import (
 	"database/sql"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"burma2d/adminauth"
+	"burma2d/chatcore"
+	"burma2d/config"
+	"burma2d/dbutil"
+	"burma2d/googleauth"
+	"burma2d/ratelimit"
+	"burma2d/sessionlog"
+	"burma2d/shutdown"
+	"burma2d/tracing"
+	"burma2d/wordfilter"
+
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
-	"google.golang.org/api/idtoken"
 )
 
 var db *sql.DB
@@ -25,6 +37,10 @@ var myanmarLocation *time.Location
 // Firebase OAuth Client ID
 var googleClientID string
 
+// insecureAuth accepts ID tokens without verifying signature or expiry
+// (CHAT_INSECURE_AUTH, development only)
+var insecureAuth bool
+
 // WebSocket upgrader
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
@@ -40,39 +56,40 @@ type WSClient struct {
 	Username string
 	PhotoURL string
 	Conn     *websocket.Conn
-	Send     chan []byte
+	Send     chan []byte   // Outgoing events; never closed, senders must not block
+	done     chan struct{} // Closed by stop; the write pump flushes Send and closes the connection
+	stopOnce sync.Once
+
+	lastTyping time.Time // Last time a typing event was broadcast (read pump only)
+	isAdmin    bool      // Connected with an admin session; exempt from the daily limit
 }
 
+// Typing indicator timing
+const (
+	typingDebounce = 2 * time.Second // Re-broadcast a client's typing event at most this often
+	typingTimeout  = 4 * time.Second // Clients hide the indicator after this long without a new event
+)
+
 var (
 	clients      = make(map[*WSClient]bool)
 	clientsMutex sync.RWMutex
-	broadcast    = make(chan []byte, 256)
+	broadcast    = make(chan outbound, 256)
+	relaysOnce   sync.Once
 )
 
-// Message represents a chat message
-type Message struct {
-	ID        int64     `json:"id"`
-	UserID    string    `json:"user_id"`
-	Username  string    `json:"username"`
-	PhotoURL  string    `json:"photo_url"`
-	Message   string    `json:"message"`
-	CreatedAt time.Time `json:"created_at"`
+// outbound is a queued broadcast; events from a user (from != "") skip
+// clients that have blocked them
+type outbound struct {
+	data []byte
+	from string
 }
 
 // WSEvent types for WebSocket communication
 type WSEvent struct {
-	Type string      `json:"type"` // "message", "online_count", "user_joined", "user_left"
+	Type string      `json:"type"` // "message", "online_count", "user_joined", "user_left", "typing", "error", "banned", "presence", "server_closing"
 	Data interface{} `json:"data"`
 }
 
-// AuthRequest for initial WebSocket authentication
-type AuthRequest struct {
-	IDToken  string `json:"id_token"`
-	Email    string `json:"email"`
-	Username string `json:"username"`
-	PhotoURL string `json:"photo_url"`
-}
-
 // Initialize database connection and timezone
 func InitDB(database *sql.DB) error {
 	db = database
@@ -85,11 +102,45 @@ func InitDB(database *sql.DB) error {
 		myanmarLocation = time.FixedZone("Myanmar Time", 6*3600+30*60)
 	}
 
+	insecureAuth = config.Bool("CHAT_INSECURE_AUTH", false)
+	if insecureAuth {
+		log.Println("⚠️  CHAT_INSECURE_AUTH=true: WebSocket chat trusts unverified ID tokens (development only)")
+	}
+
 	// Create tables if they don't exist
 	createTables()
+	if err := loadBlocks(); err != nil {
+		log.Printf("❌ Error loading WebSocket chat blocks: %v", err)
+	}
+
+	// No connections exist yet, so any online flag is left over from a crash or restart
+	if result, err := db.Exec("UPDATE chatws_users SET is_online = FALSE WHERE is_online = TRUE"); err != nil {
+		log.Printf("⚠️ Failed to reset online flags: %v", err)
+	} else if n, _ := result.RowsAffected(); n > 0 {
+		log.Printf("🧹 Marked %d stale WebSocket chat users offline", n)
+	}
+
+	// Earlier releases kept WebSocket messages in their own table
+	if err := migrateMessages(); err != nil {
+		log.Printf("❌ Error moving WebSocket messages to the shared store: %v", err)
+	}
 
-	// Start broadcast goroutine
-	go handleBroadcast()
+	// Register once so calling InitDB again doesn't deliver every event twice
+	relaysOnce.Do(func() {
+		// Relay messages, connections and presence from either transport
+		chatcore.OnMessage(relayMessage)
+		chatcore.OnConnection(relayConnection)
+		chatcore.OnPresence(broadcastPresence)
+
+		// Disconnect WebSocket sessions of users banned through the shared ban logic
+		chatcore.OnBan(disconnectBanned)
+
+		// Warn clients before the server shuts down
+		shutdown.OnClosing(broadcastClosing)
+
+		// Start broadcast goroutine
+		go handleBroadcast()
+	})
 
 	log.Println("✅ WebSocket Chat initialized")
 	return nil
@@ -166,6 +217,14 @@ func HandleWebSocket(c *gin.Context) {
 		return
 	}
 
+	// Verify before upgrading so a bad token gets a plain 401
+	claims, err := verifyToken(c.Request.Context(), idToken)
+	if err != nil {
+		log.Printf("❌ WebSocket token rejected: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired ID token"})
+		return
+	}
+
 	// Upgrade HTTP connection to WebSocket
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
@@ -173,8 +232,13 @@ func HandleWebSocket(c *gin.Context) {
 		return
 	}
 
-	// Authenticate using the ID token from query parameter
-	client, err := authenticateClientWithToken(conn, idToken)
+	// Authenticate using the verified token claims
+	client, err := authenticateClientWithToken(conn, claims)
+	if errors.Is(err, errBanned) {
+		log.Printf("🚫 Rejected WebSocket connection from banned user")
+		rejectBanned(conn)
+		return
+	}
 	if err != nil {
 		log.Printf("❌ WebSocket authentication failed: %v", err)
 		conn.WriteJSON(map[string]string{"error": "Authentication failed"})
@@ -182,6 +246,8 @@ func HandleWebSocket(c *gin.Context) {
 		return
 	}
 
+	client.isAdmin = adminauth.IsAdmin(c)
+
 	// Register client
 	clientsMutex.Lock()
 	clients[client] = true
@@ -191,13 +257,13 @@ func HandleWebSocket(c *gin.Context) {
 
 	// Update user online status
 	updateUserOnlineStatus(client.UserID, true)
+	sessionlog.Record(client.UserID, sessionlog.StatusOnline, "ws")
 
-	// Send initial online users list to the new client FIRST
+	// Register with the shared hub, which tells clients on both transports
+	// that this user joined, then send the online list to the new client
+	chatcore.Connect(chatcore.OnlineUser{UserID: client.UserID, Username: client.Username, PhotoURL: client.PhotoURL})
 	sendOnlineUsersToClient(client)
 
-	// Then notify others that this user joined
-	broadcastUserJoined(client)
-
 	// Start write pump in goroutine
 	go client.writePump()
 
@@ -206,36 +272,59 @@ func HandleWebSocket(c *gin.Context) {
 	client.readPump()
 }
 
-// Authenticate WebSocket client with ID token from query parameter
-func authenticateClientWithToken(conn *websocket.Conn, idToken string) (*WSClient, error) {
-	// LOW SECURITY MODE: Parse token WITHOUT expiration validation
-	// This allows expired tokens to work (user requested: "low security and perfect")
+// verifyToken checks the ID token's signature, audience and expiry against
+// the configured client ID and returns its claims. With CHAT_INSECURE_AUTH
+// the payload is decoded without any verification.
+func verifyToken(ctx context.Context, idToken string) (map[string]interface{}, error) {
+	if insecureAuth {
+		return decodeUnverified(idToken)
+	}
+	if googleClientID == "" {
+		return nil, fmt.Errorf("GOOGLE_OAUTH_CLIENT_ID not configured")
+	}
+
+	payload, err := googleauth.Validate(ctx, idToken, googleClientID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %v", err)
+	}
+	return payload.Claims, nil
+}
+
+// decodeUnverified reads a JWT payload without checking signature or expiry
+func decodeUnverified(idToken string) (map[string]interface{}, error) {
 	parts := strings.Split(idToken, ".")
 	if len(parts) != 3 {
 		return nil, fmt.Errorf("invalid token format")
 	}
-	
+
 	// Decode payload (middle part of JWT)
 	payloadBytes, err := base64.RawURLEncoding.DecodeString(parts[1])
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode token: %v", err)
 	}
-	
+
 	// Parse JSON payload
 	var claims map[string]interface{}
 	if err := json.Unmarshal(payloadBytes, &claims); err != nil {
 		return nil, fmt.Errorf("failed to parse token: %v", err)
 	}
-	
+	return claims, nil
+}
+
+// Authenticate WebSocket client from verified ID token claims
+func authenticateClientWithToken(conn *websocket.Conn, claims map[string]interface{}) (*WSClient, error) {
 	// Extract user info from claims
 	userID, _ := claims["sub"].(string)
 	email, _ := claims["email"].(string)
 	name, _ := claims["name"].(string)
 	picture, _ := claims["picture"].(string)
-	
+
 	if userID == "" {
 		return nil, fmt.Errorf("missing user ID in token")
 	}
+	if chatcore.IsBanned(userID, email) {
+		return nil, errBanned
+	}
 
 	// Use name from token if available
 	username := name
@@ -244,18 +333,8 @@ func authenticateClientWithToken(conn *websocket.Conn, idToken string) (*WSClien
 	}
 
 	// Create or update user in database
-	_, err = db.Exec(`
-		INSERT INTO chatws_users (id, email, username, photo_url, is_online, last_seen)
-		VALUES (?, ?, ?, ?, TRUE, CURRENT_TIMESTAMP)
-		ON CONFLICT(id) DO UPDATE SET
-			username = excluded.username,
-			photo_url = excluded.photo_url,
-			is_online = TRUE,
-			last_seen = CURRENT_TIMESTAMP
-	`, userID, email, username, picture)
-
-	if err != nil {
-		log.Printf("⚠️ Error updating user: %v", err)
+	if err := saveUser(userID, email, username, picture); err != nil {
+		return nil, fmt.Errorf("failed to save user: %v", err)
 	}
 
 	log.Printf("✅ User authenticated: %s (%s)", username, email)
@@ -267,34 +346,16 @@ func authenticateClientWithToken(conn *websocket.Conn, idToken string) (*WSClien
 		PhotoURL: picture,
 		Conn:     conn,
 		Send:     make(chan []byte, 256),
+		done:     make(chan struct{}),
 	}
 
 	return client, nil
 }
 
-// Authenticate WebSocket client (legacy - for JSON-based auth)
-func authenticateClient(conn *websocket.Conn) (*WSClient, error) {
-	// Set read deadline for authentication
-	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
-
-	// Read authentication message
-	var authReq AuthRequest
-	err := conn.ReadJSON(&authReq)
-	if err != nil {
-		return nil, err
-	}
-
-	// Verify Google ID token
-	payload, err := idtoken.Validate(context.Background(), authReq.IDToken, googleClientID)
-	if err != nil {
-		return nil, fmt.Errorf("invalid ID token: %v", err)
-	}
-
-	userID := payload.Subject
-	email := payload.Claims["email"].(string)
-
-	// Create or update user in database
-	_, err = db.Exec(`
+// saveUser upserts the WebSocket profile (referenced by blocks) and the
+// shared chat_users row (referenced by messages)
+func saveUser(userID, email, username, photoURL string) error {
+	_, err := db.Exec(`
 		INSERT INTO chatws_users (id, email, username, photo_url, is_online, last_seen)
 		VALUES (?, ?, ?, ?, TRUE, CURRENT_TIMESTAMP)
 		ON CONFLICT(id) DO UPDATE SET
@@ -302,25 +363,11 @@ func authenticateClient(conn *websocket.Conn) (*WSClient, error) {
 			photo_url = excluded.photo_url,
 			is_online = TRUE,
 			last_seen = CURRENT_TIMESTAMP
-	`, userID, email, authReq.Username, authReq.PhotoURL)
-
+	`, userID, email, username, photoURL)
 	if err != nil {
-		log.Printf("⚠️ Error updating user: %v", err)
-	}
-
-	// Remove read deadline
-	conn.SetReadDeadline(time.Time{})
-
-	// Create client
-	client := &WSClient{
-		UserID:   userID,
-		Username: authReq.Username,
-		PhotoURL: authReq.PhotoURL,
-		Conn:     conn,
-		Send:     make(chan []byte, 256),
+		return err
 	}
-
-	return client, nil
+	return chatcore.EnsureUser(userID, email, username, photoURL)
 }
 
 // Read pump - reads messages from WebSocket
@@ -354,8 +401,13 @@ func (c *WSClient) readPump() {
 		switch msgType {
 		case "message":
 			c.handleChatMessage(msg)
+		case "typing":
+			c.handleTyping()
 		case "ping":
-			c.Send <- []byte(`{"type":"pong"}`)
+			select {
+			case c.Send <- []byte(`{"type":"pong"}`):
+			default:
+			}
 		}
 	}
 }
@@ -370,18 +422,17 @@ func (c *WSClient) writePump() {
 
 	for {
 		select {
-		case message, ok := <-c.Send:
+		case message := <-c.Send:
 			c.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
-			if !ok {
-				c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
-				return
-			}
-
 			err := c.Conn.WriteMessage(websocket.TextMessage, message)
 			if err != nil {
 				return
 			}
 
+		case <-c.done:
+			c.flush()
+			return
+
 		case <-ticker.C:
 			c.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
 			if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
@@ -391,85 +442,293 @@ func (c *WSClient) writePump() {
 	}
 }
 
+// flush writes the events queued before the stop (e.g. the banned event),
+// then a close frame
+func (c *WSClient) flush() {
+	c.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	for {
+		select {
+		case message := <-c.Send:
+			if err := c.Conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				return
+			}
+		default:
+			c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
+			return
+		}
+	}
+}
+
 // Handle incoming chat message
 func (c *WSClient) handleChatMessage(msg map[string]interface{}) {
 	messageText, ok := msg["message"].(string)
-	if !ok || messageText == "" {
+	if !ok {
 		return
 	}
 
-	// Save message to database
-	result, err := db.Exec(`
-		INSERT INTO chatws_messages (user_id, username, photo_url, message, created_at)
-		VALUES (?, ?, ?, ?, ?)
-	`, c.UserID, c.Username, c.PhotoURL, messageText, time.Now().In(myanmarLocation))
-
+	messageText, err := chatcore.ValidateMessage(messageText)
 	if err != nil {
-		log.Printf("❌ Error saving message: %v", err)
+		c.sendError("invalid_message", err.Error(), nil)
 		return
 	}
 
-	messageID, _ := result.LastInsertId()
+	if until, muted := chatcore.MutedUntil(c.UserID); muted {
+		c.sendError("muted", "You are muted", gin.H{"muted_until": until})
+		return
+	}
 
-	// Create message object
-	chatMessage := Message{
-		ID:        messageID,
+	// Throttle per user, shared with the SSE chat
+	if ok, wait := chatcore.AllowMessage(c.UserID); !ok {
+		c.sendError("rate_limited", "You are sending messages too fast", gin.H{
+			"retry_after": ratelimit.RetryAfterSeconds(wait),
+		})
+		return
+	}
+
+	// Banned words are rejected or masked depending on CHAT_BANNED_WORDS_MODE
+	filtered, allowed := wordfilter.Allow(messageText)
+	if !allowed {
+		c.sendError("banned_word", "Message contains banned words", nil)
+		return
+	}
+	messageText = filtered
+
+	// External moderation (CHAT_MODERATION_URL) decides before anything is stored
+	if v := chatcore.Moderate(context.Background(), c.UserID, messageText, "ws"); v.Verdict == chatcore.VerdictBlock {
+		c.sendError("moderated", "Message was blocked by moderation", gin.H{"reason": v.Reason})
+		return
+	}
+
+	// Count against the daily limit before storing
+	if !c.isAdmin {
+		var qe *chatcore.QuotaError
+		if err := chatcore.ConsumeQuota(db, c.UserID, time.Now()); errors.As(err, &qe) {
+			c.sendError("daily_limit", "You have reached your daily message limit", gin.H{
+				"daily_limit": qe.Limit,
+				"reset_at":    qe.ResetAt.In(myanmarLocation),
+				"retry_after": int(time.Until(qe.ResetAt).Seconds()) + 1,
+			})
+			return
+		} else if err != nil {
+			log.Printf("❌ Error checking daily limit for %s: %v", c.UserID, err)
+			c.sendError("send_failed", "Failed to send message", nil)
+			return
+		}
+	}
+
+	// Save to the shared store and deliver to clients on both transports
+	chatMessage, err := chatcore.SaveMessage(chatcore.Message{
 		UserID:    c.UserID,
 		Username:  c.Username,
 		PhotoURL:  c.PhotoURL,
 		Message:   messageText,
 		CreatedAt: time.Now().In(myanmarLocation),
+	})
+	if err != nil {
+		log.Printf("❌ Error saving message: %v", err)
+		c.sendError("send_failed", "Failed to send message", nil)
+		return
 	}
 
-	// Broadcast to all clients
+	chatcore.PublishMessage(chatMessage)
+	chatcore.Touch(c.UserID)
+
+	log.Printf("💬 Message from %s: %s", c.Username, messageText)
+}
+
+// Send an error event to this client only
+func (c *WSClient) sendError(code, message string, extra map[string]interface{}) {
+	data := map[string]interface{}{
+		"code":  code,
+		"error": message,
+	}
+	for k, v := range extra {
+		data[k] = v
+	}
+
+	eventJSON, _ := json.Marshal(WSEvent{Type: "error", Data: data})
+	select {
+	case c.Send <- eventJSON:
+	default:
+	}
+}
+
+// Handle incoming typing indicator (broadcast only, never persisted)
+func (c *WSClient) handleTyping() {
+	chatcore.Touch(c.UserID)
+
+	now := time.Now()
+	if now.Sub(c.lastTyping) < typingDebounce {
+		return
+	}
+	c.lastTyping = now
+
 	event := WSEvent{
-		Type: "message",
-		Data: chatMessage,
+		Type: "typing",
+		Data: map[string]interface{}{
+			"user_id":    c.UserID,
+			"username":   c.Username,
+			"timeout_ms": typingTimeout.Milliseconds(),
+		},
 	}
 
 	eventJSON, _ := json.Marshal(event)
-	broadcast <- eventJSON
+	broadcastToOthers(eventJSON, c)
+}
 
-	log.Printf("💬 Message from %s: %s", c.Username, messageText)
+// stop ends the write pump once it has flushed the queued events. Send is
+// left open, so the read pump and broadcasters can never send on a closed channel.
+func (c *WSClient) stop() {
+	c.stopOnce.Do(func() { close(c.done) })
 }
 
 // Disconnect client
 func (c *WSClient) disconnect() {
 	clientsMutex.Lock()
-	if _, ok := clients[c]; ok {
-		delete(clients, c)
-		close(c.Send)
-	}
+	delete(clients, c)
 	clientsMutex.Unlock()
+	c.stop()
 
 	// Update user online status
 	updateUserOnlineStatus(c.UserID, false)
+	sessionlog.Record(c.UserID, sessionlog.StatusOffline, "ws")
 
-	// Notify others that user left
-	broadcastUserLeft(c)
+	// Notify clients on both transports that the user left
+	chatcore.Disconnect(c.UserID)
 
 	log.Printf("👋 WebSocket client disconnected: %s", c.Username)
 }
 
+// errBanned is returned by authentication when the user is on the ban list
+var errBanned = errors.New("user is banned")
+
+// rejectBanned tells a banned user why the connection is refused and closes it
+func rejectBanned(conn *websocket.Conn) {
+	eventJSON, _ := json.Marshal(WSEvent{
+		Type: "banned",
+		Data: map[string]interface{}{
+			"message": chatcore.BanMessage(),
+		},
+	})
+	conn.WriteMessage(websocket.TextMessage, eventJSON)
+	conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "banned"))
+	conn.Close()
+}
+
+// disconnectBanned sends a banned event with the reason to every connection
+// of userID and closes them. The write pump flushes the event before the
+// close frame; the read pump then ends and runs the usual disconnect.
+func disconnectBanned(userID, reason string) {
+	eventJSON, _ := json.Marshal(WSEvent{
+		Type: "banned",
+		Data: map[string]interface{}{
+			"message": chatcore.BanMessage(),
+			"reason":  reason,
+		},
+	})
+
+	clientsMutex.Lock()
+	count := 0
+	for client := range clients {
+		if client.UserID != userID {
+			continue
+		}
+		select {
+		case client.Send <- eventJSON:
+		default:
+		}
+		delete(clients, client)
+		client.stop()
+		count++
+	}
+	clientsMutex.Unlock()
+
+	if count > 0 {
+		log.Printf("🚫 Disconnected %d WebSocket connection(s) of banned user %s", count, userID)
+	}
+}
+
+// relayMessage delivers a message sent on either transport to WebSocket
+// clients, skipping those that blocked the sender
+func relayMessage(m chatcore.Message) {
+	eventJSON, _ := json.Marshal(WSEvent{
+		Type: "message",
+		Data: m,
+	})
+	broadcast <- outbound{data: eventJSON, from: m.UserID}
+}
+
+// relayConnection announces a connection opening or closing on either transport
+func relayConnection(user chatcore.OnlineUser, connected bool) {
+	if connected {
+		broadcastUserJoined(user)
+	} else {
+		broadcastUserLeft(user)
+	}
+}
+
+// broadcastPresence tells every client that a user became active or away
+func broadcastPresence(userID, state string) {
+	eventJSON, _ := json.Marshal(WSEvent{
+		Type: "presence",
+		Data: map[string]interface{}{
+			"user_id": userID,
+			"state":   state,
+		},
+	})
+	broadcast <- outbound{data: eventJSON}
+}
+
+// broadcastClosing tells every client the server is shutting down
+func broadcastClosing(n shutdown.Notice) {
+	eventJSON, _ := json.Marshal(WSEvent{Type: shutdown.EventType, Data: n})
+	broadcast <- outbound{data: eventJSON}
+}
+
 // Broadcast goroutine
 func handleBroadcast() {
 	for {
 		message := <-broadcast
-		clientsMutex.RLock()
+		// Write lock: slow clients are removed from the map below
+		clientsMutex.Lock()
 		for client := range clients {
+			if message.from != "" && isBlocking(client.UserID, message.from) {
+				continue
+			}
 			select {
-			case client.Send <- message:
+			case client.Send <- message.data:
 			default:
-				close(client.Send)
+				client.stop()
 				delete(clients, client)
 			}
 		}
-		clientsMutex.RUnlock()
+		clientsMutex.Unlock()
+	}
+}
+
+// Send an event to every client except the sender's own connections and
+// clients that have blocked the sender
+func broadcastToOthers(message []byte, sender *WSClient) {
+	_, span := tracing.Start(context.Background(), "chatws.broadcast")
+	defer span.End()
+
+	clientsMutex.RLock()
+	defer clientsMutex.RUnlock()
+
+	for client := range clients {
+		if client.UserID == sender.UserID || isBlocking(client.UserID, sender.UserID) {
+			continue
+		}
+		select {
+		case client.Send <- message:
+		default:
+			// Drop rather than block on a slow client
+		}
 	}
 }
 
 // Broadcast user joined event
-func broadcastUserJoined(client *WSClient) {
+func broadcastUserJoined(client chatcore.OnlineUser) {
 	event := WSEvent{
 		Type: "user_joined",
 		Data: map[string]interface{}{
@@ -480,11 +739,11 @@ func broadcastUserJoined(client *WSClient) {
 	}
 
 	eventJSON, _ := json.Marshal(event)
-	broadcast <- eventJSON
+	broadcast <- outbound{data: eventJSON}
 }
 
 // Broadcast user left event
-func broadcastUserLeft(client *WSClient) {
+func broadcastUserLeft(client chatcore.OnlineUser) {
 	event := WSEvent{
 		Type: "user_left",
 		Data: map[string]interface{}{
@@ -495,38 +754,30 @@ func broadcastUserLeft(client *WSClient) {
 	}
 
 	eventJSON, _ := json.Marshal(event)
-	broadcast <- eventJSON
+	broadcast <- outbound{data: eventJSON}
 }
 
 // Send initial online users list to newly connected client
 func sendOnlineUsersToClient(client *WSClient) {
-	clientsMutex.RLock()
-	
-	// Build list of online users
-	onlineUsers := []map[string]interface{}{}
-	for c := range clients {
-		// Don't include the client themselves in the list
-		if c.UserID != client.UserID {
-			onlineUsers = append(onlineUsers, map[string]interface{}{
-				"user_id":   c.UserID,
-				"username":  c.Username,
-				"photo_url": c.PhotoURL,
-			})
+	// Users on either transport, without the client themselves
+	onlineUsers := []chatcore.OnlineUser{}
+	for _, u := range chatcore.OnlineUsers() {
+		if u.UserID != client.UserID {
+			onlineUsers = append(onlineUsers, u)
 		}
 	}
-	clientsMutex.RUnlock()
-	
+
 	// Send online users list to the new client
 	event := WSEvent{
 		Type: "online",
 		Data: map[string]interface{}{
 			"users": onlineUsers,
-			"count": len(clients),
+			"count": getOnlineCount(),
 		},
 	}
-	
+
 	eventJSON, _ := json.Marshal(event)
-	
+
 	// Send directly to this client only
 	select {
 	case client.Send <- eventJSON:
@@ -547,45 +798,40 @@ func updateUserOnlineStatus(userID string, isOnline bool) {
 	if err != nil {
 		log.Printf("❌ Error updating user status: %v", err)
 	}
+
+	// Keep the shared profile in step
+	db.Exec("UPDATE chat_users SET is_online = ?, last_seen = CURRENT_TIMESTAMP WHERE id = ?", isOnline, userID)
 }
 
-// Get online user count
+// Get online user count across both transports
 func getOnlineCount() int {
+	return chatcore.OnlineCount()
+}
+
+// ClientCount returns the number of connected WebSocket clients
+func ClientCount() int {
 	clientsMutex.RLock()
 	defer clientsMutex.RUnlock()
 	return len(clients)
 }
 
 // HTTP endpoint to get recent messages
+// GetRecentMessagesHandler returns recent messages; with ?user_id= messages
+// from users that viewer blocked are left out
 func GetRecentMessagesHandler(c *gin.Context) {
-	limit := c.DefaultQuery("limit", "50")
-
-	rows, err := db.Query(`
-		SELECT id, user_id, username, photo_url, message, created_at
-		FROM chatws_messages
-		ORDER BY created_at DESC
-		LIMIT ?
-	`, limit)
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
 
+	messages, err := chatcore.RecentMessages(limit, blockedBy(c.Query("user_id")))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
 		return
 	}
-	defer rows.Close()
-
-	messages := []Message{}
-	for rows.Next() {
-		var msg Message
-		err := rows.Scan(&msg.ID, &msg.UserID, &msg.Username, &msg.PhotoURL, &msg.Message, &msg.CreatedAt)
-		if err != nil {
-			continue
-		}
-		messages = append(messages, msg)
-	}
-
-	// Reverse to chronological order
-	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
-		messages[i], messages[j] = messages[j], messages[i]
+	for i := range messages {
+		messages[i].CreatedAt = messages[i].CreatedAt.In(myanmarLocation)
 	}
 
 	// Return wrapped in object for Android app compatibility
@@ -600,3 +846,61 @@ func GetOnlineCountHandler(c *gin.Context) {
 		"count": getOnlineCount(),
 	})
 }
+
+// migrateMessages moves messages from the old chatws_messages table into the
+// shared store, creating the chat_users rows they need. Safe to run on
+// every startup; migrated rows are removed from the old table.
+func migrateMessages() error {
+	var pending int
+	if err := db.QueryRow("SELECT COUNT(*) FROM chatws_messages").Scan(&pending); err != nil || pending == 0 {
+		return err
+	}
+
+	rows, err := db.Query(`
+		SELECT id, email, username, COALESCE(photo_url, '') FROM chatws_users
+		WHERE id IN (SELECT DISTINCT user_id FROM chatws_messages)
+	`)
+	if err != nil {
+		return err
+	}
+	var users [][4]string
+	for rows.Next() {
+		var u [4]string
+		if err := rows.Scan(&u[0], &u[1], &u[2], &u[3]); err == nil {
+			users = append(users, u)
+		}
+	}
+	rows.Close()
+
+	for _, u := range users {
+		if err := chatcore.EnsureUser(u[0], u[1], u[2], u[3]); err != nil {
+			return fmt.Errorf("user %s: %w", u[0], err)
+		}
+	}
+	db.Exec("UPDATE chat_users SET is_online = 0 WHERE id IN (SELECT id FROM chatws_users)")
+
+	var moved int64
+	err = dbutil.WithTx(db, func(tx *sql.Tx) error {
+		// Stored in local time here; chat_messages holds UTC like CURRENT_TIMESTAMP
+		result, err := tx.Exec(`
+			INSERT INTO chat_messages (user_id, username, photo_url, message, created_at)
+			SELECT user_id, username, photo_url, message, COALESCE(datetime(created_at), created_at)
+			FROM chatws_messages
+			WHERE user_id IN (SELECT id FROM chat_users)
+			ORDER BY id
+		`)
+		if err != nil {
+			return err
+		}
+		moved, _ = result.RowsAffected()
+
+		_, err = tx.Exec("DELETE FROM chatws_messages WHERE user_id IN (SELECT id FROM chat_users)")
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("✅ Moved %d WebSocket messages to the shared message store", moved)
+	return nil
+}
@@ -0,0 +1,219 @@
+package chatws
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"burma2d/chatcore"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// setupTestDB points the package at a fresh in-memory database with the
+// shared chat tables (normally created by the chat package) and the
+// WebSocket tables
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	database.SetMaxOpenConns(1)
+	t.Cleanup(func() { database.Close() })
+
+	_, err = database.Exec(`
+		CREATE TABLE chat_users (
+			id TEXT PRIMARY KEY, email TEXT UNIQUE NOT NULL, username TEXT NOT NULL, photo_url TEXT,
+			last_seen DATETIME DEFAULT CURRENT_TIMESTAMP, is_online BOOLEAN DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE chat_messages (
+			id INTEGER PRIMARY KEY AUTOINCREMENT, user_id TEXT NOT NULL, username TEXT NOT NULL,
+			photo_url TEXT, message TEXT NOT NULL, created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			deleted_at DATETIME, deleted_reason TEXT
+		);
+		CREATE TABLE chat_banned_users (user_id TEXT PRIMARY KEY, username TEXT, banned_by TEXT, reason TEXT, created_at DATETIME DEFAULT CURRENT_TIMESTAMP);
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db = database
+	chatcore.InitDB(database)
+	createTables()
+}
+
+func TestInitDBMarksEveryoneOffline(t *testing.T) {
+	setupTestDB(t)
+	if _, err := db.Exec(`
+		INSERT INTO chatws_users (id, email, username, is_online) VALUES
+			('alice', 'alice@example.com', 'alice', TRUE),
+			('bob', 'bob@example.com', 'bob', TRUE)
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := InitDB(db); err != nil {
+		t.Fatal(err)
+	}
+
+	var online int
+	if err := db.QueryRow("SELECT COUNT(*) FROM chatws_users WHERE is_online = TRUE").Scan(&online); err != nil {
+		t.Fatal(err)
+	}
+	if online != 0 {
+		t.Fatalf("%d users still online after init", online)
+	}
+}
+
+var hubOnce sync.Once
+
+// startServer initializes the package on a fresh database and serves the
+// WebSocket endpoint, trusting unsigned test tokens
+func startServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	setupTestDB(t)
+	hubOnce.Do(chatcore.Init)
+	if err := InitDB(db); err != nil {
+		t.Fatal(err)
+	}
+	insecureAuth = true
+	t.Cleanup(func() { insecureAuth = false })
+
+	// Handlers keep using the database until their disconnect has run, so
+	// wait for them before the next test replaces it. Connections from dial
+	// are closed first, as their cleanups are registered later.
+	var handlers sync.WaitGroup
+	t.Cleanup(handlers.Wait)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/ws", func(c *gin.Context) {
+		handlers.Add(1)
+		defer handlers.Done()
+		HandleWebSocket(c)
+	})
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// testToken builds an unsigned ID token for userID
+func testToken(userID string) string {
+	claims, _ := json.Marshal(map[string]string{
+		"sub":   userID,
+		"email": userID + "@example.com",
+		"name":  userID,
+	})
+	return "header." + base64.RawURLEncoding.EncodeToString(claims) + ".signature"
+}
+
+// dial connects userID to srv and waits until the hub has registered them
+func dial(t *testing.T, srv *httptest.Server, userID string) *websocket.Conn {
+	t.Helper()
+	u := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws?idtoken=" + url.QueryEscape(testToken(userID))
+	conn, _, err := websocket.DefaultDialer.Dial(u, nil)
+	if err != nil {
+		t.Fatalf("dial %s: %v", userID, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	readEvent(t, conn, "online")
+	return conn
+}
+
+// readEvent reads until an event of type typ arrives, failing after 2s
+func readEvent(t *testing.T, conn *websocket.Conn, typ string) map[string]interface{} {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	defer conn.SetReadDeadline(time.Time{})
+	for {
+		var event struct {
+			Type string                 `json:"type"`
+			Data map[string]interface{} `json:"data"`
+		}
+		if err := conn.ReadJSON(&event); err != nil {
+			t.Fatalf("waiting for %q: %v", typ, err)
+		}
+		if event.Type == typ {
+			return event.Data
+		}
+	}
+}
+
+func TestBannedUserIsDisconnectedWithReason(t *testing.T) {
+	srv := startServer(t)
+	conn := dial(t, srv, "alice")
+
+	chatcore.NotifyBanned("alice", "spamming")
+
+	data := readEvent(t, conn, "banned")
+	if data["reason"] != "spamming" || data["message"] != chatcore.BanMessage() {
+		t.Errorf("banned event = %v", data)
+	}
+
+	// The server closes the connection after the notice
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseNoStatusReceived) {
+				t.Errorf("connection ended with %v, want a close", err)
+			}
+			break
+		}
+	}
+}
+
+func TestBanStopsClientWithoutClosingSend(t *testing.T) {
+	client := &WSClient{UserID: "mallory", Send: make(chan []byte, 4), done: make(chan struct{})}
+	clientsMutex.Lock()
+	clients[client] = true
+	clientsMutex.Unlock()
+
+	disconnectBanned("mallory", "spamming")
+
+	// The read pump may still be running and queue a reply; that must not panic
+	client.sendError("rate_limited", "too fast", nil)
+	select {
+	case <-client.done:
+	default:
+		t.Fatal("client not stopped")
+	}
+	clientsMutex.RLock()
+	_, registered := clients[client]
+	clientsMutex.RUnlock()
+	if registered {
+		t.Error("banned client still registered")
+	}
+	if first := string(<-client.Send); !strings.Contains(first, `"banned"`) {
+		t.Errorf("first queued event = %s, want the banned notice", first)
+	}
+
+	// Stopping again, e.g. from the read pump's disconnect, is harmless
+	client.stop()
+}
+
+func TestBannedUserCannotReconnect(t *testing.T) {
+	srv := startServer(t)
+	chatcore.SetBanChecker(func(userID string) bool { return userID == "alice" })
+	t.Cleanup(func() { chatcore.SetBanChecker(nil) })
+
+	u := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws?idtoken=" + url.QueryEscape(testToken("alice"))
+	conn, _, err := websocket.DefaultDialer.Dial(u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if data := readEvent(t, conn, "banned"); data["message"] != chatcore.BanMessage() {
+		t.Errorf("banned event = %v", data)
+	}
+}
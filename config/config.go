@@ -0,0 +1,108 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redacted replaces secret values in Snapshot
+const redacted = "[REDACTED]"
+
+var (
+	effectiveMu sync.RWMutex
+	effective   = make(map[string]string) // key -> value in use, as looked up
+)
+
+// record remembers the effective value of a setting for Snapshot
+func record(key, value string) {
+	effectiveMu.Lock()
+	effective[key] = value
+	effectiveMu.Unlock()
+}
+
+// Snapshot returns every setting looked up so far with its effective value.
+// Secrets are redacted.
+func Snapshot() map[string]string {
+	effectiveMu.RLock()
+	defer effectiveMu.RUnlock()
+
+	out := make(map[string]string, len(effective))
+	for k, v := range effective {
+		out[k] = v
+	}
+	return out
+}
+
+// Secret returns the environment variable like String, but is redacted in Snapshot
+func Secret(key, def string) string {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		v = def
+	}
+	if v == "" {
+		record(key, "")
+	} else {
+		record(key, redacted)
+	}
+	return v
+}
+
+// String returns the environment variable or def when it is unset
+func String(key, def string) string {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		v = def
+	}
+	record(key, v)
+	return v
+}
+
+// Int returns the environment variable parsed as an int, or def when unset/invalid
+func Int(key string, def int) int {
+	n := def
+	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil {
+			log.Printf("⚠️ Invalid %s=%q, using default %d", key, v, def)
+		} else {
+			n = parsed
+		}
+	}
+	record(key, strconv.Itoa(n))
+	return n
+}
+
+// Bool returns the environment variable parsed as a bool, or def when unset/invalid
+func Bool(key string, def bool) bool {
+	b := def
+	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			log.Printf("⚠️ Invalid %s=%q, using default %t", key, v, def)
+		} else {
+			b = parsed
+		}
+	}
+	record(key, strconv.FormatBool(b))
+	return b
+}
+
+// Duration returns the environment variable parsed as a duration (e.g. "30s", "5m"),
+// or def when unset/invalid
+func Duration(key string, def time.Duration) time.Duration {
+	d := def
+	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			log.Printf("⚠️ Invalid %s=%q, using default %s", key, v, def)
+		} else {
+			d = parsed
+		}
+	}
+	record(key, d.String())
+	return d
+}
@@ -0,0 +1,94 @@
+package dbutil
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec("CREATE TABLE items (name TEXT NOT NULL)"); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func countItems(t *testing.T, db *sql.DB) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM items").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func insertItem(tx *sql.Tx) error {
+	_, err := tx.Exec("INSERT INTO items (name) VALUES ('a')")
+	return err
+}
+
+func TestWithTxCommits(t *testing.T) {
+	db := setupTestDB(t)
+	err := WithTx(db, func(tx *sql.Tx) error {
+		if err := insertItem(tx); err != nil {
+			return err
+		}
+		return insertItem(tx)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := countItems(t, db); n != 2 {
+		t.Errorf("%d rows, want both inserts committed", n)
+	}
+}
+
+func TestWithTxRollsBackOnError(t *testing.T) {
+	db := setupTestDB(t)
+	failed := errors.New("second step failed")
+	err := WithTx(db, func(tx *sql.Tx) error {
+		if err := insertItem(tx); err != nil {
+			return err
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("err = %v, want fn's error returned as is", err)
+	}
+	if n := countItems(t, db); n != 0 {
+		t.Errorf("%d rows, want the insert rolled back", n)
+	}
+}
+
+func TestWithTxRollsBackAndRepanics(t *testing.T) {
+	db := setupTestDB(t)
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Errorf("recovered %v, want the original panic", p)
+			}
+		}()
+		WithTx(db, func(tx *sql.Tx) error {
+			insertItem(tx)
+			panic("boom")
+		})
+		t.Error("WithTx returned instead of panicking")
+	}()
+
+	if n := countItems(t, db); n != 0 {
+		t.Errorf("%d rows, want the insert rolled back", n)
+	}
+	// The connection was released, so the database is still usable
+	if err := WithTx(db, insertItem); err != nil || countItems(t, db) != 1 {
+		t.Errorf("after the panic: %v", err)
+	}
+}
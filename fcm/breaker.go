@@ -0,0 +1,105 @@
+package fcm
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned when sends are short-circuited during an outage
+var ErrCircuitOpen = errors.New("FCM circuit breaker open, skipping send")
+
+// Breaker states
+const (
+	stateClosed   = "closed"
+	stateOpen     = "open"
+	stateHalfOpen = "half-open"
+)
+
+// breaker stops calling FCM after repeated failures. After threshold
+// consecutive failures it opens for cooldown, then lets a single probe
+// through (half-open); the probe's result closes or re-opens it.
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	state     string
+	failures  int
+	openedAt  time.Time
+	probing   bool
+	now       func() time.Time
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		state:     stateClosed,
+		now:       time.Now,
+	}
+}
+
+// allow reports whether a send may proceed
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case stateOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = stateHalfOpen
+		b.probing = true
+		log.Println("ℹ️  FCM circuit breaker half-open, probing")
+		return true
+	case stateHalfOpen:
+		// Only one probe at a time
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	return true
+}
+
+// success records a successful send and closes the breaker
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != stateClosed {
+		log.Println("✅ FCM circuit breaker closed, sends resumed")
+	}
+	b.state = stateClosed
+	b.failures = 0
+	b.probing = false
+}
+
+// failure records a failed send, opening the breaker when needed
+func (b *breaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+
+	if b.state == stateHalfOpen || (b.state == stateClosed && b.failures >= b.threshold) {
+		b.state = stateOpen
+		b.openedAt = b.now()
+		log.Printf("⚠️ FCM circuit breaker open after %d consecutive failures, pausing sends for %s",
+			b.failures, b.cooldown)
+	}
+}
+
+// status returns the current state for diagnostics
+func (b *breaker) status() (string, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state, b.failures
+}
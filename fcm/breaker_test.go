@@ -0,0 +1,99 @@
+package fcm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	// Each step advances the clock, applies op and checks the resulting state
+	type step struct {
+		advance time.Duration
+		op      string // "allow", "success" or "failure"
+		allowed bool   // for "allow"
+		state   string
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"opens after the threshold", []step{
+			{0, "failure", false, stateClosed},
+			{0, "failure", false, stateClosed},
+			{0, "allow", true, stateClosed},
+			{0, "failure", false, stateOpen},
+		}},
+		{"success resets the failure count", []step{
+			{0, "failure", false, stateClosed},
+			{0, "failure", false, stateClosed},
+			{0, "success", false, stateClosed},
+			{0, "failure", false, stateClosed},
+			{0, "failure", false, stateClosed},
+		}},
+		{"refuses sends while open", []step{
+			{0, "failure", false, stateClosed},
+			{0, "failure", false, stateClosed},
+			{0, "failure", false, stateOpen},
+			{0, "allow", false, stateOpen},
+			{59 * time.Second, "allow", false, stateOpen},
+		}},
+		{"half-open after the cooldown lets one probe through", []step{
+			{0, "failure", false, stateClosed},
+			{0, "failure", false, stateClosed},
+			{0, "failure", false, stateOpen},
+			{time.Minute, "allow", true, stateHalfOpen},
+			{0, "allow", false, stateHalfOpen},
+		}},
+		{"successful probe closes", []step{
+			{0, "failure", false, stateClosed},
+			{0, "failure", false, stateClosed},
+			{0, "failure", false, stateOpen},
+			{time.Minute, "allow", true, stateHalfOpen},
+			{0, "success", false, stateClosed},
+			{0, "allow", true, stateClosed},
+			{0, "allow", true, stateClosed},
+		}},
+		{"failed probe reopens for another cooldown", []step{
+			{0, "failure", false, stateClosed},
+			{0, "failure", false, stateClosed},
+			{0, "failure", false, stateOpen},
+			{time.Minute, "allow", true, stateHalfOpen},
+			{0, "failure", false, stateOpen},
+			{59 * time.Second, "allow", false, stateOpen},
+			{time.Second, "allow", true, stateHalfOpen},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+			b := newBreaker(3, time.Minute)
+			b.now = func() time.Time { return now }
+
+			for i, s := range tt.steps {
+				now = now.Add(s.advance)
+				switch s.op {
+				case "allow":
+					if got := b.allow(); got != s.allowed {
+						t.Fatalf("step %d: allow() = %v, want %v", i, got, s.allowed)
+					}
+				case "success":
+					b.success()
+				case "failure":
+					b.failure()
+				}
+				if state, _ := b.status(); state != s.state {
+					t.Fatalf("step %d (%s): state %s, want %s", i, s.op, state, s.state)
+				}
+			}
+		})
+	}
+}
+
+func TestNewBreakerMinimumThreshold(t *testing.T) {
+	b := newBreaker(0, time.Minute)
+	b.failure()
+	if state, failures := b.status(); state != stateOpen || failures != 1 {
+		t.Errorf("status = %s, %d; want open after a single failure", state, failures)
+	}
+}
@@ -0,0 +1,158 @@
+package fcm
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strings"
+
+	"burma2d/config"
+	"burma2d/jsonutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+var db *sql.DB
+
+// Welcome push settings, read in InitDB
+var (
+	welcomeEnabled bool
+	welcomeTitle   string
+	welcomeBody    string
+)
+
+// maxTokenLength bounds device tokens accepted at registration
+const maxTokenLength = 4096
+
+// InitDB creates the device token and notification log tables and loads the welcome push settings.
+// The welcome push is opt-in via FCM_WELCOME_ENABLED (default false).
+func InitDB(database *sql.DB) {
+	db = database
+	welcomeEnabled = config.Bool("FCM_WELCOME_ENABLED", false)
+	welcomeTitle = config.String("FCM_WELCOME_TITLE", "Welcome to Burma 2D 🎉")
+	welcomeBody = config.String("FCM_WELCOME_BODY", "Notifications are on. We'll let you know when new gifts are available.")
+
+	createHistoryTable()
+
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS fcm_devices (
+			token TEXT PRIMARY KEY,
+			user_id TEXT,
+			platform TEXT,
+			welcomed_at DATETIME,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_fcm_devices_user ON fcm_devices(user_id);
+	`)
+	if err != nil {
+		log.Printf("❌ Error creating fcm_devices table: %v", err)
+		return
+	}
+
+	log.Println("✅ FCM device table initialized")
+}
+
+// RegisterDeviceRequest is the body for registering a device token
+type RegisterDeviceRequest struct {
+	Token    string `json:"token" binding:"required"`
+	UserID   string `json:"user_id"`
+	Platform string `json:"platform"`
+}
+
+// RegisterDeviceHandler stores a device token and sends the welcome push
+// the first time the token is seen, when enabled
+func RegisterDeviceHandler(c *gin.Context) {
+	var req RegisterDeviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		jsonutil.BindError(c, err)
+		return
+	}
+
+	req.Token = strings.TrimSpace(req.Token)
+	if req.Token == "" || len(req.Token) > maxTokenLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token"})
+		return
+	}
+
+	created, err := registerDevice(req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register device"})
+		return
+	}
+
+	welcome := false
+	if welcomeEnabled && claimWelcome(req.Token) {
+		welcome = true
+		go sendWelcome(req.Token)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":        true,
+		"new_device":     created,
+		"welcome_queued": welcome,
+	})
+}
+
+// registerDevice upserts a token and reports whether it was new
+func registerDevice(req RegisterDeviceRequest) (bool, error) {
+	result, err := db.Exec(`
+		INSERT OR IGNORE INTO fcm_devices (token, user_id, platform) VALUES (?, ?, ?)
+	`, req.Token, req.UserID, req.Platform)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		return true, nil
+	}
+
+	// Known token: refresh owner and platform when given
+	_, err = db.Exec(`
+		UPDATE fcm_devices SET
+			user_id = COALESCE(NULLIF(?, ''), user_id),
+			platform = COALESCE(NULLIF(?, ''), platform),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE token = ?
+	`, req.UserID, req.Platform, req.Token)
+	return false, err
+}
+
+// claimWelcome marks the token as welcomed; only the first caller wins,
+// so concurrent or repeated registrations send at most one welcome
+func claimWelcome(token string) bool {
+	result, err := db.Exec(`
+		UPDATE fcm_devices SET welcomed_at = CURRENT_TIMESTAMP
+		WHERE token = ? AND welcomed_at IS NULL
+	`, token)
+	if err != nil {
+		log.Printf("⚠️ Failed to claim welcome push: %v", err)
+		return false
+	}
+	n, _ := result.RowsAffected()
+	return n > 0
+}
+
+// sendWelcome delivers the welcome push, releasing the claim on failure
+// so a later registration can retry
+func sendWelcome(token string) {
+	if _, err := SendNotificationToToken(token, welcomeTitle, welcomeBody); err != nil {
+		log.Printf("⚠️ Welcome push failed, will retry on next registration: %v", err)
+		if _, err := db.Exec("UPDATE fcm_devices SET welcomed_at = NULL WHERE token = ?", token); err != nil {
+			log.Printf("⚠️ Failed to release welcome claim: %v", err)
+		}
+	}
+}
+
+// removeDevice forgets a token FCM no longer accepts and reports whether it was stored
+func removeDevice(token string) bool {
+	if db == nil {
+		return false
+	}
+	result, err := db.Exec("DELETE FROM fcm_devices WHERE token = ?", token)
+	if err != nil {
+		log.Printf("⚠️ Failed to remove invalid device token: %v", err)
+		return false
+	}
+	n, _ := result.RowsAffected()
+	return n > 0
+}
@@ -0,0 +1,162 @@
+package fcm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"firebase.google.com/go/v4/messaging"
+	"github.com/gin-gonic/gin"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	database.SetMaxOpenConns(1)
+	t.Cleanup(func() { database.Close() })
+	InitDB(database)
+}
+
+// fakeSender replaces sendFunc and collects every message sent
+type fakeSender struct {
+	mu   sync.Mutex
+	sent []*messaging.Message
+	err  error
+}
+
+func useFakeSender(t *testing.T) *fakeSender {
+	t.Helper()
+	f := &fakeSender{}
+	oldSend, oldCircuit := sendFunc, circuit
+	sendFunc = f.send
+	circuit = newBreaker(100, time.Minute)
+	t.Cleanup(func() { sendFunc, circuit = oldSend, oldCircuit })
+	return f
+}
+
+func (f *fakeSender) send(_ context.Context, m *messaging.Message) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, m)
+	if f.err != nil {
+		return "", f.err
+	}
+	return "projects/test/messages/1", nil
+}
+
+func (f *fakeSender) messages() []*messaging.Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*messaging.Message(nil), f.sent...)
+}
+
+// waitForLogged waits until n sends are in the notification log, which also
+// means every background write has finished
+func waitForLogged(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		var got int
+		db.QueryRow("SELECT COUNT(*) FROM fcm_notifications").Scan(&got)
+		if got >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d notifications logged, want %d", got, n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func registerDeviceRequest(t *testing.T, body string) int {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/devices", RegisterDeviceHandler)
+
+	req := httptest.NewRequest(http.MethodPost, "/devices", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestRegisterDeviceWelcomesOnce(t *testing.T) {
+	t.Setenv("FCM_WELCOME_ENABLED", "true")
+	setupTestDB(t)
+	sender := useFakeSender(t)
+
+	if code := registerDeviceRequest(t, `{"token":"device-1","user_id":"alice"}`); code != http.StatusOK {
+		t.Fatalf("first registration: status %d", code)
+	}
+	waitForLogged(t, 1)
+
+	if code := registerDeviceRequest(t, `{"token":"device-1","user_id":"alice"}`); code != http.StatusOK {
+		t.Fatalf("repeat registration: status %d", code)
+	}
+	// Give a wrongly queued second welcome the chance to show up
+	time.Sleep(50 * time.Millisecond)
+
+	sent := sender.messages()
+	if len(sent) != 1 {
+		t.Fatalf("%d pushes sent, want 1", len(sent))
+	}
+	if sent[0].Token != "device-1" || sent[0].Notification.Title != welcomeTitle {
+		t.Errorf("welcome = token %q title %q", sent[0].Token, sent[0].Notification.Title)
+	}
+}
+
+func TestRegisterDeviceRetriesFailedWelcome(t *testing.T) {
+	t.Setenv("FCM_WELCOME_ENABLED", "true")
+	setupTestDB(t)
+	sender := useFakeSender(t)
+	sender.err = errors.New("unavailable")
+
+	registerDeviceRequest(t, `{"token":"device-1"}`)
+	waitForLogged(t, 1)
+	// The failed send releases the claim just after it is logged
+	deadline := time.Now().Add(time.Second)
+	for {
+		var welcomed sql.NullTime
+		db.QueryRow("SELECT welcomed_at FROM fcm_devices WHERE token = 'device-1'").Scan(&welcomed)
+		if !welcomed.Valid {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("failed welcome was never released")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	sender.mu.Lock()
+	sender.err = nil
+	sender.mu.Unlock()
+	registerDeviceRequest(t, `{"token":"device-1"}`)
+	waitForLogged(t, 2)
+
+	if n := len(sender.messages()); n != 2 {
+		t.Errorf("%d pushes sent, want a retry after the failure", n)
+	}
+}
+
+func TestRegisterDeviceWelcomeDisabled(t *testing.T) {
+	setupTestDB(t)
+	sender := useFakeSender(t)
+
+	registerDeviceRequest(t, `{"token":"device-1"}`)
+	time.Sleep(50 * time.Millisecond)
+
+	if n := len(sender.messages()); n != 0 {
+		t.Errorf("%d pushes sent with the welcome disabled", n)
+	}
+}
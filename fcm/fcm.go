@@ -2,20 +2,46 @@ package fcm
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"time"
+
+	"burma2d/config"
+	"burma2d/tracing"
 
 	firebase "firebase.google.com/go/v4"
 	"firebase.google.com/go/v4/messaging"
+	"go.opentelemetry.io/otel/attribute"
 	"google.golang.org/api/option"
 )
 
 var (
 	fcmClient *messaging.Client
+
+	// sendFunc hands a message to FCM; set by InitFCM, replaced in tests
+	sendFunc func(ctx context.Context, message *messaging.Message) (string, error)
+
+	// sendTimeout bounds a single FCM request so outages can't pile up goroutines
+	sendTimeout = 10 * time.Second
+
+	circuit = newBreaker(5, time.Minute)
 )
 
+// ErrInvalidToken is returned when FCM rejects a device token as unregistered
+// or malformed; callers should stop sending to it
+var ErrInvalidToken = errors.New("FCM device token invalid or unregistered")
+
 // InitFCM initializes Firebase Cloud Messaging
 func InitFCM(serviceAccountPath string) error {
+	sendTimeout = config.Duration("FCM_SEND_TIMEOUT", 10*time.Second)
+	circuit = newBreaker(
+		config.Int("FCM_BREAKER_THRESHOLD", 5),
+		config.Duration("FCM_BREAKER_COOLDOWN", time.Minute),
+	)
+	loadResultConfig()
+	loadGiftTopicConfig()
+
 	opt := option.WithCredentialsFile(serviceAccountPath)
 	app, err := firebase.NewApp(context.Background(), nil, opt)
 	if err != nil {
@@ -26,6 +52,7 @@ func InitFCM(serviceAccountPath string) error {
 	if err != nil {
 		return fmt.Errorf("error getting messaging client: %v", err)
 	}
+	sendFunc = fcmClient.Send
 
 	log.Println("✅ Firebase Cloud Messaging initialized")
 	return nil
@@ -33,11 +60,23 @@ func InitFCM(serviceAccountPath string) error {
 
 // SendNotificationToTopic sends a notification to all devices subscribed to a topic
 func SendNotificationToTopic(topic, title, body string) error {
-	if fcmClient == nil {
-		return fmt.Errorf("FCM client not initialized")
-	}
+	message := newMessage(title, body)
+	message.Topic = topic
+	_, err := send(message)
+	return err
+}
+
+// SendNotificationToToken sends a notification to a single device token and
+// returns the message ID. Dead or malformed tokens return ErrInvalidToken.
+func SendNotificationToToken(token, title, body string) (string, error) {
+	message := newMessage(title, body)
+	message.Token = token
+	return send(message)
+}
 
-	message := &messaging.Message{
+// newMessage builds a notification with the shared Android config
+func newMessage(title, body string) *messaging.Message {
+	return &messaging.Message{
 		Notification: &messaging.Notification{
 			Title: title,
 			Body:  body,
@@ -55,30 +94,93 @@ func SendNotificationToTopic(topic, title, body string) error {
 				Tag:          "gift_update",
 			},
 		},
-		Topic: topic,
+	}
+}
+
+// send delivers a message through the circuit breaker and returns its ID
+func send(message *messaging.Message) (response string, err error) {
+	_, span := tracing.Start(context.Background(), "fcm.send",
+		attribute.String("fcm.topic", message.Topic))
+	defer func() {
+		tracing.End(span, err)
+		recordSend(message, response, err)
+	}()
+
+	if sendFunc == nil {
+		return "", fmt.Errorf("FCM client not initialized")
 	}
 
-	response, err := fcmClient.Send(context.Background(), message)
+	if !circuit.allow() {
+		return "", ErrCircuitOpen
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+
+	response, err = sendFunc(ctx, message)
+	if err != nil && isInvalidToken(err) {
+		// FCM answered; a dead token says nothing about its health
+		circuit.success()
+		log.Printf("⚠️ FCM rejected device token: %v", err)
+		return "", fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
 	if err != nil {
+		circuit.failure()
 		log.Printf("❌ Error sending FCM notification: %v", err)
-		return err
+		return "", err
 	}
+	circuit.success()
 
 	log.Printf("✅ FCM notification sent successfully: %s", response)
-	return nil
+	return response, nil
 }
 
-// SendGiftAvailableNotification sends notification when a gift is updated
-func SendGiftAvailableNotification(giftName string) error {
+// isInvalidToken reports whether err means the target token is unusable
+func isInvalidToken(err error) bool {
+	return messaging.IsUnregistered(err) || messaging.IsInvalidArgument(err) || messaging.IsSenderIDMismatch(err)
+}
+
+// SendGiftAvailableNotification sends notification when a gift is updated.
+// It goes to the gift type's topic (e.g. "gifts_cash") and, unless
+// FCM_GIFTS_GLOBAL_TOPIC=false, to the "gifts" topic as well.
+func SendGiftAvailableNotification(giftName, giftType string) error {
 	title := giftName
 	body := "Available 🎁"
 
-	// Send to "gifts" topic - all users should subscribe to this topic
-	return SendNotificationToTopic("gifts", title, body)
+	var errs []error
+	for _, topic := range giftTopics(giftType) {
+		if err := SendNotificationToTopic(topic, title, body); err != nil {
+			errs = append(errs, fmt.Errorf("topic %s: %w", topic, err))
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // SendCustomNotification sends a custom notification to gifts topic
 func SendCustomNotification(title, body string) error {
 	// Send to "gifts" topic
-	return SendNotificationToTopic("gifts", title, body)
+	return SendNotificationToTopic(GiftsTopic, title, body)
+}
+
+// IsInitialized reports whether the FCM client is ready
+func IsInitialized() bool {
+	return fcmClient != nil
+}
+
+// CircuitStatus returns the breaker state and consecutive failure count
+func CircuitStatus() (string, int) {
+	return circuit.status()
+}
+
+// Ping validates credentials and reachability with a dry-run send to the
+// results topic; nothing is delivered to devices
+func Ping(ctx context.Context) error {
+	if fcmClient == nil {
+		return fmt.Errorf("FCM client not initialized")
+	}
+	_, err := fcmClient.SendDryRun(ctx, &messaging.Message{
+		Topic: ResultsTopic(),
+		Data:  map[string]string{"type": "selftest"},
+	})
+	return err
 }
@@ -0,0 +1,119 @@
+package fcm
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"burma2d/config"
+	"burma2d/jsonutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GiftsTopic receives every gift notification unless FCM_GIFTS_GLOBAL_TOPIC=false
+const GiftsTopic = "gifts"
+
+// maxGiftTypesPerRequest bounds the types one device subscribes to at once
+const maxGiftTypesPerRequest = 20
+
+// giftsGlobalTopic sends type notifications to GiftsTopic as well (FCM_GIFTS_GLOBAL_TOPIC)
+var giftsGlobalTopic = true
+
+// topicUnsafeChars are replaced when a gift type becomes part of a topic name
+var topicUnsafeChars = regexp.MustCompile(`[^a-z0-9\-_.~]+`)
+
+func loadGiftTopicConfig() {
+	giftsGlobalTopic = config.Bool("FCM_GIFTS_GLOBAL_TOPIC", true)
+}
+
+// GiftTypeTopic returns the topic for one gift type, e.g. "cash" -> "gifts_cash".
+// It returns an empty string for a type with no usable characters.
+func GiftTypeTopic(giftType string) string {
+	name := topicUnsafeChars.ReplaceAllString(strings.ToLower(strings.TrimSpace(giftType)), "_")
+	name = strings.Trim(name, "_")
+	if name == "" {
+		return ""
+	}
+	return GiftsTopic + "_" + name
+}
+
+// giftTopics returns the topics a notification for giftType goes to
+func giftTopics(giftType string) []string {
+	var topics []string
+	if topic := GiftTypeTopic(giftType); topic != "" {
+		topics = append(topics, topic)
+	}
+	if giftsGlobalTopic || len(topics) == 0 {
+		topics = append(topics, GiftsTopic)
+	}
+	return topics
+}
+
+// GiftTopicsRequest subscribes or unsubscribes one device to gift types
+type GiftTopicsRequest struct {
+	Token string   `json:"token" binding:"required"`
+	Types []string `json:"types" binding:"required"`
+}
+
+// SubscribeGiftTypesHandler subscribes a device to notifications for the given gift types
+func SubscribeGiftTypesHandler(c *gin.Context) {
+	giftTopicsHandler(c, true)
+}
+
+// UnsubscribeGiftTypesHandler unsubscribes a device from the given gift types
+func UnsubscribeGiftTypesHandler(c *gin.Context) {
+	giftTopicsHandler(c, false)
+}
+
+func giftTopicsHandler(c *gin.Context, subscribe bool) {
+	var req GiftTopicsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		jsonutil.BindError(c, err)
+		return
+	}
+
+	req.Token = strings.TrimSpace(req.Token)
+	if req.Token == "" || len(req.Token) > maxTokenLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token"})
+		return
+	}
+	if len(req.Types) == 0 || len(req.Types) > maxGiftTypesPerRequest {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("types must contain 1 to %d items", maxGiftTypesPerRequest)})
+		return
+	}
+
+	topics := make([]string, 0, len(req.Types))
+	for _, t := range req.Types {
+		topic := GiftTypeTopic(t)
+		if topic == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid gift type: %q", t)})
+			return
+		}
+		topics = append(topics, topic)
+	}
+
+	results := make(map[string]bool, len(topics))
+	for _, topic := range topics {
+		r, err := manageTopic([]string{req.Token}, topic, subscribe)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, ErrCircuitOpen) {
+				status = http.StatusServiceUnavailable
+			}
+			c.JSON(status, gin.H{
+				"error":   "Failed to update gift type subscription",
+				"message": err.Error(),
+			})
+			return
+		}
+		results[topic] = r[0].Success
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"topics":  results,
+	})
+}
@@ -0,0 +1,91 @@
+package fcm
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGiftTypeTopic(t *testing.T) {
+	for giftType, want := range map[string]string{
+		"cash":        "gifts_cash",
+		" Cash ":      "gifts_cash",
+		"Phone Card":  "gifts_phone_card",
+		"top-up/data": "gifts_top-up_data",
+		"ငွေ":         "",
+		"":            "",
+	} {
+		if got := GiftTypeTopic(giftType); got != want {
+			t.Errorf("GiftTypeTopic(%q) = %q, want %q", giftType, got, want)
+		}
+	}
+}
+
+// sentTopics returns the topics of every message sent, sorted
+func sentTopics(sender *fakeSender) []string {
+	topics := []string{}
+	for _, m := range sender.messages() {
+		topics = append(topics, m.Topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+func TestGiftUpdateNotifiesTypeTopic(t *testing.T) {
+	t.Cleanup(func() { giftsGlobalTopic = true })
+
+	tests := []struct {
+		name, global, giftType string
+		want                   []string
+	}{
+		{"type and global", "true", "cash", []string{"gifts", "gifts_cash"}},
+		{"type only", "false", "cash", []string{"gifts_cash"}},
+		// A type with no usable topic name still reaches someone
+		{"unusable type", "false", "ငွေ", []string{"gifts"}},
+	}
+	for _, tt := range tests {
+		setupTestDB(t)
+		sender := useFakeSender(t)
+		t.Setenv("FCM_GIFTS_GLOBAL_TOPIC", tt.global)
+		loadGiftTopicConfig()
+
+		if err := SendGiftAvailableNotification("Top-up 1000", tt.giftType); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		waitForLogged(t, len(tt.want))
+
+		if got := sentTopics(sender); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: sent to %v, want %v", tt.name, got, tt.want)
+		}
+		for _, m := range sender.messages() {
+			if m.Notification.Title != "Top-up 1000" {
+				t.Errorf("%s: title %q", tt.name, m.Notification.Title)
+			}
+		}
+	}
+}
+
+func TestGiftTopicsHandlerValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/gift-types", SubscribeGiftTypesHandler)
+
+	tooMany := `"` + strings.Repeat(`a","`, maxGiftTypesPerRequest) + `a"`
+	for name, body := range map[string]string{
+		"no token":     `{"types":["cash"]}`,
+		"blank token":  `{"token":"  ","types":["cash"]}`,
+		"no types":     `{"token":"device-1","types":[]}`,
+		"too many":     `{"token":"device-1","types":[` + tooMany + `]}`,
+		"invalid type": `{"token":"device-1","types":["cash","!!"]}`,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/gift-types", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", name, w.Code)
+		}
+	}
+}
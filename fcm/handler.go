@@ -1,8 +1,11 @@
 package fcm
 
 import (
+	"errors"
 	"net/http"
 
+	"burma2d/jsonutil"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -16,16 +19,17 @@ type NotificationRequest struct {
 func SendNotificationHandler(c *gin.Context) {
 	var req NotificationRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Invalid request",
-			"message": err.Error(),
-		})
+		jsonutil.BindError(c, err)
 		return
 	}
 
 	// Send notification to gifts topic
 	if err := SendCustomNotification(req.Title, req.Body); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrCircuitOpen) {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, gin.H{
 			"error":   "Failed to send notification",
 			"message": err.Error(),
 		})
@@ -39,3 +43,47 @@ func SendNotificationHandler(c *gin.Context) {
 		"body":    req.Body,
 	})
 }
+
+// DeviceNotificationRequest is the body for notifying a single device
+type DeviceNotificationRequest struct {
+	Token string `json:"token" binding:"required"`
+	Title string `json:"title" binding:"required"`
+	Body  string `json:"body" binding:"required"`
+}
+
+// SendToDeviceHandler sends a notification to one device token (admin).
+// An invalid token answers 410 Gone with invalid_token=true and is removed
+// from the registered devices.
+func SendToDeviceHandler(c *gin.Context) {
+	var req DeviceNotificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		jsonutil.BindError(c, err)
+		return
+	}
+
+	messageID, err := SendNotificationToToken(req.Token, req.Title, req.Body)
+	if errors.Is(err, ErrInvalidToken) {
+		c.JSON(http.StatusGone, gin.H{
+			"error":         "Device token is invalid or no longer registered",
+			"invalid_token": true,
+			"removed":       removeDevice(req.Token),
+		})
+		return
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrCircuitOpen) {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, gin.H{
+			"error":   "Failed to send notification",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"message_id": messageID,
+	})
+}
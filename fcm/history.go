@@ -0,0 +1,160 @@
+package fcm
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+
+	"burma2d/pagination"
+	"burma2d/userdata"
+
+	"firebase.google.com/go/v4/messaging"
+	"github.com/gin-gonic/gin"
+)
+
+// SentNotification is one logged send attempt
+type SentNotification struct {
+	ID        int64     `json:"id"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	Topic     string    `json:"topic,omitempty"`
+	Token     string    `json:"token,omitempty"`
+	Success   bool      `json:"success"`
+	MessageID string    `json:"message_id,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// createHistoryTable creates the notification log table
+func createHistoryTable() {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS fcm_notifications (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			title TEXT NOT NULL DEFAULT '',
+			body TEXT NOT NULL DEFAULT '',
+			topic TEXT NOT NULL DEFAULT '',
+			token TEXT NOT NULL DEFAULT '',
+			success BOOLEAN NOT NULL,
+			message_id TEXT NOT NULL DEFAULT '',
+			error TEXT NOT NULL DEFAULT '',
+			created_at DATETIME NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_fcm_notifications_created ON fcm_notifications(created_at DESC);
+	`)
+	if err != nil {
+		log.Printf("❌ Error creating fcm_notifications table: %v", err)
+	}
+
+	// Sends to the user's devices; runs before their devices are deleted
+	userdata.Register(userdata.Table{
+		Section: "notifications",
+		Export: `SELECT * FROM fcm_notifications
+			WHERE token != '' AND token IN (SELECT token FROM fcm_devices WHERE user_id = ?) ORDER BY id`,
+		Delete: `DELETE FROM fcm_notifications
+			WHERE token != '' AND token IN (SELECT token FROM fcm_devices WHERE user_id = ?)`,
+	})
+}
+
+// recordSend logs a send attempt in the background so a slow or failing
+// database never delays or breaks the send itself
+func recordSend(message *messaging.Message, messageID string, sendErr error) {
+	if db == nil {
+		return
+	}
+
+	n := SentNotification{
+		Topic:     message.Topic,
+		Token:     message.Token,
+		Success:   sendErr == nil,
+		MessageID: messageID,
+		CreatedAt: time.Now().UTC(),
+	}
+	if message.Notification != nil {
+		n.Title = message.Notification.Title
+		n.Body = message.Notification.Body
+	}
+	if sendErr != nil {
+		n.Error = sendErr.Error()
+	}
+
+	go func() {
+		_, err := db.Exec(`
+			INSERT INTO fcm_notifications (title, body, topic, token, success, message_id, error, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, n.Title, n.Body, n.Topic, n.Token, n.Success, n.MessageID, n.Error, n.CreatedAt)
+		if err != nil {
+			log.Printf("⚠️ Failed to record FCM notification: %v", err)
+		}
+	}()
+}
+
+// GetHistory returns logged sends, newest first, with the total matching count
+func GetHistory(where pagination.Where, p pagination.Params) ([]SentNotification, int, error) {
+	where.DateRange("created_at", pagination.DateLayout, p)
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM fcm_notifications"+where.SQL(), where.Args()...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	limitSQL, limitArgs := p.LimitOffset()
+	rows, err := db.Query(`
+		SELECT id, title, body, topic, token, success, message_id, error, created_at
+		FROM fcm_notifications`+where.SQL()+`
+		ORDER BY created_at DESC, id DESC`+limitSQL, append(where.Args(), limitArgs...)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	notifications := []SentNotification{}
+	for rows.Next() {
+		var n SentNotification
+		var createdAt sql.NullTime
+		if err := rows.Scan(&n.ID, &n.Title, &n.Body, &n.Topic, &n.Token, &n.Success, &n.MessageID, &n.Error, &createdAt); err != nil {
+			continue
+		}
+		n.CreatedAt = createdAt.Time
+		notifications = append(notifications, n)
+	}
+	return notifications, total, rows.Err()
+}
+
+// GetHistoryHandler lists past sends for auditing (admin)
+// Optional query params: topic, success (true/false), from, to (YYYY-MM-DD), limit, offset
+func GetHistoryHandler(c *gin.Context) {
+	p, err := pagination.Parse(c, pagination.Options{DefaultLimit: 50, MaxLimit: 500})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var where pagination.Where
+	if topic := c.Query("topic"); topic != "" {
+		where.Add("topic = ?", topic)
+	}
+	switch c.Query("success") {
+	case "":
+	case "true":
+		where.Add("success = 1")
+	case "false":
+		where.Add("success = 0")
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "success must be true or false"})
+		return
+	}
+
+	notifications, total, err := GetHistory(where, p)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get notification history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notifications": notifications,
+		"total":         total,
+		"limit":         p.Limit,
+		"offset":        p.Offset,
+	})
+}
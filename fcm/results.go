@@ -0,0 +1,101 @@
+package fcm
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"burma2d/config"
+	"burma2d/jsonutil"
+
+	"firebase.google.com/go/v4/messaging"
+	"github.com/gin-gonic/gin"
+)
+
+// Result notification settings, loaded by InitFCM
+var (
+	resultsTopic        = "2d_results"
+	resultTitleTemplate = "2D Result {date}"
+	resultBodyTemplate  = "Today's 4:30 PM result: {number}"
+)
+
+// loadResultConfig reads FCM_RESULTS_TOPIC, FCM_RESULT_TITLE and
+// FCM_RESULT_BODY; the templates may use {date} and {number}
+func loadResultConfig() {
+	resultsTopic = config.String("FCM_RESULTS_TOPIC", resultsTopic)
+	resultTitleTemplate = config.String("FCM_RESULT_TITLE", resultTitleTemplate)
+	resultBodyTemplate = config.String("FCM_RESULT_BODY", resultBodyTemplate)
+}
+
+// ResultsTopic returns the topic 2D result notifications are sent to
+func ResultsTopic() string {
+	return resultsTopic
+}
+
+// newResultMessage builds the result notification for a draw date
+func newResultMessage(date, number string) *messaging.Message {
+	r := strings.NewReplacer("{date}", date, "{number}", number)
+	message := newMessage(r.Replace(resultTitleTemplate), r.Replace(resultBodyTemplate))
+	message.Topic = resultsTopic
+	message.Android.Notification.Tag = "2d_result"
+	message.Data = map[string]string{
+		"type":   "2d_result",
+		"date":   date,
+		"number": number,
+	}
+	return message
+}
+
+// SendResultNotification sends a draw result to the results topic and
+// returns the FCM message ID
+func SendResultNotification(date, number string) (string, error) {
+	return send(newResultMessage(date, number))
+}
+
+// SendTestResultHandler sends a sample result notification to the results
+// topic so admins can check the pipeline. Optional JSON body: {date, number}
+func SendTestResultHandler(c *gin.Context) {
+	var req struct {
+		Date   string `json:"date"`
+		Number string `json:"number"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			jsonutil.BindError(c, err)
+			return
+		}
+	}
+	if req.Date == "" {
+		req.Date = time.Now().In(time.FixedZone("Myanmar", 6*3600+30*60)).Format("2006-01-02")
+	}
+	if req.Number == "" {
+		req.Number = "00"
+	}
+
+	message := newResultMessage(req.Date, req.Number)
+	message.Data["test"] = "true"
+
+	messageID, err := send(message)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrCircuitOpen) {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, gin.H{
+			"error":   "Failed to send test notification",
+			"message": err.Error(),
+			"topic":   resultsTopic,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"message_id": messageID,
+		"topic":      resultsTopic,
+		"title":      message.Notification.Title,
+		"body":       message.Notification.Body,
+		"data":       message.Data,
+	})
+}
@@ -0,0 +1,79 @@
+package fcm
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSendTestResultHandler(t *testing.T) {
+	setupTestDB(t)
+	sender := useFakeSender(t)
+	t.Setenv("FCM_RESULTS_TOPIC", "results_test")
+	t.Setenv("FCM_RESULT_TITLE", "Result {date}")
+	t.Setenv("FCM_RESULT_BODY", "Number {number} on {date}")
+	loadResultConfig()
+	t.Cleanup(func() {
+		resultsTopic, resultTitleTemplate, resultBodyTemplate = "2d_results", "2D Result {date}", "Today's 4:30 PM result: {number}"
+	})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/test-result", SendTestResultHandler)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/test-result",
+		strings.NewReader(`{"date":"2026-03-01","number":"47"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	waitForLogged(t, 1)
+
+	sent := sender.messages()
+	if len(sent) != 1 {
+		t.Fatalf("%d messages sent, want 1", len(sent))
+	}
+	m := sent[0]
+	if m.Topic != "results_test" || m.Token != "" {
+		t.Errorf("sent to topic %q token %q, want the results topic", m.Topic, m.Token)
+	}
+	if m.Notification.Title != "Result 2026-03-01" || m.Notification.Body != "Number 47 on 2026-03-01" {
+		t.Errorf("notification = %q / %q", m.Notification.Title, m.Notification.Body)
+	}
+	if m.Data["type"] != "2d_result" || m.Data["number"] != "47" || m.Data["test"] != "true" {
+		t.Errorf("data = %v", m.Data)
+	}
+
+	var resp struct {
+		Topic string `json:"topic"`
+		Title string `json:"title"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Topic != "results_test" || resp.Title != "Result 2026-03-01" {
+		t.Errorf("response = %s", w.Body.String())
+	}
+}
+
+func TestSendTestResultHandlerDefaults(t *testing.T) {
+	setupTestDB(t)
+	sender := useFakeSender(t)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/test-result", SendTestResultHandler)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/test-result", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	waitForLogged(t, 1)
+
+	m := sender.messages()[0]
+	if m.Topic != ResultsTopic() || m.Data["number"] != "00" || len(m.Data["date"]) != len("2006-01-02") {
+		t.Errorf("topic %q data %v, want today's date and 00", m.Topic, m.Data)
+	}
+}
@@ -0,0 +1,156 @@
+package fcm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"burma2d/jsonutil"
+
+	"firebase.google.com/go/v4/messaging"
+	"github.com/gin-gonic/gin"
+)
+
+// maxTopicTokens is the Firebase limit for one subscribe/unsubscribe call
+const maxTopicTokens = 1000
+
+// topicNamePattern matches names Firebase accepts for topics
+var topicNamePattern = regexp.MustCompile(`^[a-zA-Z0-9\-_.~%]+$`)
+
+// TopicRequest is the body for subscribing or unsubscribing device tokens
+type TopicRequest struct {
+	Tokens []string `json:"tokens" binding:"required"`
+	Topic  string   `json:"topic" binding:"required"`
+}
+
+// TokenResult is the outcome of a topic operation for one token
+type TokenResult struct {
+	Token   string `json:"token"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// SubscribeToTopic subscribes tokens to topic and returns per-token results
+func SubscribeToTopic(tokens []string, topic string) ([]TokenResult, error) {
+	return manageTopic(tokens, topic, true)
+}
+
+// UnsubscribeFromTopic unsubscribes tokens from topic and returns per-token results
+func UnsubscribeFromTopic(tokens []string, topic string) ([]TokenResult, error) {
+	return manageTopic(tokens, topic, false)
+}
+
+func manageTopic(tokens []string, topic string, subscribe bool) ([]TokenResult, error) {
+	if fcmClient == nil {
+		return nil, fmt.Errorf("FCM client not initialized")
+	}
+	if !circuit.allow() {
+		return nil, ErrCircuitOpen
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+
+	var resp *messaging.TopicManagementResponse
+	var err error
+	if subscribe {
+		resp, err = fcmClient.SubscribeToTopic(ctx, tokens, topic)
+	} else {
+		resp, err = fcmClient.UnsubscribeFromTopic(ctx, tokens, topic)
+	}
+	if err != nil {
+		circuit.failure()
+		log.Printf("❌ Error updating FCM topic %s: %v", topic, err)
+		return nil, err
+	}
+	circuit.success()
+
+	results := make([]TokenResult, len(tokens))
+	for i, token := range tokens {
+		results[i] = TokenResult{Token: token, Success: true}
+	}
+	for _, e := range resp.Errors {
+		if e.Index >= 0 && e.Index < len(results) {
+			results[e.Index].Success = false
+			results[e.Index].Error = e.Reason
+		}
+	}
+
+	action := "subscribed to"
+	if !subscribe {
+		action = "unsubscribed from"
+	}
+	log.Printf("✅ FCM: %d tokens %s %s (%d failed)", resp.SuccessCount, action, topic, resp.FailureCount)
+	return results, nil
+}
+
+// validateTopicRequest trims the request and checks the topic and token count
+func validateTopicRequest(req *TopicRequest) error {
+	req.Topic = strings.TrimPrefix(strings.TrimSpace(req.Topic), "/topics/")
+	if !topicNamePattern.MatchString(req.Topic) {
+		return fmt.Errorf("invalid topic name: %q", req.Topic)
+	}
+	if len(req.Tokens) == 0 || len(req.Tokens) > maxTopicTokens {
+		return fmt.Errorf("tokens must contain 1 to %d items", maxTopicTokens)
+	}
+	for i, token := range req.Tokens {
+		req.Tokens[i] = strings.TrimSpace(token)
+		if req.Tokens[i] == "" || len(req.Tokens[i]) > maxTokenLength {
+			return fmt.Errorf("invalid token at index %d", i)
+		}
+	}
+	return nil
+}
+
+// SubscribeHandler subscribes device tokens to a topic (admin)
+func SubscribeHandler(c *gin.Context) {
+	topicHandler(c, true)
+}
+
+// UnsubscribeHandler unsubscribes device tokens from a topic (admin)
+func UnsubscribeHandler(c *gin.Context) {
+	topicHandler(c, false)
+}
+
+func topicHandler(c *gin.Context, subscribe bool) {
+	var req TopicRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		jsonutil.BindError(c, err)
+		return
+	}
+	if err := validateTopicRequest(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, err := manageTopic(req.Tokens, req.Topic, subscribe)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrCircuitOpen) {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, gin.H{
+			"error":   "Failed to update topic subscription",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	succeeded := 0
+	for _, r := range results {
+		if r.Success {
+			succeeded++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"topic":         req.Topic,
+		"success_count": succeeded,
+		"failure_count": len(results) - succeeded,
+		"results":       results,
+	})
+}
@@ -0,0 +1,58 @@
+package gift
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	"burma2d/config"
+	"burma2d/googleauth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// googleClientID is the audience of the ID tokens redemptions must carry
+var googleClientID string
+
+// insecureRedeem takes the redeeming user from the request body when no
+// client ID is configured (GIFT_INSECURE_REDEEM, development only)
+var insecureRedeem bool
+
+// errSignInRequired is returned by redeemingUser without a valid ID token
+var errSignInRequired = errors.New("sign in to redeem gifts")
+
+// errAuthNotConfigured is returned by redeemingUser when neither a client
+// ID nor the development opt-out is set
+var errAuthNotConfigured = errors.New("gift redemption is not configured")
+
+// SetGoogleClientID sets the Google OAuth client ID for token verification
+func SetGoogleClientID(clientID string) {
+	googleClientID = clientID
+}
+
+func initRedeemAuth() {
+	insecureRedeem = config.Bool("GIFT_INSECURE_REDEEM", false)
+	if insecureRedeem {
+		log.Println("⚠️ GIFT_INSECURE_REDEEM=true: gifts can be redeemed for any user_id without signing in (development only)")
+	}
+}
+
+// redeemingUser returns the verified Google subject of the request. Only
+// with GIFT_INSECURE_REDEEM and no client ID is bodyUserID trusted instead.
+func redeemingUser(c *gin.Context, bodyUserID string) (string, error) {
+	if googleClientID == "" {
+		if !insecureRedeem {
+			return "", errAuthNotConfigured
+		}
+		if bodyUserID = strings.TrimSpace(bodyUserID); bodyUserID == "" {
+			return "", errors.New("user_id required")
+		}
+		return bodyUserID, nil
+	}
+
+	userID, err := googleauth.Authenticate(c, googleClientID)
+	if err != nil {
+		return "", errSignInRequired
+	}
+	return userID, nil
+}
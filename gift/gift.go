@@ -2,26 +2,33 @@ package gift
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"burma2d/dbutil"
 	"burma2d/fcm"
+	"burma2d/pagination"
+	"burma2d/shutdown"
 
 	"github.com/gin-gonic/gin"
 )
 
 type Gift struct {
-	ID          int       `json:"gift_id"`
-	Name        string    `json:"gift_name"`
-	ImageLink   string    `json:"image_url"`
-	Type        string    `json:"reward_type"`
-	Description string    `json:"gift_description"`
-	Points      int       `json:"required_points"`
-	Stock       int       `json:"available_stock"`
-	IsActive    bool      `json:"is_available"`
-	CreatedAt   time.Time `json:"created_date"`
+	ID          int        `json:"gift_id"`
+	Name        string     `json:"gift_name"`
+	ImageLink   string     `json:"image_url"`
+	Type        string     `json:"reward_type"`
+	Description string     `json:"gift_description"`
+	Points      int        `json:"required_points"`
+	Stock       int        `json:"available_stock"`
+	IsActive    bool       `json:"is_available"`
+	CreatedAt   time.Time  `json:"created_date"`
+	DeletedAt   *time.Time `json:"deleted_date,omitempty"` // Admin listings only
 }
 
 var db *sql.DB
@@ -30,6 +37,10 @@ var db *sql.DB
 func InitDB(database *sql.DB) {
 	db = database
 	createTable()
+	createRedemptionTable()
+	initRedeemAuth()
+	initLowStock()
+	shutdown.OnClosing(notifyClosing)
 }
 
 type GiftType struct {
@@ -64,9 +75,16 @@ func createTable() {
 	_, err := db.Exec(query)
 	if err != nil {
 		log.Printf("❌ Error creating gifts tables: %v", err)
-	} else {
-		log.Println("✅ Gifts tables ready")
+		return
+	}
+
+	// Soft delete: deleted gifts keep their row for redemption history
+	if err := dbutil.AddColumnIfMissing(db, "gifts", "deleted_at", "DATETIME"); err != nil {
+		log.Printf("❌ Error migrating gifts table: %v", err)
+		return
 	}
+
+	log.Println("✅ Gifts tables ready")
 }
 
 // GetAllGifts retrieves all active gifts grouped by type
@@ -74,7 +92,7 @@ func GetAllGifts() (map[string][]Gift, error) {
 	query := `
 		SELECT id, name, image_link, type, description, points, stock, is_active, created_at
 		FROM gifts
-		WHERE is_active = true
+		WHERE is_active = true AND deleted_at IS NULL
 		ORDER BY type, created_at DESC
 	`
 	rows, err := db.Query(query)
@@ -98,20 +116,75 @@ func GetAllGifts() (map[string][]Gift, error) {
 	return giftsMap, nil
 }
 
-// GetAllGiftsForAdmin retrieves all gifts (including inactive)
-func GetAllGiftsForAdmin() ([]Gift, error) {
+// GetAffordableGifts retrieves active, in-stock gifts costing at most points,
+// grouped by type and sorted by points ascending
+func GetAffordableGifts(points int) (map[string][]Gift, error) {
 	query := `
 		SELECT id, name, image_link, type, description, points, stock, is_active, created_at
 		FROM gifts
-		ORDER BY created_at DESC
+		WHERE is_active = true AND deleted_at IS NULL AND stock > 0 AND points <= ?
+		ORDER BY type, points ASC, id ASC
 	`
-	rows, err := db.Query(query)
+	rows, err := db.Query(query, points)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	giftsMap := make(map[string][]Gift)
+	for rows.Next() {
+		var gift Gift
+		err := rows.Scan(&gift.ID, &gift.Name, &gift.ImageLink, &gift.Type,
+			&gift.Description, &gift.Points, &gift.Stock, &gift.IsActive, &gift.CreatedAt)
+		if err != nil {
+			log.Printf("Error scanning gift: %v", err)
+			continue
+		}
+		giftsMap[gift.Type] = append(giftsMap[gift.Type], gift)
+	}
+
+	return giftsMap, nil
+}
+
+// SearchFilter holds optional gift search criteria; zero values match all
+type SearchFilter struct {
+	Query     string // case-insensitive name substring
+	Type      string
+	MinPoints *int
+	MaxPoints *int
+	InStock   bool
+}
+
+// SearchGifts returns active gifts matching every given criterion, as a flat list
+func SearchGifts(f SearchFilter) ([]Gift, error) {
+	var where pagination.Where
+	where.Add("is_active = true")
+	where.Add("deleted_at IS NULL")
+	where.Contains("name", f.Query)
+	if f.Type != "" {
+		where.Add("type = ?", f.Type)
+	}
+	if f.MinPoints != nil {
+		where.Add("points >= ?", *f.MinPoints)
+	}
+	if f.MaxPoints != nil {
+		where.Add("points <= ?", *f.MaxPoints)
+	}
+	if f.InStock {
+		where.Add("stock > 0")
+	}
+
+	query := `
+		SELECT id, name, image_link, type, description, points, stock, is_active, created_at
+		FROM gifts` + where.SQL() + `
+		ORDER BY type, created_at DESC`
+	rows, err := db.Query(query, where.Args()...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var gifts []Gift
+	gifts := []Gift{}
 	for rows.Next() {
 		var gift Gift
 		err := rows.Scan(&gift.ID, &gift.Name, &gift.ImageLink, &gift.Type,
@@ -126,41 +199,109 @@ func GetAllGiftsForAdmin() ([]Gift, error) {
 	return gifts, nil
 }
 
+// GetAllGiftsForAdmin retrieves gifts (including inactive), filtered by
+// creation date range and paged by limit/offset. Soft-deleted gifts are
+// skipped unless includeDeleted is set. It also returns the total matching
+// count, read in the same transaction as the page so they agree.
+func GetAllGiftsForAdmin(p pagination.Params, includeDeleted bool) ([]Gift, int, error) {
+	var where pagination.Where
+	if !includeDeleted {
+		where.Add("deleted_at IS NULL")
+	}
+	where.DateRange("created_at", pagination.DateLayout, p)
+	limitSQL, limitArgs := p.LimitOffset()
+
+	gifts := []Gift{}
+	var total int
+
+	err := dbutil.WithTx(db, func(tx *sql.Tx) error {
+		if err := tx.QueryRow("SELECT COUNT(*) FROM gifts"+where.SQL(), where.Args()...).Scan(&total); err != nil {
+			return err
+		}
+
+		query := `
+			SELECT id, name, image_link, type, description, points, stock, is_active, created_at, deleted_at
+			FROM gifts` + where.SQL() + `
+			ORDER BY created_at DESC, id DESC` + limitSQL
+		rows, err := tx.Query(query, append(where.Args(), limitArgs...)...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var gift Gift
+			var deletedAt sql.NullTime
+			err := rows.Scan(&gift.ID, &gift.Name, &gift.ImageLink, &gift.Type,
+				&gift.Description, &gift.Points, &gift.Stock, &gift.IsActive, &gift.CreatedAt, &deletedAt)
+			if err != nil {
+				log.Printf("Error scanning gift: %v", err)
+				continue
+			}
+			if deletedAt.Valid {
+				gift.DeletedAt = &deletedAt.Time
+			}
+			gifts = append(gifts, gift)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return gifts, total, nil
+}
+
 // InsertGift adds a new gift
 func InsertGift(gift Gift) error {
 	query := `
 		INSERT INTO gifts (name, image_link, type, description, points, stock, is_active)
 		VALUES ($1, $2, $3, $4, $5, $6, $7)
 	`
-	_, err := db.Exec(query, gift.Name, gift.ImageLink, gift.Type,
+	result, err := db.Exec(query, gift.Name, gift.ImageLink, gift.Type,
 		gift.Description, gift.Points, gift.Stock, gift.IsActive)
 	if err != nil {
 		log.Printf("❌ Error inserting gift: %v", err)
 		return err
 	}
 	log.Printf("✅ Gift inserted: %s", gift.Name)
+
+	if id, err := result.LastInsertId(); err == nil {
+		notifyGiftAvailable(int(id))
+	}
 	return nil
 }
 
-// UpdateGift updates an existing gift
+// GetImageLink returns the current image link of a gift
+func GetImageLink(id int) (string, error) {
+	var link string
+	err := db.QueryRow("SELECT image_link FROM gifts WHERE id = ?", id).Scan(&link)
+	return link, err
+}
+
+// UpdateGift updates an existing gift. Stock is not written here: it is
+// managed by SetStock and AdjustStock so edits can't overwrite redemptions.
 func UpdateGift(gift Gift) error {
 	query := `
 		UPDATE gifts
 		SET name = $1, image_link = $2, type = $3, description = $4,
-		    points = $5, stock = $6, is_active = $7
-		WHERE id = $8
+		    points = $5, is_active = $6
+		WHERE id = $7
 	`
 	_, err := db.Exec(query, gift.Name, gift.ImageLink, gift.Type,
-		gift.Description, gift.Points, gift.Stock, gift.IsActive, gift.ID)
+		gift.Description, gift.Points, gift.IsActive, gift.ID)
 	if err != nil {
 		log.Printf("❌ Error updating gift: %v", err)
 		return err
 	}
 	log.Printf("✅ Gift updated: %s", gift.Name)
+	notifyGiftAvailable(gift.ID)
+	checkLowStock(gift.ID) // Reactivating a gift can put it below its threshold
 
 	// Send FCM notification about gift availability
 	go func() {
-		if err := fcm.SendGiftAvailableNotification(gift.Name); err != nil {
+		err := fcm.SendGiftAvailableNotification(gift.Name, gift.Type)
+		if err != nil && !errors.Is(err, fcm.ErrCircuitOpen) {
 			log.Printf("⚠️ Failed to send FCM notification for gift '%s': %v", gift.Name, err)
 		}
 	}()
@@ -168,15 +309,58 @@ func UpdateGift(gift Gift) error {
 	return nil
 }
 
-// DeleteGift deletes a gift
+// DeleteGift soft-deletes a gift: it is deactivated and hidden from
+// listings but kept so redemption history still resolves
 func DeleteGift(id int) error {
+	query := `
+		UPDATE gifts SET is_active = 0, deleted_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+	result, err := db.Exec(query, id)
+	if err != nil {
+		log.Printf("❌ Error deleting gift: %v", err)
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrGiftNotFound
+	}
+	log.Printf("✅ Gift soft-deleted: ID %d", id)
+	notifyGiftRemoved(id)
+	return nil
+}
+
+// PurgeGift permanently removes a gift row
+func PurgeGift(id int) error {
 	query := `DELETE FROM gifts WHERE id = $1`
-	_, err := db.Exec(query, id)
+	result, err := db.Exec(query, id)
 	if err != nil {
 		log.Printf("❌ Error deleting gift: %v", err)
 		return err
 	}
-	log.Printf("✅ Gift deleted: ID %d", id)
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrGiftNotFound
+	}
+	log.Printf("✅ Gift permanently deleted: ID %d", id)
+	notifyGiftRemoved(id)
+	return nil
+}
+
+// RestoreGift undoes a soft delete and reactivates the gift
+func RestoreGift(id int) error {
+	query := `
+		UPDATE gifts SET is_active = 1, deleted_at = NULL
+		WHERE id = $1 AND deleted_at IS NOT NULL
+	`
+	result, err := db.Exec(query, id)
+	if err != nil {
+		log.Printf("❌ Error restoring gift: %v", err)
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrGiftNotFound
+	}
+	log.Printf("✅ Gift restored: ID %d", id)
+	notifyGiftAvailable(id)
 	return nil
 }
 
@@ -337,6 +521,76 @@ func DeleteGiftTypeHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Gift type deleted successfully"})
 }
 
+// GetAffordableGiftsHandler returns gifts a user with ?points=N can afford
+func GetAffordableGiftsHandler(c *gin.Context) {
+	points, err := strconv.Atoi(c.Query("points"))
+	if err != nil || points < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "points must be a non-negative integer"})
+		return
+	}
+
+	gifts, err := GetAffordableGifts(points)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gifts)
+}
+
+// SearchGiftsHandler returns a flat list of active gifts filtered by
+// q, type, min_points, max_points and in_stock=true (all optional)
+func SearchGiftsHandler(c *gin.Context) {
+	f := SearchFilter{
+		Query: strings.TrimSpace(c.Query("q")),
+		Type:  c.Query("type"),
+	}
+
+	var err error
+	if f.MinPoints, err = optionalPoints(c, "min_points"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if f.MaxPoints, err = optionalPoints(c, "max_points"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if f.MinPoints != nil && f.MaxPoints != nil && *f.MinPoints > *f.MaxPoints {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "min_points must not exceed max_points"})
+		return
+	}
+
+	if v := c.Query("in_stock"); v != "" {
+		inStock, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "in_stock must be true or false"})
+			return
+		}
+		f.InStock = inStock
+	}
+
+	gifts, err := SearchGifts(f)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gifts)
+}
+
+// optionalPoints parses a non-negative points query param; nil when absent
+func optionalPoints(c *gin.Context, param string) (*int, error) {
+	v := c.Query(param)
+	if v == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("%s must be a non-negative integer", param)
+	}
+	return &n, nil
+}
+
 // GetGiftsHandler returns gifts grouped by type
 func GetGiftsHandler(c *gin.Context) {
 	gifts, err := GetAllGifts()
@@ -0,0 +1,159 @@
+package gift
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func addNamedGift(t *testing.T, name, giftType string, points, stock int) int {
+	t.Helper()
+	result, err := db.Exec(`INSERT INTO gifts (name, image_link, type, description, points, stock) VALUES (?, '', ?, '', ?, ?)`,
+		name, giftType, points, stock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := result.LastInsertId()
+	return int(id)
+}
+
+func giftNames(gifts []Gift) []string {
+	names := []string{}
+	for _, g := range gifts {
+		names = append(names, g.Name)
+	}
+	return names
+}
+
+func sameNames(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestGetAffordableGifts(t *testing.T) {
+	setupTestDB(t)
+	addNamedGift(t, "Card 500", "card", 500, 3)
+	addNamedGift(t, "Card 100", "card", 100, 3)
+	addNamedGift(t, "Card 300", "card", 300, 3)
+	addNamedGift(t, "Phone", "phone", 250, 1)
+	addNamedGift(t, "Sold out", "card", 50, 0)
+	addNamedGift(t, "Too dear", "phone", 301, 1)
+	inactive := addNamedGift(t, "Inactive", "card", 10, 5)
+	db.Exec("UPDATE gifts SET is_active = false WHERE id = ?", inactive)
+
+	gifts, err := GetAffordableGifts(300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := giftNames(gifts["card"]); !sameNames(got, []string{"Card 100", "Card 300"}) {
+		t.Errorf("card gifts = %v, want cheapest first and none above 300", got)
+	}
+	if got := giftNames(gifts["phone"]); !sameNames(got, []string{"Phone"}) {
+		t.Errorf("phone gifts = %v, want [Phone]", got)
+	}
+	if len(gifts) != 2 {
+		t.Errorf("types = %d, want 2", len(gifts))
+	}
+
+	if gifts, err := GetAffordableGifts(0); err != nil || len(gifts) != 0 {
+		t.Errorf("0 points: %v, %v; want no gifts", gifts, err)
+	}
+}
+
+func TestGetAffordableGiftsHandler(t *testing.T) {
+	setupTestDB(t)
+	addNamedGift(t, "Card 100", "card", 100, 3)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/affordable", GetAffordableGiftsHandler)
+
+	for query, want := range map[string]int{
+		"points=100": http.StatusOK,
+		"points=-1":  http.StatusBadRequest,
+		"points=abc": http.StatusBadRequest,
+		"":           http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/affordable?"+query, nil))
+		if w.Code != want {
+			t.Errorf("%q: status %d, want %d", query, w.Code, want)
+		}
+		if w.Code == http.StatusOK {
+			var gifts map[string][]Gift
+			if err := json.Unmarshal(w.Body.Bytes(), &gifts); err != nil || len(gifts["card"]) != 1 {
+				t.Errorf("%q: body %s", query, w.Body.String())
+			}
+		}
+	}
+}
+
+func TestSearchGifts(t *testing.T) {
+	setupTestDB(t)
+	addNamedGift(t, "Top-up Card", "card", 100, 3)
+	addNamedGift(t, "Gift Card", "card", 500, 0)
+	addNamedGift(t, "Phone", "phone", 300, 1)
+	addNamedGift(t, "100%_Bonus", "bonus", 50, 1)
+	deleted := addNamedGift(t, "Old Card", "card", 100, 3)
+	db.Exec("UPDATE gifts SET deleted_at = CURRENT_TIMESTAMP WHERE id = ?", deleted)
+	// Later inserts are newer
+	db.Exec("UPDATE gifts SET created_at = datetime('2026-01-01', '+' || id || ' days')")
+
+	intp := func(n int) *int { return &n }
+	tests := []struct {
+		name   string
+		filter SearchFilter
+		want   []string
+	}{
+		{"all", SearchFilter{}, []string{"100%_Bonus", "Gift Card", "Top-up Card", "Phone"}},
+		{"name", SearchFilter{Query: "CARD"}, []string{"Gift Card", "Top-up Card"}},
+		{"name wildcard is literal", SearchFilter{Query: "%_"}, []string{"100%_Bonus"}},
+		{"type", SearchFilter{Type: "phone"}, []string{"Phone"}},
+		{"min points", SearchFilter{MinPoints: intp(300)}, []string{"Gift Card", "Phone"}},
+		{"max points", SearchFilter{MaxPoints: intp(100)}, []string{"100%_Bonus", "Top-up Card"}},
+		{"in stock", SearchFilter{InStock: true}, []string{"100%_Bonus", "Top-up Card", "Phone"}},
+		{"type and range", SearchFilter{Type: "card", MinPoints: intp(50), MaxPoints: intp(200)}, []string{"Top-up Card"}},
+		{"name and in stock", SearchFilter{Query: "card", InStock: true}, []string{"Top-up Card"}},
+		{"no match", SearchFilter{Type: "card", MaxPoints: intp(10)}, []string{}},
+	}
+	for _, tt := range tests {
+		gifts, err := SearchGifts(tt.filter)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		// Within a type, newer gifts come first
+		if got := giftNames(gifts); !sameNames(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSearchGiftsHandlerValidation(t *testing.T) {
+	setupTestDB(t)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/search", SearchGiftsHandler)
+
+	for query, want := range map[string]int{
+		"q=card&type=card&min_points=1&max_points=9&in_stock=true": http.StatusOK,
+		"min_points=-1":              http.StatusBadRequest,
+		"max_points=x":               http.StatusBadRequest,
+		"min_points=10&max_points=5": http.StatusBadRequest,
+		"in_stock=maybe":             http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/search?"+query, nil))
+		if w.Code != want {
+			t.Errorf("%q: status %d, want %d", query, w.Code, want)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package gift
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"burma2d/config"
+	"burma2d/dbutil"
+	"burma2d/fcm"
+	"burma2d/jsonutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Low-stock alerts, read in initLowStock
+var (
+	lowStockThreshold int    // Default alert level for gifts without their own
+	lowStockTopic     string // FCM topic admins subscribe to
+)
+
+// initLowStock loads GIFT_LOW_STOCK_THRESHOLD (default 5, 0 = off unless a
+// gift sets its own) and GIFT_LOW_STOCK_TOPIC (default admin_alerts), and
+// adds the per-gift threshold and alert state columns
+func initLowStock() {
+	lowStockThreshold = config.Int("GIFT_LOW_STOCK_THRESHOLD", 5)
+	lowStockTopic = config.String("GIFT_LOW_STOCK_TOPIC", "admin_alerts")
+
+	// NULL threshold means the default; low_stock_alerted debounces the
+	// alert until stock is back at or above the threshold
+	if err := dbutil.AddColumnIfMissing(db, "gifts", "low_stock_threshold", "INTEGER"); err != nil {
+		log.Printf("❌ Error migrating gifts table: %v", err)
+		return
+	}
+	if err := dbutil.AddColumnIfMissing(db, "gifts", "low_stock_alerted", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		log.Printf("❌ Error migrating gifts table: %v", err)
+		return
+	}
+
+	log.Printf("✅ Gift low-stock alerts below %d to topic %s", lowStockThreshold, lowStockTopic)
+}
+
+// checkLowStock alerts admins once when an active gift's stock drops below
+// its threshold, and re-arms the alert once stock is back at or above it
+func checkLowStock(giftID int) {
+	var name string
+	var stock, threshold int
+	err := db.QueryRow(`
+		UPDATE gifts SET low_stock_alerted = 1
+		WHERE id = ? AND low_stock_alerted = 0 AND is_active = 1 AND deleted_at IS NULL
+		  AND stock < COALESCE(low_stock_threshold, ?)
+		RETURNING name, stock, COALESCE(low_stock_threshold, ?)
+	`, giftID, lowStockThreshold, lowStockThreshold).Scan(&name, &stock, &threshold)
+	if err == nil {
+		alertLowStock(name, stock, threshold)
+		return
+	}
+	if err != sql.ErrNoRows {
+		log.Printf("⚠️ Failed to check low stock for gift %d: %v", giftID, err)
+		return
+	}
+
+	_, err = db.Exec(`
+		UPDATE gifts SET low_stock_alerted = 0
+		WHERE id = ? AND low_stock_alerted = 1 AND stock >= COALESCE(low_stock_threshold, ?)
+	`, giftID, lowStockThreshold)
+	if err != nil {
+		log.Printf("⚠️ Failed to re-arm low stock alert for gift %d: %v", giftID, err)
+	}
+}
+
+// alertLowStock logs the alert and pushes it to the admin topic
+func alertLowStock(name string, stock, threshold int) {
+	title := "Gift stock low"
+	body := fmt.Sprintf("%s: %d left (alert level %d)", name, stock, threshold)
+	log.Printf("📣 %s: %s", title, body)
+	if lowStockTopic == "" || !fcm.IsInitialized() {
+		return
+	}
+
+	go func() {
+		err := fcm.SendNotificationToTopic(lowStockTopic, title, body)
+		if err != nil && !errors.Is(err, fcm.ErrCircuitOpen) {
+			log.Printf("⚠️ Failed to send low stock alert for gift '%s': %v", name, err)
+		}
+	}()
+}
+
+// SetLowStockThresholdHandler sets a gift's alert level (admin).
+// Body: {"threshold": N}; null or a missing threshold uses the default.
+func SetLowStockThresholdHandler(c *gin.Context) {
+	giftID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var req struct {
+		Threshold *int `json:"threshold"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		jsonutil.BindError(c, err)
+		return
+	}
+	if req.Threshold != nil && *req.Threshold < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "threshold must not be negative"})
+		return
+	}
+
+	result, err := db.Exec("UPDATE gifts SET low_stock_threshold = ? WHERE id = ?", req.Threshold, giftID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrGiftNotFound.Error()})
+		return
+	}
+
+	// A new level can put the current stock on either side of it
+	checkLowStock(giftID)
+
+	threshold := lowStockThreshold
+	if req.Threshold != nil {
+		threshold = *req.Threshold
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":             true,
+		"gift_id":             giftID,
+		"low_stock_threshold": threshold,
+		"uses_default":        req.Threshold == nil,
+	})
+}
@@ -0,0 +1,439 @@
+package gift
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"burma2d/dbutil"
+	"burma2d/jsonutil"
+	"burma2d/pagination"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Redemption statuses
+const (
+	RedemptionPending   = "pending"
+	RedemptionFulfilled = "fulfilled"
+)
+
+// Redemption errors
+var (
+	ErrGiftNotFound    = errors.New("gift not found")
+	ErrGiftUnavailable = errors.New("gift is not available")
+	ErrOutOfStock      = errors.New("gift is out of stock")
+
+	ErrInsufficientPoints = errors.New("not enough points")
+)
+
+// Redemption records a user redeeming a gift
+type Redemption struct {
+	ID          int64     `json:"redemption_id"`
+	GiftID      int       `json:"gift_id"`
+	UserID      string    `json:"user_id"`
+	PointsSpent int       `json:"points_spent"`
+	Status      string    `json:"status"`
+	CreatedAt   time.Time `json:"created_date"`
+}
+
+// RedemptionDetail is a redemption joined with its gift and chat user
+type RedemptionDetail struct {
+	Redemption
+	GiftName  string `json:"gift_name"`
+	GiftType  string `json:"reward_type"`
+	Username  string `json:"username"`
+	UserPhoto string `json:"photo_url"`
+}
+
+// createRedemptionTable creates the gift_redemptions table
+func createRedemptionTable() {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS gift_redemptions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			gift_id INTEGER NOT NULL,
+			user_id TEXT NOT NULL,
+			points_spent INTEGER NOT NULL DEFAULT 0,
+			status TEXT NOT NULL DEFAULT 'pending',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_redemptions_gift ON gift_redemptions(gift_id);
+		CREATE INDEX IF NOT EXISTS idx_redemptions_user ON gift_redemptions(user_id);
+		CREATE INDEX IF NOT EXISTS idx_redemptions_status ON gift_redemptions(status, created_at);
+	`)
+	if err != nil {
+		log.Printf("❌ Error creating gift_redemptions table: %v", err)
+	}
+}
+
+// queryRower runs a single-row query; *sql.DB and *sql.Tx both satisfy it
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// pointsBalance is the user's chat points earned minus the points spent on
+// redemptions. gift_redemptions is the debit ledger for chat_points.
+func pointsBalance(q queryRower, userID string) (int, error) {
+	var balance int
+	err := q.QueryRow(`
+		SELECT (SELECT COALESCE(SUM(points), 0) FROM chat_points WHERE user_id = ?)
+		     - (SELECT COALESCE(SUM(points_spent), 0) FROM gift_redemptions WHERE user_id = ?)
+	`, userID, userID).Scan(&balance)
+	return balance, err
+}
+
+// RedeemGift takes one unit of stock, checks the user's points balance and
+// records the redemption, which debits the gift's points, in a single
+// transaction. The decrement goes through adjustStock, so concurrent
+// redemptions can never oversell or overspend. Returns the redemption and
+// remaining stock.
+func RedeemGift(giftID int, userID string) (Redemption, int, error) {
+	redemption := Redemption{GiftID: giftID, UserID: userID, Status: RedemptionPending}
+	var remaining int
+
+	err := dbutil.WithTx(db, func(tx *sql.Tx) error {
+		// Write first so the transaction takes SQLite's write lock up front;
+		// read-then-write transactions can deadlock under concurrency
+		var stockErr error
+		remaining, stockErr = adjustStock(tx, giftID, -1)
+		if errors.Is(stockErr, ErrGiftNotFound) {
+			return stockErr
+		}
+
+		var isActive bool
+		err := tx.QueryRow("SELECT is_active, points FROM gifts WHERE id = ?", giftID).
+			Scan(&isActive, &redemption.PointsSpent)
+		if err != nil {
+			return err
+		}
+		if !isActive {
+			return ErrGiftUnavailable // rollback restores the unit taken above
+		}
+		if stockErr != nil {
+			return stockErr
+		}
+
+		balance, err := pointsBalance(tx, userID)
+		if err != nil {
+			return err
+		}
+		if balance < redemption.PointsSpent {
+			return ErrInsufficientPoints
+		}
+
+		result, err := tx.Exec(`
+			INSERT INTO gift_redemptions (gift_id, user_id, points_spent, status)
+			VALUES (?, ?, ?, ?)
+		`, giftID, userID, redemption.PointsSpent, redemption.Status)
+		if err != nil {
+			return err
+		}
+		redemption.ID, _ = result.LastInsertId()
+		return nil
+	})
+	if err != nil {
+		return Redemption{}, 0, err
+	}
+
+	redemption.CreatedAt = time.Now()
+	log.Printf("🎁 Gift %d redeemed by %s (remaining stock: %d)", giftID, userID, remaining)
+	notifyStockChanged(giftID, remaining)
+	checkLowStock(giftID)
+	return redemption, remaining, nil
+}
+
+// RedeemGiftHandler redeems one unit of a gift for the signed-in user,
+// identified by "Authorization: Bearer <Google ID token>"
+func RedeemGiftHandler(c *gin.Context) {
+	giftID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var req struct {
+		UserID string `json:"user_id"` // GIFT_INSECURE_REDEEM only
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			jsonutil.BindError(c, err)
+			return
+		}
+	}
+
+	userID, err := redeemingUser(c, req.UserID)
+	switch {
+	case errors.Is(err, errSignInRequired):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, errAuthNotConfigured):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	redemption, remaining, err := RedeemGift(giftID, userID)
+	switch {
+	case errors.Is(err, ErrGiftNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, ErrGiftUnavailable), errors.Is(err, ErrOutOfStock), errors.Is(err, ErrInsufficientPoints):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		log.Printf("❌ Error redeeming gift %d: %v", giftID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to redeem gift"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":         true,
+		"redemption":      redemption,
+		"remaining_stock": remaining,
+	})
+}
+
+// RedemptionFilter narrows an admin redemption listing
+type RedemptionFilter struct {
+	GiftID      int    // 0 = any gift
+	UserID      string // empty = any user
+	Status      string // empty = any status
+	GiftType    string // empty = any gift type
+	OldestFirst bool   // queue order instead of newest first
+}
+
+// GetRedemptions returns redemptions newest first (or oldest first), with
+// the total matching count, filtered by gift, user, status, gift type and
+// created_at date range
+func GetRedemptions(f RedemptionFilter, p pagination.Params) ([]RedemptionDetail, int, error) {
+	var where pagination.Where
+	if f.GiftID > 0 {
+		where.Add("r.gift_id = ?", f.GiftID)
+	}
+	if f.UserID != "" {
+		where.Add("r.user_id = ?", f.UserID)
+	}
+	if f.Status != "" {
+		where.Add("r.status = ?", f.Status)
+	}
+	if f.GiftType != "" {
+		where.Add("g.type = ?", f.GiftType)
+	}
+	where.DateRange("r.created_at", pagination.DateLayout, p)
+
+	var total int
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM gift_redemptions r
+		LEFT JOIN gifts g ON g.id = r.gift_id`+where.SQL(), where.Args()...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	order := " ORDER BY r.created_at DESC, r.id DESC"
+	if f.OldestFirst {
+		order = " ORDER BY r.created_at ASC, r.id ASC"
+	}
+
+	limitSQL, limitArgs := p.LimitOffset()
+	rows, err := db.Query(`
+		SELECT r.id, r.gift_id, r.user_id, r.points_spent, r.status, r.created_at,
+		       COALESCE(g.name, ''), COALESCE(g.type, ''),
+		       COALESCE(u.username, ''), COALESCE(u.photo_url, '')
+		FROM gift_redemptions r
+		LEFT JOIN gifts g ON g.id = r.gift_id
+		LEFT JOIN chat_users u ON u.id = r.user_id`+where.SQL()+order+limitSQL,
+		append(where.Args(), limitArgs...)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	redemptions := []RedemptionDetail{}
+	for rows.Next() {
+		var rd RedemptionDetail
+		if err := rows.Scan(&rd.ID, &rd.GiftID, &rd.UserID, &rd.PointsSpent, &rd.Status, &rd.CreatedAt,
+			&rd.GiftName, &rd.GiftType, &rd.Username, &rd.UserPhoto); err != nil {
+			log.Printf("Error scanning redemption: %v", err)
+			continue
+		}
+		redemptions = append(redemptions, rd)
+	}
+
+	return redemptions, total, nil
+}
+
+// GetRedemptionsHandler lists redemptions for admins.
+// Query params: gift_id, user_id, from, to, limit (default 50), offset
+func GetRedemptionsHandler(c *gin.Context) {
+	p, err := pagination.Parse(c, pagination.Options{DefaultLimit: 50, MaxLimit: 500})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	f := RedemptionFilter{UserID: c.Query("user_id")}
+	if v := c.Query("gift_id"); v != "" {
+		if f.GiftID, err = strconv.Atoi(v); err != nil || f.GiftID <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid gift_id"})
+			return
+		}
+	}
+
+	redemptions, total, err := GetRedemptions(f, p)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"redemptions": redemptions,
+		"total":       total,
+		"limit":       p.Limit,
+		"offset":      p.Offset,
+	})
+}
+
+// GetPendingRedemptionsHandler is the fulfillment work queue: pending
+// redemptions oldest first. Query params: type, limit (default 50), offset
+func GetPendingRedemptionsHandler(c *gin.Context) {
+	p, err := pagination.Parse(c, pagination.Options{DefaultLimit: 50, MaxLimit: 500})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	f := RedemptionFilter{
+		Status:      RedemptionPending,
+		GiftType:    c.Query("type"),
+		OldestFirst: true,
+	}
+
+	redemptions, total, err := GetRedemptions(f, p)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"redemptions": redemptions,
+		"total":       total,
+		"limit":       p.Limit,
+		"offset":      p.Offset,
+	})
+}
+
+// GetRedemptionCountHandler returns redemption totals for one gift (admin)
+func GetRedemptionCountHandler(c *gin.Context) {
+	giftID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var total, pending, points int
+	err = db.QueryRow(`
+		SELECT COUNT(*),
+		       COALESCE(SUM(CASE WHEN status = ? THEN 1 ELSE 0 END), 0),
+		       COALESCE(SUM(points_spent), 0)
+		FROM gift_redemptions WHERE gift_id = ?
+	`, RedemptionPending, giftID).Scan(&total, &pending, &points)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"gift_id":      giftID,
+		"total":        total,
+		"pending":      pending,
+		"points_spent": points,
+	})
+}
+
+// Eligibility reasons
+const (
+	ReasonNotActive          = "not_active"
+	ReasonOutOfStock         = "out_of_stock"
+	ReasonInsufficientPoints = "insufficient_points"
+)
+
+// Eligibility describes whether a user can redeem a gift right now
+type Eligibility struct {
+	GiftID         int      `json:"gift_id"`
+	UserID         string   `json:"user_id"`
+	Eligible       bool     `json:"eligible"`
+	IsActive       bool     `json:"is_available"`
+	InStock        bool     `json:"in_stock"`
+	HasPoints      bool     `json:"has_enough_points"`
+	RequiredPoints int      `json:"required_points"`
+	UserPoints     int      `json:"user_points"`
+	Reasons        []string `json:"reasons"`
+}
+
+// CheckEligibility evaluates a gift against the user's stored point balance
+func CheckEligibility(giftID int, userID string) (Eligibility, error) {
+	e := Eligibility{GiftID: giftID, UserID: userID, Reasons: []string{}}
+
+	var stock int
+	err := db.QueryRow("SELECT is_active, stock, points FROM gifts WHERE id = ?", giftID).
+		Scan(&e.IsActive, &stock, &e.RequiredPoints)
+	if err == sql.ErrNoRows {
+		return e, ErrGiftNotFound
+	}
+	if err != nil {
+		return e, err
+	}
+	if e.UserPoints, err = pointsBalance(db, userID); err != nil {
+		return e, err
+	}
+
+	e.InStock = stock > 0
+	e.HasPoints = e.UserPoints >= e.RequiredPoints
+
+	if !e.IsActive {
+		e.Reasons = append(e.Reasons, ReasonNotActive)
+	}
+	if !e.InStock {
+		e.Reasons = append(e.Reasons, ReasonOutOfStock)
+	}
+	if !e.HasPoints {
+		e.Reasons = append(e.Reasons, ReasonInsufficientPoints)
+	}
+	e.Eligible = len(e.Reasons) == 0
+
+	return e, nil
+}
+
+// GetEligibilityHandler reports whether ?user_id= can redeem the gift with
+// their current points balance, listing every reason when not
+func GetEligibilityHandler(c *gin.Context) {
+	giftID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	userID := strings.TrimSpace(c.Query("user_id"))
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id required"})
+		return
+	}
+
+	e, err := CheckEligibility(giftID, userID)
+	if errors.Is(err, ErrGiftNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, e)
+}
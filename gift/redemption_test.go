@@ -0,0 +1,281 @@
+package gift
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// setupTestDB points the package at a fresh in-memory database with the
+// gift tables and the chat_points table balances are read from
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each :memory: connection is its own database
+	database.SetMaxOpenConns(1)
+	t.Cleanup(func() { database.Close() })
+
+	db = database
+	createTable()
+	createRedemptionTable()
+	initLowStock()
+	if _, err := db.Exec(`CREATE TABLE chat_points (user_id TEXT, day TEXT, points INTEGER, last_awarded_at INTEGER, PRIMARY KEY (user_id, day))`); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func addGift(t *testing.T, points, stock int) int {
+	t.Helper()
+	result, err := db.Exec(`INSERT INTO gifts (name, image_link, type, points, stock) VALUES ('Gift', '', 'phone', ?, ?)`, points, stock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := result.LastInsertId()
+	return int(id)
+}
+
+func addPoints(t *testing.T, userID string, points int) {
+	t.Helper()
+	if _, err := db.Exec(`INSERT INTO chat_points (user_id, day, points, last_awarded_at) VALUES (?, '2025-01-01', ?, 0)`, userID, points); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRedeemGiftConcurrentLastUnit(t *testing.T) {
+	setupTestDB(t)
+	giftID := addGift(t, 0, 1)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var redeemed, outOfStock int
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, _, err := RedeemGift(giftID, fmt.Sprintf("user-%d", i))
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				redeemed++
+			case errors.Is(err, ErrOutOfStock):
+				outOfStock++
+			default:
+				t.Errorf("unexpected error: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if redeemed != 1 || outOfStock != 19 {
+		t.Fatalf("redeemed %d, out of stock %d; want 1 and 19", redeemed, outOfStock)
+	}
+	var stock, rows int
+	db.QueryRow("SELECT stock FROM gifts WHERE id = ?", giftID).Scan(&stock)
+	db.QueryRow("SELECT COUNT(*) FROM gift_redemptions WHERE gift_id = ?", giftID).Scan(&rows)
+	if stock != 0 || rows != 1 {
+		t.Fatalf("stock %d, redemptions %d; want 0 and 1", stock, rows)
+	}
+}
+
+func TestRedeemGiftDebitsPoints(t *testing.T) {
+	setupTestDB(t)
+	giftID := addGift(t, 30, 5)
+	addPoints(t, "u1", 50)
+
+	if _, _, err := RedeemGift(giftID, "u1"); err != nil {
+		t.Fatal(err)
+	}
+	if balance, _ := pointsBalance(db, "u1"); balance != 20 {
+		t.Fatalf("balance = %d, want 20", balance)
+	}
+
+	// 20 points left cannot pay for another 30-point gift
+	_, _, err := RedeemGift(giftID, "u1")
+	if !errors.Is(err, ErrInsufficientPoints) {
+		t.Fatalf("err = %v, want ErrInsufficientPoints", err)
+	}
+	var stock int
+	db.QueryRow("SELECT stock FROM gifts WHERE id = ?", giftID).Scan(&stock)
+	if stock != 4 {
+		t.Fatalf("stock = %d, want 4 (the refused redemption must not take a unit)", stock)
+	}
+}
+
+func TestRedeemGiftInactive(t *testing.T) {
+	setupTestDB(t)
+	giftID := addGift(t, 0, 3)
+	db.Exec("UPDATE gifts SET is_active = 0 WHERE id = ?", giftID)
+
+	if _, _, err := RedeemGift(giftID, "u1"); !errors.Is(err, ErrGiftUnavailable) {
+		t.Fatalf("err = %v, want ErrGiftUnavailable", err)
+	}
+	if _, _, err := RedeemGift(9999, "u1"); !errors.Is(err, ErrGiftNotFound) {
+		t.Fatalf("err = %v, want ErrGiftNotFound", err)
+	}
+}
+
+func TestCheckEligibilityUsesStoredBalance(t *testing.T) {
+	setupTestDB(t)
+	giftID := addGift(t, 30, 1)
+	addPoints(t, "rich", 100)
+
+	e, err := CheckEligibility(giftID, "rich")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !e.Eligible || e.UserPoints != 100 {
+		t.Fatalf("rich user: %+v", e)
+	}
+
+	e, err = CheckEligibility(giftID, "poor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Eligible || e.HasPoints || e.UserPoints != 0 {
+		t.Fatalf("poor user: %+v", e)
+	}
+}
+
+func TestCheckEligibilityReasons(t *testing.T) {
+	setupTestDB(t)
+	addPoints(t, "user", 50)
+
+	tests := []struct {
+		name    string
+		points  int
+		stock   int
+		reasons []string
+	}{
+		{"eligible", 50, 1, nil},
+		{"insufficient points", 51, 1, []string{ReasonInsufficientPoints}},
+		{"out of stock", 10, 0, []string{ReasonOutOfStock}},
+		{"both", 100, 0, []string{ReasonOutOfStock, ReasonInsufficientPoints}},
+	}
+	for _, tt := range tests {
+		giftID := addGift(t, tt.points, tt.stock)
+		e, err := CheckEligibility(giftID, "user")
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if e.Eligible != (len(tt.reasons) == 0) || fmt.Sprint(e.Reasons) != fmt.Sprint(tt.reasons) {
+			t.Errorf("%s: eligible %v, reasons %v; want reasons %v", tt.name, e.Eligible, e.Reasons, tt.reasons)
+		}
+	}
+
+	if _, err := CheckEligibility(9999, "user"); !errors.Is(err, ErrGiftNotFound) {
+		t.Errorf("missing gift: err %v, want ErrGiftNotFound", err)
+	}
+}
+
+func redeemRequest(giftID int, body string, headers map[string]string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/gifts/:id/redeem", RedeemGiftHandler)
+
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/gifts/%d/redeem", giftID), strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRedeemGiftHandlerIdentity(t *testing.T) {
+	setupTestDB(t)
+	giftID := addGift(t, 0, 5)
+	t.Cleanup(func() { googleClientID, insecureRedeem = "", false })
+
+	// Not configured: refused
+	googleClientID, insecureRedeem = "", false
+	if w := redeemRequest(giftID, `{"user_id":"u1"}`, nil); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("unconfigured: status %d, want 503", w.Code)
+	}
+
+	// Configured: a body user_id is not an identity
+	googleClientID = "client-id"
+	if w := redeemRequest(giftID, `{"user_id":"u1"}`, nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("no token: status %d, want 401", w.Code)
+	}
+	if w := redeemRequest(giftID, "", map[string]string{"Authorization": "Bearer not-a-jwt"}); w.Code != http.StatusUnauthorized {
+		t.Fatalf("bad token: status %d, want 401", w.Code)
+	}
+
+	// Development opt-out trusts the body
+	googleClientID, insecureRedeem = "", true
+	if w := redeemRequest(giftID, `{"user_id":"u1"}`, nil); w.Code != http.StatusOK {
+		t.Fatalf("insecure: status %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestGetPendingRedemptionsHandler(t *testing.T) {
+	setupTestDB(t)
+	if _, err := db.Exec(`CREATE TABLE chat_users (id TEXT PRIMARY KEY, username TEXT, photo_url TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+	card := addNamedGift(t, "Card", "card", 0, 10)
+	phone := addNamedGift(t, "Phone", "phone", 0, 10)
+	for _, r := range []struct {
+		gift             int
+		user, status, at string
+	}{
+		{card, "u1", RedemptionPending, "2026-03-03 10:00:00"},
+		{phone, "u2", RedemptionPending, "2026-03-01 10:00:00"},
+		{card, "u3", RedemptionFulfilled, "2026-02-28 10:00:00"},
+		{card, "u4", RedemptionPending, "2026-03-02 10:00:00"},
+	} {
+		if _, err := db.Exec(`INSERT INTO gift_redemptions (gift_id, user_id, status, created_at) VALUES (?, ?, ?, ?)`,
+			r.gift, r.user, r.status, r.at); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/pending", GetPendingRedemptionsHandler)
+	pending := func(query string) ([]string, int) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pending?"+query, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%q: status %d", query, w.Code)
+		}
+		var body struct {
+			Redemptions []RedemptionDetail `json:"redemptions"`
+			Total       int                `json:"total"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		users := []string{}
+		for _, rd := range body.Redemptions {
+			if rd.Status != RedemptionPending {
+				t.Errorf("%q: listed a %s redemption", query, rd.Status)
+			}
+			users = append(users, rd.UserID)
+		}
+		return users, body.Total
+	}
+
+	// Oldest first, so the longest-waiting request is fulfilled first
+	if users, total := pending(""); !sameNames(users, []string{"u2", "u4", "u1"}) || total != 3 {
+		t.Errorf("pending = %v (total %d), want [u2 u4 u1] (3)", users, total)
+	}
+	if users, total := pending("limit=2&offset=1"); !sameNames(users, []string{"u4", "u1"}) || total != 3 {
+		t.Errorf("page = %v (total %d), want [u4 u1] (3)", users, total)
+	}
+	if users, _ := pending("type=card"); !sameNames(users, []string{"u4", "u1"}) {
+		t.Errorf("card pending = %v, want [u4 u1]", users)
+	}
+}
@@ -0,0 +1,154 @@
+package gift
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"burma2d/jsonutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrStockConflict means stock changed since the admin last read it
+var ErrStockConflict = errors.New("stock was changed by another update")
+
+// querier is satisfied by both *sql.DB and *sql.Tx
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// adjustStock atomically adds delta to a gift's stock, refusing to go below
+// zero, and returns the new stock. Concurrent adjustments never lose updates.
+func adjustStock(q querier, giftID, delta int) (int, error) {
+	result, err := q.Exec(`
+		UPDATE gifts SET stock = stock + ?
+		WHERE id = ? AND stock + ? >= 0
+	`, delta, giftID, delta)
+	if err != nil {
+		return 0, err
+	}
+
+	var stock int
+	err = q.QueryRow("SELECT stock FROM gifts WHERE id = ?", giftID).Scan(&stock)
+	if err == sql.ErrNoRows {
+		return 0, ErrGiftNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return stock, ErrOutOfStock
+	}
+	return stock, nil
+}
+
+// AdjustStock adds delta (positive to restock, negative to take) to a gift's stock
+func AdjustStock(giftID, delta int) (int, error) {
+	stock, err := adjustStock(db, giftID, delta)
+	if err == nil {
+		log.Printf("✅ Gift %d stock adjusted by %+d (now %d)", giftID, delta, stock)
+		notifyStockChanged(giftID, stock)
+		checkLowStock(giftID)
+	}
+	return stock, err
+}
+
+// SetStock sets a gift's stock to an absolute value. When expected is given,
+// the write only happens if stock still equals it, so an admin edit based on
+// a stale read can't silently undo redemptions made in the meantime.
+func SetStock(giftID, stock int, expected *int) (int, error) {
+	query := "UPDATE gifts SET stock = ? WHERE id = ?"
+	args := []interface{}{stock, giftID}
+	if expected != nil {
+		query += " AND stock = ?"
+		args = append(args, *expected)
+	}
+
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	var current int
+	err = db.QueryRow("SELECT stock FROM gifts WHERE id = ?", giftID).Scan(&current)
+	if err == sql.ErrNoRows {
+		return 0, ErrGiftNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return current, ErrStockConflict
+	}
+
+	log.Printf("✅ Gift %d stock set to %d", giftID, current)
+	notifyStockChanged(giftID, current)
+	checkLowStock(giftID)
+	return current, nil
+}
+
+// SetStockHandler sets absolute stock (admin).
+// Body: {"stock": N, "expected_stock": M} where expected_stock is optional.
+func SetStockHandler(c *gin.Context) {
+	giftID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var req struct {
+		Stock         *int `json:"stock" binding:"required"`
+		ExpectedStock *int `json:"expected_stock"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		jsonutil.BindError(c, err)
+		return
+	}
+	if *req.Stock < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "stock must not be negative"})
+		return
+	}
+
+	stock, err := SetStock(giftID, *req.Stock, req.ExpectedStock)
+	writeStockResult(c, giftID, stock, err)
+}
+
+// AdjustStockHandler applies a relative stock change (admin).
+// Body: {"delta": N}; the result can never go below zero.
+func AdjustStockHandler(c *gin.Context) {
+	giftID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var req struct {
+		Delta int `json:"delta" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		jsonutil.BindError(c, err)
+		return
+	}
+
+	stock, err := AdjustStock(giftID, req.Delta)
+	writeStockResult(c, giftID, stock, err)
+}
+
+func writeStockResult(c *gin.Context, giftID, stock int, err error) {
+	switch {
+	case errors.Is(err, ErrGiftNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrOutOfStock), errors.Is(err, ErrStockConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "available_stock": stock})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, gin.H{"success": true, "gift_id": giftID, "available_stock": stock})
+	}
+}
@@ -0,0 +1,111 @@
+package gift
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func stockOf(t *testing.T, giftID int) int {
+	t.Helper()
+	var stock int
+	if err := db.QueryRow("SELECT stock FROM gifts WHERE id = ?", giftID).Scan(&stock); err != nil {
+		t.Fatal(err)
+	}
+	return stock
+}
+
+func TestSetStockRejectsStaleExpected(t *testing.T) {
+	setupTestDB(t)
+	giftID := addGift(t, 0, 5)
+
+	// The admin read 5, then a user redeemed one
+	if _, _, err := RedeemGift(giftID, "user"); err != nil {
+		t.Fatal(err)
+	}
+	expected := 5
+	stock, err := SetStock(giftID, 10, &expected)
+	if !errors.Is(err, ErrStockConflict) || stock != 4 {
+		t.Fatalf("SetStock = %d, %v; want 4 and ErrStockConflict", stock, err)
+	}
+	if got := stockOf(t, giftID); got != 4 {
+		t.Fatalf("stock = %d, the stale write must not land", got)
+	}
+}
+
+func TestSetStockConcurrentWithRedemptions(t *testing.T) {
+	setupTestDB(t)
+	const initial, target = 20, 100
+	giftID := addGift(t, 0, initial)
+
+	var wg sync.WaitGroup
+	var redeemed atomic.Int32
+	for i := 0; i < initial; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, _, err := RedeemGift(giftID, fmt.Sprintf("user-%d", i)); err == nil {
+				redeemed.Add(1)
+			} else if !errors.Is(err, ErrOutOfStock) {
+				t.Errorf("redeem: %v", err)
+			}
+		}(i)
+	}
+
+	// The admin retries with a fresh read until the set lands
+	var setAt int
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			var read int
+			db.QueryRow("SELECT stock FROM gifts WHERE id = ?", giftID).Scan(&read)
+			_, err := SetStock(giftID, target, &read)
+			if err == nil {
+				setAt = read
+				return
+			}
+			if !errors.Is(err, ErrStockConflict) {
+				t.Errorf("set: %v", err)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+
+	// Redemptions before the set are overwritten by it; the rest come out of the new stock
+	after := int(redeemed.Load()) - (initial - setAt)
+	if got := stockOf(t, giftID); got != target-after {
+		t.Fatalf("stock = %d, want %d (%d redeemed, set at %d)", got, target-after, redeemed.Load(), setAt)
+	}
+}
+
+func TestAdjustStockConcurrent(t *testing.T) {
+	setupTestDB(t)
+	giftID := addGift(t, 0, 5)
+
+	var wg sync.WaitGroup
+	var taken, refused atomic.Int32
+	for i := 0; i < 30; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if _, err := AdjustStock(giftID, -1); err == nil {
+				taken.Add(1)
+			} else if errors.Is(err, ErrOutOfStock) {
+				refused.Add(1)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			AdjustStock(giftID, 1)
+		}()
+	}
+	wg.Wait()
+
+	if got, want := stockOf(t, giftID), 5+30-int(taken.Load()); got != want || got < 0 {
+		t.Fatalf("stock = %d, want %d (%d taken, %d refused)", got, want, taken.Load(), refused.Load())
+	}
+}
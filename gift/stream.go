@@ -0,0 +1,142 @@
+package gift
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"burma2d/shutdown"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Gift stream event types
+const (
+	EventGiftAvailable = "gift_available" // created, edited or restored while active and in stock
+	EventStockChanged  = "stock_changed"  // redeemed or restocked
+	EventGiftRemoved   = "gift_removed"   // deleted
+	EventServerClosing = shutdown.EventType
+)
+
+// GiftEvent is pushed to gift stream clients
+type GiftEvent struct {
+	Type   string           `json:"type"`
+	GiftID int              `json:"gift_id"`
+	Gift   *Gift            `json:"gift,omitempty"`   // gift_available only
+	Stock  *int             `json:"stock,omitempty"`  // stock_changed only
+	Notice *shutdown.Notice `json:"notice,omitempty"` // server_closing only
+}
+
+var (
+	streamClients      = make(map[chan string]bool)
+	streamClientsMutex sync.RWMutex
+)
+
+// StreamHandler streams gift events over SSE for GET /api/burma2d/gifts/stream
+func StreamHandler(c *gin.Context) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+
+	clientChan := make(chan string, 20)
+
+	streamClientsMutex.Lock()
+	streamClients[clientChan] = true
+	streamClientsMutex.Unlock()
+
+	defer func() {
+		streamClientsMutex.Lock()
+		delete(streamClients, clientChan)
+		streamClientsMutex.Unlock()
+	}()
+
+	c.Writer.Write([]byte("data: {\"type\":\"connected\"}\n\n"))
+	c.Writer.Flush()
+
+	// Heartbeat keeps idle connections open through proxies
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+
+	notify := c.Request.Context().Done()
+	for {
+		select {
+		case <-notify:
+			return
+		case <-ticker.C:
+			if _, err := c.Writer.Write([]byte(": heartbeat\n\n")); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case message := <-clientChan:
+			if _, err := c.Writer.Write([]byte(fmt.Sprintf("data: %s\n\n", message))); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// StreamClientCount returns the number of connected gift stream clients
+func StreamClientCount() int {
+	streamClientsMutex.RLock()
+	defer streamClientsMutex.RUnlock()
+	return len(streamClients)
+}
+
+// broadcastGiftEvent sends an event to every stream client, dropping it for
+// clients whose buffer is full rather than blocking the caller
+func broadcastGiftEvent(event GiftEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("❌ Failed to marshal gift event: %v", err)
+		return
+	}
+	message := string(data)
+
+	streamClientsMutex.RLock()
+	defer streamClientsMutex.RUnlock()
+	for clientChan := range streamClients {
+		select {
+		case clientChan <- message:
+		default:
+		}
+	}
+}
+
+// notifyGiftAvailable broadcasts gift_available when the gift is active,
+// not deleted and in stock
+func notifyGiftAvailable(giftID int) {
+	if StreamClientCount() == 0 {
+		return
+	}
+
+	var g Gift
+	err := db.QueryRow(`
+		SELECT id, name, image_link, type, description, points, stock, is_active, created_at
+		FROM gifts
+		WHERE id = ? AND is_active = 1 AND deleted_at IS NULL AND stock > 0
+	`, giftID).Scan(&g.ID, &g.Name, &g.ImageLink, &g.Type, &g.Description,
+		&g.Points, &g.Stock, &g.IsActive, &g.CreatedAt)
+	if err != nil {
+		return
+	}
+
+	broadcastGiftEvent(GiftEvent{Type: EventGiftAvailable, GiftID: g.ID, Gift: &g})
+}
+
+// notifyStockChanged broadcasts a gift's new stock level
+func notifyStockChanged(giftID, stock int) {
+	broadcastGiftEvent(GiftEvent{Type: EventStockChanged, GiftID: giftID, Stock: &stock})
+}
+
+// notifyGiftRemoved broadcasts that a gift is no longer offered
+func notifyGiftRemoved(giftID int) {
+	broadcastGiftEvent(GiftEvent{Type: EventGiftRemoved, GiftID: giftID})
+}
+
+// notifyClosing tells stream clients the server is shutting down
+func notifyClosing(n shutdown.Notice) {
+	broadcastGiftEvent(GiftEvent{Type: EventServerClosing, Notice: &n})
+}
@@ -0,0 +1,100 @@
+package gift
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// openGiftStream connects to the gift stream and returns its events as they arrive
+func openGiftStream(t *testing.T) <-chan GiftEvent {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/stream", StreamHandler)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	resp, err := http.Get(srv.URL + "/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+
+	events := make(chan GiftEvent, 10)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			payload, ok := strings.CutPrefix(scanner.Text(), "data: ")
+			if !ok {
+				continue
+			}
+			var event GiftEvent
+			if json.Unmarshal([]byte(payload), &event) == nil {
+				events <- event
+			}
+		}
+	}()
+
+	if event := nextGiftEvent(t, events); event.Type != "connected" {
+		t.Fatalf("first event = %+v, want connected", event)
+	}
+	return events
+}
+
+func nextGiftEvent(t *testing.T, events <-chan GiftEvent) GiftEvent {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no gift event within 1s")
+		return GiftEvent{}
+	}
+}
+
+func TestGiftUpdateBroadcastsAvailability(t *testing.T) {
+	setupTestDB(t)
+	giftID := addNamedGift(t, "Phone", "phone", 300, 2)
+	events := openGiftStream(t)
+
+	err := UpdateGift(Gift{ID: giftID, Name: "Phone X", Type: "phone", Points: 250, IsActive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := nextGiftEvent(t, events)
+	if event.Type != EventGiftAvailable || event.GiftID != giftID || event.Gift == nil ||
+		event.Gift.Name != "Phone X" || event.Gift.Points != 250 || event.Gift.Stock != 2 {
+		t.Fatalf("event = %+v, want gift_available with the updated gift", event)
+	}
+
+	addPoints(t, "user", 250)
+	if _, _, err := RedeemGift(giftID, "user"); err != nil {
+		t.Fatal(err)
+	}
+	event = nextGiftEvent(t, events)
+	if event.Type != EventStockChanged || event.Stock == nil || *event.Stock != 1 {
+		t.Fatalf("event = %+v, want stock_changed to 1", event)
+	}
+}
+
+func TestGiftUpdateSkipsUnavailable(t *testing.T) {
+	setupTestDB(t)
+	soldOut := addNamedGift(t, "Card", "card", 100, 0)
+	events := openGiftStream(t)
+
+	// Out of stock, then deactivated: neither is available
+	UpdateGift(Gift{ID: soldOut, Name: "Card", Type: "card", Points: 100, IsActive: true})
+	if err := DeleteGift(soldOut); err != nil {
+		t.Fatal(err)
+	}
+	if event := nextGiftEvent(t, events); event.Type != EventGiftRemoved || event.GiftID != soldOut {
+		t.Fatalf("event = %+v, want only gift_removed", event)
+	}
+}
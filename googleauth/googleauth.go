@@ -0,0 +1,151 @@
+package googleauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"burma2d/config"
+	"burma2d/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/api/idtoken"
+)
+
+// validateFunc performs the external Google validation; swappable for tests
+var validateFunc = idtoken.Validate
+
+var (
+	cacheTTL = 5 * time.Minute
+
+	cache      = make(map[string]cachedToken)
+	cacheMutex sync.RWMutex
+
+	ipLimiter *ratelimit.Limiter
+	initOnce  sync.Once
+)
+
+// cachedToken is a validated payload and when it stops being trusted
+type cachedToken struct {
+	payload *idtoken.Payload
+	expires time.Time
+}
+
+// Init reads AUTH_RATE_LIMIT (default 10), AUTH_RATE_WINDOW (default 1m)
+// and AUTH_TOKEN_CACHE_TTL (default 5m; 0 disables caching)
+func Init() {
+	initOnce.Do(func() {
+		limit := config.Int("AUTH_RATE_LIMIT", 10)
+		window := config.Duration("AUTH_RATE_WINDOW", time.Minute)
+		ipLimiter = ratelimit.New(limit, window)
+		cacheTTL = config.Duration("AUTH_TOKEN_CACHE_TTL", 5*time.Minute)
+
+		go cleanupLoop()
+		log.Printf("✅ Auth rate limit: %d per %s per IP, token cache TTL %s", limit, window, cacheTTL)
+	})
+}
+
+// Validate checks a Google ID token, reusing a recent successful validation
+// of the same token. Cache entries never outlive the token's own expiry.
+func Validate(ctx context.Context, token, audience string) (*idtoken.Payload, error) {
+	key := cacheKey(token, audience)
+	now := time.Now()
+
+	cacheMutex.RLock()
+	entry, ok := cache[key]
+	cacheMutex.RUnlock()
+	if ok && now.Before(entry.expires) {
+		return entry.payload, nil
+	}
+
+	payload, err := validateFunc(ctx, token, audience)
+	if err != nil {
+		return nil, err
+	}
+
+	if cacheTTL > 0 {
+		expires := now.Add(cacheTTL)
+		if tokenExp := time.Unix(payload.Expires, 0); tokenExp.Before(expires) {
+			expires = tokenExp
+		}
+		cacheMutex.Lock()
+		cache[key] = cachedToken{payload: payload, expires: expires}
+		cacheMutex.Unlock()
+	}
+
+	return payload, nil
+}
+
+// ErrNoToken is returned by Authenticate for requests without a bearer token
+var ErrNoToken = errors.New("ID token required")
+
+// Authenticate validates the request's "Authorization: Bearer <ID token>"
+// for audience and returns the verified Google subject, which is the chat
+// user ID on every transport
+func Authenticate(c *gin.Context, audience string) (string, error) {
+	auth := c.GetHeader("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return "", ErrNoToken
+	}
+	token := strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	if token == "" {
+		return "", ErrNoToken
+	}
+	if audience == "" {
+		return "", errors.New("GOOGLE_OAUTH_CLIENT_ID not configured")
+	}
+
+	payload, err := Validate(c.Request.Context(), token, audience)
+	if err != nil {
+		return "", err
+	}
+	if payload.Subject == "" {
+		return "", errors.New("ID token has no subject")
+	}
+	return payload.Subject, nil
+}
+
+// cacheKey hashes the token so raw credentials are never kept in memory
+func cacheKey(token, audience string) string {
+	sum := sha256.Sum256([]byte(audience + "\x00" + token))
+	return hex.EncodeToString(sum[:])
+}
+
+// cleanupLoop drops expired cache entries
+func cleanupLoop() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		cacheMutex.Lock()
+		for key, entry := range cache {
+			if !now.Before(entry.expires) {
+				delete(cache, key)
+			}
+		}
+		cacheMutex.Unlock()
+	}
+}
+
+// RateLimit throttles auth attempts per client IP, returning 429 with
+// retry_after when exceeded
+func RateLimit() gin.HandlerFunc {
+	Init()
+	return func(c *gin.Context) {
+		if ok, wait := ipLimiter.Allow(c.ClientIP()); !ok {
+			log.Printf("⚠️ Auth rate limit hit for %s", c.ClientIP())
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":       "Too many authentication attempts",
+				"retry_after": ratelimit.RetryAfterSeconds(wait),
+			})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,169 @@
+package googleauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"burma2d/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/api/idtoken"
+)
+
+// fakeValidator replaces validateFunc and counts external validations
+type fakeValidator struct {
+	calls   int
+	expires time.Time
+}
+
+func useFakeValidator(t *testing.T, expires time.Time) *fakeValidator {
+	t.Helper()
+	f := &fakeValidator{expires: expires}
+	oldValidate, oldTTL := validateFunc, cacheTTL
+	validateFunc = f.validate
+	cacheTTL = 5 * time.Minute
+	resetCache()
+	t.Cleanup(func() {
+		validateFunc, cacheTTL = oldValidate, oldTTL
+		resetCache()
+	})
+	return f
+}
+
+func resetCache() {
+	cacheMutex.Lock()
+	cache = make(map[string]cachedToken)
+	cacheMutex.Unlock()
+}
+
+func (f *fakeValidator) validate(_ context.Context, token, _ string) (*idtoken.Payload, error) {
+	f.calls++
+	if token == "bad" {
+		return nil, errors.New("invalid token")
+	}
+	return &idtoken.Payload{Subject: "sub-" + token, Expires: f.expires.Unix()}, nil
+}
+
+func TestValidateCachesWithinWindow(t *testing.T) {
+	f := useFakeValidator(t, time.Now().Add(time.Hour))
+
+	for i := 0; i < 3; i++ {
+		payload, err := Validate(context.Background(), "tok", "aud")
+		if err != nil || payload.Subject != "sub-tok" {
+			t.Fatalf("Validate = %+v, %v", payload, err)
+		}
+	}
+	if f.calls != 1 {
+		t.Fatalf("external validations = %d, want 1", f.calls)
+	}
+
+	// Another audience is a separate entry
+	Validate(context.Background(), "tok", "other")
+	if f.calls != 2 {
+		t.Fatalf("external validations = %d, want 2 after a new audience", f.calls)
+	}
+}
+
+func TestValidateRevalidatesExpiredEntry(t *testing.T) {
+	f := useFakeValidator(t, time.Now().Add(time.Hour))
+
+	Validate(context.Background(), "tok", "aud")
+
+	// Age the entry past its cache window
+	key := cacheKey("tok", "aud")
+	cacheMutex.Lock()
+	entry := cache[key]
+	entry.expires = time.Now().Add(-time.Second)
+	cache[key] = entry
+	cacheMutex.Unlock()
+
+	Validate(context.Background(), "tok", "aud")
+	if f.calls != 2 {
+		t.Fatalf("external validations = %d, want an expired entry re-validated", f.calls)
+	}
+}
+
+func TestValidateCacheNeverOutlivesToken(t *testing.T) {
+	f := useFakeValidator(t, time.Now().Add(-time.Second))
+
+	Validate(context.Background(), "tok", "aud")
+	Validate(context.Background(), "tok", "aud")
+	if f.calls != 2 {
+		t.Fatalf("external validations = %d, want an expired token never served from cache", f.calls)
+	}
+}
+
+func TestValidateDoesNotCacheFailures(t *testing.T) {
+	f := useFakeValidator(t, time.Now().Add(time.Hour))
+
+	for i := 0; i < 2; i++ {
+		if _, err := Validate(context.Background(), "bad", "aud"); err == nil {
+			t.Fatal("bad token accepted")
+		}
+	}
+	if f.calls != 2 {
+		t.Fatalf("external validations = %d, want failures re-checked", f.calls)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	useFakeValidator(t, time.Now().Add(time.Hour))
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name, header, audience, subject string
+		noToken, fails                  bool
+	}{
+		{name: "valid", header: "Bearer tok", audience: "aud", subject: "sub-tok"},
+		{name: "missing", header: "", audience: "aud", noToken: true},
+		{name: "not bearer", header: "Basic tok", audience: "aud", noToken: true},
+		{name: "empty bearer", header: "Bearer  ", audience: "aud", noToken: true},
+		{name: "no audience", header: "Bearer tok", audience: "", fails: true},
+		{name: "invalid", header: "Bearer bad", audience: "aud", fails: true},
+	}
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+		if tt.header != "" {
+			c.Request.Header.Set("Authorization", tt.header)
+		}
+
+		subject, err := Authenticate(c, tt.audience)
+		switch {
+		case tt.noToken:
+			if !errors.Is(err, ErrNoToken) {
+				t.Errorf("%s: err %v, want ErrNoToken", tt.name, err)
+			}
+		case tt.fails:
+			if err == nil || errors.Is(err, ErrNoToken) {
+				t.Errorf("%s: err %v, want a validation error", tt.name, err)
+			}
+		default:
+			if err != nil || subject != tt.subject {
+				t.Errorf("%s: %q, %v; want %q", tt.name, subject, err, tt.subject)
+			}
+		}
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/auth", RateLimit(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	old := ipLimiter
+	ipLimiter = ratelimit.New(2, time.Minute)
+	t.Cleanup(func() { ipLimiter = old })
+
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/auth", nil))
+		if w.Code != want {
+			t.Errorf("attempt %d: status %d, want %d", i+1, w.Code, want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"burma2d/admin"
+	"burma2d/chat"
+	"burma2d/chatws"
+	"burma2d/fcm"
+	"burma2d/gift"
+	"burma2d/live"
+	"burma2d/twodhistory"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pingTimeout bounds the database check so a stuck DB can't hang probes
+const pingTimeout = 500 * time.Millisecond
+
+var startTime = time.Now()
+
+// Handler reports overall status and per-dependency checks for probes.
+// Returns 503 when the database is unreachable, 200 otherwise.
+func Handler(c *gin.Context) {
+	status := http.StatusOK
+	dbStatus := "ok"
+
+	if db := twodhistory.GetDB(); db == nil {
+		dbStatus = "disabled"
+		status = http.StatusServiceUnavailable
+	} else {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), pingTimeout)
+		defer cancel()
+		if err := db.PingContext(ctx); err != nil {
+			dbStatus = "error"
+			status = http.StatusServiceUnavailable
+		}
+	}
+
+	overall := "ok"
+	if status != http.StatusOK {
+		overall = "unavailable"
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, gin.H{
+		"status":         overall,
+		"uptime_seconds": int64(time.Since(startTime).Seconds()),
+		"checks": gin.H{
+			"database": dbStatus,
+			"fcm":      fcm.IsInitialized(),
+			"r2":       admin.IsR2Enabled(),
+		},
+		"clients": gin.H{
+			"live_sse": live.ClientCount(),
+			"chat_sse": chat.ClientCount(),
+			"chat_ws":  chatws.ClientCount(),
+			"gift_sse": gift.StreamClientCount(),
+		},
+	})
+}
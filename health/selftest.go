@@ -0,0 +1,159 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"strings"
+	"time"
+
+	"burma2d/admin"
+	"burma2d/config"
+	"burma2d/fcm"
+	"burma2d/twodhistory"
+)
+
+// checkTimeout bounds each self-test check
+const checkTimeout = 5 * time.Second
+
+// errSkipped marks a check that does not apply to this configuration
+var errSkipped = errors.New("skipped")
+
+// requiredTables must exist once the database modules have initialized
+var requiredTables = []string{
+	"twodhistory", "threed", "gifts", "gift_redemptions", "sliders",
+	"paper_types", "paper_images", "chat_users", "chat_messages", "fcm_devices",
+}
+
+// Check is one self-test step. Critical failures can stop startup.
+type Check struct {
+	Name     string
+	Critical bool
+	Run      func(ctx context.Context) error
+}
+
+// CheckResult is the outcome of a single Check
+type CheckResult struct {
+	Name     string
+	Critical bool
+	Status   string // "pass", "fail" or "skip"
+	Err      error
+}
+
+// RunSelfTest runs every check, logs a pass/fail summary and reports whether
+// any critical check failed
+func RunSelfTest(ctx context.Context, checks []Check) (results []CheckResult, criticalFailed bool) {
+	for _, check := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+		err := check.Run(checkCtx)
+		cancel()
+
+		result := CheckResult{Name: check.Name, Critical: check.Critical, Status: "pass"}
+		switch {
+		case errors.Is(err, errSkipped):
+			result.Status = "skip"
+			log.Printf("ℹ️  Self-test %-10s skipped", check.Name)
+		case err != nil:
+			result.Status = "fail"
+			result.Err = err
+			if check.Critical {
+				criticalFailed = true
+				log.Printf("❌ Self-test %-10s FAILED (critical): %v", check.Name, err)
+			} else {
+				log.Printf("⚠️ Self-test %-10s failed: %v", check.Name, err)
+			}
+		default:
+			log.Printf("✅ Self-test %-10s passed", check.Name)
+		}
+		results = append(results, result)
+	}
+
+	var passed, failed, skipped int
+	for _, r := range results {
+		switch r.Status {
+		case "pass":
+			passed++
+		case "fail":
+			failed++
+		case "skip":
+			skipped++
+		}
+	}
+	log.Printf("📝 Self-test summary: %d passed, %d failed, %d skipped", passed, failed, skipped)
+	return results, criticalFailed
+}
+
+// StartupChecks returns the subsystem checks run on boot
+func StartupChecks(templateGlob string) []Check {
+	return []Check{
+		{Name: "database", Critical: true, Run: checkDatabase},
+		{Name: "schema", Critical: true, Run: checkSchema},
+		{Name: "timezone", Critical: true, Run: checkTimezone},
+		{Name: "templates", Critical: true, Run: func(context.Context) error {
+			_, err := template.ParseGlob(templateGlob)
+			return err
+		}},
+		{Name: "fcm", Run: fcm.Ping},
+		{Name: "r2", Run: func(ctx context.Context) error {
+			if !config.Bool("USE_R2", false) {
+				return errSkipped
+			}
+			return admin.CheckR2(ctx)
+		}},
+	}
+}
+
+// RunStartupSelfTest runs StartupChecks when SELFTEST_ON_STARTUP=true. With
+// SELFTEST_STRICT=true a critical failure is returned as an error so the
+// caller can refuse to start.
+func RunStartupSelfTest(templateGlob string) error {
+	if !config.Bool("SELFTEST_ON_STARTUP", false) {
+		return nil
+	}
+
+	log.Println("🔍 Running startup self-test...")
+	_, criticalFailed := RunSelfTest(context.Background(), StartupChecks(templateGlob))
+	if criticalFailed && config.Bool("SELFTEST_STRICT", false) {
+		return fmt.Errorf("startup self-test failed a critical check (SELFTEST_STRICT=true)")
+	}
+	return nil
+}
+
+func checkDatabase(ctx context.Context) error {
+	db := twodhistory.GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+	return db.PingContext(ctx)
+}
+
+func checkSchema(ctx context.Context) error {
+	db := twodhistory.GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	var missing []string
+	for _, table := range requiredTables {
+		var name string
+		err := db.QueryRowContext(ctx,
+			"SELECT name FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&name)
+		if err == sql.ErrNoRows {
+			missing = append(missing, table)
+		} else if err != nil {
+			return err
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing tables: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func checkTimezone(context.Context) error {
+	_, err := time.LoadLocation("Asia/Yangon")
+	return err
+}
@@ -0,0 +1,98 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func statuses(results []CheckResult) map[string]string {
+	m := make(map[string]string)
+	for _, r := range results {
+		m[r.Name] = r.Status
+	}
+	return m
+}
+
+func TestRunSelfTestReportsFailures(t *testing.T) {
+	pass := func(context.Context) error { return nil }
+	broken := func(context.Context) error { return errors.New("connection refused") }
+	skip := func(context.Context) error { return errSkipped }
+
+	results, criticalFailed := RunSelfTest(context.Background(), []Check{
+		{Name: "ok", Critical: true, Run: pass},
+		{Name: "optional", Run: broken},
+		{Name: "unused", Run: skip},
+	})
+	if criticalFailed {
+		t.Error("a non-critical failure was reported as critical")
+	}
+	got := statuses(results)
+	if got["ok"] != "pass" || got["optional"] != "fail" || got["unused"] != "skip" {
+		t.Errorf("statuses = %v", got)
+	}
+	if results[1].Err == nil || results[1].Err.Error() != "connection refused" {
+		t.Errorf("failure error = %v", results[1].Err)
+	}
+
+	_, criticalFailed = RunSelfTest(context.Background(), []Check{
+		{Name: "database", Critical: true, Run: broken},
+		{Name: "ok", Run: pass},
+	})
+	if !criticalFailed {
+		t.Error("a critical failure was not reported")
+	}
+}
+
+func TestRunSelfTestStopsWithContext(t *testing.T) {
+	hang := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, criticalFailed := RunSelfTest(ctx, []Check{{Name: "hangs", Critical: true, Run: hang}})
+	if !criticalFailed || !errors.Is(results[0].Err, context.Canceled) {
+		t.Fatalf("hanging check = %+v, want a critical failure", results[0])
+	}
+}
+
+func TestStartupChecksSimulatedBrokenSubsystems(t *testing.T) {
+	t.Setenv("USE_R2", "false")
+
+	// No database is open and the template glob matches nothing
+	results, criticalFailed := RunSelfTest(context.Background(), StartupChecks(t.TempDir()+"/*.html"))
+	if !criticalFailed {
+		t.Fatal("broken database and templates were not critical")
+	}
+	got := statuses(results)
+	want := map[string]string{
+		"database": "fail", "schema": "fail", "timezone": "pass",
+		"templates": "fail", "fcm": "fail", "r2": "skip",
+	}
+	for name, status := range want {
+		if got[name] != status {
+			t.Errorf("%s: %s, want %s", name, got[name], status)
+		}
+	}
+}
+
+func TestRunStartupSelfTest(t *testing.T) {
+	glob := t.TempDir() + "/*.html"
+
+	t.Setenv("SELFTEST_ON_STARTUP", "false")
+	if err := RunStartupSelfTest(glob); err != nil {
+		t.Errorf("disabled self-test returned %v", err)
+	}
+
+	t.Setenv("SELFTEST_ON_STARTUP", "true")
+	if err := RunStartupSelfTest(glob); err != nil {
+		t.Errorf("non-strict self-test returned %v", err)
+	}
+
+	t.Setenv("SELFTEST_STRICT", "true")
+	if err := RunStartupSelfTest(glob); err == nil {
+		t.Error("strict self-test started despite critical failures")
+	}
+}
@@ -0,0 +1,105 @@
+package jsonutil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError describes one invalid field in a request body. Field is the
+// JSON name, or empty when the body as a whole could not be parsed.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// useJSONFieldNames makes validation errors report JSON names (user_id)
+// rather than Go field names (UserID)
+func useJSONFieldNames() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return f.Name
+		}
+		return name
+	})
+}
+
+// FieldErrors translates a binding error into field-level errors
+func FieldErrors(err error) []FieldError {
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+
+	switch {
+	case errors.As(err, &validationErrs):
+		fields := make([]FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			fields = append(fields, FieldError{Field: fe.Field(), Message: validationMessage(fe)})
+		}
+		return fields
+	case errors.As(err, &typeErr):
+		return []FieldError{{Field: typeErr.Field, Message: fmt.Sprintf("%s must be a %s", typeErr.Field, jsonType(typeErr.Type))}}
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return []FieldError{{Message: "Request body is not valid JSON"}}
+	case errors.Is(err, io.EOF):
+		return []FieldError{{Message: "Request body is empty"}}
+	}
+	return []FieldError{{Message: err.Error()}}
+}
+
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return fe.Field() + " is required"
+	case "min":
+		return fmt.Sprintf("%s must be at least %s", fe.Field(), fe.Param())
+	case "max":
+		return fmt.Sprintf("%s must be at most %s", fe.Field(), fe.Param())
+	case "oneof":
+		return fmt.Sprintf("%s must be one of: %s", fe.Field(), fe.Param())
+	}
+	return fmt.Sprintf("%s is invalid (%s)", fe.Field(), fe.Tag())
+}
+
+// jsonType names a Go type the way a client sees it in JSON
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	}
+	return "object"
+}
+
+// BindError responds 400 for a failed ShouldBindJSON. "error" keeps a
+// single readable message; "errors" lists every invalid field.
+func BindError(c *gin.Context, err error) {
+	fields := FieldErrors(err)
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":  fields[0].Message,
+		"errors": fields,
+	})
+}
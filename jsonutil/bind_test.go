@@ -0,0 +1,104 @@
+package jsonutil
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type sendRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+	Text   string `json:"text" binding:"required,max=5"`
+	Count  int    `json:"count"`
+}
+
+type bindResponse struct {
+	Error  string       `json:"error"`
+	Errors []FieldError `json:"errors"`
+}
+
+// bind posts body to a handler that binds sendRequest
+func bind(t *testing.T, body string) (int, bindResponse) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/", func(c *gin.Context) {
+		var req sendRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			BindError(c, err)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+	var resp bindResponse
+	if w.Code != http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("body %s: %v", w.Body.String(), err)
+		}
+	}
+	return w.Code, resp
+}
+
+func TestBindErrorMissingRequiredField(t *testing.T) {
+	useJSONFieldNames()
+
+	code, resp := bind(t, `{"text": "hi"}`)
+	if code != http.StatusBadRequest {
+		t.Fatalf("status %d, want 400", code)
+	}
+	want := FieldError{Field: "user_id", Message: "user_id is required"}
+	if len(resp.Errors) != 1 || resp.Errors[0] != want {
+		t.Fatalf("errors = %+v, want [%+v]", resp.Errors, want)
+	}
+	if resp.Error != want.Message {
+		t.Errorf("error = %q, want the first field message", resp.Error)
+	}
+}
+
+func TestBindErrorListsEveryField(t *testing.T) {
+	useJSONFieldNames()
+
+	_, resp := bind(t, `{"text": "too long"}`)
+	want := []FieldError{
+		{Field: "user_id", Message: "user_id is required"},
+		{Field: "text", Message: "text must be at most 5"},
+	}
+	if len(resp.Errors) != len(want) {
+		t.Fatalf("errors = %+v, want %+v", resp.Errors, want)
+	}
+	for i := range want {
+		if resp.Errors[i] != want[i] {
+			t.Errorf("errors[%d] = %+v, want %+v", i, resp.Errors[i], want[i])
+		}
+	}
+}
+
+func TestBindErrorMalformedBody(t *testing.T) {
+	tests := []struct {
+		name, body string
+		want       FieldError
+	}{
+		{"wrong type", `{"user_id": "u", "text": "hi", "count": "three"}`, FieldError{Field: "count", Message: "count must be a number"}},
+		{"invalid json", `{"user_id": `, FieldError{Message: "Request body is not valid JSON"}},
+		{"empty", ``, FieldError{Message: "Request body is empty"}},
+	}
+	for _, tt := range tests {
+		code, resp := bind(t, tt.body)
+		if code != http.StatusBadRequest || len(resp.Errors) != 1 || resp.Errors[0] != tt.want {
+			t.Errorf("%s: %d %+v, want 400 [%+v]", tt.name, code, resp.Errors, tt.want)
+		}
+	}
+}
+
+func TestBindValidBody(t *testing.T) {
+	if code, _ := bind(t, `{"user_id": "u", "text": "hi"}`); code != http.StatusOK {
+		t.Fatalf("status %d, want 200", code)
+	}
+}
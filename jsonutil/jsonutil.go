@@ -0,0 +1,57 @@
+package jsonutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+
+	"burma2d/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxPooledBuffer keeps unusually large buffers out of the pool so one huge
+// response doesn't pin its memory for the life of the process
+const maxPooledBuffer = 1 << 20
+
+var (
+	bufferPool = sync.Pool{
+		New: func() interface{} {
+			return new(bytes.Buffer)
+		},
+	}
+
+	// escapeHTML controls <, > and & escaping. API clients parse the JSON
+	// rather than embedding it in HTML, so it is off unless JSON_ESCAPE_HTML=true.
+	escapeHTML = false
+)
+
+// Init loads encoder settings and makes binding errors use JSON field names
+func Init() {
+	escapeHTML = config.Bool("JSON_ESCAPE_HTML", false)
+	useJSONFieldNames()
+}
+
+// Write encodes v into a pooled buffer and writes it as the JSON response.
+// Use it for large list responses in place of c.JSON.
+func Write(c *gin.Context, status int, v interface{}) {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer func() {
+		if buf.Cap() <= maxPooledBuffer {
+			bufferPool.Put(buf)
+		}
+	}()
+
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(escapeHTML)
+	if err := encoder.Encode(v); err != nil {
+		log.Printf("❌ Failed to encode JSON response: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode response"})
+		return
+	}
+
+	c.Data(status, "application/json; charset=utf-8", buf.Bytes())
+}
@@ -0,0 +1,80 @@
+package jsonutil
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// historyRow has the shape of a 2D history record
+type historyRow struct {
+	ID          int       `json:"history_id"`
+	Date        string    `json:"draw_date"`
+	Set1200     string    `json:"noon_set"`
+	Value1200   string    `json:"noon_value"`
+	Result1200  string    `json:"noon_result"`
+	Set430      string    `json:"evening_set"`
+	Value430    string    `json:"evening_value"`
+	Result430   string    `json:"evening_result"`
+	Modern930   string    `json:"morning_modern"`
+	Internet930 string    `json:"morning_internet"`
+	Modern200   string    `json:"afternoon_modern"`
+	Internet200 string    `json:"afternoon_internet"`
+	CreatedAt   time.Time `json:"created_date"`
+}
+
+func largeHistory(n int) gin.H {
+	rows := make([]historyRow, n)
+	start := time.Date(2020, 1, 1, 16, 30, 0, 0, time.UTC)
+	for i := range rows {
+		day := start.AddDate(0, 0, i)
+		rows[i] = historyRow{
+			ID: i + 1, Date: day.Format("2006/01/02"),
+			Set1200: "1,456.78", Value1200: "23,456.01", Result1200: fmt.Sprintf("%02d", i%100),
+			Set430: "1,460.12", Value430: "45,678.90", Result430: fmt.Sprintf("%02d", (i*7)%100),
+			Modern930: "12", Internet930: "34", Modern200: "56", Internet200: "78",
+			CreatedAt: day,
+		}
+	}
+	return gin.H{"success": true, "data": rows}
+}
+
+func TestWrite(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	Write(c, http.StatusCreated, gin.H{"link": "a?b=1&c=<2>"})
+
+	if w.Code != http.StatusCreated || w.Header().Get("Content-Type") != "application/json; charset=utf-8" {
+		t.Fatalf("status %d, content type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	if got := w.Body.String(); got != `{"link":"a?b=1&c=<2>"}`+"\n" {
+		t.Fatalf("body = %q, want HTML left unescaped", got)
+	}
+}
+
+func benchmarkResponse(b *testing.B, write func(c *gin.Context, v interface{})) {
+	gin.SetMode(gin.TestMode)
+	v := largeHistory(2000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		write(c, v)
+		b.SetBytes(int64(w.Body.Len()))
+	}
+}
+
+func BenchmarkWritePooled(b *testing.B) {
+	benchmarkResponse(b, func(c *gin.Context, v interface{}) { Write(c, http.StatusOK, v) })
+}
+
+func BenchmarkWriteDefault(b *testing.B) {
+	benchmarkResponse(b, func(c *gin.Context, v interface{}) { c.JSON(http.StatusOK, v) })
+}
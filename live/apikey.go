@@ -0,0 +1,46 @@
+package live
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"burma2d/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// headerAPIKey carries the update key; "Authorization: Bearer <key>" also works
+const headerAPIKey = "X-API-Key"
+
+// updateKey is the shared secret required to post updates (LOTTERY_UPDATE_KEY).
+// Without it updates are refused unless insecureUpdates is set.
+var updateKey string
+
+// insecureUpdates accepts updates without a key when LOTTERY_UPDATE_KEY is
+// unset (LOTTERY_INSECURE_UPDATES, development only)
+var insecureUpdates bool
+
+func initAPIKey() {
+	updateKey = config.Secret("LOTTERY_UPDATE_KEY", "")
+	insecureUpdates = config.Bool("LOTTERY_INSECURE_UPDATES", false)
+}
+
+// apiKeyRequired reports whether update requests must carry the key
+func apiKeyRequired() bool {
+	return updateKey != ""
+}
+
+// updatesAllowed reports whether updates can be accepted at all: a key is
+// configured, or the development opt-out is on
+func updatesAllowed() bool {
+	return apiKeyRequired() || insecureUpdates
+}
+
+// validAPIKey checks the request's key against LOTTERY_UPDATE_KEY
+func validAPIKey(c *gin.Context) bool {
+	key := c.GetHeader(headerAPIKey)
+	if auth := c.GetHeader("Authorization"); key == "" && strings.HasPrefix(auth, "Bearer ") {
+		key = strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	return key != "" && subtle.ConstantTimeCompare([]byte(key), []byte(updateKey)) == 1
+}
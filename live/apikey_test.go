@@ -0,0 +1,83 @@
+package live
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func postUpdate(headers map[string]string) int {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/update", UpdateLotteryData)
+
+	req := httptest.NewRequest(http.MethodPost, "/update", strings.NewReader(`{}`))
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func setUpdateKey(t *testing.T, key string, insecure bool) {
+	t.Helper()
+	oldKey, oldInsecure := updateKey, insecureUpdates
+	updateKey, insecureUpdates = key, insecure
+	t.Cleanup(func() { updateKey, insecureUpdates = oldKey, oldInsecure })
+}
+
+func TestUpdateRefusedWithoutConfiguredKey(t *testing.T) {
+	setUpdateKey(t, "", false)
+	if code := postUpdate(nil); code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", code)
+	}
+	if code := postUpdate(map[string]string{headerAPIKey: "anything"}); code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", code)
+	}
+}
+
+func TestUpdateRequiresKey(t *testing.T) {
+	setUpdateKey(t, "secret", false)
+	for name, headers := range map[string]map[string]string{
+		"missing":      nil,
+		"wrong":        {headerAPIKey: "guess"},
+		"wrong bearer": {"Authorization": "Bearer guess"},
+	} {
+		if code := postUpdate(headers); code != http.StatusUnauthorized {
+			t.Errorf("%s key: status = %d, want 401", name, code)
+		}
+	}
+}
+
+func TestValidAPIKey(t *testing.T) {
+	setUpdateKey(t, "secret", false)
+	gin.SetMode(gin.TestMode)
+
+	for _, tc := range []struct {
+		header, value string
+		want          bool
+	}{
+		{headerAPIKey, "secret", true},
+		{"Authorization", "Bearer secret", true},
+		{headerAPIKey, "secret2", false},
+		{"Authorization", "Basic secret", false},
+	} {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, "/update", nil)
+		c.Request.Header.Set(tc.header, tc.value)
+		if got := validAPIKey(c); got != tc.want {
+			t.Errorf("%s: %s = %v, want %v", tc.header, tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestInsecureUpdatesOptOut(t *testing.T) {
+	setUpdateKey(t, "", true)
+	if !updatesAllowed() || apiKeyRequired() {
+		t.Fatal("LOTTERY_INSECURE_UPDATES should allow keyless updates")
+	}
+}
@@ -0,0 +1,28 @@
+package live
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+
+	"burma2d/shutdown"
+)
+
+var (
+	closingOnce    sync.Once
+	closingSignal  = make(chan struct{}) // Closed once the shutdown notice is set
+	closingMessage string
+)
+
+// announceClosing sends the shutdown notice to every stream client, and to
+// any that connect before the server exits, as a named server_closing SSE
+// event so clients that only read unnamed messages ignore it
+func announceClosing(n shutdown.Notice) {
+	closingOnce.Do(func() {
+		data, _ := json.Marshal(n)
+		closingMessage = fmt.Sprintf("event: %s\ndata: %s\n\n", shutdown.EventType, data)
+		close(closingSignal)
+		log.Printf("🛑 Sent server_closing to %d live stream clients", ClientCount())
+	})
+}
@@ -0,0 +1,89 @@
+package live
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"burma2d/shutdown"
+
+	"github.com/gin-gonic/gin"
+)
+
+// resetClosing lets a test announce the shutdown again
+func resetClosing(t *testing.T) {
+	t.Helper()
+	closingOnce, closingSignal, closingMessage = sync.Once{}, make(chan struct{}), ""
+	t.Cleanup(func() {
+		closingOnce, closingSignal, closingMessage = sync.Once{}, make(chan struct{}), ""
+	})
+}
+
+// readLine reads one line from an SSE stream, failing after a second
+func readLine(t *testing.T, lines <-chan string) string {
+	t.Helper()
+	select {
+	case line := <-lines:
+		return line
+	case <-time.After(time.Second):
+		t.Fatal("no line received")
+		return ""
+	}
+}
+
+func TestStreamSendsClosingNotice(t *testing.T) {
+	setupHistory(t, 10, 0)
+	resetSources(t)
+	resetClosing(t)
+	Update(&LotteryDataInput{Date: "2026-03-02", Live: "33"})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/stream", StreamLotteryData)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	lines := make(chan string, 16)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if scanner.Text() != "" {
+				lines <- scanner.Text()
+			}
+		}
+		close(lines)
+	}()
+
+	if line := readLine(t, lines); !strings.HasPrefix(line, "data: {") {
+		t.Fatalf("initial event = %q", line)
+	}
+
+	notice := shutdown.Notice{ClosingInSeconds: 5, ReconnectAfterMs: 10000, ReconnectJitterMs: 20000}
+	announceClosing(notice)
+	// A repeated announcement is not sent twice
+	announceClosing(shutdown.Notice{ClosingInSeconds: 1})
+
+	if line := readLine(t, lines); line != "event: server_closing" {
+		t.Fatalf("event line = %q, want the named closing event", line)
+	}
+	var got shutdown.Notice
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(readLine(t, lines), "data: ")), &got); err != nil || got != notice {
+		t.Fatalf("notice = %+v, %v; want %+v", got, err, notice)
+	}
+
+	// The stream stays open and keeps delivering updates until the server exits
+	Update(&LotteryDataInput{Date: "2026-03-02", Live: "34"})
+	if line := readLine(t, lines); !strings.Contains(line, `"live_number":"34"`) {
+		t.Fatalf("after the notice: %q, want the next update", line)
+	}
+}
@@ -0,0 +1,93 @@
+package live
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"burma2d/config"
+)
+
+// PublishedAtLookup returns when the history row for a draw date was inserted.
+// It returns a zero time when no row exists yet.
+type PublishedAtLookup func(date string) (time.Time, error)
+
+var (
+	publishedAtLookup PublishedAtLookup
+
+	// newResultWindow is how long a published result counts as new
+	newResultWindow = 30 * time.Minute
+
+	published      publishedResult
+	publishedMutex sync.RWMutex
+
+	myanmarLocation = loadMyanmarLocation()
+)
+
+// publishedResult caches the history insert time for one draw date
+type publishedResult struct {
+	date string
+	at   time.Time
+}
+
+func loadMyanmarLocation() *time.Location {
+	loc, err := time.LoadLocation("Asia/Yangon")
+	if err != nil {
+		return time.FixedZone("Myanmar", 6*3600+30*60)
+	}
+	return loc
+}
+
+// initFreshness reads LIVE_NEW_RESULT_MINUTES (default 30)
+func initFreshness() {
+	newResultWindow = time.Duration(config.Int("LIVE_NEW_RESULT_MINUTES", 30)) * time.Minute
+}
+
+// SetPublishedAtLookup sets the callback used to find history insert times
+func SetPublishedAtLookup(lookup PublishedAtLookup) {
+	publishedAtLookup = lookup
+}
+
+// refreshPublished loads the insert time for date. Lookups only happen when
+// the draw date changes or after a history insert, not on every read.
+func refreshPublished(date string, force bool) {
+	if publishedAtLookup == nil || date == "" {
+		return
+	}
+
+	publishedMutex.RLock()
+	known := published.date == date
+	publishedMutex.RUnlock()
+	if known && !force {
+		return
+	}
+
+	at, err := publishedAtLookup(date)
+	if err != nil {
+		log.Printf("⚠️ Failed to look up publish time for %s: %v", date, err)
+		return
+	}
+
+	publishedMutex.Lock()
+	published = publishedResult{date: date, at: at}
+	publishedMutex.Unlock()
+}
+
+// isNewResult reports whether today's result was published within the window.
+// Results from a previous Myanmar calendar day are never new.
+func isNewResult(now time.Time) bool {
+	publishedMutex.RLock()
+	at := published.at
+	publishedMutex.RUnlock()
+
+	if at.IsZero() {
+		return false
+	}
+
+	now = now.In(myanmarLocation)
+	at = at.In(myanmarLocation)
+	if at.Format("2006-01-02") != now.Format("2006-01-02") {
+		return false
+	}
+	return now.Sub(at) < newResultWindow
+}
@@ -0,0 +1,63 @@
+package live
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsNewResult(t *testing.T) {
+	myanmarLocation = loadMyanmarLocation()
+	old := newResultWindow
+	t.Cleanup(func() {
+		newResultWindow = old
+		published = publishedResult{}
+	})
+	newResultWindow = 30 * time.Minute
+
+	utc := func(day, hour, minute, second int) time.Time {
+		return time.Date(2026, 3, day, hour, minute, second, 0, time.UTC)
+	}
+	noon := utc(2, 5, 31, 0) // 12:01 in Myanmar
+
+	tests := []struct {
+		name    string
+		at, now time.Time
+		want    bool
+	}{
+		{"not published yet", time.Time{}, noon, false},
+		{"just published", noon, noon, true},
+		{"inside the window", noon, noon.Add(29*time.Minute + 59*time.Second), true},
+		{"window ends exclusively", noon, noon.Add(30 * time.Minute), false},
+		{"after the window", noon, noon.Add(2 * time.Hour), false},
+		// 23:50 and 00:05 Myanmar time: same UTC day, different Myanmar days
+		{"published the Myanmar day before", utc(2, 17, 20, 0), utc(2, 17, 35, 0), false},
+		// 06:20 and 06:40 Myanmar time: different UTC days, same Myanmar day
+		{"same Myanmar day across UTC midnight", utc(2, 23, 50, 0), utc(3, 0, 10, 0), true},
+		{"published at Myanmar midnight", utc(2, 17, 30, 0), utc(2, 17, 59, 59), true},
+	}
+	for _, tt := range tests {
+		published = publishedResult{date: "2026-03-02", at: tt.at}
+		if got := isNewResult(tt.now); got != tt.want {
+			t.Errorf("%s: isNewResult = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNewResultWindowConfigured(t *testing.T) {
+	old := newResultWindow
+	t.Cleanup(func() {
+		newResultWindow = old
+		published = publishedResult{}
+	})
+	t.Setenv("LIVE_NEW_RESULT_MINUTES", "5")
+	initFreshness()
+
+	at := time.Date(2026, 3, 2, 5, 31, 0, 0, time.UTC)
+	published = publishedResult{date: "2026-03-02", at: at}
+	if !isNewResult(at.Add(4 * time.Minute)) {
+		t.Error("not new 4 minutes after publishing with a 5 minute window")
+	}
+	if isNewResult(at.Add(5 * time.Minute)) {
+		t.Error("still new after the 5 minute window")
+	}
+}
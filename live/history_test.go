@@ -0,0 +1,106 @@
+package live
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeHistory stands in for the history table: one row per date, and a
+// count of inserter calls
+type fakeHistory struct {
+	mu    sync.Mutex
+	rows  map[string]string // date -> result430
+	calls int
+}
+
+func (h *fakeHistory) insert(data *LotteryData) (bool, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.calls++
+	_, exists := h.rows[data.Date]
+	h.rows[data.Date] = data.Result430
+	return !exists, nil
+}
+
+// setupHistory installs a fake inserter and a clock fixed at the given
+// Myanmar wall time, restoring the package state afterwards
+func setupHistory(t *testing.T, hour, minute int) *fakeHistory {
+	t.Helper()
+	myanmarLocation = loadMyanmarLocation()
+	at := time.Date(2026, 3, 2, hour, minute, 0, 0, myanmarLocation)
+
+	h := &fakeHistory{rows: make(map[string]string)}
+	oldInserter, oldNow, oldWindow := historyInserter, nowFunc, insertWindow
+	historyInserter = h.insert
+	nowFunc = func() time.Time { return at }
+	lastStored.date, lastStored.result = "", ""
+	t.Cleanup(func() {
+		historyInserter, nowFunc, insertWindow = oldInserter, oldNow, oldWindow
+		lastStored.date, lastStored.result = "", ""
+	})
+	return h
+}
+
+func TestCheckAndInsertHistoryConcurrent(t *testing.T) {
+	h := setupHistory(t, 16, 31)
+	data := &LotteryData{Date: "2026-03-02", Result430: "45"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkAndInsertHistory(data)
+		}()
+	}
+	wg.Wait()
+
+	if len(h.rows) != 1 || h.rows["2026-03-02"] != "45" {
+		t.Fatalf("rows = %v, want a single row for 2026-03-02", h.rows)
+	}
+	if h.calls != 1 {
+		t.Fatalf("inserter called %d times, want 1", h.calls)
+	}
+}
+
+func TestCheckAndInsertHistoryWindow(t *testing.T) {
+	ready := &LotteryData{Date: "2026-03-02", Result430: "45"}
+	tests := []struct {
+		name         string
+		hour, minute int
+		data         *LotteryData
+		inserts      int
+	}{
+		{"inside with a ready result", 16, 30, ready, 1},
+		{"last minute of the window", 16, 34, ready, 1},
+		{"before the window", 16, 29, ready, 0},
+		{"after the window", 16, 35, ready, 0},
+		{"noon", 12, 1, ready, 0},
+		{"result not ready", 16, 31, &LotteryData{Date: "2026-03-02", Result430: "--"}, 0},
+	}
+	for _, tt := range tests {
+		h := setupHistory(t, tt.hour, tt.minute)
+		checkAndInsertHistory(tt.data)
+		if h.calls != tt.inserts {
+			t.Errorf("%s: inserter called %d times, want %d", tt.name, h.calls, tt.inserts)
+		}
+	}
+}
+
+func TestCheckAndInsertHistoryDedup(t *testing.T) {
+	h := setupHistory(t, 16, 31)
+
+	// The feed repeats the same result every few seconds
+	checkAndInsertHistory(&LotteryData{Date: "2026-03-02", Result430: "45"})
+	checkAndInsertHistory(&LotteryData{Date: "2026-03-02", Result430: "45"})
+	if h.calls != 1 {
+		t.Fatalf("inserter called %d times for a repeated result, want 1", h.calls)
+	}
+
+	// A corrected result is written again
+	checkAndInsertHistory(&LotteryData{Date: "2026-03-02", Result430: "46"})
+	if h.calls != 2 || h.rows["2026-03-02"] != "46" {
+		t.Fatalf("after a correction: %d calls, rows %v", h.calls, h.rows)
+	}
+}
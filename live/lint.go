@@ -0,0 +1,171 @@
+package live
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// optionalInputKeys may be left out without being reported as missing
+var optionalInputKeys = map[string]bool{"source": true}
+
+// LintIssue points at one input key
+type LintIssue struct {
+	Key     string `json:"key"`
+	Message string `json:"message"`
+}
+
+// LintNormalization is a value that will be replaced by a placeholder
+type LintNormalization struct {
+	Key          string `json:"key"`
+	OutputKey    string `json:"output_key"`
+	Value        string `json:"value"`
+	NormalizedTo string `json:"normalized_to"`
+}
+
+// LintReport describes how an update payload would be handled
+type LintReport struct {
+	Valid         bool                `json:"valid"` // would be accepted by UpdateLotteryData
+	Present       []string            `json:"present"`
+	Missing       []string            `json:"missing"`
+	Extra         []string            `json:"extra"`
+	TypeErrors    []LintIssue         `json:"type_errors"`
+	InvalidValues []LintIssue         `json:"invalid_values"`
+	Warnings      []LintIssue         `json:"warnings"`
+	Normalized    []LintNormalization `json:"normalized"`
+	Output        *LotteryData        `json:"output"`
+}
+
+// inputField pairs an input key with its output key
+type inputField struct {
+	key, outputKey string
+	index          int // field index in LotteryDataInput
+}
+
+// inputFields lists the keys of LotteryDataInput with their output keys,
+// matched by Go field name, so the lint follows the structs
+func inputFields() []inputField {
+	in := reflect.TypeOf(LotteryDataInput{})
+	out := reflect.TypeOf(LotteryData{})
+
+	fields := make([]inputField, 0, in.NumField())
+	for i := 0; i < in.NumField(); i++ {
+		f := in.Field(i)
+		field := inputField{key: jsonKey(f), index: i}
+		if o, ok := out.FieldByName(f.Name); ok {
+			field.outputKey = jsonKey(o)
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+func jsonKey(f reflect.StructField) string {
+	return strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+}
+
+// lintPayload checks a raw update body without storing or broadcasting it
+func lintPayload(body []byte) (LintReport, error) {
+	report := LintReport{
+		Present:       []string{},
+		Missing:       []string{},
+		Extra:         []string{},
+		TypeErrors:    []LintIssue{},
+		InvalidValues: []LintIssue{},
+		Warnings:      []LintIssue{},
+		Normalized:    []LintNormalization{},
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return report, err
+	}
+
+	var input LotteryDataInput
+	values := reflect.ValueOf(&input).Elem()
+	known := make(map[string]bool)
+
+	for _, f := range inputFields() {
+		known[f.key] = true
+		value, ok := raw[f.key]
+		if !ok {
+			if !optionalInputKeys[f.key] {
+				report.Missing = append(report.Missing, f.key)
+			}
+			continue
+		}
+		report.Present = append(report.Present, f.key)
+
+		var s string
+		if err := json.Unmarshal(value, &s); err != nil {
+			report.TypeErrors = append(report.TypeErrors, LintIssue{f.key, "must be a string, got " + string(value)})
+			continue
+		}
+		values.Field(f.index).SetString(s)
+	}
+
+	for key := range raw {
+		if !known[key] {
+			report.Extra = append(report.Extra, key)
+		}
+	}
+	sort.Strings(report.Extra)
+
+	for _, key := range input.invalidFields() {
+		report.InvalidValues = append(report.InvalidValues, LintIssue{key, "not an allowed value"})
+	}
+
+	if input.UpdateTime != "" {
+		if _, err := time.Parse(updateTimeLayout, input.UpdateTime); err != nil {
+			report.Warnings = append(report.Warnings, LintIssue{"updatetime", "not in " + updateTimeLayout + " format, so source failover uses the arrival time"})
+		}
+	}
+
+	output := input.ToLotteryData()
+	outputValues := reflect.ValueOf(output).Elem()
+	for _, f := range inputFields() {
+		if f.outputKey == "" {
+			continue
+		}
+		before := values.Field(f.index).String()
+		after := outputValues.FieldByName(reflect.TypeOf(input).Field(f.index).Name).String()
+		if isPlaceholder(before) && before != after {
+			report.Normalized = append(report.Normalized, LintNormalization{
+				Key:          f.key,
+				OutputKey:    f.outputKey,
+				Value:        before,
+				NormalizedTo: after,
+			})
+		}
+	}
+	report.Output = output
+
+	report.Valid = len(report.TypeErrors) == 0 && len(report.InvalidValues) == 0
+	return report, nil
+}
+
+// LintUpdateHandler reports how a runner payload would be parsed,
+// validated and normalized, without storing or broadcasting it (admin)
+func LintUpdateHandler(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Failed to read request body"})
+		return
+	}
+
+	report, err := lintPayload(body)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid JSON format", "details": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"status": "success",
+		"report": report,
+	})
+}
@@ -0,0 +1,141 @@
+package live
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fullPayload returns a complete runner payload with overrides applied;
+// a nil override removes the key
+func fullPayload(t *testing.T, overrides map[string]interface{}) []byte {
+	t.Helper()
+	payload := map[string]interface{}{
+		"date": "2026-03-02", "live": "47", "status": "On",
+		"1200set": "1,456.78", "1200value": "23,456.01", "1200": "68",
+		"430set": "1,460.10", "430value": "24,000.50", "430": "05",
+		"930modern": "12", "930internet": "34", "200modern": "56", "200internet": "78",
+		"updatetime": "16:31:05 02/03/2026",
+	}
+	for k, v := range overrides {
+		if v == nil {
+			delete(payload, k)
+		} else {
+			payload[k] = v
+		}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func lint(t *testing.T, overrides map[string]interface{}) LintReport {
+	t.Helper()
+	report, err := lintPayload(fullPayload(t, overrides))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+func TestLintCleanPayload(t *testing.T) {
+	report := lint(t, nil)
+	if !report.Valid || len(report.Missing) != 0 || len(report.Extra) != 0 ||
+		len(report.Normalized) != 0 || len(report.Warnings) != 0 {
+		t.Fatalf("report = %+v, want a clean report", report)
+	}
+	if len(report.Present) != 14 {
+		t.Errorf("present = %v, want every key but source", report.Present)
+	}
+	if report.Output.Live != "47" || report.Output.Result430 != "05" || report.Output.Date != "2026-03-02" {
+		t.Errorf("output = %+v", report.Output)
+	}
+}
+
+func TestLintMissingAndExtraKeys(t *testing.T) {
+	report := lint(t, map[string]interface{}{
+		"430": nil, "930modern": nil,
+		"4:30": "05", "extra": 1,
+	})
+
+	if got := strings.Join(report.Missing, ","); got != "430,930modern" {
+		t.Errorf("missing = %s, want 430,930modern", got)
+	}
+	if got := strings.Join(report.Extra, ","); got != "4:30,extra" {
+		t.Errorf("extra = %s, want 4:30,extra", got)
+	}
+	// Missing keys fall back to placeholders, so the update is still accepted
+	if !report.Valid || report.Output.Result430 != "---" || report.Output.Modern930 != "--" {
+		t.Errorf("valid %v, output %+v", report.Valid, report.Output)
+	}
+}
+
+func TestLintNormalization(t *testing.T) {
+	report := lint(t, map[string]interface{}{"live": "", "1200": "", "430": "---", "200modern": "--"})
+
+	want := map[string]LintNormalization{
+		"live": {Key: "live", OutputKey: "live_number", Value: "", NormalizedTo: "--"},
+		"1200": {Key: "1200", OutputKey: "noon_result", Value: "", NormalizedTo: "---"},
+	}
+	if len(report.Normalized) != len(want) {
+		t.Fatalf("normalized = %+v, want live and 1200", report.Normalized)
+	}
+	for _, n := range report.Normalized {
+		if n != want[n.Key] {
+			t.Errorf("normalized %+v, want %+v", n, want[n.Key])
+		}
+	}
+	// Placeholders already in output form are not reported
+	if report.Output.Result430 != "---" || report.Output.Modern200 != "--" {
+		t.Errorf("output = %+v", report.Output)
+	}
+}
+
+func TestLintTypeAndValueErrors(t *testing.T) {
+	report := lint(t, map[string]interface{}{"live": 47, "430": "4a", "updatetime": "16:31"})
+
+	if report.Valid {
+		t.Errorf("a payload with type and value errors is valid")
+	}
+	if len(report.TypeErrors) != 1 || report.TypeErrors[0].Key != "live" {
+		t.Errorf("type errors = %+v, want live", report.TypeErrors)
+	}
+	if len(report.InvalidValues) != 1 || report.InvalidValues[0].Key != "430" {
+		t.Errorf("invalid values = %+v, want 430", report.InvalidValues)
+	}
+	if len(report.Warnings) != 1 || report.Warnings[0].Key != "updatetime" {
+		t.Errorf("warnings = %+v, want updatetime", report.Warnings)
+	}
+}
+
+func TestLintHandlerDoesNotStore(t *testing.T) {
+	setupHistory(t, 10, 0)
+	resetSources(t)
+	Update(&LotteryDataInput{Date: "2026-03-02", Live: "33"})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/lint", LintUpdateHandler)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/lint",
+		strings.NewReader(string(fullPayload(t, map[string]interface{}{"live": "99"})))))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	if Current().Live != "33" {
+		t.Errorf("lint stored the payload: live %q", Current().Live)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/lint", strings.NewReader(`{"live":`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("malformed JSON: status %d, want 400", w.Code)
+	}
+}
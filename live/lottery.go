@@ -2,6 +2,7 @@ package live
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,7 +10,11 @@ import (
 	"sync"
 	"time"
 
+	"burma2d/shutdown"
+	"burma2d/tracing"
+
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // LotteryDataInput represents incoming data with old JSON key format from API runner
@@ -28,6 +33,7 @@ type LotteryDataInput struct {
 	Modern200   string `json:"200modern"`
 	Internet200 string `json:"200internet"`
 	UpdateTime  string `json:"updatetime"`
+	Source      string `json:"source"` // Upstream scraper name, optional
 }
 
 // LotteryData represents the lottery information with new JSON key format for output
@@ -47,6 +53,7 @@ type LotteryData struct {
 	Internet200 string `json:"afternoon_internet"`
 	UpdateTime  string `json:"last_update"`
 	ViewCount   int    `json:"active_viewers"`
+	IsNewResult bool   `json:"is_new_result"` // Today's result was published within LIVE_NEW_RESULT_MINUTES
 }
 
 // ToLotteryData converts LotteryDataInput to LotteryData
@@ -85,17 +92,25 @@ func (input *LotteryDataInput) ToLotteryData() *LotteryData {
 	}
 }
 
-// HistoryInserter is a callback function type for inserting history
-type HistoryInserter func(data *LotteryData) error
+// HistoryInserter is a callback function type for inserting history. It
+// upserts on the draw date and reports whether a new row was inserted.
+type HistoryInserter func(data *LotteryData) (bool, error)
 
 // Global state
 var (
 	currentData     *LotteryData
 	dataMutex       sync.RWMutex
-	clients         = make(map[chan string]bool)
+	clients         = make(map[chan *LotteryData]bool)
 	clientsMutex    sync.RWMutex
 	historyInserter HistoryInserter
-	lastCheckTime   time.Time
+
+	// historyMutex serializes checkAndInsertHistory; lastStored skips
+	// writes that would not change the stored row
+	historyMutex sync.Mutex
+	lastStored   struct{ date, result string }
+
+	// nowFunc is the clock for the history window; tests can replace it
+	nowFunc = time.Now
 
 	// Performance optimization: Reuse JSON buffers
 	jsonBufferPool = sync.Pool{
@@ -104,9 +119,11 @@ var (
 		},
 	}
 
-	// Cached JSON string to avoid re-marshaling for every client
-	cachedJSONMessage string
-	cachedJSONMutex   sync.RWMutex
+	// Cached JSON string to avoid re-marshaling for every client, along
+	// with the broadcast snapshot it encodes
+	cachedJSONMessage  string
+	cachedJSONSnapshot *LotteryData
+	cachedJSONMutex    sync.RWMutex
 )
 
 // SetHistoryInserter sets the callback function for history insertion
@@ -132,6 +149,27 @@ func Init() {
 		Internet200: "---",
 		UpdateTime:  time.Now().Format("15:04:05 02/01/2006"),
 	}
+
+	refreshWidgetCache()
+	initSignature()
+	initAPIKey()
+	initSnapshots()
+	initFreshness()
+	initSources()
+	initHistoryWindow()
+	shutdown.OnClosing(announceClosing)
+	if signingEnabled() {
+		log.Println("✅ Signed lottery updates required (replay protection on)")
+	}
+	switch {
+	case apiKeyRequired():
+		log.Println("✅ Lottery updates require LOTTERY_UPDATE_KEY")
+	case insecureUpdates:
+		log.Println("⚠️ LOTTERY_INSECURE_UPDATES=true: anyone can post lottery updates (development only)")
+	default:
+		log.Println("❌ LOTTERY_UPDATE_KEY not set - lottery updates are refused")
+	}
+
 	log.Println("✅ Live package initialized with default data")
 }
 
@@ -139,6 +177,17 @@ func Init() {
 func UpdateLotteryData(c *gin.Context) {
 	var inputData LotteryDataInput
 
+	if !updatesAllowed() {
+		log.Printf("⚠️ Rejected lottery update from %s: LOTTERY_UPDATE_KEY not configured", c.ClientIP())
+		c.JSON(503, gin.H{"error": "Lottery updates are not configured"})
+		return
+	}
+	if apiKeyRequired() && !validAPIKey(c) {
+		log.Printf("⚠️ Rejected lottery update from %s: missing or invalid API key", c.ClientIP())
+		c.JSON(401, gin.H{"error": "Invalid API key"})
+		return
+	}
+
 	// Read and parse JSON body
 	body, err := io.ReadAll(c.Request.Body)
 	if err != nil {
@@ -146,87 +195,189 @@ func UpdateLotteryData(c *gin.Context) {
 		return
 	}
 
+	// Reject unsigned, stale or replayed requests when signing is configured
+	if signingEnabled() {
+		err := verifySignature(
+			c.GetHeader(headerTimestamp),
+			c.GetHeader(headerNonce),
+			c.GetHeader(headerSignature),
+			body,
+		)
+		if err != nil {
+			log.Printf("⚠️ Rejected lottery update from %s: %v", c.ClientIP(), err)
+			c.JSON(401, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	if err := json.Unmarshal(body, &inputData); err != nil {
 		c.JSON(400, gin.H{"error": "Invalid JSON format", "details": err.Error()})
 		return
 	}
 
+	// Only digits (or placeholders) may reach clients and history
+	if err := inputData.Validate(); err != nil {
+		log.Printf("⚠️ Rejected lottery update from %s: %v", c.ClientIP(), err)
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	newData, accepted := Update(&inputData)
+	if !accepted {
+		c.JSON(200, gin.H{
+			"status":      "ignored",
+			"message":     "A fresher source is live",
+			"live_source": LiveSource(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"status":  "success",
+		"message": "Data updated successfully",
+		"data":    newData,
+	})
+}
+
+// Update stores already validated input as the current data, records history
+// when due and pushes it to every subscriber. Input from a source that lost
+// to a fresher one is ignored and reported as not accepted.
+func Update(input *LotteryDataInput) (*LotteryData, bool) {
+	source := sourceName(input)
+	if !acceptSource(source, input.UpdateTime, time.Now()) {
+		log.Printf("⏭️  Ignored lottery update from %s (live source: %s)", source, LiveSource())
+		return nil, false
+	}
+
 	// Transform input data to output format
-	newData := inputData.ToLotteryData()
+	newData := input.ToLotteryData()
 
 	// Update current data
 	dataMutex.Lock()
 	currentData = newData
 	dataMutex.Unlock()
+	refreshWidgetCache()
+	recordSnapshot(*newData)
 
-	log.Printf("📊 Lottery data updated - Live: %s, Status: %s", newData.Live, newData.Status)
+	log.Printf("📊 Lottery data updated - Live: %s, Status: %s, Source: %s", newData.Live, newData.Status, source)
 
-	// Check if we should insert to history database (16:30-16:35 GMT+6:30)
+	// Check if we should insert to history database (LIVE_HISTORY_WINDOW_*)
 	checkAndInsertHistory(newData)
+	refreshPublished(newData.Date, false)
 
 	// Broadcast to all SSE clients
 	broadcastUpdate()
 
-	c.JSON(200, gin.H{
-		"status":  "success",
-		"message": "Data updated successfully",
-		"data":    newData,
-	})
+	return newData, true
 }
 
-// checkAndInsertHistory checks if current time is 16:30-16:35 GMT+6:30 and inserts to database
+// checkAndInsertHistory inserts to the database when the current Myanmar time
+// is inside the configured window and the guarded result is published
 func checkAndInsertHistory(data *LotteryData) {
 	if historyInserter == nil {
 		return // No history inserter registered
 	}
 
-	// Get Myanmar time (GMT+6:30)
-	loc, err := time.LoadLocation("Asia/Yangon")
-	if err != nil {
-		log.Printf("❌ Error loading timezone: %v", err)
+	now := nowFunc().In(myanmarLocation)
+	w := insertWindow
+	if !w.contains(now) {
 		return
 	}
 
-	now := time.Now().In(loc)
-	hour := now.Hour()
-	minute := now.Minute()
+	// Check if the result has real data (not a "--"/"---" placeholder)
+	result := w.result(data)
+	if isPlaceholder(result) {
+		log.Printf("⏭️  Skipping insert - %s result is not ready yet: %s", w.resultField, result)
+		return
+	}
 
-	// Check if time is between 16:30 and 16:35
-	if hour == 16 && minute >= 30 && minute < 35 {
-		// Check if 430 result has real data (not "--")
-		if data.Result430 == "--" || data.Result430 == "" {
-			log.Printf("⏭️  Skipping insert - 430 result is not ready yet: %s", data.Result430)
-			return
-		}
+	historyMutex.Lock()
+	defer historyMutex.Unlock()
 
-		// Avoid duplicate checks within the same minute
-		if time.Since(lastCheckTime) < time.Minute {
-			return
-		}
-		lastCheckTime = now
+	// Already stored this result for the draw date; the upsert makes a
+	// repeat harmless, this just saves the write
+	if lastStored.date == data.Date && lastStored.result == result {
+		return
+	}
 
-		log.Printf("⏰ Time check: %02d:%02d - Within insert window (16:30-16:35)", hour, minute)
-		log.Printf("📊 430 result is ready: %s - Attempting to insert history for date: %s", data.Result430, data.Date)
+	log.Printf("⏰ Time check: %s - Within insert window (%s)", now.Format("15:04"), w)
+	log.Printf("📊 %s result is ready: %s - Attempting to insert history for date: %s", w.resultField, result, data.Date)
 
-		// Call the history inserter callback
-		if err := historyInserter(data); err != nil {
-			log.Printf("❌ Error inserting history: %v", err)
-		} else {
-			log.Printf("✅ History checked/inserted for date: %s", data.Date)
-		}
+	// Call the history inserter callback
+	_, span := tracing.Start(context.Background(), "live.history_insert",
+		attribute.String("lottery.date", data.Date))
+	inserted, err := historyInserter(data)
+	tracing.End(span, err)
+	if err != nil {
+		log.Printf("❌ Error inserting history: %v", err)
+		return
+	}
+
+	if inserted {
+		log.Printf("✅ History inserted for date: %s", data.Date)
+	} else {
+		log.Printf("🔁 History updated for date: %s", data.Date)
 	}
+	lastStored.date, lastStored.result = data.Date, result
+	refreshPublished(data.Date, true)
 }
 
 // GetCurrentData returns the current lottery data
 func GetCurrentData(c *gin.Context) {
+	c.JSON(200, gin.H{
+		"status":  "success",
+		"data":    Current(),
+		"source":  LiveSource(),
+		"sources": Sources(),
+	})
+}
+
+// Current returns a copy of the current lottery data
+func Current() LotteryData {
 	dataMutex.RLock()
-	data := currentData
+	data := *currentData
 	dataMutex.RUnlock()
+	data.IsNewResult = isNewResult(time.Now())
+	return data
+}
 
-	c.JSON(200, gin.H{
-		"status": "success",
-		"data":   data,
-	})
+// Subscribe registers a receiver for lottery updates. Every broadcast sends
+// a snapshot that receivers must not modify; updates are dropped while the
+// buffer is full. Call the returned function to unsubscribe and close the
+// channel.
+func Subscribe() (<-chan *LotteryData, func()) {
+	// Larger buffer for high concurrency (50 instead of 10)
+	ch := make(chan *LotteryData, 50)
+
+	clientsMutex.Lock()
+	clients[ch] = true
+	clientsMutex.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			clientsMutex.Lock()
+			delete(clients, ch)
+			clientsMutex.Unlock()
+			close(ch)
+		})
+	}
+	return ch, unsubscribe
+}
+
+// encodeSnapshot returns the JSON for a broadcast snapshot, reusing the
+// cached encoding when it is the latest one
+func encodeSnapshot(data *LotteryData) string {
+	cachedJSONMutex.RLock()
+	if cachedJSONSnapshot == data {
+		message := cachedJSONMessage
+		cachedJSONMutex.RUnlock()
+		return message
+	}
+	cachedJSONMutex.RUnlock()
+
+	encoded, _ := json.Marshal(data)
+	return string(encoded)
 }
 
 // StreamLotteryData handles SSE streaming for real-time updates
@@ -237,14 +388,9 @@ func StreamLotteryData(c *gin.Context) {
 	c.Header("Connection", "keep-alive")
 	c.Header("Access-Control-Allow-Origin", "*")
 
-	// Create a client channel with larger buffer for high concurrency (50 instead of 10)
-	clientChan := make(chan string, 50)
-
 	// Register client
-	clientsMutex.Lock()
-	clients[clientChan] = true
-	clientCount := len(clients)
-	clientsMutex.Unlock()
+	updates, unsubscribe := Subscribe()
+	clientCount := ClientCount()
 
 	// Log less frequently at high concurrency (every 100 connections)
 	if clientCount%100 == 0 || clientCount < 100 {
@@ -258,46 +404,66 @@ func StreamLotteryData(c *gin.Context) {
 	cachedJSONMutex.RUnlock()
 
 	if initialMessage == "" {
-		// No cached data, marshal fresh
-		dataMutex.RLock()
-		currentData.ViewCount = clientCount
-		initialData, _ := json.Marshal(currentData)
-		dataMutex.RUnlock()
+		// No cached data, marshal a copy so concurrent connects don't race
+		data := Current()
+		data.ViewCount = clientCount
+		initialData, _ := json.Marshal(data)
 		initialMessage = string(initialData)
 	}
 
 	c.Writer.Write([]byte(fmt.Sprintf("data: %s\n\n", initialMessage)))
 	c.Writer.Flush()
 
-	// Listen for updates and client disconnect
+	// Listen for updates, the shutdown notice and client disconnect
 	notify := c.Request.Context().Done()
+	closing := closingSignal
 
 	for {
 		select {
+		case <-closing:
+			// Keep streaming until the server closes the connection
+			closing = nil
+			c.Writer.Write([]byte(closingMessage))
+			c.Writer.Flush()
 		case <-notify:
 			// Client disconnected
-			clientsMutex.Lock()
-			delete(clients, clientChan)
-			remainingClients := len(clients)
-			clientsMutex.Unlock()
-			close(clientChan)
+			unsubscribe()
+			remainingClients := ClientCount()
 
 			// Log less frequently at high concurrency
 			if remainingClients%100 == 0 || remainingClients < 100 {
 				log.Printf("📴 SSE client disconnected (Remaining clients: %d)", remainingClients)
 			}
 			return
-		case message := <-clientChan:
+		case data, ok := <-updates:
+			if !ok {
+				return
+			}
 			// Send update to client
-			c.Writer.Write([]byte(fmt.Sprintf("data: %s\n\n", message)))
+			c.Writer.Write([]byte(fmt.Sprintf("data: %s\n\n", encodeSnapshot(data))))
 			c.Writer.Flush()
 		}
 	}
 }
 
+// ClientCount returns the number of connected lottery stream clients
+func ClientCount() int {
+	clientsMutex.RLock()
+	defer clientsMutex.RUnlock()
+	return len(clients)
+}
+
 // broadcastUpdate sends updates to all connected SSE clients
 // OPTIMIZED for 10,000+ concurrent connections
 func broadcastUpdate() {
+	// Stored data still changes while paused; Resume sends the latest once
+	if suppressBroadcast() {
+		return
+	}
+
+	_, span := tracing.Start(context.Background(), "live.broadcast")
+	defer span.End()
+
 	// Step 1: Get client count first (quick lock)
 	clientsMutex.RLock()
 	clientCount := len(clients)
@@ -307,11 +473,16 @@ func broadcastUpdate() {
 	buf := jsonBufferPool.Get().(*bytes.Buffer)
 	buf.Reset()
 
-	dataMutex.RLock()
+	isNew := isNewResult(time.Now())
+
+	dataMutex.Lock()
 	currentData.ViewCount = clientCount
+	currentData.IsNewResult = isNew
+	snapshot := *currentData
+	dataMutex.Unlock()
+
 	encoder := json.NewEncoder(buf)
-	err := encoder.Encode(currentData)
-	dataMutex.RUnlock()
+	err := encoder.Encode(&snapshot)
 
 	if err != nil {
 		log.Printf("❌ Failed to marshal data: %v", err)
@@ -326,6 +497,7 @@ func broadcastUpdate() {
 	// Cache the JSON message for new connections
 	cachedJSONMutex.Lock()
 	cachedJSONMessage = message
+	cachedJSONSnapshot = &snapshot
 	cachedJSONMutex.Unlock()
 
 	// Step 3: Broadcast to all clients (minimize lock time)
@@ -337,7 +509,7 @@ func broadcastUpdate() {
 
 	for clientChan := range clients {
 		select {
-		case clientChan <- message:
+		case clientChan <- &snapshot:
 			sentCount++
 		default:
 			// Channel is full, skip this client (prevents blocking)
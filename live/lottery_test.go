@@ -0,0 +1,106 @@
+package live
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestStreamLotteryDataConcurrentConnects(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	currentData = &LotteryData{Live: "12", Status: "On"}
+	cachedJSONMutex.Lock()
+	cachedJSONMessage = ""
+	cachedJSONMutex.Unlock()
+
+	r := gin.New()
+	r.GET("/stream", StreamLotteryData)
+
+	// Disconnected clients get the initial event and return at once
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/stream", nil).WithContext(ctx)
+			r.ServeHTTP(w, req)
+			if !strings.HasPrefix(w.Body.String(), "data: {") {
+				t.Errorf("initial event = %q", w.Body.String())
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The stream works on a copy; the shared data keeps no per-client count
+	if currentData.ViewCount != 0 {
+		t.Fatalf("currentData.ViewCount = %d, want 0", currentData.ViewCount)
+	}
+}
+
+func receive(t *testing.T, ch <-chan *LotteryData) *LotteryData {
+	t.Helper()
+	select {
+	case data := <-ch:
+		return data
+	default:
+		t.Fatal("no update queued")
+		return nil
+	}
+}
+
+func TestSubscribeWithoutHTTP(t *testing.T) {
+	setupHistory(t, 10, 0)
+	resetSources(t)
+
+	first, unsubscribeFirst := Subscribe()
+	second, unsubscribeSecond := Subscribe()
+	defer unsubscribeSecond()
+
+	if _, ok := Update(&LotteryDataInput{Live: "47", Status: "On"}); !ok {
+		t.Fatal("update was ignored")
+	}
+	for _, ch := range []<-chan *LotteryData{first, second} {
+		if data := receive(t, ch); data.Live != "47" || data.ViewCount != 2 {
+			t.Fatalf("received %+v, want live 47 seen by 2", data)
+		}
+	}
+	if got := Current(); got.Live != "47" || got.Status != "On" {
+		t.Fatalf("Current() = %+v", got)
+	}
+
+	// Unsubscribing closes the channel and stops delivery; a second call is harmless
+	unsubscribeFirst()
+	unsubscribeFirst()
+	if _, open := <-first; open {
+		t.Fatal("channel still open after unsubscribe")
+	}
+
+	Update(&LotteryDataInput{Live: "48", Status: "On"})
+	if data := receive(t, second); data.Live != "48" || data.ViewCount != 1 {
+		t.Fatalf("received %+v, want live 48 seen by 1", data)
+	}
+}
+
+func TestSubscribeSlowReceiverDoesNotBlock(t *testing.T) {
+	setupHistory(t, 10, 0)
+	resetSources(t)
+	ch, unsubscribe := Subscribe()
+	defer unsubscribe()
+
+	// Nobody reads: updates past the buffer are dropped, not waited on
+	for i := 0; i < cap(ch)+10; i++ {
+		Update(&LotteryDataInput{Live: "12", Status: "On"})
+	}
+	if len(ch) != cap(ch) {
+		t.Fatalf("%d updates queued, want a full buffer of %d", len(ch), cap(ch))
+	}
+}
@@ -0,0 +1,73 @@
+package live
+
+import (
+	"log"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	// broadcastPaused stops pushes to stream clients while updates keep
+	// being stored; suppressed counts the updates held back
+	broadcastPaused bool
+	suppressed      int
+	pauseMutex      sync.Mutex
+)
+
+// Pause stops broadcasting updates; UpdateLotteryData still stores them
+func Pause() {
+	pauseMutex.Lock()
+	defer pauseMutex.Unlock()
+
+	if !broadcastPaused {
+		broadcastPaused = true
+		suppressed = 0
+		log.Println("⏸️  Lottery broadcasting paused")
+	}
+}
+
+// Resume restarts broadcasting and sends the current state once
+func Resume() {
+	pauseMutex.Lock()
+	if !broadcastPaused {
+		pauseMutex.Unlock()
+		return
+	}
+	broadcastPaused = false
+	held := suppressed
+	pauseMutex.Unlock()
+
+	log.Printf("▶️  Lottery broadcasting resumed (%d update(s) held back)", held)
+	broadcastUpdate()
+}
+
+// Paused reports whether broadcasting is paused
+func Paused() bool {
+	pauseMutex.Lock()
+	defer pauseMutex.Unlock()
+	return broadcastPaused
+}
+
+// suppressBroadcast reports whether a broadcast should be skipped, counting it
+func suppressBroadcast() bool {
+	pauseMutex.Lock()
+	defer pauseMutex.Unlock()
+
+	if broadcastPaused {
+		suppressed++
+	}
+	return broadcastPaused
+}
+
+// PauseBroadcastHandler pauses broadcasting (admin)
+func PauseBroadcastHandler(c *gin.Context) {
+	Pause()
+	c.JSON(200, gin.H{"status": "success", "paused": true})
+}
+
+// ResumeBroadcastHandler resumes broadcasting and pushes the current state (admin)
+func ResumeBroadcastHandler(c *gin.Context) {
+	Resume()
+	c.JSON(200, gin.H{"status": "success", "paused": false})
+}
@@ -0,0 +1,61 @@
+package live
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPauseHoldsBroadcastsUntilResume(t *testing.T) {
+	setupHistory(t, 10, 0)
+	resetSources(t)
+	ch, unsubscribe := Subscribe()
+	defer unsubscribe()
+	t.Cleanup(Resume)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/pause", PauseBroadcastHandler)
+	r.POST("/resume", ResumeBroadcastHandler)
+	post := func(path string) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status %d", path, w.Code)
+		}
+	}
+
+	post("/pause")
+	if !Paused() {
+		t.Fatal("not paused")
+	}
+	for _, live := range []string{"11", "22", "33"} {
+		Update(&LotteryDataInput{Live: live, Status: "On"})
+	}
+	if len(ch) != 0 {
+		t.Fatalf("%d broadcasts while paused", len(ch))
+	}
+	// Updates are still stored
+	if got := Current(); got.Live != "33" {
+		t.Fatalf("stored live = %s, want 33", got.Live)
+	}
+
+	post("/resume")
+	if Paused() {
+		t.Fatal("still paused")
+	}
+	if len(ch) != 1 {
+		t.Fatalf("%d broadcasts on resume, want 1", len(ch))
+	}
+	if data := <-ch; data.Live != "33" {
+		t.Fatalf("resume broadcast live %s, want the latest (33)", data.Live)
+	}
+
+	// Resuming again sends nothing more
+	post("/resume")
+	if len(ch) != 0 {
+		t.Fatal("a second resume broadcast again")
+	}
+}
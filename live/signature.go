@@ -0,0 +1,84 @@
+package live
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"burma2d/config"
+)
+
+// Signed update headers. The signature is hex(HMAC-SHA256(secret,
+// timestamp + "\n" + nonce + "\n" + body)) and timestamp is Unix seconds.
+const (
+	headerTimestamp = "X-Signature-Timestamp"
+	headerNonce     = "X-Signature-Nonce"
+	headerSignature = "X-Signature"
+)
+
+var (
+	signingSecret    []byte        // LOTTERY_SIGNING_SECRET; signing is off when empty
+	signatureMaxSkew time.Duration // LOTTERY_SIGNATURE_MAX_AGE
+
+	seenNonces      = make(map[string]time.Time) // nonce -> when it can be forgotten
+	seenNoncesMutex sync.Mutex
+)
+
+func initSignature() {
+	signingSecret = []byte(config.Secret("LOTTERY_SIGNING_SECRET", ""))
+	signatureMaxSkew = config.Duration("LOTTERY_SIGNATURE_MAX_AGE", 5*time.Minute)
+}
+
+// signingEnabled reports whether update requests must be signed
+func signingEnabled() bool {
+	return len(signingSecret) > 0
+}
+
+// verifySignature checks the signed headers of an update request and
+// records its nonce so the same request can't be replayed
+func verifySignature(timestamp, nonce, signature string, body []byte) error {
+	if timestamp == "" || nonce == "" || signature == "" {
+		return errors.New("missing signature headers")
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("invalid signature timestamp")
+	}
+	now := time.Now()
+	sent := time.Unix(unix, 0)
+	if sent.Before(now.Add(-signatureMaxSkew)) || sent.After(now.Add(signatureMaxSkew)) {
+		return errors.New("stale signature timestamp")
+	}
+
+	mac := hmac.New(sha256.New, signingSecret)
+	mac.Write([]byte(timestamp + "\n" + nonce + "\n"))
+	mac.Write(body)
+	expected := mac.Sum(nil)
+
+	given, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(given, expected) {
+		return errors.New("invalid signature")
+	}
+
+	// Only remember nonces of valid requests; they expire once the
+	// timestamp would be rejected anyway
+	seenNoncesMutex.Lock()
+	defer seenNoncesMutex.Unlock()
+
+	for n, expiry := range seenNonces {
+		if now.After(expiry) {
+			delete(seenNonces, n)
+		}
+	}
+	if _, used := seenNonces[nonce]; used {
+		return errors.New("nonce already used")
+	}
+	seenNonces[nonce] = sent.Add(signatureMaxSkew)
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package live
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func setSigningSecret(t *testing.T, secret string) {
+	t.Helper()
+	oldSecret, oldSkew := signingSecret, signatureMaxSkew
+	signingSecret, signatureMaxSkew = []byte(secret), 5*time.Minute
+	t.Cleanup(func() { signingSecret, signatureMaxSkew = oldSecret, oldSkew })
+
+	seenNoncesMutex.Lock()
+	seenNonces = make(map[string]time.Time)
+	seenNoncesMutex.Unlock()
+}
+
+func sign(secret, timestamp, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + nonce + "\n"))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifySignatureRejectsReplayedNonce(t *testing.T) {
+	setSigningSecret(t, "secret")
+	body := []byte(`{"live":"12"}`)
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+
+	if err := verifySignature(ts, "nonce-1", sign("secret", ts, "nonce-1", body), body); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	if err := verifySignature(ts, "nonce-1", sign("secret", ts, "nonce-1", body), body); err == nil {
+		t.Fatal("replayed nonce was accepted")
+	}
+	if err := verifySignature(ts, "nonce-2", sign("secret", ts, "nonce-2", body), body); err != nil {
+		t.Fatalf("fresh nonce: %v", err)
+	}
+}
+
+func TestVerifySignatureRejectsBadRequests(t *testing.T) {
+	setSigningSecret(t, "secret")
+	body := []byte(`{"live":"12"}`)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	old := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+
+	tests := []struct {
+		name, ts, nonce, sig string
+	}{
+		{"missing headers", "", "n", sign("secret", "", "n", body)},
+		{"bad timestamp", "soon", "n", sign("secret", "soon", "n", body)},
+		{"stale timestamp", old, "n", sign("secret", old, "n", body)},
+		{"wrong secret", now, "n", sign("other", now, "n", body)},
+		{"not hex", now, "n", "zz"},
+	}
+	for _, tt := range tests {
+		if err := verifySignature(tt.ts, tt.nonce, tt.sig, body); err == nil {
+			t.Errorf("%s: accepted", tt.name)
+		}
+	}
+
+	// A rejected request must not burn its nonce
+	if err := verifySignature(now, "n", sign("secret", now, "n", body), body); err != nil {
+		t.Errorf("valid request after rejections: %v", err)
+	}
+}
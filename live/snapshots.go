@@ -0,0 +1,266 @@
+package live
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"burma2d/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Snapshot is one recorded lottery update
+type Snapshot struct {
+	RecordedAt time.Time   `json:"recorded_at"`
+	Data       LotteryData `json:"data"`
+}
+
+var (
+	// Ring buffer of recent updates (LIVE_SNAPSHOT_SIZE, 0 disables)
+	snapshots      []Snapshot
+	snapshotNext   int
+	snapshotFull   bool
+	snapshotsMutex sync.RWMutex
+
+	// Optional persistence, written off the request path
+	snapshotDB        *sql.DB
+	snapshotWrites    chan Snapshot
+	snapshotRetention time.Duration
+)
+
+func initSnapshots() {
+	size := config.Int("LIVE_SNAPSHOT_SIZE", 500)
+	if size < 0 {
+		size = 0
+	}
+	snapshots = make([]Snapshot, size)
+	snapshotNext, snapshotFull = 0, false
+	if size > 0 {
+		log.Printf("✅ Live snapshots enabled (last %d updates)", size)
+	}
+}
+
+// EnableSnapshotPersistence stores snapshots in the live_snapshots table so
+// they survive restarts (LIVE_SNAPSHOT_PERSIST=true). Recent rows are loaded
+// back into the ring buffer.
+func EnableSnapshotPersistence(database *sql.DB) {
+	if len(snapshots) == 0 || !config.Bool("LIVE_SNAPSHOT_PERSIST", false) {
+		return
+	}
+	snapshotRetention = time.Duration(config.Int("LIVE_SNAPSHOT_RETENTION_DAYS", 7)) * 24 * time.Hour
+
+	_, err := database.Exec(`
+		CREATE TABLE IF NOT EXISTS live_snapshots (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			recorded_at DATETIME NOT NULL,
+			data TEXT NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_live_snapshots_recorded ON live_snapshots(recorded_at);
+	`)
+	if err != nil {
+		log.Printf("❌ Error creating live_snapshots table: %v", err)
+		return
+	}
+
+	snapshotDB = database
+	loadSnapshots()
+
+	snapshotWrites = make(chan Snapshot, 256)
+	go snapshotWriter()
+	log.Printf("✅ Live snapshots persisted to database (retention: %s)", snapshotRetention)
+}
+
+// recordSnapshot adds an update to the ring buffer and queues it for persistence
+func recordSnapshot(data LotteryData) {
+	if len(snapshots) == 0 {
+		return
+	}
+	s := Snapshot{RecordedAt: time.Now(), Data: data}
+
+	snapshotsMutex.Lock()
+	addSnapshotLocked(s)
+	snapshotsMutex.Unlock()
+
+	if snapshotWrites != nil {
+		select {
+		case snapshotWrites <- s:
+		default:
+			log.Println("⚠️ Snapshot write queue full, dropping snapshot")
+		}
+	}
+}
+
+func addSnapshotLocked(s Snapshot) {
+	snapshots[snapshotNext] = s
+	snapshotNext = (snapshotNext + 1) % len(snapshots)
+	if snapshotNext == 0 {
+		snapshotFull = true
+	}
+}
+
+// recentSnapshots returns up to limit snapshots in chronological order
+func recentSnapshots(limit int) []Snapshot {
+	snapshotsMutex.RLock()
+	defer snapshotsMutex.RUnlock()
+
+	count := snapshotNext
+	if snapshotFull {
+		count = len(snapshots)
+	}
+	if limit <= 0 || limit > count {
+		limit = count
+	}
+
+	out := make([]Snapshot, 0, limit)
+	start := snapshotNext - limit
+	for i := 0; i < limit; i++ {
+		idx := (start + i + len(snapshots)) % len(snapshots)
+		out = append(out, snapshots[idx])
+	}
+	return out
+}
+
+func snapshotWriter() {
+	lastPrune := time.Time{}
+	for s := range snapshotWrites {
+		payload, err := json.Marshal(s.Data)
+		if err != nil {
+			continue
+		}
+		if _, err := snapshotDB.Exec(
+			"INSERT INTO live_snapshots (recorded_at, data) VALUES (?, ?)",
+			s.RecordedAt.UTC(), string(payload),
+		); err != nil {
+			log.Printf("⚠️ Failed to persist live snapshot: %v", err)
+		}
+
+		if time.Since(lastPrune) > time.Hour {
+			lastPrune = time.Now()
+			if _, err := snapshotDB.Exec(
+				"DELETE FROM live_snapshots WHERE recorded_at < ?",
+				time.Now().UTC().Add(-snapshotRetention),
+			); err != nil {
+				log.Printf("⚠️ Failed to prune live snapshots: %v", err)
+			}
+		}
+	}
+}
+
+// loadSnapshots fills the ring buffer with the most recent persisted snapshots
+func loadSnapshots() {
+	rows, err := snapshotDB.Query(`
+		SELECT recorded_at, data FROM (
+			SELECT id, recorded_at, data FROM live_snapshots ORDER BY id DESC LIMIT ?
+		) ORDER BY id ASC
+	`, len(snapshots))
+	if err != nil {
+		log.Printf("⚠️ Failed to load live snapshots: %v", err)
+		return
+	}
+	defer rows.Close()
+
+	snapshotsMutex.Lock()
+	defer snapshotsMutex.Unlock()
+
+	loaded := 0
+	for rows.Next() {
+		var s Snapshot
+		var payload string
+		if err := rows.Scan(&s.RecordedAt, &payload); err != nil {
+			continue
+		}
+		if err := json.Unmarshal([]byte(payload), &s.Data); err != nil {
+			continue
+		}
+		addSnapshotLocked(s)
+		loaded++
+	}
+	if loaded > 0 {
+		log.Printf("✅ Restored %d live snapshots", loaded)
+	}
+}
+
+// GetSnapshots returns recent live updates in chronological order (?limit=N)
+func GetSnapshots(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	if err != nil || limit < 0 {
+		c.JSON(400, gin.H{"error": "Invalid limit"})
+		return
+	}
+
+	list := recentSnapshots(limit)
+	c.JSON(200, gin.H{
+		"status":    "success",
+		"enabled":   len(snapshots) > 0,
+		"count":     len(list),
+		"snapshots": list,
+	})
+}
+
+// RecentUpdate is a snapshot with the gap since the update before it
+type RecentUpdate struct {
+	ReceivedAt time.Time   `json:"received_at"`
+	GapSeconds *float64    `json:"gap_seconds"` // null for the oldest update held
+	Data       LotteryData `json:"data"`
+}
+
+// recentUpdates returns up to limit snapshots in chronological order with
+// the gap before each. One extra snapshot is read so the oldest returned
+// update still gets a gap when the buffer holds its predecessor.
+func recentUpdates(limit int) []RecentUpdate {
+	list := recentSnapshots(limit + 1)
+
+	updates := make([]RecentUpdate, 0, len(list))
+	for i, s := range list {
+		u := RecentUpdate{ReceivedAt: s.RecordedAt, Data: s.Data}
+		if i > 0 {
+			gap := s.RecordedAt.Sub(list[i-1].RecordedAt).Seconds()
+			u.GapSeconds = &gap
+		}
+		updates = append(updates, u)
+	}
+	if len(updates) > limit {
+		updates = updates[len(updates)-limit:]
+	}
+	return updates
+}
+
+// GetRecentUpdatesHandler returns the last updates with receipt times and
+// gaps so operators can spot a stalled feed (admin, ?limit=N, default 50)
+func GetRecentUpdatesHandler(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		c.JSON(400, gin.H{"error": "Invalid limit"})
+		return
+	}
+	if limit > len(snapshots) {
+		limit = len(snapshots)
+	}
+
+	updates := recentUpdates(limit)
+
+	var sinceLast *float64
+	maxGap := 0.0
+	if len(updates) > 0 {
+		seconds := time.Since(updates[len(updates)-1].ReceivedAt).Seconds()
+		sinceLast = &seconds
+	}
+	for _, u := range updates {
+		if u.GapSeconds != nil && *u.GapSeconds > maxGap {
+			maxGap = *u.GapSeconds
+		}
+	}
+
+	c.JSON(200, gin.H{
+		"status":             "success",
+		"enabled":            len(snapshots) > 0,
+		"count":              len(updates),
+		"seconds_since_last": sinceLast,
+		"max_gap_seconds":    maxGap,
+		"updates":            updates,
+	})
+}
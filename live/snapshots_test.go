@@ -0,0 +1,121 @@
+package live
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// useSnapshots replaces the ring buffer with one of size holding an update
+// at each offset from a fixed start
+func useSnapshots(t *testing.T, size int, offsets ...time.Duration) {
+	t.Helper()
+	snapshotsMutex.Lock()
+	oldSnapshots, oldNext, oldFull := snapshots, snapshotNext, snapshotFull
+	snapshots, snapshotNext, snapshotFull = make([]Snapshot, size), 0, false
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	for i, offset := range offsets {
+		addSnapshotLocked(Snapshot{RecordedAt: start.Add(offset), Data: LotteryData{Live: string(rune('a' + i))}})
+	}
+	snapshotsMutex.Unlock()
+	t.Cleanup(func() {
+		snapshotsMutex.Lock()
+		snapshots, snapshotNext, snapshotFull = oldSnapshots, oldNext, oldFull
+		snapshotsMutex.Unlock()
+	})
+}
+
+type recentResponse struct {
+	Count   int            `json:"count"`
+	MaxGap  float64        `json:"max_gap_seconds"`
+	Updates []RecentUpdate `json:"updates"`
+}
+
+func getRecent(t *testing.T, query string) (int, recentResponse) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/recent", GetRecentUpdatesHandler)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/recent?"+query, nil))
+	var resp recentResponse
+	if w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("body %s: %v", w.Body.String(), err)
+		}
+	}
+	return w.Code, resp
+}
+
+// describe renders updates as live value and gap, "-" for no gap
+func describe(updates []RecentUpdate) []string {
+	out := []string{}
+	for _, u := range updates {
+		gap := "-"
+		if u.GapSeconds != nil {
+			gap = time.Duration(*u.GapSeconds * float64(time.Second)).String()
+		}
+		out = append(out, u.Data.Live+" "+gap)
+	}
+	return out
+}
+
+func TestRecentUpdatesOrderAndGaps(t *testing.T) {
+	// Five updates into a buffer of four; the first is overwritten
+	useSnapshots(t, 4, 0, 10*time.Second, 15*time.Second, 45*time.Second, 50*time.Second)
+
+	tests := []struct {
+		query  string
+		want   []string
+		maxGap float64
+	}{
+		// The oldest returned update keeps its gap while its predecessor is held
+		{"limit=3", []string{"c 5s", "d 30s", "e 5s"}, 30},
+		{"limit=1", []string{"e 5s"}, 5},
+		// Asking for more than the buffer holds returns everything; the oldest has no gap
+		{"limit=10", []string{"b -", "c 5s", "d 30s", "e 5s"}, 30},
+		{"", []string{"b -", "c 5s", "d 30s", "e 5s"}, 30},
+	}
+	for _, tt := range tests {
+		code, resp := getRecent(t, tt.query)
+		if code != http.StatusOK {
+			t.Fatalf("%q: status %d", tt.query, code)
+		}
+		if got := describe(resp.Updates); !equalStrings(got, tt.want) || resp.Count != len(tt.want) {
+			t.Errorf("%q: updates %v (count %d), want %v", tt.query, got, resp.Count, tt.want)
+		}
+		if resp.MaxGap != tt.maxGap {
+			t.Errorf("%q: max gap %v, want %v", tt.query, resp.MaxGap, tt.maxGap)
+		}
+	}
+}
+
+func TestRecentUpdatesEmptyAndInvalid(t *testing.T) {
+	useSnapshots(t, 4)
+
+	if code, resp := getRecent(t, "limit=5"); code != http.StatusOK || resp.Count != 0 || len(resp.Updates) != 0 {
+		t.Errorf("empty buffer: %d %+v", code, resp)
+	}
+	for _, query := range []string{"limit=0", "limit=-1", "limit=x"} {
+		if code, _ := getRecent(t, query); code != http.StatusBadRequest {
+			t.Errorf("%q: status %d, want 400", query, code)
+		}
+	}
+}
+
+func equalStrings(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
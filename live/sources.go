@@ -0,0 +1,116 @@
+package live
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"burma2d/config"
+)
+
+// updateTimeLayout is the upstream "updatetime" format, in Myanmar time
+const updateTimeLayout = "15:04:05 02/01/2006"
+
+// defaultSource names updates posted without a source
+const defaultSource = "default"
+
+// SourceStatus is the last update seen from one upstream scraper
+type SourceStatus struct {
+	Name       string    `json:"name"`
+	LastUpdate time.Time `json:"last_update"` // when the server received it
+	DataTime   string    `json:"data_time"`   // its updatetime field
+	Accepted   int       `json:"accepted"`
+	Ignored    int       `json:"ignored"`
+	Live       bool      `json:"live"`
+}
+
+var (
+	// preferredSource always wins (LIVE_PREFERRED_SOURCE); the live source is
+	// replaced by fresher data or when it goes quiet for sourceStaleAfter
+	// (LIVE_SOURCE_STALE_AFTER, default 2m)
+	preferredSource  string
+	sourceStaleAfter = 2 * time.Minute
+
+	liveSource   string
+	liveDataTime time.Time
+	sources      = make(map[string]*SourceStatus)
+	sourcesMutex sync.Mutex
+)
+
+func initSources() {
+	preferredSource = strings.TrimSpace(config.String("LIVE_PREFERRED_SOURCE", ""))
+	sourceStaleAfter = config.Duration("LIVE_SOURCE_STALE_AFTER", 2*time.Minute)
+}
+
+// sourceName returns the source an input claims, or the default
+func sourceName(input *LotteryDataInput) string {
+	if s := strings.TrimSpace(input.Source); s != "" {
+		return s
+	}
+	return defaultSource
+}
+
+// acceptSource records an update from source and reports whether it should
+// replace the current data: it comes from the live or preferred source, it
+// carries a newer updatetime, or the live source has gone stale. Served data
+// never moves back to an older updatetime except on a stale takeover, and an
+// updatetime that does not parse is never newer.
+func acceptSource(source, updateTime string, now time.Time) bool {
+	dataTime, err := time.ParseInLocation(updateTimeLayout, updateTime, myanmarLocation)
+	parsed := err == nil
+
+	sourcesMutex.Lock()
+	defer sourcesMutex.Unlock()
+
+	current := sources[liveSource]
+	s := sources[source]
+	if s == nil {
+		s = &SourceStatus{Name: source}
+		sources[source] = s
+	}
+
+	newer := parsed && dataTime.After(liveDataTime)
+	regressed := parsed && dataTime.Before(liveDataTime)
+	stale := current != nil && sourceStaleAfter > 0 && now.Sub(current.LastUpdate) > sourceStaleAfter
+
+	accept := current == nil ||
+		(parsed && stale) ||
+		(!regressed && (source == liveSource || source == preferredSource || newer))
+
+	s.LastUpdate = now
+	s.DataTime = updateTime
+	if !accept {
+		s.Ignored++
+		return false
+	}
+
+	s.Accepted++
+	liveSource = source
+	if parsed {
+		liveDataTime = dataTime
+	}
+	return true
+}
+
+// LiveSource returns the source whose data is currently served
+func LiveSource() string {
+	sourcesMutex.Lock()
+	defer sourcesMutex.Unlock()
+	return liveSource
+}
+
+// Sources returns every source seen since startup, by name
+func Sources() []SourceStatus {
+	sourcesMutex.Lock()
+	defer sourcesMutex.Unlock()
+
+	list := make([]SourceStatus, 0, len(sources))
+	for _, s := range sources {
+		status := *s
+		status.Live = s.Name == liveSource
+		list = append(list, status)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
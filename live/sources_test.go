@@ -0,0 +1,76 @@
+package live
+
+import (
+	"testing"
+	"time"
+)
+
+func resetSources(t *testing.T) {
+	t.Helper()
+	myanmarLocation = loadMyanmarLocation()
+	sourcesMutex.Lock()
+	liveSource, liveDataTime = "", time.Time{}
+	sources = make(map[string]*SourceStatus)
+	sourcesMutex.Unlock()
+	preferredSource, sourceStaleAfter = "", 2*time.Minute
+}
+
+func TestAcceptSource(t *testing.T) {
+	resetSources(t)
+	now := time.Now()
+
+	steps := []struct {
+		source, updateTime string
+		after              time.Duration
+		want               bool
+	}{
+		{"primary", "12:00:00 16/10/2025", 0, true},                // first update
+		{"primary", "12:00:05 16/10/2025", time.Second, true},      // live source moves forward
+		{"backup", "12:00:03 16/10/2025", 2 * time.Second, false},  // older than served data
+		{"backup", "", 3 * time.Second, false},                     // empty time is not newer
+		{"backup", "garbage", 4 * time.Second, false},              // unparseable time is not newer
+		{"primary", "11:59:00 16/10/2025", 5 * time.Second, false}, // live source regresses
+		{"primary", "12:00:05 16/10/2025", 6 * time.Second, true},  // repeat of the same time
+		{"backup", "12:00:10 16/10/2025", 7 * time.Second, true},   // newer data takes over
+		{"primary", "12:00:08 16/10/2025", 8 * time.Second, false}, // old live source is now behind
+	}
+	for i, step := range steps {
+		got := acceptSource(step.source, step.updateTime, now.Add(step.after))
+		if got != step.want {
+			t.Fatalf("step %d (%s %q): accepted = %v, want %v", i, step.source, step.updateTime, got, step.want)
+		}
+	}
+	if LiveSource() != "backup" {
+		t.Fatalf("live source = %q, want backup", LiveSource())
+	}
+}
+
+func TestAcceptSourceStaleTakeover(t *testing.T) {
+	resetSources(t)
+	now := time.Now()
+
+	acceptSource("primary", "12:00:00 16/10/2025", now)
+
+	// Unparseable times never take over, even from a quiet source
+	if acceptSource("backup", "", now.Add(3*time.Minute)) {
+		t.Fatal("garbage updatetime took over a stale source")
+	}
+	// A parseable one does, even if its clock is behind
+	if !acceptSource("backup", "11:58:00 16/10/2025", now.Add(3*time.Minute)) {
+		t.Fatal("stale live source was not replaced")
+	}
+}
+
+func TestAcceptSourcePreferred(t *testing.T) {
+	resetSources(t)
+	preferredSource = "official"
+	now := time.Now()
+
+	acceptSource("backup", "12:00:10 16/10/2025", now)
+	if !acceptSource("official", "12:00:10 16/10/2025", now.Add(time.Second)) {
+		t.Fatal("preferred source with the same time was ignored")
+	}
+	if acceptSource("official", "12:00:00 16/10/2025", now.Add(2*time.Second)) {
+		t.Fatal("preferred source moved the data backwards")
+	}
+}
@@ -0,0 +1,67 @@
+package live
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Allowed content of lottery fields. Empty values and the "--"/"---"
+// placeholders mean "not published yet" and are always accepted.
+var (
+	// Drawn digits: live number, results and modern/internet numbers
+	digitsPattern = regexp.MustCompile(`^\d{2,3}$`)
+	// SET index and value figures, e.g. "1,456.78"
+	figurePattern = regexp.MustCompile(`^\d{1,3}(,?\d{3})*(\.\d+)?$`)
+	// Optional scraper name
+	sourcePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+)
+
+// isPlaceholder reports whether v is an empty or "not yet" value
+func isPlaceholder(v string) bool {
+	return v == "" || v == "--" || v == "---"
+}
+
+// Validate checks that every numeric field holds only digits (or an index
+// figure for set/value) so HTML or letters never reach clients or history.
+// The error lists each offending field by its input key.
+func (input *LotteryDataInput) Validate() error {
+	invalid := input.invalidFields()
+	if len(invalid) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid value for %s", strings.Join(invalid, ", "))
+}
+
+// invalidFields returns the input keys whose values are not allowed, sorted
+func (input *LotteryDataInput) invalidFields() []string {
+	fields := map[string]struct {
+		value   string
+		pattern *regexp.Regexp
+	}{
+		"live":        {input.Live, digitsPattern},
+		"1200":        {input.Result1200, digitsPattern},
+		"430":         {input.Result430, digitsPattern},
+		"930modern":   {input.Modern930, digitsPattern},
+		"930internet": {input.Internet930, digitsPattern},
+		"200modern":   {input.Modern200, digitsPattern},
+		"200internet": {input.Internet200, digitsPattern},
+		"1200set":     {input.Set1200, figurePattern},
+		"1200value":   {input.Value1200, figurePattern},
+		"430set":      {input.Set430, figurePattern},
+		"430value":    {input.Value430, figurePattern},
+		"source":      {input.Source, sourcePattern},
+	}
+
+	var invalid []string
+	for key, f := range fields {
+		if isPlaceholder(f.value) || f.pattern.MatchString(f.value) {
+			continue
+		}
+		invalid = append(invalid, key)
+	}
+
+	sort.Strings(invalid)
+	return invalid
+}
@@ -0,0 +1,66 @@
+package live
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestValidateAcceptsDigitsAndPlaceholders(t *testing.T) {
+	for _, input := range []LotteryDataInput{
+		{},
+		{Live: "47", Result1200: "--", Result430: "---", Status: "On"},
+		{Live: "123", Modern930: "05", Internet930: "63", Modern200: "---", Internet200: "00"},
+		{Set1200: "1,456.78", Value1200: "23,456.01", Set430: "1456.78", Value430: "987"},
+	} {
+		if err := input.Validate(); err != nil {
+			t.Errorf("%+v: %v", input, err)
+		}
+	}
+}
+
+func TestValidateRejectsNonDigits(t *testing.T) {
+	tests := []struct {
+		input LotteryDataInput
+		want  string
+	}{
+		{LotteryDataInput{Live: "<b>4</b>"}, "live"},
+		{LotteryDataInput{Result430: "4a"}, "430"},
+		{LotteryDataInput{Result1200: "7"}, "1200"},
+		{LotteryDataInput{Modern930: "1234"}, "930modern"},
+		{LotteryDataInput{Internet200: " 12"}, "200internet"},
+		{LotteryDataInput{Live: "٤٧"}, "live"}, // non-ASCII digits
+		{LotteryDataInput{Set1200: "1,45x.78"}, "1200set"},
+		{LotteryDataInput{Value430: "1.2.3"}, "430value"},
+		{LotteryDataInput{Live: "x", Set430: "y"}, "430set, live"},
+	}
+	for _, tt := range tests {
+		err := tt.input.Validate()
+		if err == nil || err.Error() != "invalid value for "+tt.want {
+			t.Errorf("%+v: %v, want invalid %s", tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestUpdateRejectsInvalidFields(t *testing.T) {
+	setUpdateKey(t, "", true)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/update", UpdateLotteryData)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/update",
+		strings.NewReader(`{"live":"<script>","430":"47"}`)))
+
+	var body struct {
+		Error string `json:"error"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if w.Code != http.StatusBadRequest || body.Error != "invalid value for live" {
+		t.Fatalf("status %d %s, want 400 naming live", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,55 @@
+package live
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// widgetData is the minimal payload for home-screen widgets
+type widgetData struct {
+	Live   string `json:"n"` // live number
+	Status string `json:"s"` // service status
+	Time   string `json:"t"` // last update time
+}
+
+// widgetMaxAge lets clients and proxies reuse the payload briefly between polls
+const widgetMaxAge = "public, max-age=5"
+
+var (
+	cachedWidgetJSON  []byte
+	cachedWidgetMutex sync.RWMutex
+)
+
+// refreshWidgetCache rebuilds the cached widget payload from the current data
+func refreshWidgetCache() {
+	dataMutex.RLock()
+	w := widgetData{
+		Live:   currentData.Live,
+		Status: currentData.Status,
+		Time:   currentData.UpdateTime,
+	}
+	dataMutex.RUnlock()
+
+	payload, err := json.Marshal(w)
+	if err != nil {
+		log.Printf("❌ Failed to marshal widget data: %v", err)
+		return
+	}
+
+	cachedWidgetMutex.Lock()
+	cachedWidgetJSON = payload
+	cachedWidgetMutex.Unlock()
+}
+
+// GetWidgetData returns the live number, status and update time in a tiny flat object
+func GetWidgetData(c *gin.Context) {
+	cachedWidgetMutex.RLock()
+	payload := cachedWidgetJSON
+	cachedWidgetMutex.RUnlock()
+
+	c.Header("Cache-Control", widgetMaxAge)
+	c.Data(200, "application/json; charset=utf-8", payload)
+}
@@ -0,0 +1,51 @@
+package live
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func getWidget(t *testing.T) (widgetData, *httptest.ResponseRecorder) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/widget", GetWidgetData)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/widget", nil))
+	var data widgetData
+	if err := json.Unmarshal(w.Body.Bytes(), &data); err != nil {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	return data, w
+}
+
+func TestWidgetRefreshedOnUpdate(t *testing.T) {
+	setupHistory(t, 10, 0)
+	resetSources(t)
+
+	Update(&LotteryDataInput{Date: "2026-03-02", Live: "33", Status: "1", UpdateTime: "10:00:00 02/03/2026"})
+	got, w := getWidget(t)
+	if want := (widgetData{Live: "33", Status: "1", Time: "10:00:00 02/03/2026"}); got != want {
+		t.Fatalf("widget = %+v, want %+v", got, want)
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != widgetMaxAge {
+		t.Errorf("Cache-Control = %q", cc)
+	}
+
+	// The cached payload follows the next update, using only the short keys
+	Update(&LotteryDataInput{Date: "2026-03-02", Live: "47", Status: "2", UpdateTime: "10:00:05 02/03/2026"})
+	got, w = getWidget(t)
+	if want := (widgetData{Live: "47", Status: "2", Time: "10:00:05 02/03/2026"}); got != want {
+		t.Fatalf("after the update: widget = %+v, want %+v", got, want)
+	}
+	var keys map[string]string
+	json.Unmarshal(w.Body.Bytes(), &keys)
+	if len(keys) != 3 || keys["n"] != "47" || keys["s"] != "2" || keys["t"] == "" {
+		t.Errorf("payload = %s, want only n, s and t", w.Body.String())
+	}
+}
@@ -0,0 +1,89 @@
+package live
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"burma2d/config"
+)
+
+// historyWindow is the daily Myanmar-time window in which the draw result is
+// copied into history, and the result field that must be ready first
+type historyWindow struct {
+	start, end  time.Duration // offsets from midnight; end is exclusive
+	resultField string        // input key: "430" or "1200"
+}
+
+// insertWindow defaults to the 4:30 draw, 16:30-16:35
+var insertWindow = historyWindow{
+	start:       16*time.Hour + 30*time.Minute,
+	end:         16*time.Hour + 35*time.Minute,
+	resultField: "430",
+}
+
+// initHistoryWindow reads LIVE_HISTORY_WINDOW_START and _END (HH:MM,
+// Myanmar time) and LIVE_HISTORY_RESULT_FIELD ("430" or "1200"). Invalid
+// values keep the defaults.
+func initHistoryWindow() {
+	w := insertWindow
+	var err error
+
+	if w.start, err = parseClock(config.String("LIVE_HISTORY_WINDOW_START", "16:30")); err != nil {
+		log.Printf("⚠️ Invalid LIVE_HISTORY_WINDOW_START, using %s: %v", insertWindow, err)
+		return
+	}
+	if w.end, err = parseClock(config.String("LIVE_HISTORY_WINDOW_END", "16:35")); err != nil {
+		log.Printf("⚠️ Invalid LIVE_HISTORY_WINDOW_END, using %s: %v", insertWindow, err)
+		return
+	}
+	if w.end <= w.start {
+		log.Printf("⚠️ LIVE_HISTORY_WINDOW_END must be after the start, using %s", insertWindow)
+		return
+	}
+
+	w.resultField = config.String("LIVE_HISTORY_RESULT_FIELD", "430")
+	if w.resultField != "430" && w.resultField != "1200" {
+		log.Printf("⚠️ LIVE_HISTORY_RESULT_FIELD must be 430 or 1200, using %s", insertWindow)
+		return
+	}
+
+	insertWindow = w
+}
+
+// parseClock parses HH:MM into an offset from midnight
+func parseClock(v string) (time.Duration, error) {
+	t, err := time.Parse("15:04", v)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// contains reports whether now falls inside the window on its Myanmar day
+func (w historyWindow) contains(now time.Time) bool {
+	local := now.In(myanmarLocation)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, myanmarLocation)
+	offset := local.Sub(midnight)
+	return offset >= w.start && offset < w.end
+}
+
+// result returns the guarded result from data
+func (w historyWindow) result(data *LotteryData) string {
+	if w.resultField == "1200" {
+		return data.Result1200
+	}
+	return data.Result430
+}
+
+func (w historyWindow) String() string {
+	clock := func(d time.Duration) string {
+		return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return fmt.Sprintf("%s-%s GMT+6:30, %s result", clock(w.start), clock(w.end), w.resultField)
+}
+
+// HistoryWindow describes the configured history insert window
+func HistoryWindow() string {
+	return insertWindow.String()
+}
@@ -0,0 +1,104 @@
+package live
+
+import (
+	"testing"
+	"time"
+)
+
+var defaultWindow = historyWindow{
+	start:       16*time.Hour + 30*time.Minute,
+	end:         16*time.Hour + 35*time.Minute,
+	resultField: "430",
+}
+
+// loadWindow resets the window to the defaults and reads it from env
+func loadWindow(t *testing.T, env map[string]string) historyWindow {
+	t.Helper()
+	old := insertWindow
+	t.Cleanup(func() { insertWindow = old })
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+	insertWindow = defaultWindow
+	initHistoryWindow()
+	return insertWindow
+}
+
+func TestInitHistoryWindow(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{"defaults", nil, "16:30-16:35 GMT+6:30, 430 result"},
+		{"noon draw", map[string]string{
+			"LIVE_HISTORY_WINDOW_START": "12:01",
+			"LIVE_HISTORY_WINDOW_END":   "12:10",
+			"LIVE_HISTORY_RESULT_FIELD": "1200",
+		}, "12:01-12:10 GMT+6:30, 1200 result"},
+		{"bad start", map[string]string{"LIVE_HISTORY_WINDOW_START": "4:30pm"}, "16:30-16:35 GMT+6:30, 430 result"},
+		{"end before start", map[string]string{"LIVE_HISTORY_WINDOW_END": "16:00"}, "16:30-16:35 GMT+6:30, 430 result"},
+		{"bad field", map[string]string{
+			"LIVE_HISTORY_WINDOW_END":   "16:40",
+			"LIVE_HISTORY_RESULT_FIELD": "930",
+		}, "16:30-16:35 GMT+6:30, 430 result"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := loadWindow(t, tt.env).String(); got != tt.want {
+				t.Errorf("window = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHistoryWindowContains(t *testing.T) {
+	myanmarLocation = loadMyanmarLocation()
+	w := historyWindow{start: 12 * time.Hour, end: 12*time.Hour + 10*time.Minute}
+	at := func(hour, minute, second int) time.Time {
+		return time.Date(2026, 3, 2, hour, minute, second, 0, myanmarLocation)
+	}
+
+	for _, tt := range []struct {
+		now  time.Time
+		want bool
+	}{
+		{at(11, 59, 59), false},
+		{at(12, 0, 0), true},
+		{at(12, 9, 59), true},
+		{at(12, 10, 0), false}, // the end is exclusive
+		{at(0, 5, 0), false},
+		// The clock may be in any zone; the window is Myanmar time
+		{time.Date(2026, 3, 2, 5, 35, 0, 0, time.UTC), true},
+	} {
+		if got := w.contains(tt.now); got != tt.want {
+			t.Errorf("contains(%s) = %v, want %v", tt.now.Format(time.RFC3339), got, tt.want)
+		}
+	}
+}
+
+func TestConfiguredWindowGuardsInsert(t *testing.T) {
+	loadWindow(t, map[string]string{
+		"LIVE_HISTORY_WINDOW_START": "12:01",
+		"LIVE_HISTORY_WINDOW_END":   "12:10",
+		"LIVE_HISTORY_RESULT_FIELD": "1200",
+	})
+
+	// The old 4:30 window no longer inserts
+	h := setupHistory(t, 16, 31)
+	checkAndInsertHistory(&LotteryData{Date: "2026-03-02", Result1200: "12", Result430: "45"})
+	if h.calls != 0 {
+		t.Fatalf("inserted at 16:31 with a noon window")
+	}
+
+	h = setupHistory(t, 12, 5)
+	// The configured field guards the insert, not the 4:30 result
+	checkAndInsertHistory(&LotteryData{Date: "2026-03-02", Result1200: "--", Result430: "45"})
+	if h.calls != 0 {
+		t.Fatalf("inserted before the 1200 result was ready")
+	}
+	checkAndInsertHistory(&LotteryData{Date: "2026-03-02", Result1200: "12", Result430: "--"})
+	if h.calls != 1 {
+		t.Fatalf("inserter called %d times at 12:05 with the 1200 result ready, want 1", h.calls)
+	}
+}
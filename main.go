@@ -2,19 +2,37 @@ package main
 
 import (
 	"burma2d/admin"
+	"burma2d/adminauth"
+	"burma2d/appconfig"
 	"burma2d/chat"
+	"burma2d/chatcore"
 	"burma2d/chatws"
+	"burma2d/config"
 	"burma2d/fcm"
 	"burma2d/gift"
+	"burma2d/googleauth"
+	"burma2d/health"
+	"burma2d/jsonutil"
 	"burma2d/live"
+	"burma2d/pagination"
 	"burma2d/paper"
+	"burma2d/proxy"
+	"burma2d/sessionlog"
+	"burma2d/shutdown"
 	"burma2d/slider"
 	"burma2d/threed"
+	"burma2d/tracing"
 	"burma2d/twodhistory"
+	"burma2d/userdata"
+	"burma2d/wordfilter"
+	"context"
+	"errors"
 	"fmt"
 	"log"
-	"os"
+	"net"
+	"net/http"
 	"runtime"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -41,9 +59,28 @@ func main() {
 	r.Use(gin.Recovery()) // Panic recovery
 	// Skip gin.Logger() middleware in production for better performance
 
-	// Enable CORS for all origins
+	// Optional OpenTelemetry tracing (no-op unless TRACING_ENABLED=true)
+	if err := tracing.Init(); err != nil {
+		log.Printf("⚠️ Warning: tracing initialization failed: %v", err)
+	}
+	defer tracing.Shutdown(context.Background())
+	r.Use(tracing.Middleware())
+
+	// Encoder settings for large JSON list responses (JSON_ESCAPE_HTML)
+	jsonutil.Init()
+
+	// Upload size limit (UPLOAD_MAX_MB)
+	admin.ConfigureUploads(r)
+
+	// Trusted proxies decide which forwarding headers count for client IP and scheme
+	if err := proxy.Configure(r); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// Enable CORS (all origins unless CORS_ALLOW_ORIGIN is set)
+	corsOrigin := config.String("CORS_ALLOW_ORIGIN", "*")
 	r.Use(func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
+		c.Writer.Header().Set("Access-Control-Allow-Origin", corsOrigin)
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
 		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 		if c.Request.Method == "OPTIONS" {
@@ -53,12 +90,12 @@ func main() {
 		c.Next()
 	})
 
+	// Health check for load balancers and probes (registered even without a DB)
+	r.GET("/healthz", health.Handler)
+
 	// Initialize database
-	dbPath := os.Getenv("DATABASE_PATH")
-	if dbPath == "" {
-		// Default SQLite database file
-		dbPath = "./burma2d.db"
-	}
+	// Default SQLite database file
+	dbPath := config.String("DATABASE_PATH", "./burma2d.db")
 
 	log.Printf("🔌 Attempting database connection...")
 	log.Printf("� Database file: %s", dbPath)
@@ -80,21 +117,31 @@ func main() {
 		admin.InitDB(db)
 		threed.InitDB(db)
 		paper.InitDB(db)
+		if err := appconfig.InitDB(db); err != nil {
+			log.Printf("⚠️ Warning: app config unavailable: %v", err)
+		}
+		chatcore.Init()
+		chatcore.InitDB(db)
 		chat.InitDB(db)
+		sessionlog.InitDB(db)
+		wordfilter.InitDB(db)
 		chatws.InitDB(db) // NEW: Initialize WebSocket chat
+		fcm.InitDB(db)
+		userdata.InitDB(db)
 		log.Println("✅ All database modules initialized!")
 	}
 
 	// Configure Google OAuth for chat (REPLACE WITH YOUR ACTUAL CLIENT ID)
 	// Get this from Firebase Console > Project Settings > General > Web API Key
 	// Or from Google Cloud Console > APIs & Services > Credentials
-	googleClientID := os.Getenv("GOOGLE_OAUTH_CLIENT_ID")
+	googleClientID := config.String("GOOGLE_OAUTH_CLIENT_ID", "")
 	if googleClientID == "" {
 		log.Println("⚠️ Warning: GOOGLE_OAUTH_CLIENT_ID not set - using development mode")
 		log.Println("⚠️ Set environment variable or replace with actual client ID for production")
 	} else {
 		chat.SetGoogleClientID(googleClientID)
 		chatws.SetGoogleClientID(googleClientID) // NEW: Set for WebSocket chat too
+		gift.SetGoogleClientID(googleClientID)
 	}
 
 	// Initialize live package
@@ -113,9 +160,16 @@ func main() {
 		log.Println("⚠️ Falling back to local file storage for uploads")
 	}
 
+	// Optional startup self-test (SELFTEST_ON_STARTUP, SELFTEST_STRICT)
+	if err := health.RunStartupSelfTest("admin/templates/*.html"); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
 	// Register history inserter callback if database is enabled
 	if dbEnabled {
-		live.SetHistoryInserter(func(data *live.LotteryData) error {
+		live.EnableSnapshotPersistence(twodhistory.GetDB())
+
+		live.SetHistoryInserter(func(data *live.LotteryData) (bool, error) {
 			// Convert live.LotteryData to twodhistory.LotteryData
 			histData := &twodhistory.LotteryData{
 				Date:        data.Date,
@@ -135,27 +189,57 @@ func main() {
 			}
 			return twodhistory.InsertFromLotteryData(histData)
 		})
-		log.Println("✅ History auto-insert enabled (16:30-16:35 GMT+6:30)")
+		live.SetPublishedAtLookup(twodhistory.PublishedAt)
+		if fcm.IsInitialized() {
+			twodhistory.SetResultNotifier(func(date, number string) error {
+				_, err := fcm.SendResultNotification(date, number)
+				return err
+			})
+			log.Printf("✅ 4:30 result notifications enabled (topic %s)", fcm.ResultsTopic())
+		}
+		log.Printf("✅ History auto-insert enabled (%s)", live.HistoryWindow())
 	}
 
 	// Routes - Burma2D API (public endpoints)
 	r.POST("/api/burma2d/update", live.UpdateLotteryData)
 	r.GET("/api/burma2d/stream", live.StreamLotteryData)
 	r.GET("/api/burma2d/live", live.GetCurrentData)
+	r.GET("/api/burma2d/live/widget", live.GetWidgetData)
+	r.GET("/api/burma2d/live/snapshots", live.GetSnapshots)
 
 	// History routes
 	r.GET("/api/burma2d/history", twodhistory.GetHistoryHandler)
+	r.GET("/api/burma2d/history/schema", twodhistory.GetSchemaHandler)
+	r.GET("/api/burma2d/history/stats", twodhistory.GetStatsHandler)
+	r.GET("/api/burma2d/history/streaks", twodhistory.GetStreaksHandler)
+	r.GET("/api/burma2d/history/compare", twodhistory.CompareHandler)
 	r.POST("/api/burma2d/history/check", twodhistory.CheckAndInsertHandler)
 
 	// Gifts routes
 	r.GET("/api/burma2d/gifts", gift.GetGiftsHandler)
 	r.GET("/api/burma2d/gifts/types", gift.GetGiftTypesHandler)
+	r.GET("/api/burma2d/gifts/affordable", gift.GetAffordableGiftsHandler)
+	r.GET("/api/burma2d/gifts/search", gift.SearchGiftsHandler)
+	r.GET("/api/burma2d/gifts/stream", gift.StreamHandler)
+	r.POST("/api/burma2d/gifts/:id/redeem", gift.RedeemGiftHandler)
+	r.GET("/api/burma2d/gifts/:id/eligibility", gift.GetEligibilityHandler)
+
+	// Admin authentication - every /admin and /api/admin route goes through these groups
+	adminauth.Init()
+	adminPages := r.Group("/admin", adminauth.Required())
+	adminAPI := r.Group("/api/admin", adminauth.Required())
 
 	// Admin Gift Types CRUD
-	r.GET("/api/admin/gift-types", gift.GetAllGiftTypesHandler)
-	r.POST("/api/admin/gift-types", gift.CreateGiftTypeHandler)
-	r.PUT("/api/admin/gift-types/:id", gift.UpdateGiftTypeHandler)
-	r.DELETE("/api/admin/gift-types/:id", gift.DeleteGiftTypeHandler)
+	adminAPI.GET("/gift-types", gift.GetAllGiftTypesHandler)
+	adminAPI.POST("/gift-types", gift.CreateGiftTypeHandler)
+	adminAPI.PUT("/gift-types/:id", gift.UpdateGiftTypeHandler)
+	adminAPI.DELETE("/gift-types/:id", gift.DeleteGiftTypeHandler)
+
+	// Live broadcast control (updates are still stored while paused) and feed monitor
+	adminAPI.POST("/live/pause", live.PauseBroadcastHandler)
+	adminAPI.POST("/live/resume", live.ResumeBroadcastHandler)
+	adminAPI.GET("/live/recent", live.GetRecentUpdatesHandler)
+	adminAPI.POST("/live/lint", live.LintUpdateHandler)
 
 	// Sliders routes
 	r.GET("/api/burma2d/sliders", slider.GetSlidersHandler)
@@ -169,34 +253,61 @@ func main() {
 	// Paper routes
 	r.GET("/api/burma2d/papers/types", paper.GetAllTypes)
 	r.GET("/api/burma2d/papers/types/:type_id/images", paper.GetImagesByType)
+	r.GET("/api/burma2d/papers/images/recent", paper.GetRecentImages)
 
 	// Image serving route - static files from uploads directory
-	r.Static("/uploads", "./uploads")
+	// Both routes support Range/If-Range requests and answer HEAD for download clients
+	r.Group("/uploads", admin.StaticImageCache("./uploads")).Static("/", "./uploads")
+	r.GET("/api/images/:filename", admin.ServeImageHandler)
+	r.HEAD("/api/images/:filename", admin.ServeImageHandler)
 
 	// Admin routes
+	htmlEnabled := false
 	if dbEnabled {
-		// Load HTML templates
-		r.LoadHTMLGlob("admin/templates/*.html")
+		// Load HTML templates; missing or broken templates only disable the pages
+		if err := admin.LoadTemplates(r, admin.TemplatesPattern); err != nil {
+			log.Printf("❌ Failed to load admin templates (%s), admin HTML pages disabled: %v", admin.TemplatesPattern, err)
+		} else {
+			htmlEnabled = true
+		}
+
+		// Admin login (public)
+		r.POST("/admin/login", adminauth.LoginHandler)
+		r.POST("/admin/logout", adminauth.LogoutHandler)
 
 		// Admin dashboard pages
-		r.GET("/admin", admin.AdminDashboardHandler)
-		r.GET("/admin/gifts", admin.ManageGiftsPageHandler)
-		r.GET("/admin/sliders", admin.ManageSlidersPageHandler)
-		r.GET("/admin/threed", admin.ManageThreeDPageHandler)
-		r.GET("/admin/paper", admin.ManagePaperPageHandler)
-		r.GET("/admin/gifts/create", admin.CreateGiftPageHandler)
-		r.GET("/admin/sliders/create", admin.CreateSliderPageHandler)
-		r.GET("/admin/threed/create", admin.CreateThreeDPageHandler)
-		r.POST("/admin/threed/create", admin.CreateThreeDHandler)
-		r.GET("/admin/gifts/edit/:id", admin.EditGiftPageHandler)
-		r.GET("/admin/sliders/edit/:id", admin.EditSliderPageHandler)
-		r.GET("/admin/threed/edit", admin.EditThreeDPageHandler)
-		r.POST("/admin/threed/edit", admin.EditThreeDHandler)
-		r.POST("/admin/threed/delete", admin.DeleteThreeDHandler)
+		if htmlEnabled {
+			r.GET("/admin/login", adminauth.LoginPageHandler)
+			adminPages.GET("", admin.AdminDashboardHandler)
+			adminPages.GET("/gifts", admin.ManageGiftsPageHandler)
+			adminPages.GET("/sliders", admin.ManageSlidersPageHandler)
+			adminPages.GET("/threed", admin.ManageThreeDPageHandler)
+			adminPages.GET("/paper", admin.ManagePaperPageHandler)
+			adminPages.GET("/gifts/create", admin.CreateGiftPageHandler)
+			adminPages.GET("/sliders/create", admin.CreateSliderPageHandler)
+			adminPages.GET("/threed/create", admin.CreateThreeDPageHandler)
+			adminPages.POST("/threed/create", admin.CreateThreeDHandler)
+			adminPages.GET("/gifts/edit/:id", admin.EditGiftPageHandler)
+			adminPages.GET("/sliders/edit/:id", admin.EditSliderPageHandler)
+			adminPages.GET("/threed/edit", admin.EditThreeDPageHandler)
+			adminPages.POST("/threed/edit", admin.EditThreeDHandler)
+			adminPages.POST("/threed/delete", admin.DeleteThreeDHandler)
+		} else {
+			r.GET("/admin/login", admin.PagesUnavailableHandler)
+			adminPages.GET("", admin.PagesUnavailableHandler)
+		}
+
+		// History backfill for days the auto-insert window missed
+		adminAPI.POST("/history/backfill", twodhistory.BackfillHandler)
+
+		// Admin 3D bulk corrections
+		adminAPI.PUT("/threed/batch", threed.BatchUpdateResults)
+		adminAPI.POST("/3d/import", threed.ImportResults)
 
 		// Image upload routes
-		r.POST("/api/admin/upload-image", admin.UploadImageHandler)
-		r.DELETE("/api/admin/delete-image/:filename", admin.DeleteImageHandler)
+		adminAPI.POST("/upload-image", admin.UploadImageHandler)
+		adminAPI.POST("/upload-images", admin.BatchUploadImagesHandler)
+		adminAPI.DELETE("/delete-image/:filename", admin.DeleteImageHandler)
 
 		// Version/Health check endpoint
 		r.GET("/api/version", func(c *gin.Context) {
@@ -207,16 +318,33 @@ func main() {
 		})
 
 		// Admin API routes for gifts
-		r.GET("/api/admin/gifts", func(c *gin.Context) {
-			gifts, err := gift.GetAllGiftsForAdmin()
+		adminAPI.GET("/gifts", func(c *gin.Context) {
+			p, err := pagination.Parse(c, pagination.Options{DefaultLimit: 50, MaxLimit: 500})
+			if err != nil {
+				c.JSON(400, gin.H{"error": err.Error()})
+				return
+			}
+			includeDeleted := c.Query("include_deleted") == "true"
+			gifts, total, err := gift.GetAllGiftsForAdmin(p, includeDeleted)
 			if err != nil {
 				c.JSON(500, gin.H{"error": err.Error()})
 				return
 			}
-			c.JSON(200, gifts)
+			c.JSON(200, gin.H{
+				"gifts":  gifts,
+				"total":  total,
+				"limit":  p.Limit,
+				"offset": p.Offset,
+			})
 		})
-		r.GET("/api/admin/gifts/:id", admin.GetGiftByIDHandler)
-		r.POST("/api/admin/gifts", func(c *gin.Context) {
+		adminAPI.GET("/gifts/redemptions", gift.GetRedemptionsHandler)
+		adminAPI.GET("/redemptions/pending", gift.GetPendingRedemptionsHandler)
+		adminAPI.GET("/gifts/:id", admin.GetGiftByIDHandler)
+		adminAPI.GET("/gifts/:id/redemptions/count", gift.GetRedemptionCountHandler)
+		adminAPI.PUT("/gifts/:id/stock", gift.SetStockHandler)
+		adminAPI.POST("/gifts/:id/stock/adjust", gift.AdjustStockHandler)
+		adminAPI.PUT("/gifts/:id/low-stock-threshold", gift.SetLowStockThresholdHandler)
+		adminAPI.POST("/gifts", func(c *gin.Context) {
 			var newGift gift.Gift
 			if err := c.BindJSON(&newGift); err != nil {
 				c.JSON(400, gin.H{"error": err.Error()})
@@ -228,36 +356,95 @@ func main() {
 			}
 			c.JSON(200, gin.H{"message": "Gift created"})
 		})
-		r.PUT("/api/admin/gifts/:id", func(c *gin.Context) {
+		adminAPI.PUT("/gifts/:id", func(c *gin.Context) {
 			var updatedGift gift.Gift
 			if err := c.BindJSON(&updatedGift); err != nil {
 				c.JSON(400, gin.H{"error": err.Error()})
 				return
 			}
+			oldImage, _ := gift.GetImageLink(updatedGift.ID)
 			if err := gift.UpdateGift(updatedGift); err != nil {
 				c.JSON(500, gin.H{"error": err.Error()})
 				return
 			}
+			admin.RemoveReplacedImage(oldImage, updatedGift.ImageLink, c.Request.Host)
 			c.JSON(200, gin.H{"message": "Gift updated"})
 		})
-		r.DELETE("/api/admin/gifts/:id", func(c *gin.Context) {
+		adminAPI.DELETE("/gifts/:id", func(c *gin.Context) {
 			var id int
 			if _, err := fmt.Sscanf(c.Param("id"), "%d", &id); err != nil {
 				c.JSON(400, gin.H{"error": "Invalid ID"})
 				return
 			}
-			if err := gift.DeleteGift(id); err != nil {
-				c.JSON(500, gin.H{"error": err.Error()})
+			// Soft delete by default; ?permanent=true removes the row
+			deleteFn, message := gift.DeleteGift, "Gift deleted"
+			if c.Query("permanent") == "true" {
+				deleteFn, message = gift.PurgeGift, "Gift permanently deleted"
+			}
+			if err := deleteFn(id); err != nil {
+				status := 500
+				if errors.Is(err, gift.ErrGiftNotFound) {
+					status = 404
+				}
+				c.JSON(status, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(200, gin.H{"message": message})
+		})
+		adminAPI.POST("/gifts/:id/restore", func(c *gin.Context) {
+			var id int
+			if _, err := fmt.Sscanf(c.Param("id"), "%d", &id); err != nil {
+				c.JSON(400, gin.H{"error": "Invalid ID"})
 				return
 			}
-			c.JSON(200, gin.H{"message": "Gift deleted"})
+			if err := gift.RestoreGift(id); err != nil {
+				status := 500
+				if errors.Is(err, gift.ErrGiftNotFound) {
+					status = 404
+				}
+				c.JSON(status, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(200, gin.H{"message": "Gift restored"})
 		})
 
+		// Mobile app config and version check
+		r.GET("/api/app/config", appconfig.GetConfigHandler)
+		r.GET("/api/app/version-check", appconfig.VersionCheckHandler)
+		adminAPI.PUT("/app/config", appconfig.UpdateConfigHandler)
+
+		// Device token registration (sends the opt-in welcome push)
+		r.POST("/api/fcm/devices", fcm.RegisterDeviceHandler)
+
+		// Per gift type topic subscriptions for a device
+		r.POST("/api/fcm/gift-types", fcm.SubscribeGiftTypesHandler)
+		r.DELETE("/api/fcm/gift-types", fcm.UnsubscribeGiftTypesHandler)
+
+		// User data export and deletion (privacy requests)
+		adminAPI.GET("/users/:id/export", userdata.ExportHandler)
+		adminAPI.DELETE("/users/:id", userdata.DeleteHandler)
+
+		// Effective server configuration (secrets redacted)
+		adminAPI.GET("/config", admin.GetConfigHandler)
+
 		// Send custom notification to gifts topic
-		r.POST("/api/admin/notification", fcm.SendNotificationHandler)
+		adminAPI.POST("/notification", fcm.SendNotificationHandler)
+
+		// Send a notification to a single device token
+		adminAPI.POST("/fcm/send-to-device", fcm.SendToDeviceHandler)
+
+		// Log of every push sent
+		adminAPI.GET("/fcm/history", fcm.GetHistoryHandler)
+
+		// Server-side topic subscriptions for device tokens
+		adminAPI.POST("/fcm/subscribe", fcm.SubscribeHandler)
+		adminAPI.POST("/fcm/unsubscribe", fcm.UnsubscribeHandler)
+
+		// Send a sample result to the results topic to check the pipeline
+		adminAPI.POST("/notifications/test-result", fcm.SendTestResultHandler)
 
 		// Admin API routes for sliders
-		r.GET("/api/admin/sliders", func(c *gin.Context) {
+		adminAPI.GET("/sliders", func(c *gin.Context) {
 			sliders, err := slider.GetAllSlidersForAdmin()
 			if err != nil {
 				c.JSON(500, gin.H{"error": err.Error()})
@@ -265,32 +452,42 @@ func main() {
 			}
 			c.JSON(200, sliders)
 		})
-		r.GET("/api/admin/sliders/:id", admin.GetSliderByIDHandler)
-		r.POST("/api/admin/sliders", func(c *gin.Context) {
+		adminAPI.GET("/sliders/:id", admin.GetSliderByIDHandler)
+		adminAPI.POST("/sliders", func(c *gin.Context) {
 			var newSlider slider.Slider
 			if err := c.BindJSON(&newSlider); err != nil {
 				c.JSON(400, gin.H{"error": err.Error()})
 				return
 			}
 			if err := slider.InsertSlider(newSlider); err != nil {
-				c.JSON(500, gin.H{"error": err.Error()})
+				status := 500
+				if errors.Is(err, slider.ErrInvalidWindow) {
+					status = 400
+				}
+				c.JSON(status, gin.H{"error": err.Error()})
 				return
 			}
 			c.JSON(200, gin.H{"message": "Slider created"})
 		})
-		r.PUT("/api/admin/sliders/:id", func(c *gin.Context) {
+		adminAPI.PUT("/sliders/:id", func(c *gin.Context) {
 			var updatedSlider slider.Slider
 			if err := c.BindJSON(&updatedSlider); err != nil {
 				c.JSON(400, gin.H{"error": err.Error()})
 				return
 			}
+			oldImage, _ := slider.GetImageLink(updatedSlider.ID)
 			if err := slider.UpdateSlider(updatedSlider); err != nil {
-				c.JSON(500, gin.H{"error": err.Error()})
+				status := 500
+				if errors.Is(err, slider.ErrInvalidWindow) {
+					status = 400
+				}
+				c.JSON(status, gin.H{"error": err.Error()})
 				return
 			}
+			admin.RemoveReplacedImage(oldImage, updatedSlider.ImageLink, c.Request.Host)
 			c.JSON(200, gin.H{"message": "Slider updated"})
 		})
-		r.DELETE("/api/admin/sliders/:id", func(c *gin.Context) {
+		adminAPI.DELETE("/sliders/:id", func(c *gin.Context) {
 			var id int
 			if _, err := fmt.Sscanf(c.Param("id"), "%d", &id); err != nil {
 				c.JSON(400, gin.H{"error": "Invalid ID"})
@@ -304,43 +501,76 @@ func main() {
 		})
 
 		// Admin API routes for paper
-		r.GET("/api/admin/paper/types", paper.GetAllTypesWithImages)
-		r.POST("/api/admin/paper/types", paper.CreateType)
-		r.PUT("/api/admin/paper/types/:id", paper.UpdateType)
-		r.DELETE("/api/admin/paper/types/:id", paper.DeleteType)
-		r.DELETE("/api/admin/paper/type/:type_id/removeall", paper.DeleteAllImagesByType) // Remove all images from a type
-		r.POST("/api/admin/paper/images", paper.CreateImage)
-		r.POST("/api/admin/paper/images/batch", paper.BatchCreateImages)
-		r.PUT("/api/admin/paper/images/:id", paper.UpdateImage)
-		r.DELETE("/api/admin/paper/images/:id", paper.DeleteImage)
+		adminAPI.GET("/paper/types", paper.GetAllTypesWithImages)
+		adminAPI.POST("/paper/types", paper.CreateType)
+		adminAPI.PUT("/paper/types/:id", paper.UpdateType)
+		adminAPI.DELETE("/paper/types/:id", paper.DeleteType)
+		adminAPI.DELETE("/paper/type/:type_id/removeall", paper.DeleteAllImagesByType) // Remove all images from a type
+		adminAPI.POST("/paper/images", paper.CreateImage)
+		adminAPI.POST("/paper/images/batch", paper.BatchCreateImages)
+		adminAPI.PUT("/paper/images/:id", paper.UpdateImage)
+		adminAPI.DELETE("/paper/images/:id", paper.DeleteImage)
 
 		// Chat routes (SSE - existing)
 		chat.RegisterRoutes(r)
 
 		// WebSocket Chat routes (NEW)
-		r.GET("/api/burma2d/chatws", chatws.HandleWebSocket)
+		r.GET("/api/burma2d/chatws", googleauth.RateLimit(), chatws.HandleWebSocket)
 		r.GET("/api/burma2d/chatws/messages", chatws.GetRecentMessagesHandler)
 		r.GET("/api/burma2d/chatws/online", chatws.GetOnlineCountHandler)
+		r.POST("/api/burma2d/chatws/block", chatws.BlockUserHandler)
+		r.DELETE("/api/burma2d/chatws/block", chatws.UnblockUserHandler)
+		r.GET("/api/burma2d/chatws/blocked", chatws.GetBlockedUsersHandler)
 		log.Println("✅ WebSocket chat routes registered at /api/burma2d/chatws")
 	}
 
-	// Privacy Policy route (public)
-	r.GET("/privacy-policy", func(c *gin.Context) {
-		c.HTML(200, "privacy-policy.html", gin.H{})
-	})
+	// Public pages share the admin templates
+	if htmlEnabled {
+		// Privacy Policy route (public)
+		r.GET("/privacy-policy", func(c *gin.Context) {
+			c.HTML(200, "privacy-policy.html", gin.H{})
+		})
 
-	// Landing page
-	r.GET("/", func(c *gin.Context) {
-		c.HTML(200, "index.html", nil)
-	})
+		// Landing page
+		r.GET("/", func(c *gin.Context) {
+			c.HTML(200, "index.html", nil)
+		})
+	}
 
 	// Start server
-	log.Println("🚀 Server starting on 0.0.0.0:4545")
-	log.Println("📡 SSE Stream available at: http://localhost:4545/api/burma2d/stream")
-	log.Println("� Emulator access at: http://10.0.2.2:4545/api/burma2d/stream")
-	log.Println("�📮 POST data to: http://localhost:4545/api/burma2d/update")
-	log.Println("📜 History data at: http://localhost:4545/api/burma2d/history")
-	if err := r.Run("0.0.0.0:4545"); err != nil {
+	listenAddr, port, err := resolveListenAddr()
+	if err != nil {
+		log.Fatalf("❌ Invalid listen address: %v", err)
+	}
+	log.Printf("🚀 Server starting on %s", listenAddr)
+	log.Printf("📡 SSE Stream available at: http://localhost:%d/api/burma2d/stream", port)
+	log.Printf("� Emulator access at: http://10.0.2.2:%d/api/burma2d/stream", port)
+	log.Printf("�📮 POST data to: http://localhost:%d/api/burma2d/update", port)
+	log.Printf("📜 History data at: http://localhost:%d/api/burma2d/history", port)
+	srv := &http.Server{Addr: listenAddr, Handler: r}
+	if err := shutdown.Serve(srv); err != nil {
 		log.Fatal("Failed to start server:", err)
 	}
 }
+
+// resolveListenAddr builds the bind address from LISTEN_ADDR, or from
+// SERVER_HOST (default 0.0.0.0) and SERVER_PORT (default 4545)
+func resolveListenAddr() (string, int, error) {
+	host := config.String("SERVER_HOST", "0.0.0.0")
+	portStr := config.String("SERVER_PORT", "4545")
+
+	if addr := config.String("LISTEN_ADDR", ""); addr != "" {
+		h, p, err := net.SplitHostPort(addr)
+		if err != nil {
+			return "", 0, fmt.Errorf("LISTEN_ADDR %q: expected host:port", addr)
+		}
+		host, portStr = h, p
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 1 || port > 65535 {
+		return "", 0, fmt.Errorf("port %q must be a number between 1 and 65535", portStr)
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(port)), port, nil
+}
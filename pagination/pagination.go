@@ -0,0 +1,174 @@
+package pagination
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DateLayout is the format accepted for from/to query parameters
+const DateLayout = "2006-01-02"
+
+// Options controls defaults and bounds for a list endpoint
+type Options struct {
+	DefaultLimit int // Limit used when none is given (0 = no limit)
+	MaxLimit     int // Upper bound for a requested limit (0 = unbounded)
+}
+
+// Params holds validated list parameters parsed from the query string
+type Params struct {
+	Limit  int       // 0 means no LIMIT clause
+	Offset int       // Rows to skip
+	Cursor int64     // Only rows with an ID below this value (0 = no cursor)
+	From   time.Time // Inclusive start date (zero = unbounded)
+	To     time.Time // Inclusive end date (zero = unbounded)
+}
+
+// Parse reads limit, offset, cursor, from and to from the request query
+func Parse(c *gin.Context, opts Options) (Params, error) {
+	return ParseValues(c.Request.URL.Query(), opts)
+}
+
+// ParseValues parses and clamps list parameters from raw query values
+func ParseValues(q url.Values, opts Options) (Params, error) {
+	p := Params{Limit: opts.DefaultLimit}
+
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return p, fmt.Errorf("invalid limit: %q", v)
+		}
+		if n > 0 {
+			p.Limit = n
+		}
+	}
+	if opts.MaxLimit > 0 && p.Limit > opts.MaxLimit {
+		p.Limit = opts.MaxLimit
+	}
+
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return p, fmt.Errorf("invalid offset: %q", v)
+		}
+		if n > 0 {
+			p.Offset = n
+		}
+	}
+
+	if v := q.Get("cursor"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			return p, fmt.Errorf("invalid cursor: %q", v)
+		}
+		p.Cursor = n
+	}
+
+	var err error
+	if p.From, err = parseDate(q.Get("from")); err != nil {
+		return p, fmt.Errorf("invalid from date, use YYYY-MM-DD: %q", q.Get("from"))
+	}
+	if p.To, err = parseDate(q.Get("to")); err != nil {
+		return p, fmt.Errorf("invalid to date, use YYYY-MM-DD: %q", q.Get("to"))
+	}
+	if !p.From.IsZero() && !p.To.IsZero() && p.To.Before(p.From) {
+		return p, fmt.Errorf("from date must not be after to date")
+	}
+
+	return p, nil
+}
+
+func parseDate(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(DateLayout, v)
+}
+
+// LimitOffset returns the LIMIT/OFFSET clause and its arguments
+func (p Params) LimitOffset() (string, []interface{}) {
+	if p.Limit <= 0 {
+		if p.Offset > 0 {
+			// SQLite requires a LIMIT before OFFSET; -1 means no limit
+			return " LIMIT -1 OFFSET ?", []interface{}{p.Offset}
+		}
+		return "", nil
+	}
+	return " LIMIT ? OFFSET ?", []interface{}{p.Limit, p.Offset}
+}
+
+// Where accumulates parameterized conditions joined with AND
+type Where struct {
+	conds []string
+	args  []interface{}
+}
+
+// Add appends a condition using ? placeholders for its arguments
+func (w *Where) Add(cond string, args ...interface{}) {
+	w.conds = append(w.conds, cond)
+	w.args = append(w.args, args...)
+}
+
+// DateRange bounds column by the From/To dates, formatting them with layout.
+// The upper bound is exclusive of the following day so DATETIME columns
+// include the whole of the To date.
+func (w *Where) DateRange(column, layout string, p Params) {
+	if !p.From.IsZero() {
+		w.Add(column+" >= ?", p.From.Format(layout))
+	}
+	if !p.To.IsZero() {
+		w.Add(column+" < ?", p.To.AddDate(0, 0, 1).Format(layout))
+	}
+}
+
+// LocalDateRange bounds a UTC DATETIME column by From/To read as calendar
+// dates in loc, so a day means local midnight to local midnight
+func (w *Where) LocalDateRange(column string, loc *time.Location, p Params) {
+	const stored = "2006-01-02 15:04:05"
+	if !p.From.IsZero() {
+		w.Add(column+" >= ?", localMidnight(p.From, loc).UTC().Format(stored))
+	}
+	if !p.To.IsZero() {
+		w.Add(column+" < ?", localMidnight(p.To.AddDate(0, 0, 1), loc).UTC().Format(stored))
+	}
+}
+
+func localMidnight(d time.Time, loc *time.Location) time.Time {
+	return time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, loc)
+}
+
+// Contains adds a case-insensitive substring match on column,
+// escaping LIKE wildcards in term. Empty terms are ignored.
+func (w *Where) Contains(column, term string) {
+	if term == "" {
+		return
+	}
+	escaped := likeEscaper.Replace(term)
+	w.Add(column+` LIKE ? ESCAPE '\'`, "%"+escaped+"%")
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// Cursor restricts column to values below the cursor when one is set
+func (w *Where) Cursor(column string, p Params) {
+	if p.Cursor > 0 {
+		w.Add(column+" < ?", p.Cursor)
+	}
+}
+
+// SQL returns the WHERE clause, or an empty string when there are no conditions
+func (w *Where) SQL() string {
+	if len(w.conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(w.conds, " AND ")
+}
+
+// Args returns the arguments for the placeholders in SQL
+func (w *Where) Args() []interface{} {
+	return w.args
+}
@@ -0,0 +1,152 @@
+package pagination
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseValues(t *testing.T) {
+	opts := Options{DefaultLimit: 20, MaxLimit: 100}
+	day := func(s string) time.Time {
+		d, _ := time.Parse(DateLayout, s)
+		return d
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  Params
+	}{
+		{"defaults", "", Params{Limit: 20}},
+		{"limit", "limit=50", Params{Limit: 50}},
+		{"limit clamped", "limit=1000", Params{Limit: 100}},
+		{"zero limit keeps default", "limit=0", Params{Limit: 20}},
+		{"negative limit keeps default", "limit=-1", Params{Limit: 20}},
+		{"offset", "offset=40", Params{Limit: 20, Offset: 40}},
+		{"negative offset ignored", "offset=-5", Params{Limit: 20}},
+		{"cursor", "cursor=123", Params{Limit: 20, Cursor: 123}},
+		{"dates", "from=2025-01-01&to=2025-01-31", Params{Limit: 20, From: day("2025-01-01"), To: day("2025-01-31")}},
+		{"same day", "from=2025-01-01&to=2025-01-01", Params{Limit: 20, From: day("2025-01-01"), To: day("2025-01-01")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, _ := url.ParseQuery(tt.query)
+			got, err := ParseValues(q, opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseValuesUnbounded(t *testing.T) {
+	q, _ := url.ParseQuery("limit=100000")
+	got, err := ParseValues(q, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Limit != 100000 {
+		t.Fatalf("limit = %d, want 100000 with no MaxLimit", got.Limit)
+	}
+}
+
+func TestParseValuesErrors(t *testing.T) {
+	for _, query := range []string{
+		"limit=abc",
+		"offset=1.5",
+		"cursor=-1",
+		"cursor=x",
+		"from=01-02-2025",
+		"to=2025-13-01",
+		"from=2025-02-01&to=2025-01-01",
+	} {
+		t.Run(query, func(t *testing.T) {
+			q, _ := url.ParseQuery(query)
+			if _, err := ParseValues(q, Options{DefaultLimit: 20}); err == nil {
+				t.Fatalf("expected an error for %q", query)
+			}
+		})
+	}
+}
+
+func TestLimitOffset(t *testing.T) {
+	tests := []struct {
+		p        Params
+		wantSQL  string
+		wantArgs []interface{}
+	}{
+		{Params{}, "", nil},
+		{Params{Offset: 10}, " LIMIT -1 OFFSET ?", []interface{}{10}},
+		{Params{Limit: 20}, " LIMIT ? OFFSET ?", []interface{}{20, 0}},
+		{Params{Limit: 20, Offset: 40}, " LIMIT ? OFFSET ?", []interface{}{20, 40}},
+	}
+	for _, tt := range tests {
+		sql, args := tt.p.LimitOffset()
+		if sql != tt.wantSQL || !reflect.DeepEqual(args, tt.wantArgs) {
+			t.Errorf("%+v: got %q %v, want %q %v", tt.p, sql, args, tt.wantSQL, tt.wantArgs)
+		}
+	}
+}
+
+func TestWhere(t *testing.T) {
+	var empty Where
+	if empty.SQL() != "" || len(empty.Args()) != 0 {
+		t.Fatalf("empty Where = %q %v", empty.SQL(), empty.Args())
+	}
+
+	from, _ := time.Parse(DateLayout, "2025-03-01")
+	to, _ := time.Parse(DateLayout, "2025-03-31")
+	p := Params{From: from, To: to, Cursor: 99}
+
+	var w Where
+	w.Add("status = ?", "active")
+	w.DateRange("created_at", "2006-01-02 15:04:05", p)
+	w.Contains("name", "50%_off")
+	w.Contains("title", "")
+	w.Cursor("id", p)
+
+	wantSQL := " WHERE status = ? AND created_at >= ? AND created_at < ? AND name LIKE ? ESCAPE '\\' AND id < ?"
+	if got := w.SQL(); got != wantSQL {
+		t.Fatalf("SQL:\n got %q\nwant %q", got, wantSQL)
+	}
+	wantArgs := []interface{}{
+		"active",
+		"2025-03-01 00:00:00",
+		"2025-04-01 00:00:00", // exclusive end covers the whole of the To date
+		`%50\%\_off%`,
+		int64(99),
+	}
+	if !reflect.DeepEqual(w.Args(), wantArgs) {
+		t.Fatalf("Args:\n got %#v\nwant %#v", w.Args(), wantArgs)
+	}
+}
+
+func TestWhereCursorUnset(t *testing.T) {
+	var w Where
+	w.Cursor("id", Params{})
+	w.DateRange("created_at", DateLayout, Params{})
+	if w.SQL() != "" {
+		t.Fatalf("SQL = %q, want empty", w.SQL())
+	}
+}
+
+func TestWhereLocalDateRange(t *testing.T) {
+	from, _ := time.Parse(DateLayout, "2025-03-01")
+	to, _ := time.Parse(DateLayout, "2025-03-31")
+	yangon := time.FixedZone("Myanmar", 6*3600+30*60)
+
+	var w Where
+	w.LocalDateRange("created_at", yangon, Params{From: from, To: to})
+	wantArgs := []interface{}{
+		"2025-02-28 17:30:00", // Myanmar midnight on the 1st
+		"2025-03-31 17:30:00", // Myanmar midnight after the 31st
+	}
+	if w.SQL() != " WHERE created_at >= ? AND created_at < ?" || !reflect.DeepEqual(w.Args(), wantArgs) {
+		t.Fatalf("got %q %#v, want %#v", w.SQL(), w.Args(), wantArgs)
+	}
+}
@@ -4,8 +4,13 @@ import (
 	"database/sql"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
+	"burma2d/dbutil"
+	"burma2d/jsonutil"
+	"burma2d/pagination"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -183,33 +188,94 @@ func GetAllTypesWithImages(c *gin.Context) {
 	c.JSON(http.StatusOK, results)
 }
 
-// Get images by type ID
-func GetImagesByType(c *gin.Context) {
-	typeID := c.Param("type_id")
+// imageOrders maps the order query parameter to an ORDER BY clause; the id
+// tie-break keeps paging stable
+var imageOrders = map[string]string{
+	"display": "pi.display_order ASC, pi.created_at DESC, pi.id DESC",
+	"newest":  "pi.created_at DESC, pi.id DESC",
+	"oldest":  "pi.created_at ASC, pi.id ASC",
+}
 
-	rows, err := db.Query(`
-		SELECT pi.id, pi.type_id, pt.name, pi.image_url, pi.display_order, pi.is_active, pi.created_at, pi.updated_at
+// listImages returns active images of active types matching where, plus the
+// total number of matches ignoring limit/offset
+func listImages(where pagination.Where, orderBy string, p pagination.Params) ([]PaperImage, int, error) {
+	where.Add("pi.is_active = 1")
+	where.Add("pt.is_active = 1")
+	from := `
 		FROM paper_images pi
-		JOIN paper_types pt ON pi.type_id = pt.id
-		WHERE pi.type_id = ? AND pi.is_active = 1 AND pt.is_active = 1
-		ORDER BY pi.display_order ASC, pi.created_at DESC
-	`, typeID)
+		JOIN paper_types pt ON pi.type_id = pt.id` + where.SQL()
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*)"+from, where.Args()...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	limitSQL, limitArgs := p.LimitOffset()
+	rows, err := db.Query(`
+		SELECT pi.id, pi.type_id, pt.name, pi.image_url, pi.display_order, pi.is_active, pi.created_at, pi.updated_at`+
+		from+`
+		ORDER BY `+orderBy+limitSQL, append(where.Args(), limitArgs...)...)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+		return nil, 0, err
 	}
 	defer rows.Close()
 
-	var images []PaperImage
+	images := []PaperImage{}
 	for rows.Next() {
 		var img PaperImage
 		if err := rows.Scan(&img.ID, &img.TypeID, &img.TypeName, &img.ImageURL, &img.DisplayOrder, &img.IsActive, &img.CreatedAt, &img.UpdatedAt); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
+			return nil, 0, err
 		}
 		images = append(images, img)
 	}
+	return images, total, nil
+}
+
+// Get images by type ID
+// Optional query params: limit (max 200), offset, order (display, newest, oldest).
+// The total number of images is sent in the X-Total-Count header.
+func GetImagesByType(c *gin.Context) {
+	typeID := c.Param("type_id")
+
+	p, err := pagination.Parse(c, pagination.Options{MaxLimit: 200})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	orderBy, ok := imageOrders[c.DefaultQuery("order", "display")]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order must be display, newest or oldest"})
+		return
+	}
+
+	var where pagination.Where
+	where.Add("pi.type_id = ?", typeID)
+	images, total, err := listImages(where, orderBy, p)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("X-Total-Count", strconv.Itoa(total))
+	c.JSON(http.StatusOK, images)
+}
+
+// Get the most recently added images across all types, newest first
+// Optional query params: limit (default 20, max 100), offset.
+func GetRecentImages(c *gin.Context) {
+	p, err := pagination.Parse(c, pagination.Options{DefaultLimit: 20, MaxLimit: 100})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	images, total, err := listImages(pagination.Where{}, imageOrders["newest"], p)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
+	c.Header("X-Total-Count", strconv.Itoa(total))
 	c.JSON(http.StatusOK, images)
 }
 
@@ -221,7 +287,7 @@ func CreateType(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		jsonutil.BindError(c, err)
 		return
 	}
 
@@ -254,7 +320,7 @@ func UpdateType(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		jsonutil.BindError(c, err)
 		return
 	}
 
@@ -294,7 +360,7 @@ func CreateImage(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		jsonutil.BindError(c, err)
 		return
 	}
 
@@ -328,7 +394,7 @@ func UpdateImage(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		jsonutil.BindError(c, err)
 		return
 	}
 
@@ -415,39 +481,30 @@ func BatchCreateImages(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	tx, err := db.Begin()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		jsonutil.BindError(c, err)
 		return
 	}
 
 	var insertedIDs []int
-	for i, url := range input.ImageURLs {
-		result, err := tx.Exec(`
-			INSERT INTO paper_images (type_id, image_url, display_order, is_active, created_at, updated_at)
-			VALUES (?, ?, ?, 1, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
-		`, input.TypeID, url, i)
-
-		if err != nil {
-			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
+	err := dbutil.WithTx(db, func(tx *sql.Tx) error {
+		for i, url := range input.ImageURLs {
+			result, err := tx.Exec(`
+				INSERT INTO paper_images (type_id, image_url, display_order, is_active, created_at, updated_at)
+				VALUES (?, ?, ?, 1, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+			`, input.TypeID, url, i)
+			if err != nil {
+				return err
+			}
 
-		id, err := result.LastInsertId()
-		if err != nil {
-			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
+			id, err := result.LastInsertId()
+			if err != nil {
+				return err
+			}
+			insertedIDs = append(insertedIDs, int(id))
 		}
-		insertedIDs = append(insertedIDs, int(id))
-	}
-
-	if err := tx.Commit(); err != nil {
+		return nil
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
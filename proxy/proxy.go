@@ -0,0 +1,176 @@
+package proxy
+
+import (
+	"fmt"
+	"log"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+
+	"burma2d/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultTrustedProxies only trusts a proxy on the same host
+const defaultTrustedProxies = "127.0.0.1,::1"
+
+var (
+	trusted       []netip.Prefix
+	schemeHeaders []string
+	hostHeuristic bool
+
+	// warnedUntrusted is set once forwarding headers from an untrusted
+	// peer have been logged
+	warnedUntrusted atomic.Bool
+)
+
+// Configure applies the trusted proxy settings to the engine:
+//
+//	TRUSTED_PROXIES         comma-separated IPs/CIDRs allowed to set proxy headers (default loopback)
+//	                        Must list the load balancer or CDN in production: otherwise every
+//	                        request's client IP is the proxy's and per-IP limits throttle all
+//	                        users as one.
+//	CLIENT_IP_HEADERS       headers carrying the client IP (default X-Forwarded-For,X-Real-IP)
+//	TRUSTED_PLATFORM        "cloudflare" or "google" to trust the platform's client IP header
+//	TRUSTED_SCHEME_HEADERS  headers carrying the original scheme (default X-Forwarded-Proto,CF-Visitor,X-Forwarded-Ssl)
+//	SCHEME_HOST_HEURISTIC   assume https for hosts without a port that aren't local (default true)
+func Configure(r *gin.Engine) error {
+	proxies := splitList(config.String("TRUSTED_PROXIES", defaultTrustedProxies))
+	if err := r.SetTrustedProxies(proxies); err != nil {
+		return fmt.Errorf("invalid TRUSTED_PROXIES: %v", err)
+	}
+
+	trusted = trusted[:0]
+	for _, p := range proxies {
+		prefix, err := parsePrefix(p)
+		if err != nil {
+			return fmt.Errorf("invalid TRUSTED_PROXIES entry %q: %v", p, err)
+		}
+		trusted = append(trusted, prefix)
+	}
+
+	r.RemoteIPHeaders = splitList(config.String("CLIENT_IP_HEADERS", "X-Forwarded-For,X-Real-IP"))
+
+	switch platform := strings.ToLower(config.String("TRUSTED_PLATFORM", "")); platform {
+	case "":
+	case "cloudflare":
+		r.TrustedPlatform = gin.PlatformCloudflare
+	case "google":
+		r.TrustedPlatform = gin.PlatformGoogleAppEngine
+	default:
+		return fmt.Errorf("unknown TRUSTED_PLATFORM %q", platform)
+	}
+
+	schemeHeaders = splitList(config.String("TRUSTED_SCHEME_HEADERS", "X-Forwarded-Proto,CF-Visitor,X-Forwarded-Ssl"))
+	hostHeuristic = config.Bool("SCHEME_HOST_HEURISTIC", true)
+
+	log.Printf("✅ Trusted proxies: %s", strings.Join(proxies, ", "))
+	if r.TrustedPlatform == "" {
+		if strings.Join(proxies, ",") == defaultTrustedProxies {
+			log.Println("⚠️ TRUSTED_PROXIES not set - only a proxy on this host is trusted. Behind a load balancer or CDN, " +
+				"set TRUSTED_PROXIES (or TRUSTED_PLATFORM) or every client shares the proxy's IP for rate limits")
+		}
+		r.Use(warnUntrustedForwarding)
+	}
+	return nil
+}
+
+// warnUntrustedForwarding logs the first request that carries forwarding
+// headers from a peer outside TRUSTED_PROXIES, the usual sign of a missing
+// proxy configuration
+func warnUntrustedForwarding(c *gin.Context) {
+	if !warnedUntrusted.Load() && c.GetHeader("X-Forwarded-For") != "" && !fromTrustedProxy(c) &&
+		warnedUntrusted.CompareAndSwap(false, true) {
+		log.Printf("⚠️ Ignoring X-Forwarded-For from untrusted peer %s - add it to TRUSTED_PROXIES if it is your proxy", c.RemoteIP())
+	}
+	c.Next()
+}
+
+// Scheme returns "https" or "http" for the original client request.
+// Proxy headers are only honored when the direct peer is a trusted proxy.
+func Scheme(c *gin.Context) string {
+	if c.Request.TLS != nil {
+		return "https"
+	}
+
+	if fromTrustedProxy(c) {
+		for _, header := range schemeHeaders {
+			if v := c.GetHeader(header); v != "" && headerSaysHTTPS(header, v) {
+				return "https"
+			}
+		}
+	}
+
+	if hostHeuristic && !isLocalHost(c.Request.Host) && !strings.Contains(c.Request.Host, ":") {
+		return "https"
+	}
+	return "http"
+}
+
+// BaseURL returns scheme://host for building absolute URLs
+func BaseURL(c *gin.Context) string {
+	return Scheme(c) + "://" + c.Request.Host
+}
+
+// IsSecure reports whether the original request used https
+func IsSecure(c *gin.Context) bool {
+	return Scheme(c) == "https"
+}
+
+// headerSaysHTTPS interprets the value of a known scheme header
+func headerSaysHTTPS(header, value string) bool {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch strings.ToLower(header) {
+	case "cf-visitor":
+		return strings.Contains(value, `"scheme":"https"`)
+	case "x-forwarded-ssl", "front-end-https":
+		return value == "on"
+	case "forwarded":
+		return strings.Contains(value, "proto=https")
+	default:
+		// X-Forwarded-Proto and similar may hold a list when chained
+		first, _, _ := strings.Cut(value, ",")
+		return strings.TrimSpace(first) == "https"
+	}
+}
+
+// fromTrustedProxy reports whether the direct peer is in TRUSTED_PROXIES
+func fromTrustedProxy(c *gin.Context) bool {
+	addr, err := netip.ParseAddr(c.RemoteIP())
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func isLocalHost(host string) bool {
+	return strings.Contains(host, "localhost") || strings.Contains(host, "127.0.0.1")
+}
+
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		return netip.ParsePrefix(s)
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
@@ -0,0 +1,128 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// request runs one request from remoteAddr through an engine configured
+// from env and returns the client IP and scheme the handler saw
+func request(t *testing.T, env map[string]string, remoteAddr, host string, headers map[string]string) (string, string) {
+	t.Helper()
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	if err := Configure(r); err != nil {
+		t.Fatal(err)
+	}
+
+	var ip, scheme string
+	r.GET("/", func(c *gin.Context) {
+		ip, scheme = c.ClientIP(), Scheme(c)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = remoteAddr
+	req.Host = host
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	return ip, scheme
+}
+
+func TestClientIP(t *testing.T) {
+	forwarded := map[string]string{"X-Forwarded-For": "203.0.113.7"}
+
+	tests := []struct {
+		name   string
+		env    map[string]string
+		remote string
+		want   string
+	}{
+		{"default trusts loopback", nil, "127.0.0.1:5000", "203.0.113.7"},
+		{"default ignores remote proxy", nil, "10.1.2.3:5000", "10.1.2.3"},
+		{"configured CIDR", map[string]string{"TRUSTED_PROXIES": "10.0.0.0/8"}, "10.1.2.3:5000", "203.0.113.7"},
+		{"outside configured CIDR", map[string]string{"TRUSTED_PROXIES": "10.0.0.0/8"}, "192.0.2.1:5000", "192.0.2.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ip, _ := request(t, tt.env, tt.remote, "example.com", forwarded)
+			if ip != tt.want {
+				t.Fatalf("ClientIP = %s, want %s", ip, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientIPCloudflare(t *testing.T) {
+	ip, _ := request(t, map[string]string{"TRUSTED_PLATFORM": "cloudflare"}, "198.51.100.9:443", "example.com",
+		map[string]string{"CF-Connecting-IP": "203.0.113.8"})
+	if ip != "203.0.113.8" {
+		t.Fatalf("ClientIP = %s, want the CF-Connecting-IP value", ip)
+	}
+}
+
+func TestScheme(t *testing.T) {
+	noHeuristic := map[string]string{"SCHEME_HOST_HEURISTIC": "false"}
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		remote  string
+		host    string
+		headers map[string]string
+		want    string
+	}{
+		{"plain", noHeuristic, "127.0.0.1:5000", "example.com", nil, "http"},
+		{"X-Forwarded-Proto", noHeuristic, "127.0.0.1:5000", "example.com", map[string]string{"X-Forwarded-Proto": "https"}, "https"},
+		{"chained X-Forwarded-Proto", noHeuristic, "127.0.0.1:5000", "example.com", map[string]string{"X-Forwarded-Proto": "https, http"}, "https"},
+		{"CF-Visitor", noHeuristic, "127.0.0.1:5000", "example.com", map[string]string{"CF-Visitor": `{"scheme":"https"}`}, "https"},
+		{"X-Forwarded-Ssl", noHeuristic, "127.0.0.1:5000", "example.com", map[string]string{"X-Forwarded-Ssl": "on"}, "https"},
+		{"untrusted peer", noHeuristic, "192.0.2.1:5000", "example.com", map[string]string{"X-Forwarded-Proto": "https"}, "http"},
+		{"heuristic public host", nil, "192.0.2.1:5000", "example.com", nil, "https"},
+		{"heuristic host with port", nil, "192.0.2.1:5000", "example.com:4545", nil, "http"},
+		{"heuristic localhost", nil, "127.0.0.1:5000", "localhost", nil, "http"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, scheme := request(t, tt.env, tt.remote, tt.host, tt.headers)
+			if scheme != tt.want {
+				t.Fatalf("Scheme = %s, want %s", scheme, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchemeTLS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	c.Request.Host = "localhost:4545"
+	c.Request.TLS = &tls.ConnectionState{}
+	if Scheme(c) != "https" || BaseURL(c) != "https://localhost:4545" {
+		t.Fatalf("Scheme = %s, BaseURL = %s", Scheme(c), BaseURL(c))
+	}
+}
+
+func TestConfigureRejectsBadSettings(t *testing.T) {
+	for name, env := range map[string]map[string]string{
+		"proxy":    {"TRUSTED_PROXIES": "not-an-ip"},
+		"platform": {"TRUSTED_PLATFORM": "heroku"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			for k, v := range env {
+				t.Setenv(k, v)
+			}
+			if err := Configure(gin.New()); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
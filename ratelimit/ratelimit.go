@@ -0,0 +1,97 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Limiter is an in-memory token bucket rate limiter keyed by an arbitrary
+// string (user ID, IP, ...). Each key may burst up to limit events and
+// regains tokens continuously at limit per window.
+type Limiter struct {
+	mu       sync.Mutex
+	buckets  map[string]*bucket
+	capacity float64
+	rate     float64 // tokens per second
+	window   time.Duration
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// New creates a limiter allowing limit events per window for each key and
+// starts a background cleanup of idle keys
+func New(limit int, window time.Duration) *Limiter {
+	if limit < 1 {
+		limit = 1
+	}
+	if window <= 0 {
+		window = time.Second
+	}
+
+	l := &Limiter{
+		buckets:  make(map[string]*bucket),
+		capacity: float64(limit),
+		rate:     float64(limit) / window.Seconds(),
+		window:   window,
+	}
+	go l.cleanupLoop()
+	return l
+}
+
+// Allow consumes a token for key. When the bucket is empty it returns false
+// and how long until the next token is available.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	return l.allowAt(key, time.Now())
+}
+
+func (l *Limiter) allowAt(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.capacity, last: now}
+		l.buckets[key] = b
+	}
+
+	// Refill for the time elapsed since the last event
+	b.tokens = math.Min(l.capacity, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// cleanupLoop drops buckets that have been idle long enough to be full again
+func (l *Limiter) cleanupLoop() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		l.mu.Lock()
+		for key, b := range l.buckets {
+			if now.Sub(b.last) > l.window {
+				delete(l.buckets, key)
+			}
+		}
+		l.mu.Unlock()
+	}
+}
+
+// RetryAfterSeconds rounds a wait duration up to whole seconds (minimum 1)
+func RetryAfterSeconds(wait time.Duration) int {
+	secs := int(math.Ceil(wait.Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	return secs
+}
@@ -13,7 +13,7 @@ func main() {
 	}
 
 	giftName := "Test Gift"
-	if err := fcm.SendGiftAvailableNotification(giftName); err != nil {
+	if err := fcm.SendGiftAvailableNotification(giftName, ""); err != nil {
 		log.Fatalf("Failed to send FCM notification: %v", err)
 	}
 
@@ -0,0 +1,238 @@
+package sessionlog
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"burma2d/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Transition statuses
+const (
+	StatusOnline  = "online"
+	StatusOffline = "offline"
+)
+
+// Transition represents a single online/offline change for a user
+type Transition struct {
+	ID        int64     `json:"id"`
+	UserID    string    `json:"user_id"`
+	Status    string    `json:"status"`    // "online" or "offline"
+	Transport string    `json:"transport"` // "sse" or "ws"
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Session pairs an online transition with the offline that ended it
+type Session struct {
+	Transport       string     `json:"transport"`
+	ConnectedAt     time.Time  `json:"connected_at"`
+	DisconnectedAt  *time.Time `json:"disconnected_at"`
+	DurationSeconds int64      `json:"duration_seconds"`
+}
+
+// maxQueryLimit caps how many transitions a single query returns
+const maxQueryLimit = 500
+
+var (
+	db        *sql.DB
+	enabled   bool
+	retention time.Duration
+)
+
+// The prune loop runs once per process until stopPruning ends it
+var (
+	pruneOnce sync.Once
+	pruneStop chan struct{}
+	pruneDone chan struct{}
+)
+
+// InitDB creates the transitions table and starts pruning when enabled.
+// Controlled by CHAT_SESSION_LOG (default false) and
+// CHAT_SESSION_LOG_RETENTION_DAYS (default 30).
+func InitDB(database *sql.DB) {
+	db = database
+	enabled = config.Bool("CHAT_SESSION_LOG", false)
+	retention = time.Duration(config.Int("CHAT_SESSION_LOG_RETENTION_DAYS", 30)) * 24 * time.Hour
+
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS chat_session_log (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id TEXT NOT NULL,
+			status TEXT NOT NULL,
+			transport TEXT NOT NULL,
+			created_at DATETIME NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_session_log_user ON chat_session_log(user_id, created_at DESC);
+		CREATE INDEX IF NOT EXISTS idx_session_log_created ON chat_session_log(created_at);
+	`)
+	if err != nil {
+		log.Printf("❌ Error creating chat_session_log table: %v", err)
+		return
+	}
+
+	if !enabled {
+		log.Println("ℹ️  Chat session logging disabled (set CHAT_SESSION_LOG=true to enable)")
+		return
+	}
+
+	startPruning(db, retention)
+	log.Printf("✅ Chat session logging enabled (retention: %s)", retention)
+}
+
+// Enabled reports whether transitions are being recorded
+func Enabled() bool {
+	return enabled && db != nil
+}
+
+// Record stores an online/offline transition; no-op when disabled
+func Record(userID, status, transport string) {
+	if !Enabled() {
+		return
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO chat_session_log (user_id, status, transport, created_at)
+		VALUES (?, ?, ?, ?)
+	`, userID, status, transport, time.Now().UTC())
+	if err != nil {
+		log.Printf("⚠️ Failed to record %s transition for %s: %v", status, userID, err)
+	}
+}
+
+// startPruning starts the prune loop on database unless it is already running
+func startPruning(database *sql.DB, keep time.Duration) {
+	pruneOnce.Do(func() {
+		pruneStop, pruneDone = make(chan struct{}), make(chan struct{})
+		go pruneLoop(database, keep, pruneStop, pruneDone)
+	})
+}
+
+// stopPruning ends the prune loop, if running, and waits for it to exit
+func stopPruning() {
+	if pruneStop == nil {
+		return
+	}
+	close(pruneStop)
+	<-pruneDone
+	pruneOnce, pruneStop, pruneDone = sync.Once{}, nil, nil
+}
+
+// pruneLoop keeps the table bounded by deleting rows past the retention window
+func pruneLoop(database *sql.DB, keep time.Duration, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		prune(database, keep)
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+func prune(database *sql.DB, keep time.Duration) {
+	result, err := database.Exec("DELETE FROM chat_session_log WHERE created_at < ?", time.Now().UTC().Add(-keep))
+	if err != nil {
+		log.Printf("⚠️ Failed to prune chat session log: %v", err)
+		return
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		log.Printf("🧹 Pruned %d chat session log rows", n)
+	}
+}
+
+// GetUserTransitions returns a user's most recent transitions, newest first
+func GetUserTransitions(userID string, limit int) ([]Transition, error) {
+	rows, err := db.Query(`
+		SELECT id, user_id, status, transport, created_at
+		FROM chat_session_log
+		WHERE user_id = ?
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transitions := []Transition{}
+	for rows.Next() {
+		var t Transition
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Status, &t.Transport, &t.CreatedAt); err != nil {
+			continue
+		}
+		transitions = append(transitions, t)
+	}
+	return transitions, nil
+}
+
+// buildSessions pairs transitions (newest first) into sessions, newest first
+func buildSessions(transitions []Transition) []Session {
+	sessions := []Session{}
+	open := make(map[string][]int) // transport -> indexes of sessions still open
+
+	// Walk oldest to newest so each offline closes the earliest open session
+	for i := len(transitions) - 1; i >= 0; i-- {
+		t := transitions[i]
+		switch t.Status {
+		case StatusOnline:
+			sessions = append(sessions, Session{Transport: t.Transport, ConnectedAt: t.CreatedAt})
+			open[t.Transport] = append(open[t.Transport], len(sessions)-1)
+		case StatusOffline:
+			if len(open[t.Transport]) == 0 {
+				continue
+			}
+			idx := open[t.Transport][0]
+			open[t.Transport] = open[t.Transport][1:]
+			disconnectedAt := t.CreatedAt
+			sessions[idx].DisconnectedAt = &disconnectedAt
+			sessions[idx].DurationSeconds = int64(disconnectedAt.Sub(sessions[idx].ConnectedAt).Seconds())
+		}
+	}
+
+	// Newest first
+	for i, j := 0, len(sessions)-1; i < j; i, j = i+1, j-1 {
+		sessions[i], sessions[j] = sessions[j], sessions[i]
+	}
+	return sessions
+}
+
+// GetUserSessionsHandler returns a user's recent transitions and sessions (admin)
+func GetUserSessionsHandler(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id required"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+	if limit > maxQueryLimit {
+		limit = maxQueryLimit
+	}
+
+	transitions, err := GetUserTransitions(userID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":     Enabled(),
+		"user_id":     userID,
+		"transitions": transitions,
+		"sessions":    buildSessions(transitions),
+	})
+}
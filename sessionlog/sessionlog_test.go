@@ -0,0 +1,115 @@
+package sessionlog
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupTestDB(t *testing.T, logging bool) {
+	t.Helper()
+	if logging {
+		t.Setenv("CHAT_SESSION_LOG", "true")
+	}
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	database.SetMaxOpenConns(1)
+	t.Cleanup(func() { database.Close() })
+	// Runs before the close, so the prune loop never sees a closed database
+	t.Cleanup(stopPruning)
+	InitDB(database)
+}
+
+func countRows(t *testing.T) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM chat_session_log").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestRecordWhenEnabled(t *testing.T) {
+	setupTestDB(t, true)
+
+	Record("alice", StatusOnline, "sse")
+	Record("alice", StatusOffline, "sse")
+
+	transitions, err := GetUserTransitions("alice", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transitions) != 2 || transitions[0].Status != StatusOffline || transitions[1].Status != StatusOnline {
+		t.Fatalf("transitions = %+v, want offline then online", transitions)
+	}
+}
+
+func TestRecordWhenDisabled(t *testing.T) {
+	setupTestDB(t, false)
+
+	Record("alice", StatusOnline, "ws")
+	if n := countRows(t); n != 0 {
+		t.Errorf("%d rows recorded while disabled", n)
+	}
+}
+
+func TestBuildSessions(t *testing.T) {
+	base := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	// Newest first, as GetUserTransitions returns them
+	transitions := []Transition{
+		{Status: StatusOnline, Transport: "sse", CreatedAt: base.Add(5 * time.Minute)},
+		{Status: StatusOffline, Transport: "ws", CreatedAt: base.Add(3 * time.Minute)},
+		{Status: StatusOnline, Transport: "ws", CreatedAt: base},
+	}
+
+	sessions := buildSessions(transitions)
+	if len(sessions) != 2 {
+		t.Fatalf("got %d sessions, want 2", len(sessions))
+	}
+	if sessions[0].Transport != "sse" || sessions[0].DisconnectedAt != nil {
+		t.Errorf("newest session = %+v, want an open sse session", sessions[0])
+	}
+	if sessions[1].Transport != "ws" || sessions[1].DurationSeconds != 180 {
+		t.Errorf("oldest session = %+v, want a 180s ws session", sessions[1])
+	}
+}
+
+func TestPruneKeepsRetentionWindow(t *testing.T) {
+	setupTestDB(t, true)
+	stopPruning()
+
+	now := time.Now().UTC()
+	for _, at := range []time.Time{now.Add(-31 * 24 * time.Hour), now.Add(-29 * 24 * time.Hour)} {
+		if _, err := db.Exec(`
+			INSERT INTO chat_session_log (user_id, status, transport, created_at)
+			VALUES ('alice', 'online', 'sse', ?)
+		`, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	prune(db, 30*24*time.Hour)
+	if n := countRows(t); n != 1 {
+		t.Errorf("%d rows left, want only the one inside the window", n)
+	}
+}
+
+func TestPruneLoopStartsOnce(t *testing.T) {
+	setupTestDB(t, true)
+	stop := pruneStop
+	InitDB(db)
+	if pruneStop != stop {
+		t.Fatal("a second InitDB started another prune loop")
+	}
+
+	stopPruning()
+	select {
+	case <-stop:
+	default:
+		t.Fatal("stopPruning left the loop running")
+	}
+}
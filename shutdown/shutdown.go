@@ -0,0 +1,110 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"burma2d/config"
+)
+
+// EventType is the event streaming clients receive before the server exits
+const EventType = "server_closing"
+
+// Notice tells clients the server is going away and when to reconnect.
+// Clients should wait ReconnectAfterMs plus a random share of
+// ReconnectJitterMs so they don't all come back at once.
+type Notice struct {
+	ClosingInSeconds  int `json:"closing_in_seconds"`
+	ReconnectAfterMs  int `json:"reconnect_after_ms"`
+	ReconnectJitterMs int `json:"reconnect_jitter_ms"`
+}
+
+// Listener delivers the notice to one transport's clients
+type Listener func(Notice)
+
+var (
+	listenersMutex sync.Mutex
+	listeners      []Listener
+)
+
+// OnClosing registers fn to be called with the notice when shutdown starts
+func OnClosing(fn Listener) {
+	listenersMutex.Lock()
+	listeners = append(listeners, fn)
+	listenersMutex.Unlock()
+}
+
+// Announce sends n to every registered transport
+func Announce(n Notice) {
+	listenersMutex.Lock()
+	fns := append([]Listener(nil), listeners...)
+	listenersMutex.Unlock()
+
+	for _, fn := range fns {
+		fn(n)
+	}
+}
+
+// Serve runs srv until SIGINT or SIGTERM, then announces the shutdown,
+// waits SHUTDOWN_NOTICE (default 5s) so clients can see it, and stops the
+// server within SHUTDOWN_TIMEOUT (default 10s). Clients are told to
+// reconnect after SHUTDOWN_RECONNECT_AFTER (default 10s) plus up to
+// SHUTDOWN_RECONNECT_JITTER (default 20s).
+func Serve(srv *http.Server) error {
+	noticeDelay := config.Duration("SHUTDOWN_NOTICE", 5*time.Second)
+	timeout := config.Duration("SHUTDOWN_TIMEOUT", 10*time.Second)
+	notice := Notice{
+		ClosingInSeconds:  int(noticeDelay.Seconds()),
+		ReconnectAfterMs:  int(config.Duration("SHUTDOWN_RECONNECT_AFTER", 10*time.Second).Milliseconds()),
+		ReconnectJitterMs: int(config.Duration("SHUTDOWN_RECONNECT_JITTER", 20*time.Second).Milliseconds()),
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Request contexts derive from base, so cancelling it ends open streams
+	base, cancelBase := context.WithCancel(context.Background())
+	defer cancelBase()
+	srv.BaseContext = func(net.Listener) context.Context { return base }
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+	stop() // A second signal exits immediately
+
+	log.Printf("🛑 Shutting down: notifying clients, closing in %s", noticeDelay)
+	Announce(notice)
+	if noticeDelay > 0 {
+		time.Sleep(noticeDelay)
+	}
+
+	// Streams never go idle on their own: end them, then wait for the rest
+	cancelBase()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("⚠️ Graceful shutdown timed out, closing remaining connections: %v", err)
+		srv.Close()
+	}
+
+	if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	log.Println("👋 Server stopped")
+	return nil
+}
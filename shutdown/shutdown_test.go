@@ -0,0 +1,110 @@
+package shutdown
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// freeAddr returns a loopback address nothing is listening on
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	return addr
+}
+
+// streamHandler holds every request open until the server ends it
+func streamHandler(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("open\n"))
+	w.(http.Flusher).Flush()
+	<-r.Context().Done()
+}
+
+func TestServeAnnouncesBeforeClosingStreams(t *testing.T) {
+	t.Setenv("SHUTDOWN_NOTICE", "1s")
+	t.Setenv("SHUTDOWN_TIMEOUT", "2s")
+	t.Setenv("SHUTDOWN_RECONNECT_AFTER", "3s")
+	t.Setenv("SHUTDOWN_RECONNECT_JITTER", "1500ms")
+
+	type announced struct {
+		notice Notice
+		at     time.Time
+	}
+	notices := make(chan announced, 1)
+	old := listeners
+	t.Cleanup(func() { listeners = old })
+	OnClosing(func(n Notice) { notices <- announced{n, time.Now()} })
+
+	addr := freeAddr(t)
+	served := make(chan error, 1)
+	go func() {
+		served <- Serve(&http.Server{Addr: addr, Handler: http.HandlerFunc(streamHandler)})
+	}()
+
+	// Open a stream; once it answers, Serve is listening for the signal
+	var resp *http.Response
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		var err error
+		if resp, err = http.Get("http://" + addr); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server did not start: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+	if line, _ := reader.ReadString('\n'); line != "open\n" {
+		t.Fatalf("stream = %q", line)
+	}
+
+	streamClosed := make(chan time.Time, 1)
+	go func() {
+		reader.ReadString('\n') // Returns when the server ends the stream
+		streamClosed <- time.Now()
+	}()
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+
+	var got announced
+	select {
+	case got = <-notices:
+	case <-time.After(2 * time.Second):
+		t.Fatal("no closing notice")
+	}
+	want := Notice{ClosingInSeconds: 1, ReconnectAfterMs: 3000, ReconnectJitterMs: 1500}
+	if got.notice != want {
+		t.Errorf("notice = %+v, want %+v", got.notice, want)
+	}
+
+	select {
+	case closedAt := <-streamClosed:
+		// The stream stays open for the notice delay after the announcement
+		if wait := closedAt.Sub(got.at); wait < 900*time.Millisecond {
+			t.Errorf("stream closed %s after the notice, want about 1s", wait)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("stream was not closed")
+	}
+
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Fatalf("Serve = %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Serve did not return")
+	}
+}
@@ -2,21 +2,89 @@ package slider
 
 import (
 	"database/sql"
+	"errors"
 	"log"
 	"net/http"
 	"time"
 
+	"burma2d/dbutil"
+
 	"github.com/gin-gonic/gin"
 )
 
 type Slider struct {
-	ID          int       `json:"slider_id"`
-	ImageLink   string    `json:"banner_image_url"`
-	ForwardLink string    `json:"redirect_url"`
-	Title       string    `json:"banner_title"`
-	Order       int       `json:"display_order"`
-	IsActive    bool      `json:"is_visible"`
-	CreatedAt   time.Time `json:"created_date"`
+	ID          int        `json:"slider_id"`
+	ImageLink   string     `json:"banner_image_url"`
+	ForwardLink string     `json:"redirect_url"`
+	Title       string     `json:"banner_title"`
+	Order       int        `json:"display_order"`
+	IsActive    bool       `json:"is_visible"`
+	StartAt     *time.Time `json:"start_at"` // Shown from this time (nil = no start bound)
+	EndAt       *time.Time `json:"end_at"`   // Hidden after this time (nil = no end bound)
+	CreatedAt   time.Time  `json:"created_date"`
+}
+
+// myanmarLocation is used to present schedule bounds consistently
+var myanmarLocation = loadMyanmarLocation()
+
+func loadMyanmarLocation() *time.Location {
+	loc, err := time.LoadLocation("Asia/Yangon")
+	if err != nil {
+		return time.FixedZone("Myanmar", 6*3600+30*60)
+	}
+	return loc
+}
+
+// sliderColumns is the column list read by scanSlider
+const sliderColumns = "id, image_link, forward_link, title, order_num, is_active, start_at, end_at, created_at"
+
+// scanSlider reads a row selected with sliderColumns
+func scanSlider(rows *sql.Rows) (Slider, error) {
+	var slider Slider
+	var startAt, endAt sql.NullTime
+	err := rows.Scan(&slider.ID, &slider.ImageLink, &slider.ForwardLink,
+		&slider.Title, &slider.Order, &slider.IsActive, &startAt, &endAt, &slider.CreatedAt)
+	if err != nil {
+		return slider, err
+	}
+	if startAt.Valid {
+		t := startAt.Time.In(myanmarLocation)
+		slider.StartAt = &t
+	}
+	if endAt.Valid {
+		t := endAt.Time.In(myanmarLocation)
+		slider.EndAt = &t
+	}
+	return slider, nil
+}
+
+// InWindow reports whether the slider's schedule includes t
+func (s Slider) InWindow(t time.Time) bool {
+	if s.StartAt != nil && t.Before(*s.StartAt) {
+		return false
+	}
+	if s.EndAt != nil && !t.Before(*s.EndAt) {
+		return false
+	}
+	return true
+}
+
+// ErrInvalidWindow is returned when end_at is not after start_at
+var ErrInvalidWindow = errors.New("end_at must be after start_at")
+
+func validateWindow(s Slider) error {
+	if s.StartAt != nil && s.EndAt != nil && !s.EndAt.After(*s.StartAt) {
+		return ErrInvalidWindow
+	}
+	return nil
+}
+
+// utcOrNil stores schedule bounds in UTC so they compare consistently
+func utcOrNil(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
 }
 
 var db *sql.DB
@@ -45,15 +113,25 @@ func createTable() {
 	_, err := db.Exec(query)
 	if err != nil {
 		log.Printf("❌ Error creating sliders table: %v", err)
-	} else {
-		log.Println("✅ Sliders table ready")
+		return
 	}
+
+	// Optional schedule window
+	for _, column := range []string{"start_at", "end_at"} {
+		if err := dbutil.AddColumnIfMissing(db, "sliders", column, "DATETIME"); err != nil {
+			log.Printf("❌ Error migrating sliders table: %v", err)
+			return
+		}
+	}
+
+	log.Println("✅ Sliders table ready")
 }
 
-// GetActiveSliders retrieves all active sliders ordered by order_num
+// GetActiveSliders retrieves active sliders whose schedule window includes
+// now, ordered by order_num
 func GetActiveSliders() ([]Slider, error) {
 	query := `
-		SELECT id, image_link, forward_link, title, order_num, is_active, created_at
+		SELECT ` + sliderColumns + `
 		FROM sliders
 		WHERE is_active = 1
 		ORDER BY order_num ASC, created_at DESC
@@ -64,15 +142,17 @@ func GetActiveSliders() ([]Slider, error) {
 	}
 	defer rows.Close()
 
+	now := time.Now()
 	var sliders []Slider
 	for rows.Next() {
-		var slider Slider
-		err := rows.Scan(&slider.ID, &slider.ImageLink, &slider.ForwardLink,
-			&slider.Title, &slider.Order, &slider.IsActive, &slider.CreatedAt)
+		slider, err := scanSlider(rows)
 		if err != nil {
 			log.Printf("Error scanning slider: %v", err)
 			continue
 		}
+		if !slider.InWindow(now) {
+			continue
+		}
 		sliders = append(sliders, slider)
 	}
 
@@ -82,7 +162,7 @@ func GetActiveSliders() ([]Slider, error) {
 // GetAllSlidersForAdmin retrieves all sliders (including inactive)
 func GetAllSlidersForAdmin() ([]Slider, error) {
 	query := `
-		SELECT id, image_link, forward_link, title, order_num, is_active, created_at
+		SELECT ` + sliderColumns + `
 		FROM sliders
 		ORDER BY order_num ASC, created_at DESC
 	`
@@ -94,9 +174,7 @@ func GetAllSlidersForAdmin() ([]Slider, error) {
 
 	var sliders []Slider
 	for rows.Next() {
-		var slider Slider
-		err := rows.Scan(&slider.ID, &slider.ImageLink, &slider.ForwardLink,
-			&slider.Title, &slider.Order, &slider.IsActive, &slider.CreatedAt)
+		slider, err := scanSlider(rows)
 		if err != nil {
 			log.Printf("Error scanning slider: %v", err)
 			continue
@@ -109,12 +187,16 @@ func GetAllSlidersForAdmin() ([]Slider, error) {
 
 // InsertSlider adds a new slider
 func InsertSlider(slider Slider) error {
+	if err := validateWindow(slider); err != nil {
+		return err
+	}
+
 	query := `
-		INSERT INTO sliders (image_link, forward_link, title, order_num, is_active)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO sliders (image_link, forward_link, title, order_num, is_active, start_at, end_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 	`
 	_, err := db.Exec(query, slider.ImageLink, slider.ForwardLink,
-		slider.Title, slider.Order, slider.IsActive)
+		slider.Title, slider.Order, slider.IsActive, utcOrNil(slider.StartAt), utcOrNil(slider.EndAt))
 	if err != nil {
 		log.Printf("❌ Error inserting slider: %v", err)
 		return err
@@ -123,15 +205,27 @@ func InsertSlider(slider Slider) error {
 	return nil
 }
 
+// GetImageLink returns the current image link of a slider
+func GetImageLink(id int) (string, error) {
+	var link string
+	err := db.QueryRow("SELECT image_link FROM sliders WHERE id = ?", id).Scan(&link)
+	return link, err
+}
+
 // UpdateSlider updates an existing slider
 func UpdateSlider(slider Slider) error {
+	if err := validateWindow(slider); err != nil {
+		return err
+	}
+
 	query := `
 		UPDATE sliders
-		SET image_link = $1, forward_link = $2, title = $3, order_num = $4, is_active = $5
-		WHERE id = $6
+		SET image_link = $1, forward_link = $2, title = $3, order_num = $4, is_active = $5,
+		    start_at = $6, end_at = $7
+		WHERE id = $8
 	`
 	_, err := db.Exec(query, slider.ImageLink, slider.ForwardLink,
-		slider.Title, slider.Order, slider.IsActive, slider.ID)
+		slider.Title, slider.Order, slider.IsActive, utcOrNil(slider.StartAt), utcOrNil(slider.EndAt), slider.ID)
 	if err != nil {
 		log.Printf("❌ Error updating slider: %v", err)
 		return err
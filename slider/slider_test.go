@@ -0,0 +1,96 @@
+package slider
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	database.SetMaxOpenConns(1)
+	t.Cleanup(func() { database.Close() })
+	InitDB(database)
+}
+
+func at(t time.Time) *time.Time { return &t }
+
+func TestGetActiveSlidersScheduleWindow(t *testing.T) {
+	setupTestDB(t)
+	now := time.Now()
+	for _, s := range []Slider{
+		{Title: "past", StartAt: at(now.Add(-48 * time.Hour)), EndAt: at(now.Add(-24 * time.Hour))},
+		{Title: "current", StartAt: at(now.Add(-time.Hour)), EndAt: at(now.Add(time.Hour))},
+		{Title: "future", StartAt: at(now.Add(24 * time.Hour))},
+		{Title: "open ended", StartAt: at(now.Add(-time.Hour))},
+		{Title: "unscheduled", Order: 1},
+	} {
+		s.ImageLink = "/uploads/" + s.Title + ".png"
+		s.IsActive = true
+		if err := InsertSlider(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	InsertSlider(Slider{Title: "hidden", ImageLink: "/uploads/hidden.png"})
+
+	sliders, err := GetActiveSliders()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, s := range sliders {
+		got = append(got, s.Title)
+	}
+	if len(got) != 3 || got[0] != "current" || got[1] != "open ended" || got[2] != "unscheduled" {
+		t.Fatalf("active sliders = %v, want [current open ended unscheduled]", got)
+	}
+	if sliders[0].StartAt == nil || sliders[0].StartAt.Location() != myanmarLocation || sliders[2].StartAt != nil {
+		t.Errorf("bounds = %v / %v, want Myanmar time and nil when unset", sliders[0].StartAt, sliders[2].StartAt)
+	}
+
+	// The admin list still includes everything
+	if all, _ := GetAllSlidersForAdmin(); len(all) != 6 {
+		t.Errorf("admin list has %d sliders, want 6", len(all))
+	}
+}
+
+func TestInWindowBounds(t *testing.T) {
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	s := Slider{StartAt: &start, EndAt: &end}
+
+	for _, tt := range []struct {
+		t    time.Time
+		want bool
+	}{
+		{start.Add(-time.Second), false},
+		{start, true},
+		{end.Add(-time.Second), true},
+		{end, false},
+	} {
+		if got := s.InWindow(tt.t); got != tt.want {
+			t.Errorf("InWindow(%s) = %v, want %v", tt.t.Format(time.TimeOnly), got, tt.want)
+		}
+	}
+}
+
+func TestInvalidWindowRejected(t *testing.T) {
+	setupTestDB(t)
+	now := time.Now()
+	s := Slider{ImageLink: "/uploads/a.png", StartAt: at(now), EndAt: at(now)}
+
+	if err := InsertSlider(s); !errors.Is(err, ErrInvalidWindow) {
+		t.Errorf("insert: %v, want ErrInvalidWindow", err)
+	}
+	s.ID = 1
+	if err := UpdateSlider(s); !errors.Is(err, ErrInvalidWindow) {
+		t.Errorf("update: %v, want ErrInvalidWindow", err)
+	}
+}
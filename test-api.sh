@@ -22,6 +22,7 @@ echo ""
 echo "📮 Testing POST /api/burma2d/update..."
 curl -X POST http://localhost:4545/api/burma2d/update \
   -H "Content-Type: application/json" \
+  -H "X-API-Key: ${LOTTERY_UPDATE_KEY}" \
   -d '{
     "live": "22",
     "status": "On",
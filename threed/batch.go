@@ -0,0 +1,199 @@
+package threed
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"time"
+
+	"burma2d/adminauth"
+	"burma2d/dbutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxBatchSize bounds a single batch update request
+const maxBatchSize = 100
+
+var threeDigitResult = regexp.MustCompile(`^[0-9]{3}$`)
+
+// BatchUpdateItem is one change in a batch update
+type BatchUpdateItem struct {
+	ID     int    `json:"id"`
+	Result string `json:"result"`
+	Date   string `json:"date,omitempty"` // optional new date (YYYY-MM-DD)
+}
+
+// BatchItemResult reports the outcome of one item
+type BatchItemResult struct {
+	ID     int           `json:"id"`
+	Status string        `json:"status"` // "updated", "invalid", "not_found", "conflict", "skipped", "rolled_back"
+	Error  string        `json:"error,omitempty"`
+	Result *ThreeDResult `json:"result,omitempty"`
+}
+
+// validate checks the item's fields and returns a message when invalid
+func (item BatchUpdateItem) validate() string {
+	if item.ID <= 0 {
+		return "id is required"
+	}
+	if !threeDigitResult.MatchString(item.Result) {
+		return "Result must be 3 digits"
+	}
+	if item.Date != "" {
+		if _, err := time.Parse("2006-01-02", item.Date); err != nil {
+			return "Invalid date format. Use YYYY-MM-DD"
+		}
+	}
+	return ""
+}
+
+// BatchUpdateResults updates several 3D results in one transaction.
+// All items are applied or none are; each item gets its own status.
+func BatchUpdateResults(c *gin.Context) {
+	var items []BatchUpdateItem
+	if err := c.BindJSON(&items); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON, expected an array of {id, result, date}"})
+		return
+	}
+	if len(items) == 0 || len(items) > maxBatchSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Batch must contain 1-%d items", maxBatchSize)})
+		return
+	}
+
+	results := make([]BatchItemResult, len(items))
+	seen := make(map[int]bool, len(items))
+	invalid := false
+	for i, item := range items {
+		results[i] = BatchItemResult{ID: item.ID, Status: "skipped"}
+		msg := item.validate()
+		if msg == "" && seen[item.ID] {
+			msg = "duplicate id in batch"
+		}
+		seen[item.ID] = true
+		if msg != "" {
+			results[i].Status = "invalid"
+			results[i].Error = msg
+			invalid = true
+		}
+	}
+	if invalid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed, nothing was updated", "results": results})
+		return
+	}
+
+	type change struct{ before, after ThreeDResult }
+	changes := make([]change, 0, len(items))
+	admin := c.GetString(adminauth.ContextUserKey)
+
+	err := dbutil.WithTx(db, func(tx *sql.Tx) error {
+		for i, item := range items {
+			before, err := getResultTx(tx, item.ID)
+			if err == sql.ErrNoRows {
+				results[i].Status = "not_found"
+				results[i].Error = "Result not found"
+				markRolledBack(results[:i])
+				return batchError{http.StatusNotFound}
+			} else if err != nil {
+				return err
+			}
+
+			date := item.Date
+			if date == "" {
+				date = before.Date
+			}
+
+			var after ThreeDResult
+			var afterDate time.Time
+			err = tx.QueryRow(`
+				UPDATE threed
+				SET result = $1, date = $2, updated_at = CURRENT_TIMESTAMP
+				WHERE id = $3
+				RETURNING id, date, result, created_at, updated_at
+			`, item.Result, date, item.ID).Scan(
+				&after.ID, &afterDate, &after.Result, &after.CreatedAt, &after.UpdatedAt,
+			)
+			if err != nil {
+				results[i].Status = "conflict"
+				results[i].Error = "Result for this date already exists or database error"
+				markRolledBack(results[:i])
+				return batchError{http.StatusConflict}
+			}
+			after.Date = afterDate.Format("2006-01-02")
+
+			// The audit row commits or rolls back with the change itself
+			if err := recordAudit(tx, before, after, admin); err != nil {
+				return err
+			}
+
+			results[i].Status = "updated"
+			results[i].Result = &after
+			changes = append(changes, change{before, after})
+		}
+		return nil
+	})
+
+	var failed batchError
+	if errors.As(err, &failed) {
+		c.JSON(failed.status, gin.H{"error": "Batch rolled back", "results": results})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, ch := range changes {
+		log.Printf("📝 3D batch update by %q: #%d %s %s -> %s %s",
+			admin, ch.after.ID, ch.before.Date, ch.before.Result, ch.after.Date, ch.after.Result)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"updated": len(changes),
+		"results": results,
+	})
+}
+
+// batchError aborts the batch transaction with the HTTP status to report
+type batchError struct{ status int }
+
+func (e batchError) Error() string {
+	return http.StatusText(e.status)
+}
+
+// markRolledBack flags items that were applied before the batch failed
+func markRolledBack(results []BatchItemResult) {
+	for i := range results {
+		results[i].Status = "rolled_back"
+		results[i].Result = nil
+	}
+}
+
+// recordAudit stores who changed a 3D result and what it was before
+func recordAudit(tx *sql.Tx, before, after ThreeDResult, actor string) error {
+	_, err := tx.Exec(`
+		INSERT INTO threed_audit (result_id, old_date, old_result, new_date, new_result, actor)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, after.ID, before.Date, before.Result, after.Date, after.Result, actor)
+	if err != nil {
+		return fmt.Errorf("failed to record 3D audit: %w", err)
+	}
+	return nil
+}
+
+// getResultTx loads a 3D result inside a transaction
+func getResultTx(tx *sql.Tx, id int) (ThreeDResult, error) {
+	var result ThreeDResult
+	var date time.Time
+	err := tx.QueryRow(`
+		SELECT id, date, result, created_at, updated_at FROM threed WHERE id = $1
+	`, id).Scan(&result.ID, &date, &result.Result, &result.CreatedAt, &result.UpdatedAt)
+	if err != nil {
+		return result, err
+	}
+	result.Date = date.Format("2006-01-02")
+	return result, nil
+}
@@ -0,0 +1,161 @@
+package threed
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"burma2d/adminauth"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	database.SetMaxOpenConns(1)
+	t.Cleanup(func() { database.Close() })
+	InitDB(database)
+
+	for _, row := range [][2]string{{"2026-03-01", "111"}, {"2026-03-16", "222"}} {
+		if _, err := db.Exec("INSERT INTO threed (date, result) VALUES (?, ?)", row[0], row[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func batchUpdate(t *testing.T, items []BatchUpdateItem) (int, []BatchItemResult) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.PUT("/batch", func(c *gin.Context) {
+		c.Set(adminauth.ContextUserKey, "admin")
+		BatchUpdateResults(c)
+	})
+
+	body, _ := json.Marshal(items)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/batch", bytes.NewReader(body)))
+	var resp struct {
+		Results []BatchItemResult `json:"results"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp.Results
+}
+
+// stored returns the results by id, as they are in the table
+func stored(t *testing.T) map[int]string {
+	t.Helper()
+	rows, err := db.Query("SELECT id, result FROM threed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	out := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var result string
+		rows.Scan(&id, &result)
+		out[id] = result
+	}
+	return out
+}
+
+func auditCount(t *testing.T) int {
+	t.Helper()
+	var n int
+	db.QueryRow("SELECT COUNT(*) FROM threed_audit").Scan(&n)
+	return n
+}
+
+func statuses(results []BatchItemResult) []string {
+	out := []string{}
+	for _, r := range results {
+		out = append(out, r.Status)
+	}
+	return out
+}
+
+func TestBatchUpdateAllOrNothing(t *testing.T) {
+	unchanged := map[int]string{1: "111", 2: "222"}
+	tests := []struct {
+		name   string
+		items  []BatchUpdateItem
+		code   int
+		status []string
+	}{
+		{"invalid item", []BatchUpdateItem{{ID: 1, Result: "123"}, {ID: 2, Result: "12x"}},
+			http.StatusBadRequest, []string{"skipped", "invalid"}},
+		{"duplicate id", []BatchUpdateItem{{ID: 1, Result: "123"}, {ID: 1, Result: "456"}},
+			http.StatusBadRequest, []string{"skipped", "invalid"}},
+		{"missing row", []BatchUpdateItem{{ID: 1, Result: "123"}, {ID: 2, Result: "456"}, {ID: 99, Result: "789"}},
+			http.StatusNotFound, []string{"rolled_back", "rolled_back", "not_found"}},
+		{"date conflict", []BatchUpdateItem{{ID: 1, Result: "123"}, {ID: 2, Result: "456", Date: "2026-03-01"}, {ID: 3, Result: "789"}},
+			http.StatusConflict, []string{"rolled_back", "conflict", "skipped"}},
+	}
+
+	for _, tt := range tests {
+		setupTestDB(t)
+		code, results := batchUpdate(t, tt.items)
+		if code != tt.code {
+			t.Errorf("%s: status %d, want %d", tt.name, code, tt.code)
+		}
+		if got := statuses(results); len(got) != len(tt.status) {
+			t.Errorf("%s: statuses %v, want %v", tt.name, got, tt.status)
+		} else {
+			for i := range got {
+				if got[i] != tt.status[i] {
+					t.Errorf("%s: statuses %v, want %v", tt.name, got, tt.status)
+					break
+				}
+			}
+		}
+		for _, r := range results {
+			if r.Status == "rolled_back" && r.Result != nil {
+				t.Errorf("%s: rolled back item %d still reports a result", tt.name, r.ID)
+			}
+		}
+
+		got := stored(t)
+		for id, result := range unchanged {
+			if got[id] != result {
+				t.Errorf("%s: #%d = %s after a failed batch, want %s", tt.name, id, got[id], result)
+			}
+		}
+		if n := auditCount(t); n != 0 {
+			t.Errorf("%s: %d audit rows kept for a rolled back batch", tt.name, n)
+		}
+	}
+}
+
+func TestBatchUpdateRecordsAudit(t *testing.T) {
+	setupTestDB(t)
+	code, results := batchUpdate(t, []BatchUpdateItem{{ID: 1, Result: "123"}, {ID: 2, Result: "456", Date: "2026-03-17"}})
+	if code != http.StatusOK || len(results) != 2 || results[1].Result == nil || results[1].Result.Date != "2026-03-17" {
+		t.Fatalf("status %d, results %+v", code, results)
+	}
+	if got := stored(t); got[1] != "123" || got[2] != "456" {
+		t.Fatalf("stored %v", got)
+	}
+
+	var oldDate, oldResult, newDate, newResult, actor string
+	err := db.QueryRow(`
+		SELECT old_date, old_result, new_date, new_result, actor FROM threed_audit WHERE result_id = 2
+	`).Scan(&oldDate, &oldResult, &newDate, &newResult, &actor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oldDate != "2026-03-16" || oldResult != "222" || newDate != "2026-03-17" || newResult != "456" || actor != "admin" {
+		t.Errorf("audit = %s %s -> %s %s by %q", oldDate, oldResult, newDate, newResult, actor)
+	}
+	if n := auditCount(t); n != 2 {
+		t.Errorf("%d audit rows, want one per item", n)
+	}
+}
@@ -0,0 +1,182 @@
+package threed
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"burma2d/adminauth"
+	"burma2d/dbutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportRows bounds a single import request
+const maxImportRows = 5000
+
+// ImportRow is one {date, result} record to import
+type ImportRow struct {
+	Date   string `json:"date"`
+	Result string `json:"result"`
+}
+
+// ImportRowResult reports a row that was not inserted
+type ImportRowResult struct {
+	Row    int    `json:"row"` // 1-based position in the input (excluding a CSV header)
+	Date   string `json:"date"`
+	Result string `json:"result"`
+	Status string `json:"status"` // "duplicate" or "rejected"
+	Error  string `json:"error,omitempty"`
+}
+
+// validate checks the row and returns a message when invalid
+func (row ImportRow) validate() string {
+	if !threeDigitResult.MatchString(row.Result) {
+		return "Result must be 3 digits"
+	}
+	if _, err := time.Parse("2006-01-02", row.Date); err != nil {
+		return "Invalid date format. Use YYYY-MM-DD"
+	}
+	return ""
+}
+
+// ImportResults bulk-inserts historical 3D results from a JSON array of
+// {date, result} or a CSV body (Content-Type text/csv, optional header).
+// Rows whose date already exists are skipped as duplicates. Invalid rows
+// are rejected individually unless ?strict=true, which aborts the import.
+func ImportResults(c *gin.Context) {
+	rows, err := readImportRows(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(rows) == 0 || len(rows) > maxImportRows {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Import must contain 1-%d rows", maxImportRows)})
+		return
+	}
+	strict := c.Query("strict") == "true"
+
+	var skipped []ImportRowResult
+	valid := make([]int, 0, len(rows))
+	seen := make(map[string]bool, len(rows))
+	for i, row := range rows {
+		row.Date = strings.TrimSpace(row.Date)
+		row.Result = strings.TrimSpace(row.Result)
+		rows[i] = row
+
+		if msg := row.validate(); msg != "" {
+			skipped = append(skipped, ImportRowResult{Row: i + 1, Date: row.Date, Result: row.Result, Status: "rejected", Error: msg})
+			continue
+		}
+		if seen[row.Date] {
+			skipped = append(skipped, ImportRowResult{Row: i + 1, Date: row.Date, Result: row.Result, Status: "duplicate", Error: "date repeated in import"})
+			continue
+		}
+		seen[row.Date] = true
+		valid = append(valid, i)
+	}
+
+	rejected := 0
+	for _, s := range skipped {
+		if s.Status == "rejected" {
+			rejected++
+		}
+	}
+	if strict && rejected > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":    "Validation failed, nothing was imported",
+			"rejected": rejected,
+			"rows":     skipped,
+		})
+		return
+	}
+
+	inserted := 0
+	err = dbutil.WithTx(db, func(tx *sql.Tx) error {
+		stmt, err := tx.Prepare(`
+			INSERT INTO threed (date, result, created_at, updated_at)
+			VALUES ($1, $2, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+			ON CONFLICT(date) DO NOTHING
+		`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		for _, i := range valid {
+			result, err := stmt.Exec(rows[i].Date, rows[i].Result)
+			if err != nil {
+				return fmt.Errorf("row %d: %w", i+1, err)
+			}
+			if n, _ := result.RowsAffected(); n == 0 {
+				skipped = append(skipped, ImportRowResult{Row: i + 1, Date: rows[i].Date, Result: rows[i].Result, Status: "duplicate", Error: "result for this date already exists"})
+				continue
+			}
+			inserted++
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Import failed, nothing was imported: " + err.Error()})
+		return
+	}
+
+	sort.Slice(skipped, func(i, j int) bool { return skipped[i].Row < skipped[j].Row })
+	duplicates := len(skipped) - rejected
+	log.Printf("📝 3D import by %q: %d inserted, %d duplicate, %d rejected",
+		c.GetString(adminauth.ContextUserKey), inserted, duplicates, rejected)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"inserted":   inserted,
+		"duplicates": duplicates,
+		"rejected":   rejected,
+		"rows":       skipped,
+	})
+}
+
+// readImportRows parses the request body as CSV or a JSON array
+func readImportRows(c *gin.Context) ([]ImportRow, error) {
+	if strings.HasPrefix(c.ContentType(), "text/csv") {
+		return parseImportCSV(c.Request.Body)
+	}
+
+	var rows []ImportRow
+	if err := c.ShouldBindJSON(&rows); err != nil {
+		return nil, errors.New("Invalid JSON, expected an array of {date, result}")
+	}
+	return rows, nil
+}
+
+// parseImportCSV reads date,result records, skipping a header row
+func parseImportCSV(r io.Reader) ([]ImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+
+	var rows []ImportRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Invalid CSV: %v", err)
+		}
+		if len(rows) == 0 && strings.EqualFold(strings.TrimSpace(record[0]), "date") {
+			continue
+		}
+		rows = append(rows, ImportRow{Date: record[0], Result: record[1]})
+		if len(rows) > maxImportRows {
+			break
+		}
+	}
+	return rows, nil
+}
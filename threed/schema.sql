@@ -10,6 +10,19 @@ CREATE TABLE IF NOT EXISTS threed (
 -- Create index on date for faster queries
 CREATE INDEX IF NOT EXISTS idx_threed_date ON threed(date DESC);
 
+-- Audit trail of admin batch updates
+CREATE TABLE IF NOT EXISTS threed_audit (
+    id SERIAL PRIMARY KEY,
+    result_id INTEGER NOT NULL,
+    old_date TEXT NOT NULL,
+    old_result TEXT NOT NULL,
+    new_date TEXT NOT NULL,
+    new_result TEXT NOT NULL,
+    actor TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_threed_audit_result ON threed_audit(result_id, created_at DESC);
+
 -- Insert sample data
 INSERT INTO threed (date, result) VALUES 
 ('2025-10-16', '696'),
@@ -4,8 +4,11 @@ import (
 	"database/sql"
 	"log"
 	"net/http"
+	"strconv"
 	"time"
 
+	"burma2d/pagination"
+
 	"github.com/gin-gonic/gin"
 	_ "github.com/lib/pq"
 )
@@ -26,7 +29,7 @@ func InitDB(database *sql.DB) {
 	createTable()
 }
 
-// createTable creates the threed table if it doesn't exist
+// createTable creates the threed and threed_audit tables if they don't exist
 func createTable() {
 	query := `
 		CREATE TABLE IF NOT EXISTS threed (
@@ -37,6 +40,17 @@ func createTable() {
 			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 		);
 		CREATE INDEX IF NOT EXISTS idx_threed_date ON threed(date DESC);
+		CREATE TABLE IF NOT EXISTS threed_audit (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			result_id INTEGER NOT NULL,
+			old_date TEXT NOT NULL,
+			old_result TEXT NOT NULL,
+			new_date TEXT NOT NULL,
+			new_result TEXT NOT NULL,
+			actor TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_threed_audit_result ON threed_audit(result_id, created_at DESC);
 	`
 	_, err := db.Exec(query)
 	if err != nil {
@@ -44,20 +58,44 @@ func createTable() {
 	}
 }
 
-// GetAllResults fetches all 3D results ordered by date DESC
+// defaultWindowDays bounds GetAllResults when no from/to range is given
+const defaultWindowDays = 90
+
+// GetAllResults fetches 3D results ordered by date DESC.
+// Optional query params: from, to (YYYY-MM-DD), limit, offset. Without a
+// range only the last defaultWindowDays are returned. The total number of
+// matching rows is sent in the X-Total-Count header.
 func GetAllResults(c *gin.Context) {
+	p, err := pagination.Parse(c, pagination.Options{MaxLimit: 1000})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if p.From.IsZero() && p.To.IsZero() {
+		p.From = time.Now().AddDate(0, 0, -defaultWindowDays+1)
+	}
+
+	var where pagination.Where
+	where.DateRange("date", pagination.DateLayout, p)
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM threed"+where.SQL(), where.Args()...).Scan(&total); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	limitSQL, limitArgs := p.LimitOffset()
 	rows, err := db.Query(`
 		SELECT id, date, result, created_at, updated_at 
-		FROM threed 
-		ORDER BY date DESC
-	`)
+		FROM threed`+where.SQL()+`
+		ORDER BY date DESC`+limitSQL, append(where.Args(), limitArgs...)...)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	defer rows.Close()
 
-	var results []ThreeDResult
+	results := []ThreeDResult{}
 	for rows.Next() {
 		var result ThreeDResult
 		var date time.Time
@@ -70,6 +108,7 @@ func GetAllResults(c *gin.Context) {
 		results = append(results, result)
 	}
 
+	c.Header("X-Total-Count", strconv.Itoa(total))
 	c.JSON(http.StatusOK, results)
 }
 
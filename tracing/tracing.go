@@ -0,0 +1,134 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"burma2d/config"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies spans created by this server
+const tracerName = "burma2d"
+
+var (
+	enabled  bool
+	provider *sdktrace.TracerProvider
+	tracer   trace.Tracer = trace.NewNoopTracerProvider().Tracer(tracerName)
+)
+
+// Init enables tracing when TRACING_ENABLED=true and an OTLP endpoint is
+// configured (OTEL_EXPORTER_OTLP_ENDPOINT or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT,
+// read by the exporter). Otherwise every span is a no-op.
+func Init() error {
+	if !config.Bool("TRACING_ENABLED", false) {
+		log.Println("ℹ️  Tracing disabled (set TRACING_ENABLED=true to enable)")
+		return nil
+	}
+
+	endpoint := config.String("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	if endpoint == "" {
+		endpoint = config.String("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	}
+	if endpoint == "" {
+		return fmt.Errorf("TRACING_ENABLED=true but OTEL_EXPORTER_OTLP_ENDPOINT not set")
+	}
+
+	exporter, err := otlptracehttp.New(context.Background())
+	if err != nil {
+		return fmt.Errorf("create OTLP exporter: %w", err)
+	}
+
+	serviceName := config.String("OTEL_SERVICE_NAME", tracerName)
+	provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	tracer = provider.Tracer(tracerName)
+	enabled = true
+
+	log.Printf("✅ Tracing enabled: exporting to %s as %s", endpoint, serviceName)
+	return nil
+}
+
+// Enabled reports whether spans are being recorded
+func Enabled() bool {
+	return enabled
+}
+
+// Shutdown flushes pending spans; no-op when tracing is disabled
+func Shutdown(ctx context.Context) error {
+	if provider == nil {
+		return nil
+	}
+	return provider.Shutdown(ctx)
+}
+
+// Start begins a span for an internal operation. When tracing is disabled
+// it returns ctx unchanged and a no-op span.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if !enabled {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err on the span (if any) and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Middleware starts a server span per request, continuing any incoming
+// W3C trace context. It only calls c.Next when tracing is disabled.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !enabled {
+			c.Next()
+			return
+		}
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		ctx, span := tracer.Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Request.Method),
+				attribute.String("http.route", route),
+				attribute.String("client.address", c.ClientIP()),
+			),
+		)
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= 500 {
+			span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d", status))
+		}
+		if len(c.Errors) > 0 {
+			span.RecordError(c.Errors.Last())
+		}
+	}
+}
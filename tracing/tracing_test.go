@@ -0,0 +1,104 @@
+package tracing
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// useRecorder enables tracing with an in-memory span recorder
+func useRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	oldEnabled, oldTracer, oldPropagator := enabled, tracer, otel.GetTextMapPropagator()
+	enabled, tracer = true, tp.Tracer(tracerName)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		enabled, tracer = oldEnabled, oldTracer
+		otel.SetTextMapPropagator(oldPropagator)
+	})
+	return recorder
+}
+
+func tracedRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Middleware())
+	r.GET("/items/:id", func(c *gin.Context) {
+		_, span := Start(c.Request.Context(), "load.item", attribute.String("item.id", c.Param("id")))
+		End(span, errors.New("cache miss"))
+		c.Status(http.StatusInternalServerError)
+	})
+	return r
+}
+
+func attr(span sdktrace.ReadOnlySpan, key string) (attribute.Value, bool) {
+	for _, kv := range span.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestMiddlewareCreatesSpans(t *testing.T) {
+	recorder := useRecorder(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/items/7", nil)
+	// Continue a caller's trace
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	tracedRouter().ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("%d spans recorded, want 2", len(spans))
+	}
+	child, server := spans[0], spans[1]
+
+	if server.Name() != "GET /items/:id" || server.SpanKind() != trace.SpanKindServer {
+		t.Errorf("server span = %q (%v)", server.Name(), server.SpanKind())
+	}
+	if v, _ := attr(server, "http.response.status_code"); v.AsInt64() != http.StatusInternalServerError {
+		t.Errorf("status attribute = %v", v)
+	}
+	if server.Status().Code != codes.Error {
+		t.Errorf("server span status = %v, want error for a 500", server.Status())
+	}
+	if got := server.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace ID = %s, want the incoming one", got)
+	}
+
+	if child.Name() != "load.item" || child.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Errorf("child span %q is not under the request span", child.Name())
+	}
+	if child.Status().Code != codes.Error || len(child.Events()) == 0 {
+		t.Errorf("child span did not record its error")
+	}
+}
+
+func TestMiddlewareDisabled(t *testing.T) {
+	recorder := useRecorder(t)
+	enabled = false
+
+	w := httptest.NewRecorder()
+	tracedRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/items/7", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("handler did not run: status %d", w.Code)
+	}
+	if n := len(recorder.Ended()); n != 0 {
+		t.Errorf("%d spans recorded while disabled", n)
+	}
+}
@@ -0,0 +1,137 @@
+package twodhistory
+
+import (
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"burma2d/jsonutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// backfillRequest is a full day's record in the runner's input keys.
+// Only the date and the two results are required; missing figures are
+// stored as "--" like the live feed does.
+type backfillRequest struct {
+	Date        string `json:"date" binding:"required"`
+	Set1200     string `json:"1200set"`
+	Value1200   string `json:"1200value"`
+	Result1200  string `json:"1200" binding:"required"`
+	Set430      string `json:"430set"`
+	Value430    string `json:"430value"`
+	Result430   string `json:"430" binding:"required"`
+	Modern930   string `json:"930modern"`
+	Internet930 string `json:"930internet"`
+	Modern200   string `json:"200modern"`
+	Internet200 string `json:"200internet"`
+}
+
+var (
+	resultPattern = regexp.MustCompile(`^\d{2}$`)
+	figurePattern = regexp.MustCompile(`^\d{1,3}(,?\d{3})*(\.\d+)?$`)
+)
+
+// placeholderOr returns v, or "--" when it is empty
+func placeholderOr(v string) string {
+	if v = strings.TrimSpace(v); v == "" {
+		return "--"
+	}
+	return v
+}
+
+// toHistory returns the record to store and the sorted keys whose values
+// are invalid
+func (req *backfillRequest) toHistory() (*TwoDHistory, []string) {
+	h := &TwoDHistory{
+		Date:        strings.TrimSpace(req.Date),
+		Set1200:     placeholderOr(req.Set1200),
+		Value1200:   placeholderOr(req.Value1200),
+		Result1200:  strings.TrimSpace(req.Result1200),
+		Set430:      placeholderOr(req.Set430),
+		Value430:    placeholderOr(req.Value430),
+		Result430:   strings.TrimSpace(req.Result430),
+		Modern930:   placeholderOr(req.Modern930),
+		Internet930: placeholderOr(req.Internet930),
+		Modern200:   placeholderOr(req.Modern200),
+		Internet200: placeholderOr(req.Internet200),
+	}
+
+	fields := map[string]struct {
+		value    string
+		pattern  *regexp.Regexp
+		required bool
+	}{
+		"1200set":     {h.Set1200, figurePattern, false},
+		"1200value":   {h.Value1200, figurePattern, false},
+		"1200":        {h.Result1200, resultPattern, true},
+		"430set":      {h.Set430, figurePattern, false},
+		"430value":    {h.Value430, figurePattern, false},
+		"430":         {h.Result430, resultPattern, true},
+		"930modern":   {h.Modern930, resultPattern, false},
+		"930internet": {h.Internet930, resultPattern, false},
+		"200modern":   {h.Modern200, resultPattern, false},
+		"200internet": {h.Internet200, resultPattern, false},
+	}
+
+	var invalid []string
+	if _, err := time.Parse(dateLayout, h.Date); err != nil {
+		invalid = append(invalid, "date")
+	}
+	for key, f := range fields {
+		if (f.value == "--" && !f.required) || f.pattern.MatchString(f.value) {
+			continue
+		}
+		invalid = append(invalid, key)
+	}
+	sort.Strings(invalid)
+	return h, invalid
+}
+
+// BackfillHandler stores an explicit day's record regardless of the auto
+// insert window, replacing any existing row for the date (admin)
+func BackfillHandler(c *gin.Context) {
+	var req backfillRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		jsonutil.BindError(c, err)
+		return
+	}
+
+	history, invalid := req.toHistory()
+	if len(invalid) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "invalid value for " + strings.Join(invalid, ", "),
+			"fields": invalid,
+			"hint":   "date is YYYY/MM/DD, results are two digits",
+		})
+		return
+	}
+
+	inserted, err := UpsertHistory(history)
+	if err != nil {
+		log.Printf("❌ Error backfilling history for %s: %v", history.Date, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store history"})
+		return
+	}
+
+	stored, err := GetByDate(history.Date)
+	if err != nil || stored == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load stored history"})
+		return
+	}
+
+	action := "updated"
+	if inserted {
+		action = "inserted"
+	}
+	log.Printf("🛠️  History backfill %s for date %s (12:01 %s, 4:30 %s)", action, stored.Date, stored.Result1200, stored.Result430)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"inserted": inserted,
+		"history":  stored,
+	})
+}
@@ -0,0 +1,125 @@
+package twodhistory
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"burma2d/pagination"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SessionDiff compares one session result between two dates
+type SessionDiff struct {
+	Session string `json:"session"` // JSON key of the field, e.g. "noon_result"
+	A       string `json:"a"`
+	B       string `json:"b"`
+	Match   bool   `json:"match"`
+}
+
+// GetByDate returns the history record for a stored date, or nil when none
+func GetByDate(date string) (*TwoDHistory, error) {
+	var h TwoDHistory
+	err := db.QueryRow(`
+	SELECT id, date, set1200, value1200, result1200,
+	       set430, value430, result430,
+	       modern930, internet930, modern200, internet200,
+	       created_at
+	FROM twodhistory
+	WHERE date = ?`, date).Scan(
+		&h.ID, &h.Date, &h.Set1200, &h.Value1200, &h.Result1200,
+		&h.Set430, &h.Value430, &h.Result430,
+		&h.Modern930, &h.Internet930, &h.Modern200, &h.Internet200,
+		&h.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query history: %w", err)
+	}
+	return &h, nil
+}
+
+// CompareResults lists each session's results for a and b and whether they match
+func CompareResults(a, b *TwoDHistory) []SessionDiff {
+	pairs := []struct {
+		session string
+		a, b    string
+	}{
+		{"morning_modern", a.Modern930, b.Modern930},
+		{"morning_internet", a.Internet930, b.Internet930},
+		{"noon_result", a.Result1200, b.Result1200},
+		{"afternoon_modern", a.Modern200, b.Modern200},
+		{"afternoon_internet", a.Internet200, b.Internet200},
+		{"evening_result", a.Result430, b.Result430},
+	}
+
+	diffs := make([]SessionDiff, len(pairs))
+	for i, p := range pairs {
+		diffs[i] = SessionDiff{Session: p.session, A: p.a, B: p.b, Match: p.a == p.b}
+	}
+	return diffs
+}
+
+// parseHistoryDate accepts YYYY-MM-DD or the stored YYYY/MM/DD form and
+// returns the stored form
+func parseHistoryDate(v string) (string, error) {
+	for _, layout := range []string{pagination.DateLayout, dateLayout} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t.Format(dateLayout), nil
+		}
+	}
+	return "", fmt.Errorf("invalid date %q, use YYYY-MM-DD", v)
+}
+
+// CompareHandler is the Gin handler for GET /api/burma2d/history/compare?a=DATE&b=DATE
+func CompareHandler(c *gin.Context) {
+	dateA, err := parseHistoryDate(c.Query("a"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "a: " + err.Error()})
+		return
+	}
+	dateB, err := parseHistoryDate(c.Query("b"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "b: " + err.Error()})
+		return
+	}
+
+	a, err := GetByDate(dateA)
+	if err == nil && a == nil {
+		c.JSON(404, gin.H{"error": "No history for date " + dateA})
+		return
+	}
+	var b *TwoDHistory
+	if err == nil {
+		b, err = GetByDate(dateB)
+		if err == nil && b == nil {
+			c.JSON(404, gin.H{"error": "No history for date " + dateB})
+			return
+		}
+	}
+	if err != nil {
+		log.Printf("❌ Error comparing history %s and %s: %v", dateA, dateB, err)
+		c.JSON(500, gin.H{"error": "Failed to fetch history"})
+		return
+	}
+
+	diffs := CompareResults(a, b)
+	matches := 0
+	for _, d := range diffs {
+		if d.Match {
+			matches++
+		}
+	}
+
+	c.JSON(200, gin.H{
+		"a":        a,
+		"b":        b,
+		"sessions": diffs,
+		"matches":  matches,
+		"differs":  len(diffs) - matches,
+	})
+}
@@ -0,0 +1,88 @@
+package twodhistory
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func compare(t *testing.T, query string) (int, map[string]json.RawMessage) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/compare", CompareHandler)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/compare?"+query, nil))
+	var body map[string]json.RawMessage
+	json.Unmarshal(w.Body.Bytes(), &body)
+	return w.Code, body
+}
+
+func TestCompareResults(t *testing.T) {
+	a := &TwoDHistory{Modern930: "12", Internet930: "34", Result1200: "56", Modern200: "78", Internet200: "90", Result430: "11"}
+	b := &TwoDHistory{Modern930: "12", Internet930: "43", Result1200: "56", Modern200: "78", Internet200: "09", Result430: "--"}
+
+	want := map[string]bool{
+		"morning_modern": true, "morning_internet": false, "noon_result": true,
+		"afternoon_modern": true, "afternoon_internet": false, "evening_result": false,
+	}
+	diffs := CompareResults(a, b)
+	if len(diffs) != len(want) {
+		t.Fatalf("%d sessions, want %d", len(diffs), len(want))
+	}
+	for _, d := range diffs {
+		if match, ok := want[d.Session]; !ok || d.Match != match {
+			t.Errorf("%s: match %v (%q vs %q), want %v", d.Session, d.Match, d.A, d.B, match)
+		}
+	}
+}
+
+func TestCompareHandler(t *testing.T) {
+	setupTestDB(t)
+	for _, h := range []*TwoDHistory{
+		{Date: "2026/03/02", Result1200: "47", Result430: "12", Modern930: "05"},
+		{Date: "2026/03/03", Result1200: "47", Result430: "21", Modern930: "05"},
+	} {
+		if err := InsertHistory(h); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Both date forms are accepted
+	code, body := compare(t, "a=2026-03-02&b=2026/03/03")
+	if code != http.StatusOK {
+		t.Fatalf("status %d", code)
+	}
+	var sessions []SessionDiff
+	var matches, differs int
+	json.Unmarshal(body["sessions"], &sessions)
+	json.Unmarshal(body["matches"], &matches)
+	json.Unmarshal(body["differs"], &differs)
+	if matches+differs != len(sessions) || differs != 1 {
+		t.Fatalf("matches %d, differs %d, want only the evening result to differ", matches, differs)
+	}
+	for _, s := range sessions {
+		if s.Session == "evening_result" && (s.Match || s.A != "12" || s.B != "21") {
+			t.Errorf("evening result = %+v", s)
+		}
+	}
+	var a TwoDHistory
+	if json.Unmarshal(body["a"], &a); a.Date != "2026/03/02" {
+		t.Errorf("a = %+v, want the 2026/03/02 record", a)
+	}
+
+	for query, want := range map[string]int{
+		"a=2026-03-02&b=2026-03-09": http.StatusNotFound,
+		"a=2026-03-09&b=2026-03-02": http.StatusNotFound,
+		"a=yesterday&b=2026-03-02":  http.StatusBadRequest,
+		"a=2026-03-02":              http.StatusBadRequest,
+	} {
+		if code, _ := compare(t, query); code != want {
+			t.Errorf("%q: status %d, want %d", query, code, want)
+		}
+	}
+}
@@ -6,25 +6,30 @@ import (
 	"log"
 	"time"
 
+	"burma2d/dbutil"
+	"burma2d/jsonutil"
+	"burma2d/pagination"
+
 	"github.com/gin-gonic/gin"
 	_ "github.com/mattn/go-sqlite3"
 )
 
-// TwoDHistory represents a single lottery history record
+// TwoDHistory represents a single lottery history record. The label and
+// session tags describe each field for GET /api/burma2d/history/schema.
 type TwoDHistory struct {
-	ID          int       `json:"history_id,omitempty" db:"id"`
-	Date        string    `json:"draw_date" db:"date"`
-	Set1200     string    `json:"noon_set" db:"set1200"`
-	Value1200   string    `json:"noon_value" db:"value1200"`
-	Result1200  string    `json:"noon_result" db:"result1200"`
-	Set430      string    `json:"evening_set" db:"set430"`
-	Value430    string    `json:"evening_value" db:"value430"`
-	Result430   string    `json:"evening_result" db:"result430"`
-	Modern930   string    `json:"morning_modern" db:"modern930"`
-	Internet930 string    `json:"morning_internet" db:"internet930"`
-	Modern200   string    `json:"afternoon_modern" db:"modern200"`
-	Internet200 string    `json:"afternoon_internet" db:"internet200"`
-	CreatedAt   time.Time `json:"created_date,omitempty" db:"created_at"`
+	ID          int       `json:"history_id,omitempty" db:"id" label:"Record ID"`
+	Date        string    `json:"draw_date" db:"date" label:"Draw date"`
+	Set1200     string    `json:"noon_set" db:"set1200" label:"12:01 SET" session:"noon"`
+	Value1200   string    `json:"noon_value" db:"value1200" label:"12:01 Value" session:"noon"`
+	Result1200  string    `json:"noon_result" db:"result1200" label:"12:01 Result" session:"noon"`
+	Set430      string    `json:"evening_set" db:"set430" label:"4:30 SET" session:"evening"`
+	Value430    string    `json:"evening_value" db:"value430" label:"4:30 Value" session:"evening"`
+	Result430   string    `json:"evening_result" db:"result430" label:"4:30 Result" session:"evening"`
+	Modern930   string    `json:"morning_modern" db:"modern930" label:"9:30 Modern" session:"morning"`
+	Internet930 string    `json:"morning_internet" db:"internet930" label:"9:30 Internet" session:"morning"`
+	Modern200   string    `json:"afternoon_modern" db:"modern200" label:"2:00 Modern" session:"afternoon"`
+	Internet200 string    `json:"afternoon_internet" db:"internet200" label:"2:00 Internet" session:"afternoon"`
+	CreatedAt   time.Time `json:"created_date,omitempty" db:"created_at" label:"Recorded at"`
 }
 
 var db *sql.DB
@@ -68,6 +73,9 @@ func InitDB(dbPath string) error {
 	if err = createTable(); err != nil {
 		return fmt.Errorf("failed to create table: %w", err)
 	}
+	if err = migrateResultNotified(); err != nil {
+		return fmt.Errorf("failed to migrate table: %w", err)
+	}
 
 	log.Println("✅ Database connected and table created successfully")
 	return nil
@@ -141,10 +149,67 @@ func InsertHistory(history *TwoDHistory) error {
 	return nil
 }
 
-// InsertFromLotteryData inserts history from LotteryData struct
-func InsertFromLotteryData(data *LotteryData) error {
+// UpsertHistory stores the record for its date, replacing the values of an
+// existing row but keeping its created_at. Re-running it for the same date is
+// safe; it reports whether a new row was inserted rather than updated.
+func UpsertHistory(history *TwoDHistory) (bool, error) {
+	var inserted bool
+	err := dbutil.WithTx(db, func(tx *sql.Tx) error {
+		var count int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM twodhistory WHERE date = ?", history.Date).Scan(&count); err != nil {
+			return fmt.Errorf("failed to check date existence: %w", err)
+		}
+
+		query := `
+		INSERT INTO twodhistory (
+			date, set1200, value1200, result1200,
+			set430, value430, result430,
+			modern930, internet930, modern200, internet200
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(date) DO UPDATE SET
+			set1200 = excluded.set1200,
+			value1200 = excluded.value1200,
+			result1200 = excluded.result1200,
+			set430 = excluded.set430,
+			value430 = excluded.value430,
+			result430 = excluded.result430,
+			modern930 = excluded.modern930,
+			internet930 = excluded.internet930,
+			modern200 = excluded.modern200,
+			internet200 = excluded.internet200
+		`
+
+		_, err := tx.Exec(query,
+			history.Date,
+			history.Set1200,
+			history.Value1200,
+			history.Result1200,
+			history.Set430,
+			history.Value430,
+			history.Result430,
+			history.Modern930,
+			history.Internet930,
+			history.Modern200,
+			history.Internet200,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to upsert history: %w", err)
+		}
+		inserted = count == 0
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return inserted, nil
+}
+
+// InsertFromLotteryData upserts history from LotteryData struct, keyed on
+// the draw date, and announces a newly stored 4:30 result. It reports
+// whether a new row was inserted.
+func InsertFromLotteryData(data *LotteryData) (bool, error) {
 	if db == nil {
-		return fmt.Errorf("database not initialized")
+		return false, fmt.Errorf("database not initialized")
 	}
 
 	history := &TwoDHistory{
@@ -161,7 +226,29 @@ func InsertFromLotteryData(data *LotteryData) error {
 		Internet200: data.Internet200,
 	}
 
-	return InsertHistory(history)
+	inserted, err := UpsertHistory(history)
+	if err != nil {
+		return inserted, err
+	}
+
+	// Announce the evening result once per date, off the update path
+	go notifyResult(history.Date, history.Result430)
+	return inserted, nil
+}
+
+// PublishedAt returns when the history row for date was inserted,
+// or a zero time when there is none
+func PublishedAt(date string) (time.Time, error) {
+	if db == nil {
+		return time.Time{}, fmt.Errorf("database not initialized")
+	}
+
+	var createdAt time.Time
+	err := db.QueryRow("SELECT created_at FROM twodhistory WHERE date = ?", date).Scan(&createdAt)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	return createdAt, err
 }
 
 // DateExists checks if a history record for the given date already exists
@@ -175,18 +262,30 @@ func DateExists(date string) (bool, error) {
 	return count > 0, nil
 }
 
+// dateLayout is the format history dates are stored in (e.g. 2025/10/16)
+const dateLayout = "2006/01/02"
+
 // GetAllHistory retrieves all history records ordered by date DESC
 func GetAllHistory() ([]TwoDHistory, error) {
+	return GetHistory(pagination.Params{})
+}
+
+// GetHistory retrieves history records ordered by date DESC,
+// filtered by the date range and paged by limit/offset
+func GetHistory(p pagination.Params) ([]TwoDHistory, error) {
+	var where pagination.Where
+	where.DateRange("date", dateLayout, p)
+	limitSQL, limitArgs := p.LimitOffset()
+
 	query := `
 	SELECT id, date, set1200, value1200, result1200,
 	       set430, value430, result430,
 	       modern930, internet930, modern200, internet200,
 	       created_at
-	FROM twodhistory
-	ORDER BY date DESC
-	`
+	FROM twodhistory` + where.SQL() + `
+	ORDER BY date DESC` + limitSQL
 
-	rows, err := db.Query(query)
+	rows, err := db.Query(query, append(where.Args(), limitArgs...)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query history: %w", err)
 	}
@@ -211,8 +310,15 @@ func GetAllHistory() ([]TwoDHistory, error) {
 }
 
 // GetHistoryHandler is the Gin handler for GET /api/twodhistory
+// Optional query params: limit, offset, from, to (YYYY-MM-DD)
 func GetHistoryHandler(c *gin.Context) {
-	histories, err := GetAllHistory()
+	p, err := pagination.Parse(c, pagination.Options{MaxLimit: 1000})
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	histories, err := GetHistory(p)
 	if err != nil {
 		log.Printf("❌ Error fetching history: %v", err)
 		c.JSON(500, gin.H{"error": "Failed to fetch history"})
@@ -225,7 +331,7 @@ func GetHistoryHandler(c *gin.Context) {
 		return
 	}
 
-	c.JSON(200, histories)
+	jsonutil.Write(c, 200, histories)
 }
 
 // CheckAndInsertHandler is the Gin handler for POST /api/twodhistory/check
@@ -0,0 +1,76 @@
+package twodhistory
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	database.SetMaxOpenConns(1)
+	t.Cleanup(func() { database.Close() })
+
+	db = database
+	if err := createTable(); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrateResultNotified(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpsertHistorySameDateTwice(t *testing.T) {
+	setupTestDB(t)
+
+	first := &TwoDHistory{Date: "2025-10-16", Set1200: "1,234.56", Value1200: "12,345.67", Result1200: "67"}
+	inserted, err := UpsertHistory(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !inserted {
+		t.Fatal("first upsert should insert")
+	}
+
+	// Backdate the row so a replaced created_at would show
+	if _, err := db.Exec("UPDATE twodhistory SET created_at = '2025-10-16 05:31:00'"); err != nil {
+		t.Fatal(err)
+	}
+
+	second := &TwoDHistory{Date: "2025-10-16", Set1200: "1,234.56", Value1200: "12,345.67", Result1200: "67",
+		Set430: "1,240.10", Value430: "20,000.00", Result430: "00"}
+	inserted, err = UpsertHistory(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inserted {
+		t.Fatal("second upsert for the same date should update")
+	}
+
+	var rows int
+	var result430, createdAt string
+	db.QueryRow("SELECT COUNT(*) FROM twodhistory").Scan(&rows)
+	db.QueryRow("SELECT result430, created_at FROM twodhistory WHERE date = '2025-10-16'").Scan(&result430, &createdAt)
+	if rows != 1 {
+		t.Fatalf("rows = %d, want 1", rows)
+	}
+	if result430 != "00" {
+		t.Fatalf("result430 = %q, want the updated value", result430)
+	}
+	if createdAt[:19] != "2025-10-16T05:31:00" && createdAt[:19] != "2025-10-16 05:31:00" {
+		t.Fatalf("created_at = %q, want it kept", createdAt)
+	}
+}
+
+func TestUpsertHistoryDistinctDates(t *testing.T) {
+	setupTestDB(t)
+	for _, date := range []string{"2025-10-15", "2025-10-16"} {
+		inserted, err := UpsertHistory(&TwoDHistory{Date: date})
+		if err != nil || !inserted {
+			t.Fatalf("%s: inserted %v, err %v", date, inserted, err)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package twodhistory
+
+import (
+	"log"
+
+	"burma2d/dbutil"
+)
+
+// ResultNotifier pushes the evening result for a draw date
+type ResultNotifier func(date, number string) error
+
+var resultNotifier ResultNotifier
+
+// SetResultNotifier sets the callback that announces a newly stored 4:30
+// result; main registers it when FCM is available
+func SetResultNotifier(fn ResultNotifier) {
+	resultNotifier = fn
+}
+
+// migrateResultNotified adds the column recording when a date's result was
+// announced, so each date is pushed once even across restarts
+func migrateResultNotified() error {
+	return dbutil.AddColumnIfMissing(db, "twodhistory", "result_notified_at", "DATETIME")
+}
+
+// notifyResult announces date's 4:30 result once. The row is claimed before
+// sending and released again if the send fails, so a later store retries.
+func notifyResult(date, number string) {
+	if resultNotifier == nil || !resultPattern.MatchString(number) {
+		return
+	}
+
+	result, err := db.Exec(`
+		UPDATE twodhistory SET result_notified_at = CURRENT_TIMESTAMP
+		WHERE date = ? AND result430 = ? AND result_notified_at IS NULL
+	`, date, number)
+	if err != nil {
+		log.Printf("⚠️ Failed to claim result notification for %s: %v", date, err)
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return
+	}
+
+	if err := resultNotifier(date, number); err != nil {
+		log.Printf("⚠️ Failed to send result notification for %s: %v", date, err)
+		if _, err := db.Exec("UPDATE twodhistory SET result_notified_at = NULL WHERE date = ?", date); err != nil {
+			log.Printf("⚠️ Failed to release result notification for %s: %v", date, err)
+		}
+		return
+	}
+	log.Printf("📣 Sent 4:30 result notification for %s: %s", date, number)
+}
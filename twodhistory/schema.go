@@ -0,0 +1,62 @@
+package twodhistory
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SchemaField describes one field of a history record
+type SchemaField struct {
+	Key     string `json:"key"`               // JSON key in history responses
+	Label   string `json:"label"`             // Human-readable name
+	Type    string `json:"type"`              // "string", "integer" or "datetime"
+	Session string `json:"session,omitempty"` // Draw session the field belongs to
+}
+
+// historySchema lists the fields of TwoDHistory in declaration order,
+// read from its json, label and session tags
+func historySchema() []SchemaField {
+	t := reflect.TypeOf(TwoDHistory{})
+	fields := make([]SchemaField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+		if key == "" || key == "-" {
+			continue
+		}
+
+		label := f.Tag.Get("label")
+		if label == "" {
+			label = f.Name
+		}
+		fields = append(fields, SchemaField{
+			Key:     key,
+			Label:   label,
+			Type:    schemaType(f.Type),
+			Session: f.Tag.Get("session"),
+		})
+	}
+	return fields
+}
+
+func schemaType(t reflect.Type) string {
+	switch {
+	case t == reflect.TypeOf(time.Time{}):
+		return "datetime"
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return "integer"
+	default:
+		return "string"
+	}
+}
+
+// GetSchemaHandler is the Gin handler for GET /api/burma2d/history/schema
+func GetSchemaHandler(c *gin.Context) {
+	c.JSON(200, gin.H{
+		"success": true,
+		"fields":  historySchema(),
+	})
+}
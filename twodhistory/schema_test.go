@@ -0,0 +1,96 @@
+package twodhistory
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"burma2d/live"
+
+	"github.com/gin-gonic/gin"
+)
+
+// liveOnlyKeys are LotteryData fields that describe the live feed, not a draw
+var liveOnlyKeys = map[string]bool{
+	"live_number": true, "service_status": true, "last_update": true,
+	"active_viewers": true, "is_new_result": true,
+}
+
+func schemaKeys() map[string]SchemaField {
+	fields := make(map[string]SchemaField)
+	for _, f := range historySchema() {
+		fields[f.Key] = f
+	}
+	return fields
+}
+
+func TestSchemaMatchesHistoryRecord(t *testing.T) {
+	record := TwoDHistory{ID: 1, CreatedAt: time.Now()}
+	body, _ := json.Marshal(record)
+	var keys map[string]interface{}
+	json.Unmarshal(body, &keys)
+
+	schema := historySchema()
+	if len(schema) != len(keys) {
+		t.Fatalf("schema has %d fields, a history record has %d", len(schema), len(keys))
+	}
+	for _, f := range schema {
+		if _, ok := keys[f.Key]; !ok {
+			t.Errorf("schema field %q is not in a history record", f.Key)
+		}
+		if f.Label == "" || f.Label == f.Key {
+			t.Errorf("%s has no label", f.Key)
+		}
+	}
+}
+
+func TestSchemaReflectsLotteryData(t *testing.T) {
+	fields := schemaKeys()
+	lt := reflect.TypeOf(live.LotteryData{})
+	for i := 0; i < lt.NumField(); i++ {
+		key := strings.SplitN(lt.Field(i).Tag.Get("json"), ",", 2)[0]
+		if liveOnlyKeys[key] {
+			continue
+		}
+		if _, ok := fields[key]; !ok {
+			t.Errorf("LotteryData.%s (%s) is stored in history but missing from the schema", lt.Field(i).Name, key)
+		}
+	}
+
+	for key, want := range map[string]SchemaField{
+		"history_id":     {Key: "history_id", Label: "Record ID", Type: "integer"},
+		"draw_date":      {Key: "draw_date", Label: "Draw date", Type: "string"},
+		"noon_result":    {Key: "noon_result", Label: "12:01 Result", Type: "string", Session: "noon"},
+		"evening_set":    {Key: "evening_set", Label: "4:30 SET", Type: "string", Session: "evening"},
+		"morning_modern": {Key: "morning_modern", Label: "9:30 Modern", Type: "string", Session: "morning"},
+		"created_date":   {Key: "created_date", Label: "Recorded at", Type: "datetime"},
+	} {
+		if fields[key] != want {
+			t.Errorf("%s = %+v, want %+v", key, fields[key], want)
+		}
+	}
+}
+
+func TestGetSchemaHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/schema", GetSchemaHandler)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/schema", nil))
+	var resp struct {
+		Success bool          `json:"success"`
+		Fields  []SchemaField `json:"fields"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || !resp.Success {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	// Declaration order, so clients can render columns as listed
+	if len(resp.Fields) == 0 || resp.Fields[0].Key != "history_id" || resp.Fields[1].Key != "draw_date" {
+		t.Errorf("fields = %+v", resp.Fields)
+	}
+}
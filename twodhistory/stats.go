@@ -0,0 +1,188 @@
+package twodhistory
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"burma2d/pagination"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Stats range bounds
+const (
+	defaultStatsDays = 90  // window used when no from/to is given
+	maxStatsDays     = 730 // longest range a single request may cover
+	statsTopN        = 5   // hottest/coldest numbers returned
+)
+
+// NumberCount is how often a two-digit number was drawn
+type NumberCount struct {
+	Number string `json:"number"`
+	Count  int    `json:"count"`
+}
+
+// DrawCounts counts days and draws with a result in the range
+type DrawCounts struct {
+	Days    int `json:"days"`
+	Noon    int `json:"noon"`
+	Evening int `json:"evening"`
+}
+
+// HistoryStats is the aggregate view over a date range
+type HistoryStats struct {
+	From      string         `json:"from"`
+	To        string         `json:"to"`
+	Draws     DrawCounts     `json:"draws"`
+	Noon      map[string]int `json:"noon"`     // 12:00 result frequency, all 00-99
+	Evening   map[string]int `json:"evening"`  // 4:30 result frequency, all 00-99
+	Combined  map[string]int `json:"combined"` // both draws
+	Hottest   []NumberCount  `json:"hottest"`
+	Coldest   []NumberCount  `json:"coldest"`
+	Truncated bool           `json:"truncated"` // range was capped to maxStatsDays
+}
+
+// statsRange resolves the requested range, defaulting and capping it
+func statsRange(p pagination.Params, now time.Time) (from, to time.Time, truncated bool) {
+	from, to = p.From, p.To
+	switch {
+	case from.IsZero() && to.IsZero():
+		to = now
+		from = to.AddDate(0, 0, -defaultStatsDays+1)
+	case from.IsZero():
+		from = to.AddDate(0, 0, -defaultStatsDays+1)
+	case to.IsZero():
+		to = from.AddDate(0, 0, maxStatsDays-1)
+		if to.After(now) {
+			to = now
+		}
+	}
+
+	if earliest := to.AddDate(0, 0, -maxStatsDays+1); from.Before(earliest) {
+		from = earliest
+		truncated = true
+	}
+	return from, to, truncated
+}
+
+// GetStats aggregates result frequencies for the 12:00 and 4:30 draws
+func GetStats(p pagination.Params) (*HistoryStats, error) {
+	from, to, truncated := statsRange(p, time.Now())
+
+	stats := &HistoryStats{
+		From:      from.Format(pagination.DateLayout),
+		To:        to.Format(pagination.DateLayout),
+		Noon:      emptyFrequencies(),
+		Evening:   emptyFrequencies(),
+		Combined:  emptyFrequencies(),
+		Truncated: truncated,
+	}
+
+	dates := pagination.Params{From: from, To: to}
+
+	var where pagination.Where
+	where.DateRange("date", dateLayout, dates)
+	if err := db.QueryRow("SELECT COUNT(*) FROM twodhistory"+where.SQL(), where.Args()...).
+		Scan(&stats.Draws.Days); err != nil {
+		return nil, fmt.Errorf("failed to count history: %w", err)
+	}
+
+	var err error
+	if stats.Draws.Noon, err = countResults("result1200", dates, stats.Noon, stats.Combined); err != nil {
+		return nil, err
+	}
+	if stats.Draws.Evening, err = countResults("result430", dates, stats.Evening, stats.Combined); err != nil {
+		return nil, err
+	}
+
+	stats.Hottest, stats.Coldest = rankNumbers(stats.Combined, statsTopN)
+	return stats, nil
+}
+
+// countResults fills freq (and combined) with the frequency of each
+// two-digit value in column and returns the number of draws counted
+func countResults(column string, dates pagination.Params, freq, combined map[string]int) (int, error) {
+	var where pagination.Where
+	where.DateRange("date", dateLayout, dates)
+	where.Add(column + " GLOB '[0-9][0-9]'")
+
+	rows, err := db.Query(`
+	SELECT `+column+`, COUNT(*)
+	FROM twodhistory`+where.SQL()+`
+	GROUP BY `+column, where.Args()...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to aggregate %s: %w", column, err)
+	}
+	defer rows.Close()
+
+	total := 0
+	for rows.Next() {
+		var number string
+		var count int
+		if err := rows.Scan(&number, &count); err != nil {
+			return 0, fmt.Errorf("failed to scan row: %w", err)
+		}
+		freq[number] += count
+		combined[number] += count
+		total += count
+	}
+	return total, rows.Err()
+}
+
+// emptyFrequencies returns a map with every number 00-99 set to zero
+func emptyFrequencies() map[string]int {
+	freq := make(map[string]int, 100)
+	for i := 0; i < 100; i++ {
+		freq[fmt.Sprintf("%02d", i)] = 0
+	}
+	return freq
+}
+
+// rankNumbers returns the n most and least frequent numbers
+func rankNumbers(freq map[string]int, n int) (hottest, coldest []NumberCount) {
+	ranked := make([]NumberCount, 0, len(freq))
+	for number, count := range freq {
+		ranked = append(ranked, NumberCount{Number: number, Count: count})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Count != ranked[j].Count {
+			return ranked[i].Count > ranked[j].Count
+		}
+		return ranked[i].Number < ranked[j].Number
+	})
+
+	if n > len(ranked) {
+		n = len(ranked)
+	}
+	hottest = []NumberCount{}
+	for _, nc := range ranked[:n] {
+		if nc.Count > 0 { // nothing is "hot" in an empty range
+			hottest = append(hottest, nc)
+		}
+	}
+	for i := len(ranked) - 1; i >= len(ranked)-n; i-- {
+		coldest = append(coldest, ranked[i])
+	}
+	return hottest, coldest
+}
+
+// GetStatsHandler is the Gin handler for GET /api/burma2d/history/stats
+// Optional query params: from, to (YYYY-MM-DD); defaults to the last 90 days
+func GetStatsHandler(c *gin.Context) {
+	p, err := pagination.Parse(c, pagination.Options{})
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	stats, err := GetStats(p)
+	if err != nil {
+		log.Printf("❌ Error computing history stats: %v", err)
+		c.JSON(500, gin.H{"error": "Failed to compute history stats"})
+		return
+	}
+
+	c.JSON(200, stats)
+}
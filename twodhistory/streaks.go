@@ -0,0 +1,119 @@
+package twodhistory
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"time"
+
+	"burma2d/pagination"
+
+	"github.com/gin-gonic/gin"
+)
+
+var twoDigitNumber = regexp.MustCompile(`^[0-9]{2}$`)
+
+// streakDays is how far back streaks look, so each request reads a bounded
+// number of rows however long the history grows
+const streakDays = maxStatsDays
+
+// Streak describes how a number has appeared in one draw slot
+type Streak struct {
+	Draws          int     `json:"draws"`           // draws with a result
+	Appearances    int     `json:"appearances"`     // times the number was drawn
+	CurrentAbsence int     `json:"current_absence"` // consecutive recent draws without it
+	LongestGap     int     `json:"longest_gap"`     // longest run of draws without it
+	LastSeen       *string `json:"last_seen"`       // date it last appeared, null if never
+}
+
+// streakTracker accumulates a Streak over draws in chronological order
+type streakTracker struct {
+	Streak
+	gap int
+}
+
+func (t *streakTracker) add(date, result, number string) {
+	if !twoDigitNumber.MatchString(result) {
+		return // no result for this draw
+	}
+	t.Draws++
+
+	if result == number {
+		t.Appearances++
+		d := date
+		t.LastSeen = &d
+		t.gap = 0
+	} else {
+		t.gap++
+		if t.gap > t.LongestGap {
+			t.LongestGap = t.gap
+		}
+	}
+	t.CurrentAbsence = t.gap
+}
+
+// streaksSince is the first date GetStreaks looks at
+func streaksSince(now time.Time) time.Time {
+	return now.AddDate(0, 0, -streakDays+1)
+}
+
+// GetStreaks computes noon and evening streaks for a two-digit number
+// over the draws from since onwards
+func GetStreaks(number string, since time.Time) (noon, evening Streak, err error) {
+	var where pagination.Where
+	where.DateRange("date", dateLayout, pagination.Params{From: since})
+
+	rows, err := db.Query(`
+	SELECT date, result1200, result430
+	FROM twodhistory`+where.SQL()+`
+	ORDER BY date ASC`, where.Args()...)
+	if err != nil {
+		return noon, evening, fmt.Errorf("failed to query history: %w", err)
+	}
+	defer rows.Close()
+
+	var noonTracker, eveningTracker streakTracker
+	for rows.Next() {
+		var date string
+		var result1200, result430 *string
+		if err := rows.Scan(&date, &result1200, &result430); err != nil {
+			return noon, evening, fmt.Errorf("failed to scan row: %w", err)
+		}
+		if result1200 != nil {
+			noonTracker.add(date, *result1200, number)
+		}
+		if result430 != nil {
+			eveningTracker.add(date, *result430, number)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return noon, evening, err
+	}
+
+	return noonTracker.Streak, eveningTracker.Streak, nil
+}
+
+// GetStreaksHandler is the Gin handler for GET /api/burma2d/history/streaks?number=NN.
+// Streaks cover the last streakDays days, starting at the returned "since" date.
+func GetStreaksHandler(c *gin.Context) {
+	number := c.Query("number")
+	if !twoDigitNumber.MatchString(number) {
+		c.JSON(400, gin.H{"error": "number must be a two-digit string from 00 to 99"})
+		return
+	}
+
+	since := streaksSince(time.Now())
+	noon, evening, err := GetStreaks(number, since)
+	if err != nil {
+		log.Printf("❌ Error computing streaks for %s: %v", number, err)
+		c.JSON(500, gin.H{"error": "Failed to compute streaks"})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"number":  number,
+		"since":   since.Format(pagination.DateLayout),
+		"noon":    noon,
+		"evening": evening,
+	})
+}
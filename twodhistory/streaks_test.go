@@ -0,0 +1,123 @@
+package twodhistory
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestStreakTracker(t *testing.T) {
+	type draw struct{ date, result string }
+	seen := func(d string) *string { return &d }
+	tests := []struct {
+		name  string
+		draws []draw
+		want  Streak
+	}{
+		{"never drawn", []draw{{"2026/03/02", "11"}, {"2026/03/03", "22"}},
+			Streak{Draws: 2, CurrentAbsence: 2, LongestGap: 2}},
+		{"drawn last", []draw{{"2026/03/02", "11"}, {"2026/03/03", "47"}},
+			Streak{Draws: 2, Appearances: 1, CurrentAbsence: 0, LongestGap: 1, LastSeen: seen("2026/03/03")}},
+		{"longest gap in the past, shorter current absence", []draw{
+			{"2026/03/02", "47"}, {"2026/03/03", "11"}, {"2026/03/04", "12"}, {"2026/03/05", "13"},
+			{"2026/03/06", "47"}, {"2026/03/09", "14"},
+		}, Streak{Draws: 6, Appearances: 2, CurrentAbsence: 1, LongestGap: 3, LastSeen: seen("2026/03/06")}},
+		{"current absence is the longest gap", []draw{
+			{"2026/03/02", "47"}, {"2026/03/03", "11"}, {"2026/03/04", "12"},
+		}, Streak{Draws: 3, Appearances: 1, CurrentAbsence: 2, LongestGap: 2, LastSeen: seen("2026/03/02")}},
+		{"draws without a result are skipped", []draw{
+			{"2026/03/02", "47"}, {"2026/03/03", ""}, {"2026/03/04", "--"}, {"2026/03/05", "47"},
+		}, Streak{Draws: 2, Appearances: 2, CurrentAbsence: 0, LongestGap: 0, LastSeen: seen("2026/03/05")}},
+	}
+
+	for _, tt := range tests {
+		var tracker streakTracker
+		for _, d := range tt.draws {
+			tracker.add(d.date, d.result, "47")
+		}
+		got := tracker.Streak
+		if got.Draws != tt.want.Draws || got.Appearances != tt.want.Appearances ||
+			got.CurrentAbsence != tt.want.CurrentAbsence || got.LongestGap != tt.want.LongestGap {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+		if (got.LastSeen == nil) != (tt.want.LastSeen == nil) ||
+			(got.LastSeen != nil && *got.LastSeen != *tt.want.LastSeen) {
+			t.Errorf("%s: last seen %v, want %v", tt.name, got.LastSeen, tt.want.LastSeen)
+		}
+	}
+}
+
+func TestGetStreaksNoonAndEvening(t *testing.T) {
+	setupTestDB(t)
+	for _, h := range []TwoDHistory{
+		{Date: "2024/01/10", Result1200: "47", Result430: "47"}, // before the window
+		{Date: "2026/03/02", Result1200: "47", Result430: "11"},
+		{Date: "2026/03/03", Result1200: "12", Result430: "47"},
+		{Date: "2026/03/04", Result1200: "13"}, // evening draw not in yet
+	} {
+		if _, err := UpsertHistory(&h); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	noon, evening, err := GetStreaks("47", since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if noon.Draws != 3 || noon.Appearances != 1 || noon.CurrentAbsence != 2 || noon.LongestGap != 2 ||
+		noon.LastSeen == nil || *noon.LastSeen != "2026/03/02" {
+		t.Errorf("noon = %+v", noon)
+	}
+	if evening.Draws != 2 || evening.Appearances != 1 || evening.CurrentAbsence != 0 || evening.LongestGap != 1 ||
+		evening.LastSeen == nil || *evening.LastSeen != "2026/03/03" {
+		t.Errorf("evening = %+v", evening)
+	}
+
+	// Without the bound the old draw would count
+	noon, _, _ = GetStreaks("47", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	if noon.Draws != 4 || noon.Appearances != 2 {
+		t.Errorf("unbounded noon = %+v, want the 2024 draw included", noon)
+	}
+}
+
+func TestStreaksSince(t *testing.T) {
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	if got := streaksSince(now).Format("2006-01-02"); got != "2024-03-03" {
+		t.Errorf("since = %s, want %d days back including today", got, streakDays)
+	}
+}
+
+func TestGetStreaksHandler(t *testing.T) {
+	setupTestDB(t)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/streaks", GetStreaksHandler)
+
+	for query, want := range map[string]int{
+		"number=47":  http.StatusOK,
+		"number=7":   http.StatusBadRequest,
+		"number=ab":  http.StatusBadRequest,
+		"number=100": http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/streaks?"+query, nil))
+		if w.Code != want {
+			t.Errorf("%s: status %d, want %d", query, w.Code, want)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/streaks?number=47", nil))
+	var resp struct {
+		Since string `json:"since"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if want := streaksSince(time.Now()).Format("2006-01-02"); resp.Since != want {
+		t.Errorf("since = %q, want %q", resp.Since, want)
+	}
+}
@@ -0,0 +1,302 @@
+package userdata
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"burma2d/dbutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// deletedUsername replaces the name on records kept after a deletion
+const deletedUsername = "Deleted user"
+
+var db *sql.DB
+
+// InitDB sets the database used for exports and deletions. The tables are
+// owned by the chat, chatws, gift and fcm packages.
+func InitDB(database *sql.DB) {
+	db = database
+}
+
+// Table describes a user-keyed table owned by another package. Every ? in
+// Export and Delete is bound to the user ID. Packages that add a table
+// holding user IDs register it so exports and deletions stay complete.
+type Table struct {
+	Section string // Export bundle key and deletion label
+	Export  string // SELECT returning the user's rows
+	Delete  string // Statement removing or anonymizing them ("" keeps the rows)
+}
+
+var (
+	registered      []Table
+	registeredMutex sync.RWMutex
+)
+
+// Register adds t to every export and deletion. Registering the same
+// section again replaces it.
+func Register(t Table) {
+	registeredMutex.Lock()
+	defer registeredMutex.Unlock()
+	for i := range registered {
+		if registered[i].Section == t.Section {
+			registered[i] = t
+			return
+		}
+	}
+	registered = append(registered, t)
+}
+
+func registeredTables() []Table {
+	registeredMutex.RLock()
+	defer registeredMutex.RUnlock()
+	return append([]Table(nil), registered...)
+}
+
+// exportSection is one part of the bundle and the query that fills it;
+// every ? in the query is bound to the user ID
+type exportSection struct {
+	name  string
+	query string
+}
+
+var exportSections = []exportSection{
+	{"profile", "SELECT * FROM chat_users WHERE id = ?"},
+	{"ws_profile", "SELECT * FROM chatws_users WHERE id = ?"},
+	{"messages", "SELECT * FROM chat_messages WHERE user_id = ? ORDER BY id"},
+	{"ws_messages", "SELECT * FROM chatws_messages WHERE user_id = ? ORDER BY id"},
+	{"direct_messages", "SELECT * FROM chat_direct_messages WHERE sender_id = ? OR recipient_id = ? ORDER BY id"},
+	{"reactions", "SELECT * FROM chat_reactions WHERE user_id = ? ORDER BY id"},
+	{"blocks", "SELECT * FROM chat_blocks WHERE blocker_id = ? ORDER BY id"},
+	{"ws_blocks", "SELECT * FROM chatws_blocked_users WHERE blocker_id = ? ORDER BY id"},
+	{"bans", "SELECT * FROM chat_banned_users WHERE user_id = ?"},
+	{"sessions", "SELECT * FROM chat_session_log WHERE user_id = ? ORDER BY id"},
+	{"redemptions", `SELECT r.*, g.name AS gift_name FROM gift_redemptions r
+		LEFT JOIN gifts g ON g.id = r.gift_id WHERE r.user_id = ? ORDER BY r.id`},
+	{"devices", "SELECT * FROM fcm_devices WHERE user_id = ?"},
+}
+
+// Export collects every record associated with userID, keyed by section.
+// found is false when the user has no profile on either chat transport.
+func Export(userID string) (bundle map[string]interface{}, found bool, err error) {
+	bundle = map[string]interface{}{
+		"user_id":     userID,
+		"exported_at": time.Now().UTC(),
+	}
+
+	sections := exportSections
+	for _, t := range registeredTables() {
+		sections = append(sections, exportSection{t.Section, t.Export})
+	}
+
+	for _, section := range sections {
+		rows, err := queryRows(section.query, userID)
+		if err != nil {
+			return nil, false, fmt.Errorf("export %s: %w", section.name, err)
+		}
+		bundle[section.name] = rows
+		if (section.name == "profile" || section.name == "ws_profile") && len(rows) > 0 {
+			found = true
+		}
+	}
+	return bundle, found, nil
+}
+
+// queryRows runs query with every placeholder bound to userID and returns
+// the rows as column->value maps
+func queryRows(query, userID string) ([]map[string]interface{}, error) {
+	rows, err := db.Query(query, userArgs(query, userID)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []map[string]interface{}{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]interface{}, len(columns))
+		for i, col := range columns {
+			if b, ok := values[i].([]byte); ok {
+				row[col] = string(b)
+			} else {
+				row[col] = values[i]
+			}
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// userArgs binds every placeholder in query to userID
+func userArgs(query, userID string) []interface{} {
+	args := make([]interface{}, countPlaceholders(query))
+	for i := range args {
+		args[i] = userID
+	}
+	return args
+}
+
+func countPlaceholders(query string) int {
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+		}
+	}
+	return n
+}
+
+// anonymousID derives a stable, non-reversible stand-in for userID so kept
+// records can still be grouped without identifying the user
+func anonymousID(userID string) string {
+	sum := sha256.Sum256([]byte(userID))
+	return "deleted-" + hex.EncodeToString(sum[:6])
+}
+
+// ErrNotFound is returned by Delete when the user has no chat profile
+var ErrNotFound = errors.New("user not found")
+
+// deleteStep is one statement of a deletion, counted under label
+type deleteStep struct {
+	label string
+	query string
+	args  []interface{}
+}
+
+// deleteSteps lists the statements that remove or anonymize userID's data.
+// Registered tables go first so they can still refer to the user's devices
+// and messages.
+func deleteSteps(userID string, banned bool) []deleteStep {
+	var steps []deleteStep
+	for _, t := range registeredTables() {
+		if t.Delete != "" {
+			steps = append(steps, deleteStep{t.Section, t.Delete, userArgs(t.Delete, userID)})
+		}
+	}
+
+	steps = append(steps, []deleteStep{
+		// Reactions by the user and reactions others left on their messages
+		{"reactions", "DELETE FROM chat_reactions WHERE user_id = ?", []interface{}{userID}},
+		{"reactions", "DELETE FROM chat_reactions WHERE message_id IN (SELECT id FROM chat_messages WHERE user_id = ?)", []interface{}{userID}},
+		{"messages", "DELETE FROM chat_messages WHERE user_id = ?", []interface{}{userID}},
+		{"ws_messages", "DELETE FROM chatws_messages WHERE user_id = ?", []interface{}{userID}},
+		{"direct_messages", "DELETE FROM chat_direct_messages WHERE sender_id = ? OR recipient_id = ?", []interface{}{userID, userID}},
+		{"blocks", "DELETE FROM chat_blocks WHERE blocker_id = ? OR blocked_id = ?", []interface{}{userID, userID}},
+		{"ws_blocks", "DELETE FROM chatws_blocked_users WHERE blocker_id = ? OR blocked_id = ?", []interface{}{userID, userID}},
+		{"sessions", "DELETE FROM chat_session_log WHERE user_id = ?", []interface{}{userID}},
+		{"devices", "DELETE FROM fcm_devices WHERE user_id = ?", []interface{}{userID}},
+		{"redemptions", "UPDATE gift_redemptions SET user_id = ? WHERE user_id = ?", []interface{}{anonymousID(userID), userID}},
+		{"ws_profile", "DELETE FROM chatws_users WHERE id = ?", []interface{}{userID}},
+	}...)
+
+	if !banned {
+		return append(steps, deleteStep{"profile", "DELETE FROM chat_users WHERE id = ?", []interface{}{userID}})
+	}
+	return append(steps,
+		deleteStep{"bans", "UPDATE chat_banned_users SET username = ? WHERE user_id = ?", []interface{}{deletedUsername, userID}},
+		deleteStep{"profile", "UPDATE chat_users SET username = ?, email = ?, photo_url = '', is_online = 0 WHERE id = ?",
+			[]interface{}{deletedUsername, anonymousID(userID) + "@deleted.invalid", userID}},
+	)
+}
+
+// Delete purges userID's content and anonymizes records that must be kept,
+// all in one transaction. It returns the rows affected per table.
+//
+// Messages, DMs, reactions, blocks (both directions), sessions, devices,
+// the WebSocket profile and registered tables are deleted. Redemptions are kept for stock
+// accounting under an anonymous user ID. A ban is kept so it still applies
+// if the same account signs in again; the chat profile it references is
+// then scrubbed instead of deleted.
+func Delete(userID string) (map[string]int64, error) {
+	affected := make(map[string]int64)
+
+	err := dbutil.WithTx(db, func(tx *sql.Tx) error {
+		var profiles, banned int
+		err := tx.QueryRow(`
+			SELECT (SELECT COUNT(*) FROM chat_users WHERE id = ?) + (SELECT COUNT(*) FROM chatws_users WHERE id = ?),
+			       (SELECT COUNT(*) FROM chat_banned_users WHERE user_id = ?)
+		`, userID, userID, userID).Scan(&profiles, &banned)
+		if err != nil {
+			return fmt.Errorf("look up user: %w", err)
+		}
+		if profiles == 0 {
+			return ErrNotFound
+		}
+
+		for _, step := range deleteSteps(userID, banned > 0) {
+			result, err := tx.Exec(step.query, step.args...)
+			if err != nil {
+				return fmt.Errorf("%s: %w", step.label, err)
+			}
+			n, _ := result.RowsAffected()
+			affected[step.label] += n
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return affected, nil
+}
+
+// ExportHandler returns all data held about a user as a JSON download (admin)
+func ExportHandler(c *gin.Context) {
+	userID := c.Param("id")
+
+	bundle, found, err := Export(userID)
+	if err != nil {
+		log.Printf("❌ Error exporting data for %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export user data"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%s.json"`, anonymousID(userID)))
+	c.JSON(http.StatusOK, bundle)
+}
+
+// DeleteHandler purges or anonymizes all data held about a user (admin)
+func DeleteHandler(c *gin.Context) {
+	userID := c.Param("id")
+
+	affected, err := Delete(userID)
+	if errors.Is(err, ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Error deleting data for %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user data"})
+		return
+	}
+	log.Printf("🗑️ Deleted data for user %s: %v", userID, affected)
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"user_id":  userID,
+		"affected": affected,
+	})
+}
@@ -0,0 +1,202 @@
+package userdata
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// setupTestDB creates the tables userdata reads with just the columns it uses
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	database.SetMaxOpenConns(1)
+	t.Cleanup(func() { database.Close() })
+
+	_, err = database.Exec(`
+		CREATE TABLE chat_users (id TEXT PRIMARY KEY, email TEXT, username TEXT, photo_url TEXT, is_online BOOLEAN);
+		CREATE TABLE chatws_users (id TEXT PRIMARY KEY, username TEXT);
+		CREATE TABLE chat_messages (id INTEGER PRIMARY KEY, user_id TEXT, message TEXT);
+		CREATE TABLE chatws_messages (id INTEGER PRIMARY KEY, user_id TEXT, message TEXT);
+		CREATE TABLE chat_direct_messages (id INTEGER PRIMARY KEY, sender_id TEXT, recipient_id TEXT);
+		CREATE TABLE chat_reactions (id INTEGER PRIMARY KEY, message_id INTEGER, user_id TEXT);
+		CREATE TABLE chat_blocks (id INTEGER PRIMARY KEY, blocker_id TEXT, blocked_id TEXT);
+		CREATE TABLE chatws_blocked_users (id INTEGER PRIMARY KEY, blocker_id TEXT, blocked_id TEXT);
+		CREATE TABLE chat_banned_users (user_id TEXT PRIMARY KEY, username TEXT);
+		CREATE TABLE chat_session_log (id INTEGER PRIMARY KEY, user_id TEXT);
+		CREATE TABLE gifts (id INTEGER PRIMARY KEY, name TEXT);
+		CREATE TABLE gift_redemptions (id INTEGER PRIMARY KEY, gift_id INTEGER, user_id TEXT);
+		CREATE TABLE fcm_devices (token TEXT PRIMARY KEY, user_id TEXT);
+		CREATE TABLE device_events (id INTEGER PRIMARY KEY, token TEXT);
+		CREATE TABLE test_points (user_id TEXT, points INTEGER);
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db = database
+
+	registeredMutex.Lock()
+	registered = nil
+	registeredMutex.Unlock()
+
+	// A table keyed through the user's devices, which must be handled
+	// before the devices themselves are deleted
+	Register(Table{
+		Section: "device_events",
+		Export:  "SELECT * FROM device_events WHERE token IN (SELECT token FROM fcm_devices WHERE user_id = ?)",
+		Delete:  "DELETE FROM device_events WHERE token IN (SELECT token FROM fcm_devices WHERE user_id = ?)",
+	})
+	Register(Table{
+		Section: "points",
+		Export:  "SELECT * FROM test_points WHERE user_id = ?",
+		Delete:  "DELETE FROM test_points WHERE user_id = ?",
+	})
+}
+
+func seed(t *testing.T, stmts ...string) {
+	t.Helper()
+	for _, s := range stmts {
+		if _, err := db.Exec(s); err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+	}
+}
+
+func count(t *testing.T, query string, args ...interface{}) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func seedUsers(t *testing.T) {
+	seed(t,
+		`INSERT INTO chat_users (id, email, username) VALUES ('u1', 'u1@example.com', 'One'), ('u2', 'u2@example.com', 'Two')`,
+		`INSERT INTO chat_messages (id, user_id, message) VALUES (1, 'u1', 'hi'), (2, 'u2', 'hello')`,
+		`INSERT INTO chat_reactions (message_id, user_id) VALUES (1, 'u2'), (2, 'u1')`,
+		`INSERT INTO fcm_devices (token, user_id) VALUES ('tok-1', 'u1'), ('tok-2', 'u2')`,
+		`INSERT INTO device_events (token) VALUES ('tok-1'), ('tok-2')`,
+		`INSERT INTO test_points (user_id, points) VALUES ('u1', 5), ('u2', 7)`,
+		`INSERT INTO gifts (id, name) VALUES (1, 'Phone')`,
+		`INSERT INTO gift_redemptions (gift_id, user_id) VALUES (1, 'u1')`,
+	)
+}
+
+func TestRegisterReplacesSection(t *testing.T) {
+	setupTestDB(t)
+	Register(Table{Section: "points", Export: "SELECT 1 WHERE ? != ''"})
+
+	tables := registeredTables()
+	if len(tables) != 2 {
+		t.Fatalf("registered %d tables, want 2", len(tables))
+	}
+	if tables[1].Delete != "" {
+		t.Fatalf("section not replaced: %+v", tables[1])
+	}
+}
+
+func TestExportIncludesRegisteredTables(t *testing.T) {
+	setupTestDB(t)
+	seedUsers(t)
+
+	bundle, found, err := Export("u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found {
+		t.Fatal("user not found")
+	}
+
+	for section, want := range map[string]int{
+		"profile":       1,
+		"messages":      1,
+		"reactions":     1,
+		"devices":       1,
+		"redemptions":   1,
+		"device_events": 1,
+		"points":        1,
+	} {
+		rows, ok := bundle[section].([]map[string]interface{})
+		if !ok {
+			t.Fatalf("section %s missing", section)
+		}
+		if len(rows) != want {
+			t.Errorf("section %s has %d rows, want %d", section, len(rows), want)
+		}
+	}
+}
+
+func TestExportUnknownUser(t *testing.T) {
+	setupTestDB(t)
+	if _, found, err := Export("nobody"); err != nil || found {
+		t.Fatalf("found %v, err %v", found, err)
+	}
+}
+
+func TestDeleteRemovesRegisteredTables(t *testing.T) {
+	setupTestDB(t)
+	seedUsers(t)
+
+	affected, err := Delete("u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if affected["device_events"] != 1 || affected["points"] != 1 {
+		t.Fatalf("affected = %v", affected)
+	}
+
+	if n := count(t, "SELECT COUNT(*) FROM device_events WHERE token = 'tok-1'"); n != 0 {
+		t.Errorf("%d device events left for the deleted user", n)
+	}
+	if n := count(t, "SELECT COUNT(*) FROM test_points WHERE user_id = 'u1'"); n != 0 {
+		t.Errorf("%d point rows left for the deleted user", n)
+	}
+	if n := count(t, "SELECT COUNT(*) FROM chat_users WHERE id = 'u1'"); n != 0 {
+		t.Errorf("profile not deleted")
+	}
+	if n := count(t, "SELECT COUNT(*) FROM chat_reactions"); n != 0 {
+		t.Errorf("%d reactions left; both the user's and those on their messages should go", n)
+	}
+	if n := count(t, "SELECT COUNT(*) FROM gift_redemptions WHERE user_id = ?", anonymousID("u1")); n != 1 {
+		t.Errorf("redemption not anonymized")
+	}
+
+	// The other user is untouched
+	if n := count(t, "SELECT COUNT(*) FROM device_events WHERE token = 'tok-2'"); n != 1 {
+		t.Errorf("other user's device events deleted")
+	}
+	if n := count(t, "SELECT COUNT(*) FROM test_points WHERE user_id = 'u2'"); n != 1 {
+		t.Errorf("other user's points deleted")
+	}
+}
+
+func TestDeleteKeepsBannedProfile(t *testing.T) {
+	setupTestDB(t)
+	seedUsers(t)
+	seed(t, `INSERT INTO chat_banned_users (user_id, username) VALUES ('u1', 'One')`)
+
+	if _, err := Delete("u1"); err != nil {
+		t.Fatal(err)
+	}
+	var name, email string
+	if err := db.QueryRow("SELECT username, email FROM chat_users WHERE id = 'u1'").Scan(&name, &email); err != nil {
+		t.Fatal(err)
+	}
+	if name != deletedUsername || email == "u1@example.com" {
+		t.Fatalf("profile not scrubbed: %q %q", name, email)
+	}
+}
+
+func TestDeleteUnknownUser(t *testing.T) {
+	setupTestDB(t)
+	if _, err := Delete("nobody"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("err = %v, want ErrNotFound", err)
+	}
+}
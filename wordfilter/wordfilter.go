@@ -0,0 +1,351 @@
+package wordfilter
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"burma2d/config"
+	"burma2d/jsonutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Filter modes
+const (
+	ModeReject = "reject" // refuse messages containing banned words
+	ModeMask   = "mask"   // replace banned words with asterisks
+)
+
+// BannedWord represents a word admins have blocked from the chat
+type BannedWord struct {
+	ID        int64     `json:"id"`
+	Word      string    `json:"word"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+var (
+	db   *sql.DB
+	mode string
+
+	wordsMu sync.RWMutex
+	words   [][]rune // banned words, folded with foldRunes
+)
+
+// InitDB creates the banned words table and loads the word set.
+// CHAT_BANNED_WORDS_MODE selects "reject" or "mask" (default).
+func InitDB(database *sql.DB) {
+	db = database
+
+	mode = strings.ToLower(config.String("CHAT_BANNED_WORDS_MODE", ModeMask))
+	if mode != ModeReject && mode != ModeMask {
+		log.Printf("⚠️ Invalid CHAT_BANNED_WORDS_MODE %q, using %q", mode, ModeMask)
+		mode = ModeMask
+	}
+
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS chat_banned_words (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			word TEXT NOT NULL UNIQUE,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		log.Printf("❌ Error creating chat_banned_words table: %v", err)
+		return
+	}
+
+	if err := reload(); err != nil {
+		log.Printf("❌ Error loading banned words: %v", err)
+		return
+	}
+	log.Printf("✅ Banned word filter ready (%d words, mode: %s)", count(), mode)
+}
+
+// Mode returns the configured filter mode
+func Mode() string {
+	return mode
+}
+
+// reload refreshes the in-memory word set from the database
+func reload() error {
+	rows, err := db.Query("SELECT word FROM chat_banned_words")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	loaded := [][]rune{}
+	for rows.Next() {
+		var w string
+		if err := rows.Scan(&w); err != nil {
+			continue
+		}
+		if r := []rune(normalize(w)); len(r) > 0 {
+			loaded = append(loaded, r)
+		}
+	}
+
+	wordsMu.Lock()
+	words = loaded
+	wordsMu.Unlock()
+	return nil
+}
+
+func count() int {
+	wordsMu.RLock()
+	defer wordsMu.RUnlock()
+	return len(words)
+}
+
+// Check scans text for banned words. It returns the text with matches
+// masked and whether any banned word was found. Callers in reject mode
+// should refuse the message when found is true.
+func Check(text string) (masked string, found bool) {
+	wordsMu.RLock()
+	defer wordsMu.RUnlock()
+
+	if len(words) == 0 {
+		return text, false
+	}
+
+	original := []rune(text)
+	lowered := foldRunes(text)
+
+	for _, word := range words {
+		for i := 0; i+len(word) <= len(lowered); i++ {
+			if !runesEqual(lowered[i:i+len(word)], word) || !atBoundary(lowered, i, i+len(word), word) {
+				continue
+			}
+			found = true
+			for j := i; j < i+len(word); j++ {
+				original[j] = '*'
+			}
+			i += len(word) - 1
+		}
+	}
+
+	if !found {
+		return text, false
+	}
+	return string(original), true
+}
+
+// Allow applies the configured mode to text. It returns the text to store
+// and false when the message must be rejected.
+func Allow(text string) (string, bool) {
+	masked, found := Check(text)
+	if !found {
+		return text, true
+	}
+	if mode == ModeReject {
+		return text, false
+	}
+	return masked, true
+}
+
+// atBoundary reports whether the match [start, end) is a whole word.
+// Scripts written without spaces (e.g. Burmese) only require that the
+// match doesn't cut into a syllable on either side: it may not start on a
+// combining mark or on a consonant stacked under the previous one, and it
+// may not be followed by a combining mark.
+func atBoundary(text []rune, start, end int, word []rune) bool {
+	if isUnspacedScript(word) {
+		if unicode.Is(unicode.M, text[start]) {
+			return false
+		}
+		if start > 0 && isStacker(text[start-1]) {
+			return false
+		}
+		return end == len(text) || !unicode.Is(unicode.M, text[end])
+	}
+
+	if start > 0 && isWordRune(text[start-1]) {
+		return false
+	}
+	if end < len(text) && isWordRune(text[end]) {
+		return false
+	}
+	return true
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.M, r) || r == '_'
+}
+
+// isStacker reports whether r joins the following consonant to the
+// previous syllable (Myanmar virama, Khmer coeng)
+func isStacker(r rune) bool {
+	return r == '\u1039' || r == '\u17D2'
+}
+
+func isUnspacedScript(word []rune) bool {
+	for _, r := range word {
+		if unicode.In(r, unicode.Myanmar, unicode.Thai, unicode.Lao, unicode.Khmer, unicode.Han) {
+			return true
+		}
+	}
+	return false
+}
+
+func runesEqual(a, b []rune) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// foldRunes lowercases s rune by rune. It is the single case folding used
+// for both stored words and scanned text; it keeps one rune per input rune
+// so match positions in the folded text map back onto the original.
+func foldRunes(s string) []rune {
+	runes := []rune(s)
+	for i, r := range runes {
+		runes[i] = unicode.ToLower(r)
+	}
+	return runes
+}
+
+// normalize prepares an admin-entered word for storage
+func normalize(s string) string {
+	return string(foldRunes(strings.TrimSpace(s)))
+}
+
+// ListWordsHandler returns all banned words (admin)
+func ListWordsHandler(c *gin.Context) {
+	rows, err := db.Query("SELECT id, word, created_at FROM chat_banned_words ORDER BY word")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get banned words"})
+		return
+	}
+	defer rows.Close()
+
+	list := []BannedWord{}
+	for rows.Next() {
+		var w BannedWord
+		if err := rows.Scan(&w.ID, &w.Word, &w.CreatedAt); err != nil {
+			continue
+		}
+		list = append(list, w)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"mode":    mode,
+		"words":   list,
+		"count":   len(list),
+	})
+}
+
+type wordRequest struct {
+	Word string `json:"word" binding:"required"`
+}
+
+// AddWordHandler adds a banned word (admin)
+func AddWordHandler(c *gin.Context) {
+	var req wordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		jsonutil.BindError(c, err)
+		return
+	}
+
+	word := normalize(req.Word)
+	if word == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Word cannot be empty"})
+		return
+	}
+
+	result, err := db.Exec("INSERT OR IGNORE INTO chat_banned_words (word) VALUES (?)", word)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add banned word"})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Word already banned"})
+		return
+	}
+
+	id, _ := result.LastInsertId()
+	refresh()
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"id":      id,
+		"word":    word,
+	})
+}
+
+// UpdateWordHandler changes a banned word (admin)
+func UpdateWordHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid word ID"})
+		return
+	}
+
+	var req wordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		jsonutil.BindError(c, err)
+		return
+	}
+
+	word := normalize(req.Word)
+	if word == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Word cannot be empty"})
+		return
+	}
+
+	result, err := db.Exec("UPDATE chat_banned_words SET word = ? WHERE id = ?", word, id)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Failed to update banned word, it may already exist"})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Word not found"})
+		return
+	}
+
+	refresh()
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"id":      id,
+		"word":    word,
+	})
+}
+
+// DeleteWordHandler removes a banned word (admin)
+func DeleteWordHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid word ID"})
+		return
+	}
+
+	result, err := db.Exec("DELETE FROM chat_banned_words WHERE id = ?", id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete banned word"})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Word not found"})
+		return
+	}
+
+	refresh()
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+func refresh() {
+	if err := reload(); err != nil {
+		log.Printf("⚠️ Failed to reload banned words: %v", err)
+	}
+}
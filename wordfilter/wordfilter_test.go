@@ -0,0 +1,87 @@
+package wordfilter
+
+import (
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// setupWords loads list into a fresh in-memory filter, storing each word
+// the way AddWordHandler does
+func setupWords(t *testing.T, list ...string) {
+	t.Helper()
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	database.SetMaxOpenConns(1)
+	t.Cleanup(func() { database.Close() })
+
+	InitDB(database)
+	for _, w := range list {
+		if _, err := db.Exec("INSERT INTO chat_banned_words (word) VALUES (?)", normalize(w)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := reload(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	setupWords(t, "Spam", "ÉCOLE", "istanbul", "ငါး", "န", "ကူ")
+
+	tests := []struct {
+		name   string
+		text   string
+		masked string
+		found  bool
+	}{
+		{"latin case", "no SPAM here", "no **** here", true},
+		{"latin inside word", "spammer", "spammer", false},
+		{"accented upper case", "École!", "*****!", true},
+		{"dotted capital I", "İSTANBUL", "********", true},
+		{"burmese word", "ငါးကြော်စားမယ်", "***ကြော်စားမယ်", true},
+		{"burmese followed by asat", "ကန်", "ကန်", false},
+		{"burmese stacked consonant", "စက္ကူ", "စက္ကူ", false},
+		{"clean text", "မင်္ဂလာပါ", "မင်္ဂလာပါ", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			masked, found := Check(tt.text)
+			if masked != tt.masked || found != tt.found {
+				t.Errorf("Check(%q) = %q, %v; want %q, %v", tt.text, masked, found, tt.masked, tt.found)
+			}
+		})
+	}
+}
+
+func TestCheckMarkOnlyWord(t *testing.T) {
+	setupWords(t, "ါး")
+
+	if masked, found := Check("ငါး"); found {
+		t.Errorf("Check matched a word starting mid-syllable: %q", masked)
+	}
+}
+
+func TestNormalizeMatchesText(t *testing.T) {
+	for _, w := range []string{"ÉCOLE", "İSTANBUL", "ΣΊΣΥΦΟΣ", "ငါး"} {
+		if stored, scanned := normalize(" "+w+" "), string(foldRunes(w)); stored != scanned {
+			t.Errorf("normalize(%q) = %q, text folds to %q", w, stored, scanned)
+		}
+	}
+}
+
+func TestAllowRejectMode(t *testing.T) {
+	setupWords(t, "spam")
+	mode = ModeReject
+	t.Cleanup(func() { mode = ModeMask })
+
+	if _, ok := Allow("SPAM"); ok {
+		t.Error("Allow accepted a banned word in reject mode")
+	}
+	if text, ok := Allow("fine"); !ok || text != "fine" {
+		t.Errorf("Allow(%q) = %q, %v", "fine", text, ok)
+	}
+}